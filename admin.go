@@ -1,6 +1,12 @@
 package admin
 
-import "github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/auth"
+	"github.com/ovnicraft/go-advanced-admin/internal/orm/memory"
+	"github.com/ovnicraft/go-advanced-admin/internal/orm/sqldb"
+	"github.com/ovnicraft/go-advanced-admin/internal/web/nethttp"
+)
 
 // Version of the go-advanced-admin library
 const Version = "1.0.2"
@@ -8,6 +14,36 @@ const Version = "1.0.2"
 // ORMIntegrator defines the interface for ORM integrations with the admin panel.
 type ORMIntegrator = adminpanel.ORMIntegrator
 
+// PaginatedORMIntegrator is an optional extension of ORMIntegrator for integrators that paginate in the database.
+type PaginatedORMIntegrator = adminpanel.PaginatedORMIntegrator
+
+// ManyToManyORMIntegrator is an optional extension of ORMIntegrator for integrators that store many-to-many relations.
+type ManyToManyORMIntegrator = adminpanel.ManyToManyORMIntegrator
+
+// ManyToManyRelation declares a many-to-many relation between a model and another registered model.
+type ManyToManyRelation = adminpanel.ManyToManyRelation
+
+// ListQuery describes a page of instances requested from an ORM integrator by a list view.
+type ListQuery = adminpanel.ListQuery
+
+// OrderBy orders instances by a single field.
+type OrderBy = adminpanel.OrderBy
+
+// FilterCondition restricts a list query to the instances whose field satisfies an operator.
+type FilterCondition = adminpanel.FilterCondition
+
+// FilterOperator identifies how a FilterCondition compares a field with its value.
+type FilterOperator = adminpanel.FilterOperator
+
+// Filter operators used in FilterCondition.
+const (
+	FilterExact  = adminpanel.FilterExact
+	FilterGTE    = adminpanel.FilterGTE
+	FilterLTE    = adminpanel.FilterLTE
+	FilterLT     = adminpanel.FilterLT
+	FilterIsNull = adminpanel.FilterIsNull
+)
+
 // WebIntegrator defines the interface for web framework integrations with the admin panel.
 type WebIntegrator = adminpanel.WebIntegrator
 
@@ -20,6 +56,10 @@ type PermissionRequest = adminpanel.PermissionRequest
 // PermissionFunc defines a function type for checking permissions in the admin panel.
 type PermissionFunc = adminpanel.PermissionFunc
 
+// ListScopeFunc returns the filters restricting a list of instances of a model to those the user may read, see
+// Config.ListScope.
+type ListScopeFunc = adminpanel.ListScopeFunc
+
 // Panel represents the admin panel, which manages apps, models, and permissions.
 type Panel = adminpanel.AdminPanel
 
@@ -49,3 +89,279 @@ var NewErrorResponse = adminpanel.NewErrorResponse
 
 // NewSuccessResponse creates a new success response.
 var NewSuccessResponse = adminpanel.NewSuccessResponse
+
+// ForeignKeyField is a form field selecting an instance of a related model, used for fields tagged `admin:"fk:app.Model"`.
+type ForeignKeyField = adminpanel.ForeignKeyField
+
+// Inline registers a child model edited together with its parent model, see Model.RegisterInline.
+type Inline = adminpanel.Inline
+
+// ModelAction is a named bulk action run on the instances selected in a model's list view, see Model.RegisterAction.
+type ModelAction = adminpanel.ModelAction
+
+// ModelActionFunc runs a bulk action on the selected instance IDs and returns a message describing the result.
+type ModelActionFunc = adminpanel.ModelActionFunc
+
+// TransactionalORMIntegrator is an optional ORM extension running writes in a transaction.
+type TransactionalORMIntegrator = adminpanel.TransactionalORMIntegrator
+
+// WriteMode selects whether the writes of a multi-row operation are all-or-nothing or best effort.
+type WriteMode = adminpanel.WriteMode
+
+const (
+	// WriteModeAtomic applies every write of an operation or none of them.
+	WriteModeAtomic = adminpanel.WriteModeAtomic
+	// WriteModeBestEffort applies each write on its own.
+	WriteModeBestEffort = adminpanel.WriteModeBestEffort
+)
+
+// SQLIntegrator is a built-in ORMIntegrator storing models in an SQL database through database/sql.
+type SQLIntegrator = sqldb.Integrator
+
+// SQLDialect selects the SQL flavor of the queries generated by SQLIntegrator.
+type SQLDialect = sqldb.Dialect
+
+// SQL dialects supported by SQLIntegrator.
+const (
+	SQLDialectSQLite   = sqldb.DialectSQLite
+	SQLDialectPostgres = sqldb.DialectPostgres
+)
+
+// SQLTableNamer is implemented by models that choose the table SQLIntegrator stores them in.
+type SQLTableNamer = sqldb.TableNamer
+
+// NewSQLIntegrator creates a SQLIntegrator using the given database and dialect.
+var NewSQLIntegrator = sqldb.NewIntegrator
+
+// MemoryIntegrator is a built-in ORMIntegrator keeping instances in memory, for prototypes, examples and tests.
+type MemoryIntegrator = memory.Integrator
+
+// NewMemoryIntegrator creates an empty MemoryIntegrator. Use its Seed method to add initial instances.
+var NewMemoryIntegrator = memory.NewIntegrator
+
+// ErrMemoryInstanceNotFound is returned by MemoryIntegrator when no instance has the requested primary key.
+var ErrMemoryInstanceNotFound = memory.ErrNotFound
+
+// ContextORMIntegrator is an optional ORM extension binding operations to the context of the admin request.
+type ContextORMIntegrator = adminpanel.ContextORMIntegrator
+
+// ORMWithContext binds an ORM integrator to a context when it implements ContextORMIntegrator.
+var ORMWithContext = adminpanel.ORMWithContext
+
+// ContextWebIntegrator is an optional web extension handing over the context.Context of a request.
+type ContextWebIntegrator = adminpanel.ContextWebIntegrator
+
+// ValueWebIntegrator is an optional web extension keeping values for the duration of a request.
+type ValueWebIntegrator = adminpanel.ValueWebIntegrator
+
+// ContextPermissionFunc is a context-aware variant of PermissionFunc; its PermissionFunc method adapts it for NewPanel.
+type ContextPermissionFunc = adminpanel.ContextPermissionFunc
+
+// VersionedORMIntegrator is an optional ORM extension updating instances only if their version field is unchanged.
+type VersionedORMIntegrator = adminpanel.VersionedORMIntegrator
+
+// VersionConflict describes a field that differs between a stale edit form and the current instance.
+type VersionConflict = adminpanel.VersionConflict
+
+// ErrInstanceNotFound is wrapped by the errors ORM integrators return for missing instances.
+var ErrInstanceNotFound = adminpanel.ErrInstanceNotFound
+
+// ErrVersionConflict is returned when saving an instance that was changed since its version was fetched.
+var ErrVersionConflict = adminpanel.ErrVersionConflict
+
+// KeyCodec converts primary keys to the text identifying instances in URLs and back. Register one for a key type
+// with Panel.RegisterKeyCodec; TextMarshaler keys and composite struct keys are handled by default.
+type KeyCodec = adminpanel.KeyCodec
+
+// AggregateORMIntegrator is an optional ORM extension counting and aggregating instances in the database.
+type AggregateORMIntegrator = adminpanel.AggregateORMIntegrator
+
+// AggregateFunc identifies a statistic computed over the instances of a model.
+type AggregateFunc = adminpanel.AggregateFunc
+
+// Statistics computed by AggregateORMIntegrator and shown by StatTile.
+const (
+	AggregateCount = adminpanel.AggregateCount
+	AggregateSum   = adminpanel.AggregateSum
+	AggregateAvg   = adminpanel.AggregateAvg
+)
+
+// GroupCount is the number of instances sharing a value of the field they are grouped by.
+type GroupCount = adminpanel.GroupCount
+
+// StatTile configures a statistic tile on the dashboard, listed in Config.StatTiles.
+type StatTile = adminpanel.StatTile
+
+// Chart configures a server-rendered SVG chart on the dashboard, listed in Config.Charts.
+type Chart = adminpanel.Chart
+
+// ChartKind selects how a Chart is drawn.
+type ChartKind = adminpanel.ChartKind
+
+// Kinds of Chart.
+const (
+	ChartBar  = adminpanel.ChartBar
+	ChartLine = adminpanel.ChartLine
+)
+
+// ExportFormat identifies the file format of a list view export.
+type ExportFormat = adminpanel.ExportFormat
+
+// Formats of list view exports.
+const (
+	ExportCSV    = adminpanel.ExportCSV
+	ExportJSON   = adminpanel.ExportJSON
+	ExportNDJSON = adminpanel.ExportNDJSON
+)
+
+// FileWebIntegrator is an optional web extension reading uploaded files, used by the import page.
+type FileWebIntegrator = adminpanel.FileWebIntegrator
+
+// ImportRowAction is what an import does with a row of the imported file.
+type ImportRowAction = adminpanel.ImportRowAction
+
+// Actions of the rows of an import.
+const (
+	ImportCreate = adminpanel.ImportCreate
+	ImportUpdate = adminpanel.ImportUpdate
+	ImportError  = adminpanel.ImportError
+)
+
+// HTTPIntegrator is a built-in WebIntegrator serving the admin panel with an http.ServeMux of the standard library.
+type HTTPIntegrator = nethttp.Integrator
+
+// HTTPContext is the ctx handed by HTTPIntegrator to handlers, permission functions and user fetchers.
+type HTTPContext = nethttp.Context
+
+// NewHTTPIntegrator creates an HTTPIntegrator registering routes on the given mux, or on a new one if it is nil.
+var NewHTTPIntegrator = nethttp.NewIntegrator
+
+// Response is the response of a ResponseHandlerFunc, with its status, headers, cookies and body.
+type Response = adminpanel.Response
+
+// ResponseHandlerFunc handles a request with a Response.
+type ResponseHandlerFunc = adminpanel.ResponseHandlerFunc
+
+// ResponseWebIntegrator is an optional web extension sending a Response with its headers and cookies, such as list
+// exports.
+type ResponseWebIntegrator = adminpanel.ResponseWebIntegrator
+
+// NewHTMLResponse creates a Response with the given status code and HTML body.
+var NewHTMLResponse = adminpanel.NewHTMLResponse
+
+// NewRedirectResponse creates a 303 See Other Response redirecting to the given URL.
+var NewRedirectResponse = adminpanel.NewRedirectResponse
+
+// NewFileResponse creates a Response downloaded as a file of the given name.
+var NewFileResponse = adminpanel.NewFileResponse
+
+// NewHandlerResponse converts the result of a HandlerFunc to a Response.
+var NewHandlerResponse = adminpanel.NewHandlerResponse
+
+// AdaptResponseHandler adapts a ResponseHandlerFunc to a HandlerFunc, for integrators not sending a Response.
+var AdaptResponseHandler = adminpanel.AdaptResponseHandler
+
+// CSRFTokenStore issues the CSRF tokens of sessions and verifies the tokens sent back with requests changing data.
+type CSRFTokenStore = adminpanel.CSRFTokenStore
+
+// SessionIDFunc returns the ID of the session of a request, or an empty ID for requests without a session.
+type SessionIDFunc = adminpanel.SessionIDFunc
+
+// HeaderWebIntegrator is an optional web extension reading request headers, such as the CSRF header.
+type HeaderWebIntegrator = adminpanel.HeaderWebIntegrator
+
+// MemoryCSRFTokenStore is a CSRFTokenStore keeping a random token per session in memory.
+type MemoryCSRFTokenStore = adminpanel.MemoryCSRFTokenStore
+
+// NewMemoryCSRFTokenStore creates a MemoryCSRFTokenStore identifying sessions with the given function.
+var NewMemoryCSRFTokenStore = adminpanel.NewMemoryCSRFTokenStore
+
+// CookieCSRFTokenStore is a CSRFTokenStore keeping the token of each browser in a cookie, the default for web
+// integrators that can set cookies.
+type CookieCSRFTokenStore = adminpanel.CookieCSRFTokenStore
+
+// NewCookieCSRFTokenStore creates a CookieCSRFTokenStore setting its cookie with the given web integrator.
+var NewCookieCSRFTokenStore = adminpanel.NewCookieCSRFTokenStore
+
+// Names of the form field and header carrying the CSRF token.
+const (
+	CSRFFormField = adminpanel.CSRFFormField
+	CSRFHeader    = adminpanel.CSRFHeader
+)
+
+// ErrCSRFTokenInvalid is returned when a request changing data does not carry the CSRF token of its session.
+var ErrCSRFTokenInvalid = adminpanel.ErrCSRFTokenInvalid
+
+// FlashLevel is the level of a flash message.
+type FlashLevel = adminpanel.FlashLevel
+
+// Levels of flash messages.
+const (
+	FlashSuccess = adminpanel.FlashSuccess
+	FlashInfo    = adminpanel.FlashInfo
+	FlashWarning = adminpanel.FlashWarning
+	FlashError   = adminpanel.FlashError
+)
+
+// FlashMessage is a message kept for the next page shown to the user.
+type FlashMessage = adminpanel.FlashMessage
+
+// FlashStore keeps the flash messages of a user until the next page is shown to them.
+type FlashStore = adminpanel.FlashStore
+
+// CookieWebIntegrator is an optional web extension reading and setting cookies.
+type CookieWebIntegrator = adminpanel.CookieWebIntegrator
+
+// MemoryFlashStore is a FlashStore keeping the messages of each session in memory.
+type MemoryFlashStore = adminpanel.MemoryFlashStore
+
+// NewMemoryFlashStore creates a MemoryFlashStore identifying sessions with the given function.
+var NewMemoryFlashStore = adminpanel.NewMemoryFlashStore
+
+// CookieFlashStore is a FlashStore keeping the messages in a cookie of the browser.
+type CookieFlashStore = adminpanel.CookieFlashStore
+
+// NewCookieFlashStore creates a CookieFlashStore setting its cookie with the given web integrator.
+var NewCookieFlashStore = adminpanel.NewCookieFlashStore
+
+// Auth is an optional authentication module adding a login page and signed session cookies to an admin panel.
+type Auth = auth.Auth
+
+// NewAuth creates an Auth logging in the users of the given store, with session cookies signed with the given secret.
+var NewAuth = auth.New
+
+// AuthUser is a user who can log in to the admin panel through Auth.
+type AuthUser = auth.User
+
+// UserStore looks up the users who can log in through Auth.
+type UserStore = auth.UserStore
+
+// MemoryUserStore is a UserStore keeping its users in memory.
+type MemoryUserStore = auth.MemoryUserStore
+
+// NewMemoryUserStore creates an empty MemoryUserStore.
+var NewMemoryUserStore = auth.NewMemoryUserStore
+
+// ORMUserStore is a UserStore reading users from a model through an ORM integrator.
+type ORMUserStore = auth.ORMUserStore
+
+// NewORMUserStore creates an ORMUserStore reading users from the Username and PasswordHash fields of a model.
+var NewORMUserStore = auth.NewORMUserStore
+
+// RevokedSessionStore keeps the sessions of Auth ended before they expire, such as by logging out.
+type RevokedSessionStore = auth.RevokedSessionStore
+
+// MemoryRevokedSessionStore is a RevokedSessionStore keeping the revoked sessions in memory.
+type MemoryRevokedSessionStore = auth.MemoryRevokedSessionStore
+
+// NewMemoryRevokedSessionStore creates an empty MemoryRevokedSessionStore.
+var NewMemoryRevokedSessionStore = auth.NewMemoryRevokedSessionStore
+
+// PasswordHasher hashes passwords for storage and verifies passwords against stored hashes.
+type PasswordHasher = auth.PasswordHasher
+
+// PBKDF2Hasher is a PasswordHasher deriving keys with PBKDF2-HMAC-SHA256.
+type PBKDF2Hasher = auth.PBKDF2Hasher
+
+// ErrUnknownPasswordHash is returned when verifying a password against a hash of an unknown format.
+var ErrUnknownPasswordHash = auth.ErrUnknownPasswordHash
@@ -58,7 +58,11 @@ func main() {
 
 	orm := admingorm.NewIntegrator(db)
 
-	panel, err := admin.NewPanel(orm, web, permissionFunc, nil)
+	// The integrator cannot set cookies, and the example has no sessions to key CSRF tokens by, so CSRF protection is
+	// turned off. Real deployments should set config.CSRFTokenStore instead.
+	config := *admin.DefaultConfig
+	config.DisableCSRF = true
+	panel, err := admin.NewPanel(orm, web, permissionFunc, &config)
 	if err != nil {
 		log.Fatal(err)
 	}
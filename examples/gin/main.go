@@ -71,7 +71,11 @@ func main() {
 
 	// Create admin panel
 	// Wrap ORM integrator to satisfy newer interface methods if needed.
-	panel, err := admin.NewPanel(ormAdapter{ormIntegrator}, webIntegrator, permissionFunc, nil)
+	// The integrator cannot set cookies, and the example has no sessions to key CSRF tokens by, so CSRF protection is
+	// turned off. Real deployments should set config.CSRFTokenStore instead.
+	config := *admin.DefaultConfig
+	config.DisableCSRF = true
+	panel, err := admin.NewPanel(ormAdapter{ormIntegrator}, webIntegrator, permissionFunc, &config)
 	if err != nil {
 		log.Fatal("Failed to create admin panel:", err)
 	}
@@ -0,0 +1,260 @@
+package adminpanel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"github.com/ovnicraft/go-advanced-admin/internal/utils"
+	"net/http"
+	"strings"
+)
+
+// ModelActionFunc runs a bulk action on the instances with the given IDs and returns a message describing the
+// result. orm is the integrator the action should write through, bound to a transaction when the action runs in
+// WriteModeAtomic on a TransactionalORMIntegrator. ctx is the request context passed to the handler by the web
+// integrator.
+type ModelActionFunc func(orm ORMIntegrator, ids []interface{}, ctx interface{}) (string, error)
+
+// ModelAction is a named bulk action run on the instances selected in a model's list view.
+type ModelAction struct {
+	// Name identifies the action in requests, permission checks and logs.
+	Name string
+	// DisplayName labels the action in the list view. It defaults to the humanized name, with underscores read as
+	// spaces.
+	DisplayName string
+	// Confirm shows a confirmation page listing the selected instances before the action runs.
+	Confirm bool
+	// ConfirmationMessage is shown on the confirmation page instead of the default question.
+	ConfirmationMessage string
+	// Mode selects whether the writes of the action run in a transaction. It defaults to WriteModeAtomic.
+	Mode WriteMode
+	Func ModelActionFunc
+}
+
+// errUnknownAction is returned for requests naming an action that is not registered on the model.
+var errUnknownAction = errors.New("unknown action")
+
+// RegisterAction registers a bulk action on the model.
+func (m *Model) RegisterAction(action ModelAction) error {
+	if action.Name == "" {
+		return fmt.Errorf("admin model '%s' has an action without a name", m.Name)
+	}
+	if action.Func == nil {
+		return fmt.Errorf("action '%s' of admin model '%s' has no function", action.Name, m.Name)
+	}
+	if _, exists := m.getAction(action.Name); exists {
+		return fmt.Errorf("action '%s' is already registered on admin model '%s'", action.Name, m.Name)
+	}
+	if action.DisplayName == "" {
+		action.DisplayName = utils.HumanizeName(strings.ReplaceAll(action.Name, "_", " "))
+	}
+	if action.Mode == "" {
+		action.Mode = WriteModeAtomic
+	}
+	m.Actions = append(m.Actions, action)
+	return nil
+}
+
+// GetActionLink returns the full link of the endpoint running actions through AJAX.
+func (m *Model) GetActionLink() string {
+	return m.GetFullLink() + "/action"
+}
+
+// GetActionConfirmLink returns the full link of the action confirmation page.
+func (m *Model) GetActionConfirmLink() string {
+	return m.GetActionLink() + "/confirm"
+}
+
+func (m *Model) getAction(name string) (ModelAction, bool) {
+	for _, action := range m.Actions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return ModelAction{}, false
+}
+
+// getPermittedActions returns the actions the user may run.
+func (m *Model) getPermittedActions(data interface{}) ([]ModelAction, error) {
+	actions := make([]ModelAction, 0, len(m.Actions))
+	for _, action := range m.Actions {
+		allowed, err := m.App.Panel.PermissionChecker.HasModelActionPermission(m.App.Name, m.Name, action.Name, data)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			actions = append(actions, action)
+		}
+	}
+	return actions, nil
+}
+
+// prepareAction resolves an action requested by name, checks that the user may run it and parses the selected IDs.
+// The action is rejected as a whole unless the user may run it on every selected instance.
+func (m *Model) prepareAction(data interface{}, name string, idValues []string) (ModelAction, []interface{}, error) {
+	action, ok := m.getAction(name)
+	if !ok {
+		return ModelAction{}, nil, fmt.Errorf("%w '%s'", errUnknownAction, name)
+	}
+	allowed, err := m.App.Panel.PermissionChecker.HasModelActionPermission(m.App.Name, m.Name, action.Name, data)
+	if err != nil {
+		return ModelAction{}, nil, err
+	}
+	if !allowed {
+		return ModelAction{}, nil, fmt.Errorf("%w: you are not allowed to run %s", ErrPermissionDenied, action.DisplayName)
+	}
+	if len(idValues) == 0 {
+		return ModelAction{}, nil, fmt.Errorf("%w: no items selected", ErrInvalidInstanceID)
+	}
+	ids := make([]interface{}, 0, len(idValues))
+	for _, idValue := range idValues {
+		id, err := m.parseInstanceID(idValue)
+		if err != nil {
+			return ModelAction{}, nil, err
+		}
+		allowed, err = m.App.Panel.PermissionChecker.HasInstanceActionPermission(m.App.Name, m.Name, action.Name, id, data)
+		if err != nil {
+			return ModelAction{}, nil, err
+		}
+		if !allowed {
+			return ModelAction{}, nil, fmt.Errorf("%w: you are not allowed to run %s on item %s", ErrPermissionDenied, action.DisplayName, idValue)
+		}
+		ids = append(ids, id)
+	}
+	return action, ids, nil
+}
+
+// runAction runs an action on the selected IDs in the action's write mode and logs it.
+func (m *Model) runAction(data interface{}, action ModelAction, ids []interface{}) (string, error) {
+	var message string
+	err := m.runLoggedWrites(data, action.Mode, func(orm ORMIntegrator, logs *writeLogs) error {
+		var err error
+		message, err = action.Func(orm, ids, data)
+		if err != nil {
+			return err
+		}
+		logs.add(func() error { return m.CreateActionLog(data, action, ids, message) })
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return message, nil
+}
+
+// CreateActionLog creates a log entry when an action is run on the model.
+func (m *Model) CreateActionLog(ctx interface{}, action ModelAction, ids []interface{}, result string) error {
+	message, err := json.Marshal(map[string]interface{}{"action": action.Name, "ids": ids, "result": result})
+	if err != nil {
+		return err
+	}
+	return m.App.Panel.Config.CreateLog(ctx, logging.LogStoreLevelAction, fmt.Sprintf("%s | %s", m.App.Name, m.DisplayName), nil, action.DisplayName, string(message))
+}
+
+// getActionErrorCode returns the HTTP status code matching an error returned by Model.prepareAction.
+func getActionErrorCode(err error) uint {
+	if errors.Is(err, errUnknownAction) {
+		return http.StatusNotFound
+	}
+	return getRequestErrorCode(err)
+}
+
+// HandleActionAJAX handles AJAX requests running an action. The JSON body names the action under "action" and holds
+// the selected IDs under "ids".
+func (m *Model) HandleActionAJAX(ctx interface{}) error {
+	jsonBody, err := m.App.Panel.Web.GetJSONBody(ctx)
+	if err != nil {
+		response := NewErrorResponse([]string{"Invalid JSON data"})
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusBadRequest, response)
+	}
+
+	name, _ := jsonBody["action"].(string)
+	idsInterface, _ := jsonBody["ids"].([]interface{})
+	idValues := make([]string, len(idsInterface))
+	for i, idInterface := range idsInterface {
+		idValues[i] = fmt.Sprintf("%v", idInterface)
+	}
+
+	action, ids, err := m.prepareAction(ctx, name, idValues)
+	if err != nil {
+		response := NewErrorResponse([]string{err.Error()})
+		return m.App.Panel.Web.SetJSONResponse(ctx, int(getActionErrorCode(err)), response)
+	}
+	if action.Confirm && jsonBody["confirmed"] != true {
+		response := NewErrorResponse([]string{fmt.Sprintf("%s must be confirmed", action.DisplayName)})
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusBadRequest, response)
+	}
+
+	message, err := m.runAction(ctx, action, ids)
+	if err != nil {
+		response := NewErrorResponse([]string{err.Error()})
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusInternalServerError, response)
+	}
+
+	response := NewSuccessResponse(map[string]interface{}{
+		"action": action.Name,
+		"count":  len(ids),
+	}, message)
+	return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusOK, response)
+}
+
+// GetActionConfirmHandler returns the HTTP handler function of the action confirmation page. The posted form names
+// the action under "action" and holds the selected IDs under "ids"; the action runs once the form is posted again
+// with "confirmed" set.
+func (m *Model) GetActionConfirmHandler() HandlerFunc {
+	return func(data interface{}) (uint, string) {
+		formData := m.App.Panel.Web.GetFormData(data)
+		var name string
+		if values := formData["action"]; len(values) > 0 {
+			name = values[0]
+		}
+
+		action, ids, err := m.prepareAction(data, name, formData["ids"])
+		if err != nil {
+			return GetErrorHTML(getActionErrorCode(err), err)
+		}
+
+		instances := make([]Instance, 0, len(ids))
+		for _, id := range ids {
+			instance, err := m.getRequestORM(data).FetchInstance(m.PTR, id)
+			if err != nil || isNilInstance(instance) {
+				continue
+			}
+			instances = append(instances, Instance{InstanceID: id, Data: instance, Model: m})
+		}
+
+		var result string
+		if values := formData["confirmed"]; len(values) > 0 && values[0] != "" {
+			result, err = m.runAction(data, action, ids)
+			if err != nil {
+				return GetErrorHTML(http.StatusInternalServerError, err)
+			}
+		}
+
+		confirmationMessage := action.ConfirmationMessage
+		if confirmationMessage == "" {
+			confirmationMessage = fmt.Sprintf("Are you sure you want to run \"%s\" on the following %d items?", action.DisplayName, len(ids))
+		}
+
+		apps, err := GetAppsWithReadPermissions(m.App.Panel, data)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		html, err := m.App.Panel.RenderPage(data, "action_confirm", map[string]interface{}{
+			"admin":               m.App.Panel,
+			"apps":                apps,
+			"navBarItems":         m.App.Panel.Config.GetNavBarItems(data),
+			"model":               m,
+			"action":              action,
+			"ids":                 formData["ids"],
+			"instances":           instances,
+			"confirmationMessage": confirmationMessage,
+			"result":              result,
+		})
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		return http.StatusOK, html
+	}
+}
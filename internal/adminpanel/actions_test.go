@@ -0,0 +1,189 @@
+package adminpanel
+
+import (
+	"errors"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func registerActionTestModel(t *testing.T) (*Model, *MockWebIntegrator, *[]interface{}) {
+	model, _, web := registerPaginatedTestModel(t, 3)
+	shipped := make([]interface{}, 0)
+	err := model.RegisterAction(ModelAction{
+		Name: "mark_shipped",
+		Func: func(_ ORMIntegrator, ids []interface{}, _ interface{}) (string, error) {
+			shipped = append(shipped, ids...)
+			return "2 orders marked as shipped", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = model.RegisterAction(ModelAction{
+		Name:    "resend_invoice",
+		Confirm: true,
+		Func: func(_ ORMIntegrator, ids []interface{}, _ interface{}) (string, error) {
+			return "invoices sent", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model, web, &shipped
+}
+
+func TestModel_RegisterAction(t *testing.T) {
+	model, _, _ := registerActionTestModel(t)
+
+	if model.Actions[0].DisplayName != "Mark Shipped" {
+		t.Errorf("expected the display name to default to the humanized name, got %q", model.Actions[0].DisplayName)
+	}
+	if err := model.RegisterAction(ModelAction{Name: "mark_shipped", Func: model.Actions[0].Func}); err == nil {
+		t.Error("expected an error for a duplicate action")
+	}
+	if err := model.RegisterAction(ModelAction{Name: "noop"}); err == nil {
+		t.Error("expected an error for an action without a function")
+	}
+}
+
+func TestModel_HandleActionAJAX(t *testing.T) {
+	model, web, shipped := registerActionTestModel(t)
+
+	err := model.HandleActionAJAX(map[string]interface{}{"action": "mark_shipped", "ids": []interface{}{float64(1), "2"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if web.JSONStatus != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %+v", http.StatusOK, web.JSONStatus, web.JSONResponse)
+	}
+	if !reflect.DeepEqual(*shipped, []interface{}{uint(1), uint(2)}) {
+		t.Errorf("expected the action to receive IDs 1 and 2, got %v", *shipped)
+	}
+	if response := web.JSONResponse.(JSONResponse); response.Message != "2 orders marked as shipped" {
+		t.Errorf("expected the result message, got %+v", response)
+	}
+
+	entries, _ := model.App.Panel.Config.LogStore.GetLogEntries()
+	if len(entries) != 1 || entries[0].ActionFlag != logging.LogStoreLevelAction || entries[0].ObjectRepr != "Mark Shipped" {
+		t.Errorf("expected a single action log entry, got %+v", entries)
+	}
+
+	tests := []struct {
+		name     string
+		body     map[string]interface{}
+		expected int
+	}{
+		{"Unknown", map[string]interface{}{"action": "archive", "ids": []interface{}{"1"}}, http.StatusNotFound},
+		{"NoSelection", map[string]interface{}{"action": "mark_shipped"}, http.StatusBadRequest},
+		{"InvalidID", map[string]interface{}{"action": "mark_shipped", "ids": []interface{}{"abc"}}, http.StatusBadRequest},
+		{"Unconfirmed", map[string]interface{}{"action": "resend_invoice", "ids": []interface{}{"1"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := model.HandleActionAJAX(tt.body); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if web.JSONStatus != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, web.JSONStatus)
+			}
+		})
+	}
+}
+
+func TestModel_HandleActionAJAX_Permission(t *testing.T) {
+	model, web, shipped := registerActionTestModel(t)
+	model.App.Panel.PermissionChecker = func(request PermissionRequest, _ interface{}) (bool, error) {
+		if *request.Action == ExecuteAction {
+			if request.ActionName == nil {
+				return false, errors.New("expected the action name")
+			}
+			return *request.ActionName != "mark_shipped", nil
+		}
+		return true, nil
+	}
+
+	if err := model.HandleActionAJAX(map[string]interface{}{"action": "mark_shipped", "ids": []interface{}{"1"}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if web.JSONStatus != http.StatusForbidden {
+		t.Errorf("expected %v, got %v", http.StatusForbidden, web.JSONStatus)
+	}
+	if len(*shipped) != 0 {
+		t.Error("expected the action not to run")
+	}
+
+	actions, err := model.getPermittedActions(nil)
+	if err != nil || len(actions) != 1 || actions[0].Name != "resend_invoice" {
+		t.Errorf("expected only the permitted action to be offered, got %v (%v)", actions, err)
+	}
+}
+
+func TestModel_HandleActionAJAX_InstancePermission(t *testing.T) {
+	model, web, shipped := registerActionTestModel(t)
+	model.App.Panel.PermissionChecker = func(request PermissionRequest, _ interface{}) (bool, error) {
+		return *request.Action != ExecuteAction || request.InstanceID != uint(2), nil
+	}
+
+	if err := model.HandleActionAJAX(map[string]interface{}{"action": "mark_shipped", "ids": []interface{}{"1", "2"}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if web.JSONStatus != http.StatusForbidden {
+		t.Errorf("expected %v when an instance may not be acted on, got %v", http.StatusForbidden, web.JSONStatus)
+	}
+	if len(*shipped) != 0 {
+		t.Errorf("expected the action not to run, got %v", *shipped)
+	}
+
+	code, _ := model.GetActionConfirmHandler()(&MockRequest{Method: http.MethodPost, Form: map[string][]string{"action": {"mark_shipped"}, "ids": {"2"}, "confirmed": {"1"}}})
+	if code != http.StatusForbidden || len(*shipped) != 0 {
+		t.Errorf("expected the confirmed action to be rejected too, got %v %v", code, *shipped)
+	}
+}
+
+func TestModel_GetActionConfirmHandler(t *testing.T) {
+	model, _, _ := registerActionTestModel(t)
+	handler := model.GetActionConfirmHandler()
+
+	code, body := handler(map[string][]string{"action": {"resend_invoice"}, "ids": {"1", "3"}})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if !strings.Contains(body, "on the following 2 items") || !strings.Contains(body, `name="confirmed" value="true"`) {
+		t.Error("expected the confirmation question and form")
+	}
+	if !strings.Contains(body, `href="/admin/a/TestApp/TestModel/3/view"`) {
+		t.Error("expected the selected instances to be listed")
+	}
+	if entries, _ := model.App.Panel.Config.LogStore.GetLogEntries(); len(entries) != 0 {
+		t.Error("expected the action not to run before confirmation")
+	}
+
+	code, body = handler(map[string][]string{"action": {"resend_invoice"}, "ids": {"1", "3"}, "confirmed": {"true"}})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if !strings.Contains(body, "invoices sent") {
+		t.Error("expected the result message to be shown")
+	}
+	if entries, _ := model.App.Panel.Config.LogStore.GetLogEntries(); len(entries) != 1 {
+		t.Errorf("expected the confirmed action to be logged, got %d entries", len(entries))
+	}
+}
+
+func TestModel_GetViewHandler_ActionDropdown(t *testing.T) {
+	model, _, _ := registerActionTestModel(t)
+
+	code, body := model.GetViewHandler()(map[string]string{})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if !strings.Contains(body, `<option value="mark_shipped">Mark Shipped</option>`) {
+		t.Error("expected the action dropdown to offer mark_shipped")
+	}
+	if !strings.Contains(body, `<option value="resend_invoice" data-confirm="true">`) {
+		t.Error("expected actions needing confirmation to be flagged")
+	}
+}
@@ -0,0 +1,385 @@
+package adminpanel
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetAPILink returns the relative URL path of the JSON API of the model.
+func (m *Model) GetAPILink() string {
+	return m.App.Panel.Config.GetAPIPrefix() + m.GetLink()
+}
+
+// GetFullAPILink returns the full URL path of the JSON API of the model, including the admin prefix.
+func (m *Model) GetFullAPILink() string {
+	return m.App.Panel.Config.GetLink(m.GetAPILink())
+}
+
+// registerAPIRoutes registers the routes of the JSON API of the model: the collection lists and creates instances,
+// and each instance can be retrieved, partially updated and deleted.
+func (m *Model) registerAPIRoutes() {
+	web := m.App.Panel.Web
+	link := m.App.Panel.Config.GetPrefix() + m.GetAPILink()
+	web.HandleJSONRoute("GET", link, m.HandleAPIList)
+	web.HandleJSONRoute("POST", link, m.App.Panel.csrfJSONHandler(m.HandleAPICreate))
+	web.HandleJSONRoute("GET", link+"/:id", m.HandleAPIRetrieve)
+	web.HandleJSONRoute("PATCH", link+"/:id", m.App.Panel.csrfJSONHandler(m.HandleAPIUpdate))
+	web.HandleJSONRoute("DELETE", link+"/:id", m.App.Panel.csrfJSONHandler(m.HandleAPIDelete))
+}
+
+// setAPIError sends an error response of the JSON API.
+func (m *Model) setAPIError(ctx interface{}, code uint, errs ...string) error {
+	return m.App.Panel.Web.SetJSONResponse(ctx, int(code), NewErrorResponse(errs))
+}
+
+// getAPIFields returns the names of the fields of the model matching include, in declaration order.
+func (m *Model) getAPIFields(include func(fieldConfig FieldConfig) bool) []string {
+	fields := make([]string, 0, len(m.Fields))
+	for _, fieldConfig := range m.Fields {
+		if include(fieldConfig) {
+			fields = append(fields, fieldConfig.Name)
+		}
+	}
+	return fields
+}
+
+// apiObject returns the JSON API representation of an instance, holding the values of the given fields.
+func (m *Model) apiObject(instanceID interface{}, instanceData interface{}, fields []string) map[string]interface{} {
+	instance := &Instance{InstanceID: instanceID, Data: instanceData, Model: m}
+	values := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		var value interface{}
+		if fieldValue := fieldValueOf(instanceData, name); fieldValue.IsValid() {
+			value = fieldValue.Interface()
+		}
+		values[name] = value
+	}
+	return map[string]interface{}{
+		"id":     instanceID,
+		"key":    instance.GetEncodedID(),
+		"repr":   instance.GetRepr(),
+		"fields": values,
+	}
+}
+
+// getAPIInstanceID parses the ID of the instance an API request is about, from the "id" path parameter.
+func (m *Model) getAPIInstanceID(ctx interface{}) (interface{}, error) {
+	instanceIDStr := m.App.Panel.Web.GetPathParam(ctx, "id")
+	if instanceIDStr == "" {
+		return nil, fmt.Errorf("%w: instance id is required", ErrInvalidInstanceID)
+	}
+	return m.parseInstanceID(instanceIDStr)
+}
+
+// getAPIFormValues converts the JSON object of a request to the form values of f, and returns the names of the
+// fields it sets. Keys that are not fields of f are rejected.
+func getAPIFormValues(f form.Form, body map[string]interface{}) (map[string]form.HTMLType, map[string]bool, error) {
+	names := make(map[string]bool)
+	for _, field := range f.GetFields() {
+		names[field.GetName()] = true
+	}
+	formData := make(map[string][]string, len(body))
+	given := make(map[string]bool, len(body))
+	for key, value := range body {
+		if !names[key] {
+			return nil, nil, fmt.Errorf("unknown field %q", key)
+		}
+		values, err := jsonFormValues(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %q: %w", key, err)
+		}
+		formData[key] = values
+		given[key] = true
+	}
+	values, err := form.ConvertFormDataToHTMLTypeMap(formData)
+	return values, given, err
+}
+
+// jsonFormValues converts a JSON value to the form values of a field: a value per element for arrays, and a single
+// value otherwise.
+func jsonFormValues(value interface{}) ([]string, error) {
+	elements, ok := value.([]interface{})
+	if !ok {
+		formValue, err := jsonFormValue(value)
+		return []string{formValue}, err
+	}
+	values := make([]string, len(elements))
+	for i, element := range elements {
+		formValue, err := jsonFormValue(element)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = formValue
+	}
+	return values, nil
+}
+
+// jsonFormValue converts a JSON scalar to a form value. Null is an empty value.
+func jsonFormValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case json.Number:
+		return value.String(), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", value)
+}
+
+// HandleAPIList lists the instances of the model the user may read. It takes the query parameters of the list view
+// for pagination, search, filters and ordering, and returns the list columns of each instance.
+func (m *Model) HandleAPIList(ctx interface{}) error {
+	allowed, err := m.App.Panel.PermissionChecker.HasModelReadPermission(m.App.Name, m.Name, ctx)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return m.setAPIError(ctx, http.StatusForbidden, "Permission denied")
+	}
+
+	params := getListParams(m, ctx)
+	instances, totalCount, exactCount, err := fetchListPage(m, ctx, params.query(m, time.Now()))
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	fields := m.getAPIFields(func(fieldConfig FieldConfig) bool { return fieldConfig.IncludeInListDisplay })
+	objects := make([]map[string]interface{}, len(instances))
+	for i, instance := range instances {
+		instanceID, err := m.GetPrimaryKeyValue(instance)
+		if err != nil {
+			return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+		}
+		objects[i] = m.apiObject(instanceID, instance, fields)
+	}
+
+	response := NewSuccessResponse(map[string]interface{}{
+		"instances":  objects,
+		"total":      totalCount,
+		"exactTotal": exactCount,
+		"page":       params.Page,
+		"perPage":    params.PerPage,
+		"totalPages": (totalCount + params.PerPage - 1) / params.PerPage,
+	}, "")
+	return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusOK, response)
+}
+
+// HandleAPIRetrieve returns the fields of an instance shown on its page, and the keys of its related instances.
+func (m *Model) HandleAPIRetrieve(ctx interface{}) error {
+	instanceID, err := m.getAPIInstanceID(ctx)
+	if err != nil {
+		return m.setAPIError(ctx, getRequestErrorCode(err), err.Error())
+	}
+	allowed, err := m.App.Panel.PermissionChecker.HasInstanceReadPermission(m.App.Name, m.Name, instanceID, ctx)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return m.setAPIError(ctx, http.StatusForbidden, "Permission denied")
+	}
+
+	fields := m.getAPIFields(func(fieldConfig FieldConfig) bool { return fieldConfig.IncludeInInstanceView })
+	instanceData, err := m.fetchLiveInstance(m.getRequestORM(ctx), instanceID, fields)
+	if err != nil {
+		return m.setAPIError(ctx, getRequestErrorCode(err), err.Error())
+	}
+	object := m.apiObject(instanceID, instanceData, fields)
+	relatedIDValues, err := m.fetchRelatedIDValues(ctx, instanceID)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	for name, value := range relatedIDValues {
+		object["fields"].(map[string]interface{})[name] = value
+	}
+
+	instance := &Instance{InstanceID: instanceID, Data: instanceData, Model: m}
+	if err = instance.CreateViewLog(ctx); err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusOK, NewSuccessResponse(object, ""))
+}
+
+// HandleAPICreate creates an instance from the JSON object of the request, validated by the add form. Missing fields
+// are empty. It responds with 201 Created and the new instance.
+func (m *Model) HandleAPICreate(ctx interface{}) error {
+	allowed, err := m.App.Panel.PermissionChecker.HasModelCreatePermission(m.App.Name, m.Name, ctx)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return m.setAPIError(ctx, http.StatusForbidden, "Permission denied")
+	}
+
+	body, err := m.App.Panel.Web.GetJSONBody(ctx)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusBadRequest, "Invalid JSON data")
+	}
+	addForm, err := m.NewAddForm(ctx)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	values, _, err := getAPIFormValues(addForm, body)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusBadRequest, err.Error())
+	}
+	converted, conversionErrs := convertFormValues(addForm, values, nil)
+	errs, err := validateFormValues(addForm, converted, conversionErrs, nil)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	if len(errs) > 0 {
+		return m.setAPIError(ctx, http.StatusBadRequest, errs...)
+	}
+
+	var instance *Instance
+	err = m.runLoggedWrites(ctx, m.SaveMode, func(orm ORMIntegrator, logs *writeLogs) error {
+		setFormORM(addForm, orm)
+		instanceData, err := addForm.Save(values)
+		if err != nil {
+			return err
+		}
+		instanceID, err := m.GetPrimaryKeyValue(instanceData)
+		if err != nil {
+			return err
+		}
+		instance = &Instance{InstanceID: instanceID, Data: instanceData, Model: m}
+		logs.add(func() error { return instance.CreateCreateLog(ctx) })
+		return nil
+	})
+	if err != nil {
+		return m.setAPIError(ctx, getRequestErrorCode(err), err.Error())
+	}
+
+	fields := m.getAPIFields(func(fieldConfig FieldConfig) bool { return fieldConfig.IncludeInInstanceView })
+	response := NewSuccessResponse(m.apiObject(instance.InstanceID, instance.Data, fields), "Item created successfully")
+	return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusCreated, response)
+}
+
+// HandleAPIUpdate updates the fields of an instance given in the JSON object of the request, validated by the edit
+// form; the other fields are left untouched. For versioned models, the object may hold the version the client last
+// read under the name of the version field, and the update fails with 409 Conflict if the instance was saved since.
+func (m *Model) HandleAPIUpdate(ctx interface{}) error {
+	instanceID, err := m.getAPIInstanceID(ctx)
+	if err != nil {
+		return m.setAPIError(ctx, getRequestErrorCode(err), err.Error())
+	}
+	allowed, err := m.App.Panel.PermissionChecker.HasInstanceUpdatePermission(m.App.Name, m.Name, instanceID, ctx)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return m.setAPIError(ctx, http.StatusForbidden, "Permission denied")
+	}
+
+	body, err := m.App.Panel.Web.GetJSONBody(ctx)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusBadRequest, "Invalid JSON data")
+	}
+	current, err := m.fetchEditInstance(ctx, instanceID)
+	if err != nil {
+		return m.setAPIError(ctx, getRequestErrorCode(err), err.Error())
+	}
+	var version interface{}
+	if m.VersionField != "" {
+		version = m.getVersion(current)
+		if submitted, ok := body[m.VersionField]; ok {
+			delete(body, m.VersionField)
+			encoded, err := jsonFormValue(submitted)
+			if err == nil {
+				version, err = m.decodeVersion(encoded)
+			}
+			if err != nil {
+				return m.setAPIError(ctx, http.StatusBadRequest, fmt.Sprintf("invalid version: %v", err))
+			}
+		}
+	}
+
+	editForm, err := m.NewEditForm(ctx, instanceID)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	values, given, err := getAPIFormValues(editForm, body)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusBadRequest, err.Error())
+	}
+	updates, conversionErrs := convertFormValues(editForm, values, given)
+	merged := m.getEditInitialValues(current)
+	relatedIDValues, err := m.fetchRelatedIDValues(ctx, instanceID)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	for name, value := range relatedIDValues {
+		merged[name] = value
+	}
+	if err = editForm.RegisterInitialValues(merged); err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	for name, value := range updates {
+		merged[name] = value
+	}
+	errs, err := validateFormValues(editForm, merged, conversionErrs, given)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	if len(errs) > 0 {
+		return m.setAPIError(ctx, http.StatusBadRequest, errs...)
+	}
+
+	err = m.runLoggedWrites(ctx, m.SaveMode, func(orm ORMIntegrator, logs *writeLogs) error {
+		instanceData, err := m.updateInstanceFields(orm, instanceID, updates, version)
+		if err != nil {
+			return err
+		}
+		instance := &Instance{InstanceID: instanceID, Data: instanceData, Model: m}
+		logs.add(func() error { return instance.CreateUpdateLog(ctx, updates) })
+		return nil
+	})
+	if err != nil {
+		return m.setAPIError(ctx, getRequestErrorCode(err), err.Error())
+	}
+
+	updated, err := m.fetchEditInstance(ctx, instanceID)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	fields := m.getAPIFields(func(fieldConfig FieldConfig) bool { return fieldConfig.IncludeInInstanceView })
+	response := NewSuccessResponse(m.apiObject(instanceID, updated, fields), "Item updated successfully")
+	return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusOK, response)
+}
+
+// HandleAPIDelete deletes an instance, or moves it to the trash for soft-deleted models.
+func (m *Model) HandleAPIDelete(ctx interface{}) error {
+	instanceID, err := m.getAPIInstanceID(ctx)
+	if err != nil {
+		return m.setAPIError(ctx, getRequestErrorCode(err), err.Error())
+	}
+	allowed, err := m.App.Panel.PermissionChecker.HasInstanceDeletePermission(m.App.Name, m.Name, instanceID, ctx)
+	if err != nil {
+		return m.setAPIError(ctx, http.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return m.setAPIError(ctx, http.StatusForbidden, "Permission denied")
+	}
+
+	err = m.runLoggedWrites(ctx, m.SaveMode, func(orm ORMIntegrator, logs *writeLogs) error {
+		if err := m.deleteInstance(orm, instanceID, orm.DeleteByID); err != nil {
+			return err
+		}
+		instance := &Instance{InstanceID: instanceID, Model: m}
+		logs.add(func() error { return instance.CreateDeleteLog(ctx) })
+		return nil
+	})
+	if err != nil {
+		return m.setAPIError(ctx, getRequestErrorCode(err), err.Error())
+	}
+	response := NewSuccessResponse(map[string]interface{}{"id": instanceID}, "Item deleted successfully")
+	return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusOK, response)
+}
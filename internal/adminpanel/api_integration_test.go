@@ -0,0 +1,96 @@
+package adminpanel_test
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestModel_APIReads(t *testing.T) {
+	model, orm := registerProductModel(t)
+	if err := orm.Seed(&adminpanel.Product{ID: 2, Name: "Gadget", Stock: 7}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	status, response := callAPI(t, model, model.HandleAPIList, &adminpanel.MockRequest{Method: http.MethodGet, Params: map[string]string{"page": "1"}})
+	if status != http.StatusOK || !response.Success {
+		t.Fatalf("expected a successful list, got %v %v", status, response.Errors)
+	}
+	data := response.Data.(map[string]interface{})
+	instances := data["instances"].([]map[string]interface{})
+	if data["total"] != uint(2) || data["totalPages"] != uint(1) || len(instances) != 2 || instances[1]["id"] != uint(2) {
+		t.Fatalf("expected both products to be listed, got %v", data)
+	}
+	if fields := instances[1]["fields"].(map[string]interface{}); fields["Name"] != "Gadget" || fields["Stock"] != 7 {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	status, response = callAPI(t, model, model.HandleAPIRetrieve, &adminpanel.MockRequest{Method: http.MethodGet, Params: map[string]string{"id": "1"}})
+	if status != http.StatusOK || response.Data.(map[string]interface{})["repr"] == "" {
+		t.Errorf("expected the product to be retrieved, got %v %v", status, response.Errors)
+	}
+	if countLogs(t, model, logging.LogStoreLevelInstanceView) != 1 {
+		t.Error("expected the retrieval to be logged as a view")
+	}
+	if status, _ = callAPI(t, model, model.HandleAPIRetrieve, &adminpanel.MockRequest{Method: http.MethodGet, Params: map[string]string{"id": "x"}}); status != http.StatusBadRequest {
+		t.Errorf("expected %v for an invalid id, got %v", http.StatusBadRequest, status)
+	}
+
+	model.App.Panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return request.ModelName == nil, nil
+	}
+	if status, _ = callAPI(t, model, model.HandleAPIList, &adminpanel.MockRequest{Method: http.MethodGet}); status != http.StatusForbidden {
+		t.Errorf("expected %v without the read permission, got %v", http.StatusForbidden, status)
+	}
+}
+
+func TestModel_APIWrites(t *testing.T) {
+	model, orm := registerProductModel(t)
+
+	status, response := callAPI(t, model, model.HandleAPICreate, &adminpanel.MockRequest{Method: http.MethodPost, Body: map[string]interface{}{"Name": "Gadget", "Stock": float64(2)}})
+	if products := storedRows[adminpanel.Product](t, orm); status != http.StatusCreated || len(products) != 2 || products[1].Stock != 2 {
+		t.Fatalf("expected the product to be created, got %v %v", status, response.Errors)
+	}
+	if response.Data.(map[string]interface{})["id"] != uint(2) {
+		t.Errorf("expected the new product to be returned, got %v", response.Data)
+	}
+
+	status, response = callAPI(t, model, model.HandleAPICreate, &adminpanel.MockRequest{Method: http.MethodPost, Body: map[string]interface{}{"Stock": "many"}})
+	if status != http.StatusBadRequest || len(response.Errors) != 2 || !strings.HasPrefix(response.Errors[0], "Name:") || !strings.HasPrefix(response.Errors[1], "Stock:") {
+		t.Errorf("expected the validation errors of each field, got %v %v", status, response.Errors)
+	}
+	if status, _ = callAPI(t, model, model.HandleAPICreate, &adminpanel.MockRequest{Method: http.MethodPost, Body: map[string]interface{}{"Price": float64(1)}}); status != http.StatusBadRequest {
+		t.Errorf("expected %v for an unknown field, got %v", http.StatusBadRequest, status)
+	}
+
+	status, response = callAPI(t, model, model.HandleAPIUpdate, &adminpanel.MockRequest{Method: http.MethodPatch, Params: map[string]string{"id": "1"}, Body: map[string]interface{}{"Stock": float64(9)}})
+	if product := storedRows[adminpanel.Product](t, orm)[0]; status != http.StatusOK || product.Stock != 9 || product.Name != "Widget" {
+		t.Fatalf("expected the stock only to be updated, got %v %v %v", status, response.Errors, product)
+	}
+	if !reflect.DeepEqual(orm.UpdatedFields, [][]string{{"Stock"}}) {
+		t.Errorf("expected the given fields only to be saved, got %v", orm.UpdatedFields)
+	}
+	if status, _ = callAPI(t, model, model.HandleAPIUpdate, &adminpanel.MockRequest{Method: http.MethodPatch, Params: map[string]string{"id": "1"}, Body: map[string]interface{}{"Name": nil}}); status != http.StatusBadRequest {
+		t.Errorf("expected %v when clearing a required field, got %v", http.StatusBadRequest, status)
+	}
+
+	status, _ = callAPI(t, model, model.HandleAPIDelete, &adminpanel.MockRequest{Method: http.MethodDelete, Params: map[string]string{"id": "1"}})
+	if status != http.StatusOK {
+		t.Errorf("expected the product to be deleted, got %v", status)
+	}
+	for level, expected := range map[logging.LogStoreLevel]int{logging.LogStoreLevelCreate: 1, logging.LogStoreLevelUpdate: 1, logging.LogStoreLevelDelete: 1} {
+		if count := countLogs(t, model, level); count != expected {
+			t.Errorf("expected %d %s log entries, got %d", expected, level, count)
+		}
+	}
+
+	model.App.Panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return *request.Action == adminpanel.ReadAction, nil
+	}
+	if status, _ = callAPI(t, model, model.HandleAPIUpdate, &adminpanel.MockRequest{Method: http.MethodPatch, Params: map[string]string{"id": "1"}, Body: map[string]interface{}{}}); status != http.StatusForbidden {
+		t.Errorf("expected %v without the update permission, got %v", http.StatusForbidden, status)
+	}
+}
@@ -0,0 +1,60 @@
+package adminpanel
+
+import (
+	"strings"
+	"testing"
+)
+
+// registerAPITestModel registers the product model on a panel serving the JSON API under "api", and its OpenAPI
+// document at "openapi.json".
+func registerAPITestModel(t *testing.T) *Model {
+	config := *NewDefaultAdminConfig()
+	config.APIPrefix = "api"
+	config.OpenAPIPath = "openapi.json"
+	panel, err := NewAdminPanel(&MockORMIntegrator{}, &MockWebIntegrator{}, MockPermissionFunc, &config)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("Shop", "Shop", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	model, err := testApp.RegisterModel(&Product{}, &MockORMIntegrator{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model
+}
+
+func TestRegisterModel_APIRoutes(t *testing.T) {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, _ := panel.RegisterApp("Shop", "Shop", nil)
+	if _, err = testApp.RegisterModel(&Product{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, route := range panel.Web.(*MockWebIntegrator).Routes {
+		if strings.Contains(route, "/api/") {
+			t.Errorf("expected the API to be disabled by default, got route %q", route)
+		}
+	}
+
+	model := registerAPITestModel(t)
+	web := model.App.Panel.Web.(*MockWebIntegrator)
+	for _, route := range []string{
+		"GET /admin/api/a/Shop/Product",
+		"POST /admin/api/a/Shop/Product",
+		"GET /admin/api/a/Shop/Product/:id",
+		"PATCH /admin/api/a/Shop/Product/:id",
+		"DELETE /admin/api/a/Shop/Product/:id",
+	} {
+		if !containsString(web.Routes, route) {
+			t.Errorf("expected route %q to be registered, got %v", route, web.Routes)
+		}
+	}
+	if link := model.GetFullAPILink(); link != "/admin/api/a/Shop/Product" {
+		t.Errorf("unexpected API link %s", link)
+	}
+}
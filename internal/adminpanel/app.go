@@ -73,6 +73,7 @@ func (a *App) RegisterModel(model interface{}, orm ORMIntegrator) (*Model, error
 	}
 
 	var fieldConfigs []FieldConfig
+	var versionField, softDeleteField, primaryKeyField string
 	for i := 0; i < modelType.NumField(); i++ {
 		field := modelType.Field(i)
 		fieldName := field.Name
@@ -90,9 +91,32 @@ func (a *App) RegisterModel(model interface{}, orm ORMIntegrator) (*Model, error
 		if err != nil {
 			return nil, err
 		}
+		if opts.primaryKey || (fieldName == "ID" && primaryKeyField == "") {
+			primaryKeyField = fieldName
+		}
+		if opts.version {
+			if versionField != "" {
+				return nil, fmt.Errorf("admin model '%s' has more than one version field", name)
+			}
+			if !isVersionType(fieldType) {
+				return nil, fmt.Errorf("version field '%s' of admin model '%s' must be an integer or a time.Time, got %s", fieldName, name, fieldType)
+			}
+			versionField = fieldName
+		}
+		if opts.softDelete {
+			if softDeleteField != "" {
+				return nil, fmt.Errorf("admin model '%s' has more than one soft-delete field", name)
+			}
+			if !isSoftDeleteType(fieldType) {
+				return nil, fmt.Errorf("soft-delete field '%s' of admin model '%s' must be a *time.Time or a bool, got %s", fieldName, name, fieldType)
+			}
+			softDeleteField = fieldName
+		}
 
 		var formField form.Field
-		if opts.includeInAddForm || opts.includeInEditForm {
+		if opts.foreignKey != "" && (opts.includeInAddForm || opts.includeInEditForm) {
+			formField = configureForeignKeyField(tag, a.Panel, opts.foreignKey, underlyingType)
+		} else if opts.includeInAddForm || opts.includeInEditForm {
 			formField, err = buildFormField(underlyingType, fieldType, tag)
 			if err != nil {
 				return nil, err
@@ -130,26 +154,65 @@ func (a *App) RegisterModel(model interface{}, orm ORMIntegrator) (*Model, error
 			IncludeInListFetch:    opts.includeInFetch,
 			IncludeInSearch:       opts.includeInSearch,
 			IncludeInInstanceView: opts.includeInInstanceView,
+			Sortable:              opts.sortable,
+			Filterable:            opts.filterable,
+			ForeignKey:            opts.foreignKey,
 			AddFormField:          formAddField,
 			EditFormField:         formEditField,
 		})
 	}
 
 	modelInstance := &Model{
-		Name:        name,
-		DisplayName: displayName,
-		PTR:         model,
-		App:         a,
-		Fields:      fieldConfigs,
-		ORM:         orm,
+		Name:            name,
+		DisplayName:     displayName,
+		PTR:             model,
+		App:             a,
+		Fields:          fieldConfigs,
+		ORM:             orm,
+		VersionField:    versionField,
+		SoftDeleteField: softDeleteField,
+		PrimaryKeyField: primaryKeyField,
+		// Bulk deletes keep their historical best-effort behavior, while a form save should never be half applied.
+		SaveMode:       WriteModeAtomic,
+		BulkDeleteMode: WriteModeBestEffort,
+	}
+	if orderer, ok := model.(AdminModelDefaultOrderingInterface); ok {
+		ordering, err := modelInstance.parseOrdering(orderer.AdminDefaultOrdering(), false)
+		if err != nil {
+			return nil, err
+		}
+		modelInstance.DefaultOrdering = ordering
+	}
+	if relater, ok := model.(AdminModelManyToManyInterface); ok {
+		if err := modelInstance.registerRelations(relater.AdminManyToMany()); err != nil {
+			return nil, err
+		}
 	}
 	a.Panel.Web.HandleRoute("GET", a.Panel.Config.GetPrefix()+modelInstance.GetLink(), modelInstance.GetViewHandler())
 	a.Panel.Web.HandleRoute("GET", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/:id/view", modelInstance.GetInstanceViewHandler())
-	a.Panel.Web.HandleRoute("DELETE", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/:id/view", modelInstance.GetInstanceDeleteHandler())
+	a.Panel.Web.HandleRoute("DELETE", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/:id/view", a.Panel.csrfHandler(modelInstance.GetInstanceDeleteHandler()))
 	a.Panel.Web.HandleRoute("GET", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/add", modelInstance.GetAddHandler())
-	a.Panel.Web.HandleRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/add", modelInstance.GetAddHandler())
+	a.Panel.Web.HandleRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/add", a.Panel.csrfHandler(modelInstance.GetAddHandler()))
 	a.Panel.Web.HandleRoute("GET", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/:id/edit", modelInstance.GetEditHandler())
-	a.Panel.Web.HandleRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/:id/edit", modelInstance.GetEditHandler())
+	a.Panel.Web.HandleRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/:id/edit", a.Panel.csrfHandler(modelInstance.GetEditHandler()))
+	a.Panel.Web.HandleJSONRoute("GET", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/search", modelInstance.HandleSearchAJAX)
+	a.Panel.Web.HandleJSONRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/bulk-delete", a.Panel.csrfJSONHandler(modelInstance.HandleBulkDeleteAJAX))
+	a.Panel.Web.HandleJSONRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/action", a.Panel.csrfJSONHandler(modelInstance.HandleActionAJAX))
+	a.Panel.Web.HandleRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/action/confirm", a.Panel.csrfHandler(modelInstance.GetActionConfirmHandler()))
+	a.Panel.Web.HandleJSONRoute("DELETE", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/:id/delete", a.Panel.csrfJSONHandler(modelInstance.HandleDeleteAJAX))
+	a.Panel.Web.HandleRoute("GET", a.Panel.Config.GetPrefix()+modelInstance.GetImportLink(), modelInstance.GetImportHandler())
+	a.Panel.Web.HandleRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetImportLink(), a.Panel.csrfHandler(modelInstance.GetImportHandler()))
+	if a.Panel.Config.APIPrefix != "" {
+		modelInstance.registerAPIRoutes()
+	}
+	if responseWeb, ok := a.Panel.Web.(ResponseWebIntegrator); ok {
+		responseWeb.HandleResponseRoute("GET", a.Panel.Config.GetPrefix()+modelInstance.GetExportLink(), modelInstance.GetExportHandler())
+	}
+	if modelInstance.SoftDeleteField != "" {
+		a.Panel.Web.HandleRoute("GET", a.Panel.Config.GetPrefix()+modelInstance.GetTrashLink(), modelInstance.GetTrashHandler())
+		a.Panel.Web.HandleRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/:id/restore", a.Panel.csrfHandler(modelInstance.GetRestoreHandler()))
+		a.Panel.Web.HandleRoute("POST", a.Panel.Config.GetPrefix()+modelInstance.GetLink()+"/:id/purge", a.Panel.csrfHandler(modelInstance.GetPurgeHandler()))
+	}
 	a.ModelsSlice = append(a.ModelsSlice, modelInstance)
 	a.Models[name] = modelInstance
 	return modelInstance, nil
@@ -164,6 +227,12 @@ type tagOptions struct {
 	includeInInstanceView bool
 	includeInAddForm      bool
 	includeInEditForm     bool
+	sortable              bool
+	filterable            bool
+	version               bool
+	softDelete            bool
+	primaryKey            bool
+	foreignKey            string
 	fieldDisplayName      string
 }
 
@@ -208,6 +277,16 @@ func parseInclusionTags(tag, fieldName string) (tagOptions, error) {
 			opts.includeInFetch = opts.includeInList
 		}
 	}
+	if opts.version || opts.softDelete {
+		// The version is bumped by the panel on every save, and the soft-delete marker is set by deletes and cleared
+		// from the trash; neither is ever edited by hand.
+		opts.includeInAddForm = false
+		opts.includeInEditForm = false
+	}
+	if opts.softDelete {
+		// The list view only shows live instances, so the marker is shown by the trash alone.
+		opts.includeInList = false
+	}
 	if retErr != nil {
 		return opts, retErr
 	}
@@ -221,6 +300,17 @@ func applyTagToOptions(opts *tagOptions, key, value string, listFetchTagPresent
 		opts.fieldDisplayName = value
 		return nil
 	}
+	if key == "pk" {
+		opts.primaryKey = true
+		return nil
+	}
+	if key == "fk" {
+		if _, _, ok := parseModelReference(value); !ok {
+			return fmt.Errorf("invalid value for 'fk' tag: %s, expected 'app.Model'", value)
+		}
+		opts.foreignKey = value
+		return nil
+	}
 
 	boolTargets := map[string]*bool{
 		"listDisplay": &opts.includeInList,
@@ -229,6 +319,10 @@ func applyTagToOptions(opts *tagOptions, key, value string, listFetchTagPresent
 		"view":        &opts.includeInInstanceView,
 		"addForm":     &opts.includeInAddForm,
 		"editForm":    &opts.includeInEditForm,
+		"sortable":    &opts.sortable,
+		"filter":      &opts.filterable,
+		"version":     &opts.version,
+		"softDelete":  &opts.softDelete,
 	}
 
 	target, ok := boolTargets[key]
@@ -239,6 +333,10 @@ func applyTagToOptions(opts *tagOptions, key, value string, listFetchTagPresent
 	if key == "listFetch" {
 		*listFetchTagPresent = true
 	}
+	if value == "" && flagTags[key] {
+		*target = true
+		return nil
+	}
 	v, err := parseIncludeExclude(value, key)
 	if err != nil {
 		return err
@@ -247,6 +345,14 @@ func applyTagToOptions(opts *tagOptions, key, value string, listFetchTagPresent
 	return nil
 }
 
+// flagTags lists the tags that may be given without a value, which is the same as giving "include".
+var flagTags = map[string]bool{
+	"sortable":   true,
+	"filter":     true,
+	"version":    true,
+	"softDelete": true,
+}
+
 // parseIncludeExclude converts tag values "include"/"exclude" to bool or returns an error.
 func parseIncludeExclude(value, key string) (bool, error) {
 	mapping := map[string]bool{
@@ -370,6 +476,16 @@ func configureUUIDField(tag string, isUUID bool) *fields.UUIDField {
 	return tf
 }
 
+func configureForeignKeyField(tag string, panel *AdminPanel, related string, valueType reflect.Type) *ForeignKeyField {
+	tf := &ForeignKeyField{Panel: panel, Related: related, ValueType: valueType}
+	forEachTag(tag, func(key, _ string) {
+		if key == "required" {
+			tf.Required = true
+		}
+	})
+	return tf
+}
+
 func applyInitialValueTag(f form.Field, tag string, typ reflect.Type) error {
 	var convErr error
 	forEachTag(tag, func(key, value string) {
@@ -400,8 +516,11 @@ func (a *App) GetHandler() HandlerFunc {
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
+		if err = addModelCounts(models, data); err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
 
-		html, err := a.Panel.Config.Renderer.RenderTemplate("app", map[string]interface{}{"admin": a.Panel, "app": a, "models": models, "navBarItems": a.Panel.Config.GetNavBarItems(data)})
+		html, err := a.Panel.RenderPage(data, "app", map[string]interface{}{"admin": a.Panel, "app": a, "models": models, "navBarItems": a.Panel.Config.GetNavBarItems(data)})
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
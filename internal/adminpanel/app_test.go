@@ -73,6 +73,21 @@ func TestRegisterModel(t *testing.T) {
 		}
 	})
 
+	t.Run("PrimaryKeyField", func(t *testing.T) {
+		testApp := createTestApp()
+		model, err := testApp.RegisterModel(&TestModel1{}, nil)
+		if err != nil || model.PrimaryKeyField != "ID" {
+			t.Fatalf("expected ID to be the primary key, got %q (%v)", model.PrimaryKeyField, err)
+		}
+		tagged := &struct {
+			ID   uint
+			Slug string `admin:"pk"`
+		}{}
+		if model, err = testApp.RegisterModel(tagged, nil); err != nil || model.PrimaryKeyField != "Slug" {
+			t.Errorf("expected the tagged field to be the primary key, got %q (%v)", model.PrimaryKeyField, err)
+		}
+	})
+
 	t.Run("DuplicateModel", func(t *testing.T) {
 		testApp := createTestApp()
 		testModel := &TestModel1{}
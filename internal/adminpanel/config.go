@@ -19,6 +19,31 @@ type AdminConfig struct {
 	UserFetcher             UserFetchFunction
 	LogStore                logging.LogStore
 	LogStoreLevel           logging.LogStoreLevel
+	// StatTiles lists the statistic tiles shown on the dashboard.
+	StatTiles []StatTile
+	// Charts lists the charts shown on the dashboard.
+	Charts []Chart
+	// APIPrefix is the path, under the admin prefix, of the JSON API of the models, such as "api". The API is
+	// disabled when it is empty, as it is by default.
+	APIPrefix string
+	// OpenAPIPath is the path, under the admin prefix, at which the OpenAPI document of the JSON API is served, such
+	// as "openapi.json". The document is not served when it is empty, as it is by default, or the API is disabled.
+	OpenAPIPath string
+	// CSRFTokenStore issues and verifies the CSRF tokens sent with the requests changing data. When it is nil, panels
+	// whose web integrator implements CookieWebIntegrator use a CookieCSRFTokenStore, and other panels fail to be
+	// created unless DisableCSRF is set.
+	CSRFTokenStore CSRFTokenStore
+	// DisableCSRF turns CSRF protection off when CSRFTokenStore is nil, so that forged requests from other sites are
+	// accepted. It suits panels protected otherwise, such as by an API gateway.
+	DisableCSRF bool
+	// FlashStore keeps the flash messages set by handlers, such as the confirmation of a save, until the next page is
+	// shown. Messages are dropped when it is nil.
+	FlashStore FlashStore
+	// ListScope restricts the lists of instances to those the user may read within the list query, so that
+	// PaginatedORMIntegrator integrators page and count them in the database. Without it, the read permission of each
+	// instance is checked on the fetched page, and list totals are shown as upper bounds. Dashboard statistics and
+	// instance counts are restricted by it too.
+	ListScope ListScopeFunc
 }
 
 // UserFetchFunction defines a function type for fetching user information from the context.
@@ -86,6 +111,22 @@ func (c *AdminConfig) GetPrefix() string {
 	return "/" + c.Prefix
 }
 
+// GetAPIPrefix returns the URL prefix of the JSON API under the admin prefix.
+func (c *AdminConfig) GetAPIPrefix() string {
+	if c.APIPrefix == "" {
+		return ""
+	}
+	return "/" + c.APIPrefix
+}
+
+// GetOpenAPIPath returns the URL path of the OpenAPI document under the admin prefix.
+func (c *AdminConfig) GetOpenAPIPath() string {
+	if c.OpenAPIPath == "" {
+		return ""
+	}
+	return "/" + c.OpenAPIPath
+}
+
 // GetAssetsPrefix returns the URL prefix for admin panel assets.
 func (c *AdminConfig) GetAssetsPrefix() string {
 	if c.AssetsPrefix == "" {
@@ -87,3 +87,22 @@ func TestAdminConfig_GetAssetLink(t *testing.T) {
 		})
 	}
 }
+
+func TestAdminConfig_GetOpenAPIPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		openAPIPath string
+		expected    string
+	}{
+		{"Non-empty OpenAPIPath", "openapi.json", "/openapi.json"},
+		{"Empty OpenAPIPath", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := AdminConfig{OpenAPIPath: tt.openAPIPath}
+			if got := config.GetOpenAPIPath(); got != tt.expected {
+				t.Errorf("GetOpenAPIPath() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
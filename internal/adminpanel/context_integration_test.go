@@ -0,0 +1,84 @@
+package adminpanel_test
+
+import (
+	"context"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type contextKey string
+
+// contextORM is an in-memory integrator with many-to-many relations, recording the lookups made without the request
+// context.
+type contextORM struct {
+	*relatedORM
+	ctx     context.Context
+	Unbound *[]string
+}
+
+func (c *contextORM) WithContext(ctx context.Context) adminpanel.ORMIntegrator {
+	return &contextORM{relatedORM: c.relatedORM, ctx: ctx, Unbound: c.Unbound}
+}
+
+func (c *contextORM) record(lookup string) {
+	if c.ctx == nil || c.ctx.Value(contextKey("user")) != "alice" {
+		*c.Unbound = append(*c.Unbound, lookup)
+	}
+}
+
+func (c *contextORM) FetchInstances(model interface{}) (interface{}, error) {
+	c.record("FetchInstances")
+	return c.relatedORM.FetchInstances(model)
+}
+
+func (c *contextORM) FetchInstance(model interface{}, id interface{}) (interface{}, error) {
+	c.record("FetchInstance")
+	return c.relatedORM.FetchInstance(model, id)
+}
+
+func (c *contextORM) FetchInstanceOnlyFields(model interface{}, id interface{}, fields []string) (interface{}, error) {
+	c.record("FetchInstanceOnlyFields")
+	return c.relatedORM.FetchInstanceOnlyFields(model, id, fields)
+}
+
+func (c *contextORM) FetchRelatedIDs(model interface{}, id interface{}, relation string) ([]interface{}, error) {
+	c.record("FetchRelatedIDs")
+	return c.relatedORM.FetchRelatedIDs(model, id, relation)
+}
+
+func TestModel_RelationsUseRequestContext(t *testing.T) {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	orm := &contextORM{
+		relatedORM: &relatedORM{
+			Integrator: newMemoryORM(t, &adminpanel.M2MUser{ID: 3, Name: "bob"}, &adminpanel.M2MGroup{ID: 1, Name: "Admins"}, &adminpanel.M2MGroup{ID: 2, Name: "Editors"}),
+			Related:    map[string]map[interface{}][]interface{}{"Groups": {uint(3): {uint(1)}}},
+		},
+		Unbound: &[]string{},
+	}
+	authApp, _ := panel.RegisterApp("Auth", "Auth", orm)
+	user, err := authApp.RegisterModel(&adminpanel.M2MUser{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = authApp.RegisterModel(&adminpanel.M2MGroup{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey("user"), "alice")
+	request := &adminpanel.MockRequest{Method: http.MethodGet, Params: map[string]string{"id": "3"}, Context: ctx}
+	code, body := user.GetInstanceViewHandler()(request)
+	if code != http.StatusOK || !strings.Contains(body, "Admins") {
+		t.Fatalf("expected the instance view to list the related groups, got %v: %s", code, body)
+	}
+	if code, body = user.GetEditHandler()(request); code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if len(*orm.Unbound) > 0 {
+		t.Errorf("expected every lookup to be bound to the request context, got unbound %v", *orm.Unbound)
+	}
+}
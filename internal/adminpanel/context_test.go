@@ -0,0 +1,93 @@
+package adminpanel
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+type contextKey string
+
+// MockContextORMIntegrator records the contexts it is bound to.
+type MockContextORMIntegrator struct {
+	MockPaginatedORMIntegrator
+	Contexts []context.Context
+}
+
+func (m *MockContextORMIntegrator) WithContext(ctx context.Context) ORMIntegrator {
+	m.Contexts = append(m.Contexts, ctx)
+	return m
+}
+
+func TestRequestContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey("user"), "alice")
+	web := &MockWebIntegrator{}
+
+	if got := requestContext(web, &MockRequest{Context: ctx}); got != ctx {
+		t.Error("expected the context handed over by the web integrator")
+	}
+	if got := requestContext(web, ctx); got != ctx {
+		t.Error("expected data to be used when it is a context")
+	}
+	if got := requestContext(web, map[string]string{}); got != context.Background() {
+		t.Error("expected the background context by default")
+	}
+}
+
+func TestORMWithContext(t *testing.T) {
+	ctx := context.Background()
+	legacy := &MockORMIntegrator{}
+	if ORMWithContext(legacy, ctx) != legacy {
+		t.Error("expected integrators without context support to be returned unchanged")
+	}
+	contextORM := &MockContextORMIntegrator{}
+	ORMWithContext(contextORM, ctx)
+	if len(contextORM.Contexts) != 1 || contextORM.Contexts[0] != ctx {
+		t.Errorf("expected the integrator to be bound to the context, got %v", contextORM.Contexts)
+	}
+}
+
+func TestModel_HandlersPassRequestContext(t *testing.T) {
+	var permissionContexts []context.Context
+	permissionFunc := ContextPermissionFunc(func(ctx context.Context, _ PermissionRequest, _ interface{}) (bool, error) {
+		permissionContexts = append(permissionContexts, ctx)
+		return true, nil
+	})
+	panel, err := NewAdminPanel(&MockORMIntegrator{}, &MockWebIntegrator{}, permissionFunc.PermissionFunc(), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	app, _ := panel.RegisterApp("TestApp", "Test App", nil)
+	orm := &MockContextORMIntegrator{}
+	orm.Instances = []*TestModel{{ID: 1, Name: "Instance"}}
+	model, err := app.RegisterModel(&TestModel{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey("user"), "alice")
+	if code, body := model.GetViewHandler()(&MockRequest{Context: ctx}); code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	_ = model.HandleDeleteAJAX(&MockRequest{Params: map[string]string{"id": "1"}, Context: ctx})
+	if len(orm.Deleted) != 1 {
+		t.Fatalf("expected the instance to be deleted, got %v", orm.Deleted)
+	}
+
+	if len(orm.Contexts) < 2 {
+		t.Fatalf("expected the integrator to be bound for both requests, got %d contexts", len(orm.Contexts))
+	}
+	for _, got := range orm.Contexts {
+		if got.Value(contextKey("user")) != "alice" {
+			t.Error("expected the integrator to be bound to the request context")
+		}
+	}
+	if len(permissionContexts) == 0 {
+		t.Fatal("expected permissions to be checked")
+	}
+	for _, got := range permissionContexts {
+		if got.Value(contextKey("user")) != "alice" {
+			t.Error("expected permission checks to receive the request context")
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package adminpanel
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CSRFFormField is the name of the form field carrying the CSRF token in the forms of the admin panel.
+const CSRFFormField = "csrfToken"
+
+// CSRFHeader is the name of the header carrying the CSRF token in the AJAX requests of the admin panel.
+const CSRFHeader = "X-CSRF-Token"
+
+// ErrCSRFTokenInvalid is returned when a request changing data does not carry the CSRF token of its session.
+var ErrCSRFTokenInvalid = errors.New("missing or invalid CSRF token")
+
+// CSRFTokenStore issues the CSRF tokens of sessions and verifies the tokens sent back with requests changing data.
+type CSRFTokenStore interface {
+	// GetToken returns the token of the session of the request carried by ctx, issuing one if the session has none.
+	// It returns an empty token for requests without a session.
+	GetToken(ctx interface{}) (string, error)
+	// VerifyToken reports whether token is the token of the session of the request carried by ctx.
+	VerifyToken(ctx interface{}, token string) (bool, error)
+}
+
+// SessionIDFunc returns the ID of the session of the request carried by ctx, or an empty ID for requests without a
+// session.
+type SessionIDFunc = func(ctx interface{}) (string, error)
+
+// HeaderWebIntegrator is an optional extension of WebIntegrator for integrators that can read request headers.
+// Without it, AJAX requests send their CSRF token as a query parameter instead of a header.
+type HeaderWebIntegrator interface {
+	// GetHeader returns the value of the named header of the request.
+	GetHeader(ctx interface{}, name string) string
+}
+
+// MemoryCSRFTokenStore is a CSRFTokenStore keeping a random token per session in memory until it expires. It suits a
+// single server. It is safe for concurrent use.
+type MemoryCSRFTokenStore struct {
+	// MaxAge is how long a token is valid. Pages rendered after a token expired carry a new one.
+	MaxAge    time.Duration
+	sessionID SessionIDFunc
+	mu        sync.Mutex
+	tokens    map[string]memoryCSRFToken
+}
+
+// memoryCSRFToken is a token kept by a MemoryCSRFTokenStore.
+type memoryCSRFToken struct {
+	value   string
+	expires time.Time
+}
+
+// NewMemoryCSRFTokenStore creates a MemoryCSRFTokenStore identifying the session of requests with sessionID, whose
+// tokens are valid for 12 hours.
+func NewMemoryCSRFTokenStore(sessionID SessionIDFunc) *MemoryCSRFTokenStore {
+	return &MemoryCSRFTokenStore{MaxAge: 12 * time.Hour, sessionID: sessionID, tokens: make(map[string]memoryCSRFToken)}
+}
+
+// GetToken returns the token of the session of the request, issuing a random one on first use or once the previous
+// one expired. Issuing a token forgets the tokens that have expired.
+func (s *MemoryCSRFTokenStore) GetToken(ctx interface{}) (string, error) {
+	session, err := s.sessionID(ctx)
+	if err != nil || session == "" {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if token, ok := s.tokens[session]; ok && now.Before(token.expires) {
+		return token.value, nil
+	}
+	value, err := newRandomToken()
+	if err != nil {
+		return "", err
+	}
+	for id, token := range s.tokens {
+		if !now.Before(token.expires) {
+			delete(s.tokens, id)
+		}
+	}
+	s.tokens[session] = memoryCSRFToken{value: value, expires: now.Add(s.MaxAge)}
+	return value, nil
+}
+
+// VerifyToken reports whether token is the unexpired token issued to the session of the request.
+func (s *MemoryCSRFTokenStore) VerifyToken(ctx interface{}, token string) (bool, error) {
+	session, err := s.sessionID(ctx)
+	if err != nil || session == "" || token == "" {
+		return false, err
+	}
+	s.mu.Lock()
+	expected, ok := s.tokens[session]
+	s.mu.Unlock()
+	return ok && time.Now().Before(expected.expires) &&
+		subtle.ConstantTimeCompare([]byte(expected.value), []byte(token)) == 1, nil
+}
+
+// Forget removes the token of a session, for instance when the user logs out.
+func (s *MemoryCSRFTokenStore) Forget(session string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, session)
+}
+
+// CookieCSRFTokenStore is a CSRFTokenStore keeping the token of each browser in a cookie, and accepting requests
+// sending the same token back in the form, header or query ("double submit"). It needs no session, and is the
+// default store of panels whose web integrator implements CookieWebIntegrator. Sites whose subdomains are not all
+// trusted should prefer a store keyed by session, as subdomains can set the cookie.
+type CookieCSRFTokenStore struct {
+	Web CookieWebIntegrator
+	// Name is the name of the cookie.
+	Name string
+	// Path is the path of the cookie, which should cover the admin panel.
+	Path string
+}
+
+// NewCookieCSRFTokenStore creates a CookieCSRFTokenStore keeping the tokens in the "csrf_token" cookie, set with web.
+func NewCookieCSRFTokenStore(web CookieWebIntegrator) *CookieCSRFTokenStore {
+	return &CookieCSRFTokenStore{Web: web, Name: "csrf_token", Path: "/"}
+}
+
+// GetToken returns the token of the cookie, setting a cookie with a random token if the request has none. Integrators
+// implementing ResponseCookieWebIntegrator return the same token for the rest of the request.
+func (s *CookieCSRFTokenStore) GetToken(ctx interface{}) (string, error) {
+	if web, ok := s.Web.(ResponseCookieWebIntegrator); ok {
+		if token, set := web.GetResponseCookie(ctx, s.Name); set && token != "" {
+			return token, nil
+		}
+	}
+	if token := s.Web.GetCookie(ctx, s.Name); token != "" {
+		return token, nil
+	}
+	token, err := newRandomToken()
+	if err != nil {
+		return "", err
+	}
+	s.Web.SetCookie(ctx, &http.Cookie{
+		Name:     s.Name,
+		Value:    token,
+		Path:     s.Path,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token, nil
+}
+
+// VerifyToken reports whether token is the token of the cookie of the request.
+func (s *CookieCSRFTokenStore) VerifyToken(ctx interface{}, token string) (bool, error) {
+	expected := s.Web.GetCookie(ctx, s.Name)
+	return expected != "" && token != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1, nil
+}
+
+// newRandomToken returns a random URL-safe token.
+func newRandomToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// GetCSRFToken returns the CSRF token of the session of the request carried by ctx, or an empty token if CSRF
+// protection is disabled.
+func (ap *AdminPanel) GetCSRFToken(ctx interface{}) (string, error) {
+	if ap.Config.CSRFTokenStore == nil {
+		return "", nil
+	}
+	return ap.Config.CSRFTokenStore.GetToken(ctx)
+}
+
+// isSafeMethod reports whether requests with the given HTTP method leave data unchanged.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// verifyCSRFToken checks the CSRF token of a request changing data, taken from the CSRF header, then from the form
+// of HTML requests, then from the query. It returns ErrCSRFTokenInvalid if the token is not the one of the session.
+func (ap *AdminPanel) verifyCSRFToken(ctx interface{}, readForm bool) error {
+	store := ap.Config.CSRFTokenStore
+	if store == nil || isSafeMethod(ap.Web.GetRequestMethod(ctx)) {
+		return nil
+	}
+	var token string
+	if headerWeb, ok := ap.Web.(HeaderWebIntegrator); ok {
+		token = headerWeb.GetHeader(ctx, CSRFHeader)
+	}
+	if token == "" && readForm {
+		if values := ap.Web.GetFormData(ctx)[CSRFFormField]; len(values) > 0 {
+			token = values[0]
+		}
+	}
+	if token == "" {
+		token = ap.Web.GetQueryParam(ctx, CSRFFormField)
+	}
+	valid, err := store.VerifyToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrCSRFTokenInvalid
+	}
+	return nil
+}
+
+// csrfHandler returns handler, preceded by the verification of the CSRF token of requests changing data. Requests
+// failing it get a 403 page.
+func (ap *AdminPanel) csrfHandler(handler HandlerFunc) HandlerFunc {
+	return func(data interface{}) (uint, string) {
+		err := ap.verifyCSRFToken(data, true)
+		if err == nil {
+			return handler(data)
+		}
+		if !errors.Is(err, ErrCSRFTokenInvalid) {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		apps, err := GetAppsWithReadPermissions(ap, data)
+		if err != nil {
+			return GetErrorHTML(http.StatusForbidden, ErrCSRFTokenInvalid)
+		}
+		html, err := ap.RenderPage(data, "csrf", map[string]interface{}{
+			"admin":       ap,
+			"apps":        apps,
+			"navBarItems": ap.Config.GetNavBarItems(data),
+		})
+		if err != nil {
+			return GetErrorHTML(http.StatusForbidden, ErrCSRFTokenInvalid)
+		}
+		return http.StatusForbidden, html
+	}
+}
+
+// csrfJSONHandler returns handler, preceded by the verification of the CSRF token of requests changing data.
+// Requests failing it get a 403 JSON error.
+func (ap *AdminPanel) csrfJSONHandler(handler JSONHandlerFunc) JSONHandlerFunc {
+	return func(ctx interface{}) error {
+		err := ap.verifyCSRFToken(ctx, false)
+		if err == nil {
+			return handler(ctx)
+		}
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrCSRFTokenInvalid) {
+			code = http.StatusForbidden
+		}
+		return ap.Web.SetJSONResponse(ctx, code, NewErrorResponse([]string{err.Error()}))
+	}
+}
@@ -0,0 +1,90 @@
+package adminpanel_test
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAdminPanel_CSRFHandler(t *testing.T) {
+	model, orm := registerProductModel(t)
+	panel := model.App.Panel
+	panel.Config.CSRFTokenStore = adminpanel.NewMemoryCSRFTokenStore(adminpanel.MockSessionID)
+	headers := map[string]string{"Session": "alice"}
+	token, err := panel.GetCSRFToken(&adminpanel.MockRequest{Headers: headers})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	add := panel.Web.(*adminpanel.MockWebIntegrator).Handlers["POST /admin/a/Shop/Product/add"]
+
+	code, body := model.GetAddHandler()(&adminpanel.MockRequest{Method: http.MethodGet, Headers: headers})
+	if code != http.StatusOK || !strings.Contains(body, `name="csrfToken" value="`+token+`"`) {
+		t.Errorf("expected the form to carry the token, got %v", code)
+	}
+
+	form := map[string][]string{"Name": {"Gadget"}, "Stock": {"7"}}
+	code, body = add(&adminpanel.MockRequest{Method: http.MethodPost, Form: form, Headers: headers})
+	if code != http.StatusForbidden || !strings.Contains(body, "security token") {
+		t.Errorf("expected %v without a token, got %v", http.StatusForbidden, code)
+	}
+	if len(storedRows[adminpanel.Product](t, orm)) != 1 {
+		t.Fatalf("expected nothing to be added, got %v", storedRows[adminpanel.Product](t, orm))
+	}
+
+	form[adminpanel.CSRFFormField] = []string{token}
+	if code, body = add(&adminpanel.MockRequest{Method: http.MethodPost, Form: form, Headers: headers}); code != http.StatusSeeOther {
+		t.Errorf("expected a redirect with the token, got %v: %s", code, body)
+	}
+	if code, _ = add(&adminpanel.MockRequest{Method: http.MethodPost, Form: form, Headers: map[string]string{"Session": "bob"}}); code != http.StatusForbidden {
+		t.Errorf("expected the token of another session to be refused, got %v", code)
+	}
+}
+
+func TestAdminPanel_CSRFJSONHandler(t *testing.T) {
+	model, orm := registerAPIModel(t)
+	panel := model.App.Panel
+	handlers := panel.Web.(*adminpanel.MockWebIntegrator).JSONHandlers
+	create := handlers["POST /admin/api/a/Shop/Product"]
+	body := func() map[string]interface{} { return map[string]interface{}{"Name": "Gadget", "Stock": float64(7)} }
+
+	if status, _ := callAPI(t, model, create, &adminpanel.MockRequest{Method: http.MethodPost, Body: body()}); status != http.StatusForbidden {
+		t.Fatalf("expected the default store to refuse a request without token, got %v", status)
+	}
+	panel.Config.CSRFTokenStore = nil
+	if status, _ := callAPI(t, model, create, &adminpanel.MockRequest{Method: http.MethodPost, Body: body()}); status != http.StatusCreated {
+		t.Fatalf("expected CSRF protection to be disabled without a store, got %v", status)
+	}
+
+	panel.Config.CSRFTokenStore = adminpanel.NewMemoryCSRFTokenStore(adminpanel.MockSessionID)
+	token, _ := panel.GetCSRFToken(&adminpanel.MockRequest{Headers: map[string]string{"Session": "alice"}})
+	status, response := callAPI(t, model, create, &adminpanel.MockRequest{Method: http.MethodPost, Body: body(), Headers: map[string]string{"Session": "alice"}})
+	if status != http.StatusForbidden || response.Errors[0] != adminpanel.ErrCSRFTokenInvalid.Error() {
+		t.Errorf("expected %v without a token, got %v %v", http.StatusForbidden, status, response.Errors)
+	}
+	if len(storedRows[adminpanel.Product](t, orm)) != 2 {
+		t.Fatalf("expected nothing to be created, got %v", storedRows[adminpanel.Product](t, orm))
+	}
+
+	for name, request := range map[string]*adminpanel.MockRequest{
+		"Header": {Method: http.MethodPost, Body: body(), Headers: map[string]string{"Session": "alice", adminpanel.CSRFHeader: token}},
+		"Query":  {Method: http.MethodPost, Body: body(), Headers: map[string]string{"Session": "alice"}, Params: map[string]string{adminpanel.CSRFFormField: token}},
+	} {
+		if status, _ := callAPI(t, model, create, request); status != http.StatusCreated {
+			t.Errorf("%s: expected the token to be accepted, got %v", name, status)
+		}
+	}
+
+	if status, _ := callAPI(t, model, handlers["GET /admin/api/a/Shop/Product"], &adminpanel.MockRequest{Method: http.MethodGet}); status != http.StatusOK {
+		t.Errorf("expected safe requests to need no token, got %v", status)
+	}
+
+	document, err := panel.GetOpenAPIDocument(&adminpanel.MockRequest{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	collection := document["paths"].(map[string]interface{})["/a/Shop/Product"].(map[string]interface{})
+	if _, ok := collection["post"].(map[string]interface{})["security"]; !ok {
+		t.Errorf("expected the create operation to require the CSRF token")
+	}
+}
@@ -0,0 +1,125 @@
+package adminpanel
+
+import (
+	"testing"
+	"time"
+)
+
+// MockSessionID identifies the session of a MockRequest with its Session header.
+func MockSessionID(ctx interface{}) (string, error) {
+	return ctx.(*MockRequest).Headers["Session"], nil
+}
+
+func TestMemoryCSRFTokenStore(t *testing.T) {
+	store := NewMemoryCSRFTokenStore(MockSessionID)
+	alice := &MockRequest{Headers: map[string]string{"Session": "alice"}}
+	bob := &MockRequest{Headers: map[string]string{"Session": "bob"}}
+
+	token, err := store.GetToken(alice)
+	if err != nil || token == "" {
+		t.Fatalf("expected a token, got %q (%v)", token, err)
+	}
+	if again, _ := store.GetToken(alice); again != token {
+		t.Errorf("expected the token of a session to be stable, got %q and %q", token, again)
+	}
+	if other, _ := store.GetToken(bob); other == token {
+		t.Errorf("expected each session to get its own token")
+	}
+	if token, _ := store.GetToken(&MockRequest{}); token != "" {
+		t.Errorf("expected no token without a session, got %q", token)
+	}
+
+	tests := []struct {
+		name     string
+		request  *MockRequest
+		token    string
+		expected bool
+	}{
+		{"Own Token", alice, token, true},
+		{"Other Session", bob, token, false},
+		{"Empty Token", alice, "", false},
+		{"No Session", &MockRequest{}, token, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := store.VerifyToken(tt.request, tt.token)
+			if err != nil || valid != tt.expected {
+				t.Errorf("expected %v, got %v (%v)", tt.expected, valid, err)
+			}
+		})
+	}
+
+	store.Forget("alice")
+	if valid, _ := store.VerifyToken(alice, token); valid {
+		t.Errorf("expected a forgotten token to be refused")
+	}
+}
+
+func TestMemoryCSRFTokenStore_Expiry(t *testing.T) {
+	store := NewMemoryCSRFTokenStore(MockSessionID)
+	store.MaxAge = -time.Minute
+	alice := &MockRequest{Headers: map[string]string{"Session": "alice"}}
+	token, _ := store.GetToken(alice)
+	if valid, _ := store.VerifyToken(alice, token); valid {
+		t.Errorf("expected an expired token to be refused")
+	}
+	if again, _ := store.GetToken(alice); again == token {
+		t.Errorf("expected a new token once the previous one expired")
+	}
+	_, _ = store.GetToken(&MockRequest{Headers: map[string]string{"Session": "bob"}})
+	if len(store.tokens) != 1 {
+		t.Errorf("expected the expired tokens to be forgotten, got %d tokens", len(store.tokens))
+	}
+}
+
+func TestCookieCSRFTokenStore(t *testing.T) {
+	store := NewCookieCSRFTokenStore(&MockWebIntegrator{})
+	request := &MockRequest{}
+	token, err := store.GetToken(request)
+	if err != nil || token == "" || request.ResponseCookies["csrf_token"] != token {
+		t.Fatalf("expected a token set in a cookie, got %q (%v)", token, err)
+	}
+	if again, _ := store.GetToken(request); again != token {
+		t.Errorf("expected the token to be stable, got %q and %q", token, again)
+	}
+
+	tests := []struct {
+		name     string
+		request  *MockRequest
+		token    string
+		expected bool
+	}{
+		{"Cookie Token", &MockRequest{Cookies: map[string]string{"csrf_token": token}}, token, true},
+		{"Forged Token", &MockRequest{Cookies: map[string]string{"csrf_token": token}}, "forged", false},
+		{"No Cookie", &MockRequest{}, token, false},
+		{"Empty Token", &MockRequest{Cookies: map[string]string{"csrf_token": ""}}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := store.VerifyToken(tt.request, tt.token)
+			if err != nil || valid != tt.expected {
+				t.Errorf("expected %v, got %v (%v)", tt.expected, valid, err)
+			}
+		})
+	}
+}
+
+func TestNewAdminPanel_DefaultCSRFTokenStore(t *testing.T) {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := panel.Config.CSRFTokenStore.(*CookieCSRFTokenStore); !ok {
+		t.Errorf("expected a cookie token store by default, got %T", panel.Config.CSRFTokenStore)
+	}
+
+	web := struct{ WebIntegrator }{&MockWebIntegrator{}}
+	if _, err = NewAdminPanel(&MockORMIntegrator{}, web, MockPermissionFunc, nil); err == nil {
+		t.Errorf("expected an error for an integrator without cookies and no token store")
+	}
+	config := NewDefaultAdminConfig()
+	config.DisableCSRF = true
+	if panel, err = NewAdminPanel(&MockORMIntegrator{}, web, MockPermissionFunc, config); err != nil || panel.Config.CSRFTokenStore != nil {
+		t.Errorf("expected CSRF protection to be disabled, got %v (%v)", panel, err)
+	}
+}
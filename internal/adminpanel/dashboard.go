@@ -0,0 +1,229 @@
+package adminpanel
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatTile configures a statistic shown as a tile on the dashboard of the admin panel. Like every statistic, it is
+// computed over the instances within AdminConfig.ListScope, without checking the read permission of each instance.
+type StatTile struct {
+	Title string
+	// Model references the model the statistic is computed over as "app.Model".
+	Model string
+	// Aggregate is the statistic shown: AggregateCount, the default, AggregateSum or AggregateAvg.
+	Aggregate AggregateFunc
+	// Field names the numeric field summed or averaged. It is ignored for counts.
+	Field string
+	// Filters restricts the statistic to the instances matching every condition.
+	Filters []FilterCondition
+}
+
+// ChartKind selects how a Chart is drawn.
+type ChartKind string
+
+const (
+	// ChartBar draws a bar per group.
+	ChartBar ChartKind = "bar"
+	// ChartLine joins the groups with a line, in the order of their values.
+	ChartLine ChartKind = "line"
+)
+
+// Chart configures a chart shown on the dashboard of the admin panel. It counts the instances of a model for each
+// value of a field and is rendered on the server as SVG.
+type Chart struct {
+	Title string
+	// Kind is ChartBar, the default, or ChartLine.
+	Kind ChartKind
+	// Model references the model whose instances are counted as "app.Model".
+	Model string
+	// GroupBy names the field the instances are grouped by.
+	GroupBy string
+	// Filters restricts the chart to the instances matching every condition.
+	Filters []FilterCondition
+}
+
+// Dimensions of the charts in SVG user units.
+const (
+	chartWidth       = 600
+	chartHeight      = 240
+	chartPaddingLeft = 48
+	chartPaddingTop  = 12
+	chartPaddingEnd  = 12
+	chartAxisHeight  = 28
+	chartLabelLength = 14
+)
+
+// getDashboardTiles computes the statistic tiles of the dashboard, leaving out the ones over models the user may
+// not read.
+func (ap *AdminPanel) getDashboardTiles(data interface{}) ([]map[string]interface{}, error) {
+	tiles := make([]map[string]interface{}, 0, len(ap.Config.StatTiles))
+	for _, tile := range ap.Config.StatTiles {
+		model, allowed, err := ap.getDashboardModel(tile.Model, data)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			continue
+		}
+
+		var value string
+		switch tile.Aggregate {
+		case "", AggregateCount:
+			count, err := model.CountInstances(data, tile.Filters)
+			if err != nil {
+				return nil, err
+			}
+			value = strconv.FormatUint(uint64(count), 10)
+		default:
+			result, err := model.AggregateField(data, tile.Field, tile.Aggregate, tile.Filters)
+			if err != nil {
+				return nil, err
+			}
+			value = formatStatValue(result)
+		}
+		tiles = append(tiles, map[string]interface{}{"title": tile.Title, "value": value, "link": model.GetFullLink()})
+	}
+	return tiles, nil
+}
+
+// getDashboardCharts computes and draws the charts of the dashboard, leaving out the ones over models the user may
+// not read.
+func (ap *AdminPanel) getDashboardCharts(data interface{}) ([]map[string]interface{}, error) {
+	charts := make([]map[string]interface{}, 0, len(ap.Config.Charts))
+	for _, chart := range ap.Config.Charts {
+		model, allowed, err := ap.getDashboardModel(chart.Model, data)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			continue
+		}
+		groups, err := model.CountInstancesByField(data, chart.GroupBy, chart.Filters)
+		if err != nil {
+			return nil, err
+		}
+		charts = append(charts, map[string]interface{}{"title": chart.Title, "svg": renderChartSVG(chart.Kind, chart.Title, groups)})
+	}
+	return charts, nil
+}
+
+// getDashboardModel resolves the model of a tile or chart and reports whether the user may read it.
+func (ap *AdminPanel) getDashboardModel(reference string, data interface{}) (*Model, bool, error) {
+	model, err := ap.GetModelByReference(reference)
+	if err != nil {
+		return nil, false, err
+	}
+	allowed, err := ap.PermissionChecker.HasModelReadPermission(model.App.Name, model.Name, data)
+	if err != nil {
+		return nil, false, err
+	}
+	return model, allowed, nil
+}
+
+// addModelCounts adds the number of instances of each model card under "count", and sets "hasCount". Cards are only
+// counted for the models whose integrator implements AggregateORMIntegrator, as the pages showing them should not
+// fetch every instance of every model.
+func addModelCounts(models []map[string]interface{}, data interface{}) error {
+	for _, modelMap := range models {
+		model := modelMap["model"].(*Model)
+		if _, ok := model.getRequestORM(data).(AggregateORMIntegrator); !ok {
+			continue
+		}
+		count, err := model.CountInstances(data, nil)
+		if err != nil {
+			return err
+		}
+		modelMap["count"] = count
+		modelMap["hasCount"] = true
+	}
+	return nil
+}
+
+// formatStatValue formats a sum or an average, with two decimals unless it is a whole number.
+func formatStatValue(value float64) string {
+	if value == math.Trunc(value) {
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// formatGroupLabel formats the value of a chart group.
+func formatGroupLabel(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "(none)"
+	case time.Time:
+		return value.Format("2006-01-02")
+	}
+	return fmt.Sprint(value)
+}
+
+// truncateLabel shortens an axis label to chartLabelLength characters.
+func truncateLabel(label string) string {
+	runes := []rune(label)
+	if len(runes) <= chartLabelLength {
+		return label
+	}
+	return string(runes[:chartLabelLength-1]) + "…"
+}
+
+// renderChartSVG draws the counts of the groups as a bar or line chart. Each bar or point carries its label and
+// count as a tooltip.
+func renderChartSVG(kind ChartKind, title string, groups []GroupCount) template.HTML {
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg class="admin-chart text-primary" viewBox="0 0 %d %d" width="100%%" role="img" aria-label="%s">`,
+		chartWidth, chartHeight, template.HTMLEscapeString(title))
+
+	plotWidth := float64(chartWidth - chartPaddingLeft - chartPaddingEnd)
+	plotHeight := float64(chartHeight - chartPaddingTop - chartAxisHeight)
+	baseline := float64(chartPaddingTop) + plotHeight
+	fmt.Fprintf(&svg, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="currentColor" stroke-opacity="0.3"/>`,
+		chartPaddingLeft, baseline, chartWidth-chartPaddingEnd, baseline)
+	if len(groups) == 0 {
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="middle" font-size="13" fill="currentColor" fill-opacity="0.6">No data</text>`,
+			chartWidth/2, chartHeight/2)
+		svg.WriteString(`</svg>`)
+		return template.HTML(svg.String())
+	}
+
+	var maxCount uint = 1
+	for _, group := range groups {
+		if group.Count > maxCount {
+			maxCount = group.Count
+		}
+	}
+	fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="end" font-size="11" fill="currentColor" fill-opacity="0.6">%d</text>`,
+		chartPaddingLeft-6, chartPaddingTop+4, maxCount)
+	fmt.Fprintf(&svg, `<text x="%d" y="%.1f" text-anchor="end" font-size="11" fill="currentColor" fill-opacity="0.6">0</text>`,
+		chartPaddingLeft-6, baseline)
+
+	slot := plotWidth / float64(len(groups))
+	points := make([]string, len(groups))
+	for i, group := range groups {
+		label := formatGroupLabel(group.Value)
+		tooltip := template.HTMLEscapeString(fmt.Sprintf("%s: %d", label, group.Count))
+		center := float64(chartPaddingLeft) + slot*float64(i) + slot/2
+		height := plotHeight * float64(group.Count) / float64(maxCount)
+		top := baseline - height
+		if kind == ChartLine {
+			points[i] = fmt.Sprintf("%.1f,%.1f", center, top)
+			fmt.Fprintf(&svg, `<circle cx="%.1f" cy="%.1f" r="3.5" fill="currentColor"><title>%s</title></circle>`, center, top, tooltip)
+		} else {
+			width := slot * 0.7
+			fmt.Fprintf(&svg, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="2" fill="currentColor"><title>%s</title></rect>`,
+				center-width/2, top, width, height, tooltip)
+		}
+		fmt.Fprintf(&svg, `<text x="%.1f" y="%.1f" text-anchor="middle" font-size="11" fill="currentColor" fill-opacity="0.6">%s</text>`,
+			center, baseline+18, template.HTMLEscapeString(truncateLabel(label)))
+	}
+	if kind == ChartLine {
+		fmt.Fprintf(&svg, `<polyline points="%s" fill="none" stroke="currentColor" stroke-width="2"/>`, strings.Join(points, " "))
+	}
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
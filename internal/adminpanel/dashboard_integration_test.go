@@ -0,0 +1,148 @@
+package adminpanel_test
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func registerOrderModel(t *testing.T, orm adminpanel.ORMIntegrator) *adminpanel.Model {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("Shop", "Shop", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	model, err := testApp.RegisterModel(&adminpanel.Order{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model
+}
+
+func newOrders() []*adminpanel.Order {
+	discount := 5
+	return []*adminpanel.Order{
+		{ID: 1, Status: "paid", Total: 10, Discount: &discount},
+		{ID: 2, Status: "new", Total: 20},
+		{ID: 3, Status: "paid", Total: 30},
+	}
+}
+
+func TestModel_Statistics(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		orm  func(store adminpanel.ORMIntegrator) adminpanel.ORMIntegrator
+	}{
+		{"aggregate", func(store adminpanel.ORMIntegrator) adminpanel.ORMIntegrator { return store }},
+		{"fallback", func(store adminpanel.ORMIntegrator) adminpanel.ORMIntegrator { return plainORM{store} }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			model := registerOrderModel(t, test.orm(newMemoryORM(t, newOrders())))
+			paid := []adminpanel.FilterCondition{{Field: "Status", Operator: adminpanel.FilterExact, Value: "paid"}}
+
+			if count, err := model.CountInstances(nil, paid); err != nil || count != 2 {
+				t.Errorf("expected 2 paid orders, got %d (%v)", count, err)
+			}
+			groups, err := model.CountInstancesByField(nil, "Status", nil)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			expected := []adminpanel.GroupCount{{Value: "new", Count: 1}, {Value: "paid", Count: 2}}
+			if !reflect.DeepEqual(groups, expected) {
+				t.Errorf("expected groups %v, got %v", expected, groups)
+			}
+			if sum, err := model.AggregateField(nil, "Total", adminpanel.AggregateSum, paid); err != nil || sum != 40 {
+				t.Errorf("expected paid orders to total 40, got %v (%v)", sum, err)
+			}
+			if avg, err := model.AggregateField(nil, "Discount", adminpanel.AggregateAvg, nil); err != nil || avg != 5 {
+				t.Errorf("expected nil discounts to be left out of the average, got %v (%v)", avg, err)
+			}
+			if _, err = model.AggregateField(nil, "Status", adminpanel.AggregateSum, nil); err == nil {
+				t.Error("expected an error summing a text field")
+			}
+			if _, err = model.CountInstancesByField(nil, "Missing", nil); err == nil {
+				t.Error("expected an error grouping by a missing field")
+			}
+		})
+	}
+}
+
+func TestModel_Statistics_ListScope(t *testing.T) {
+	memory := plainORM{newMemoryORM(t, newOrders())}
+	aggregate := &adminpanel.MockAggregateORMIntegrator{}
+	scope := adminpanel.FilterCondition{Field: "Status", Operator: adminpanel.FilterExact, Value: "paid"}
+	for _, orm := range []adminpanel.ORMIntegrator{memory, aggregate} {
+		model := registerOrderModel(t, orm)
+		model.App.Panel.Config.ListScope = func(string, string, interface{}) ([]adminpanel.FilterCondition, bool, error) {
+			return []adminpanel.FilterCondition{scope}, false, nil
+		}
+		model.App.Panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+			return request.InstanceID == nil, nil
+		}
+		count, err := model.CountInstances(nil, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if orm == memory && count != 2 {
+			t.Errorf("expected the orders within the scope to be counted regardless of instance permissions, got %d", count)
+		}
+	}
+	if len(aggregate.Filters) != 1 || !reflect.DeepEqual(aggregate.Filters[0], []adminpanel.FilterCondition{scope}) {
+		t.Errorf("expected the scope to be passed to the integrator, got %v", aggregate.Filters)
+	}
+}
+
+func TestAdminPanel_GetHandler_Dashboard(t *testing.T) {
+	orders := newOrders()
+	orders[1].Status = "<new>"
+	model := registerOrderModel(t, plainORM{newMemoryORM(t, orders)})
+	panel := model.App.Panel
+	panel.Config.StatTiles = []adminpanel.StatTile{
+		{Title: "Orders", Model: "Shop.Order"},
+		{Title: "Revenue", Model: "Shop.Order", Aggregate: adminpanel.AggregateSum, Field: "Total"},
+		{Title: "Average order", Model: "Shop.Order", Aggregate: adminpanel.AggregateAvg, Field: "Total", Filters: []adminpanel.FilterCondition{{Field: "Status", Operator: adminpanel.FilterExact, Value: "paid"}}},
+	}
+	panel.Config.Charts = []adminpanel.Chart{
+		{Title: "Orders by status", Model: "Shop.Order", GroupBy: "Status"},
+		{Title: "Orders by total", Kind: adminpanel.ChartLine, Model: "Shop.Order", GroupBy: "Total"},
+	}
+
+	code, body := panel.GetHandler()(map[string]string{})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	for _, expected := range []string{
+		"Revenue", ">60<", "Average order", ">20<",
+		"Orders by status", "<rect", "<title>paid: 2</title>", "&lt;new&gt;: 1",
+		"Orders by total", "<polyline",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected the dashboard to contain %q", expected)
+		}
+	}
+	if strings.Contains(body, "<new>") {
+		t.Error("expected chart labels to be escaped")
+	}
+
+	if strings.Contains(body, `title="Instances"`) {
+		t.Error("expected no instance count without an integrator counting in the database")
+	}
+
+	panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return request.ModelName == nil, nil
+	}
+	if _, body = panel.GetHandler()(map[string]string{}); strings.Contains(body, "Revenue") || strings.Contains(body, "<svg") {
+		t.Error("expected statistics over models the user may not read to be hidden")
+	}
+
+	panel.PermissionChecker = adminpanel.MockPermissionFunc
+	panel.Config.Charts = []adminpanel.Chart{{Title: "Broken", Model: "Shop.Missing", GroupBy: "Status"}}
+	if code, _ = panel.GetHandler()(map[string]string{}); code != http.StatusInternalServerError {
+		t.Errorf("expected %v for a chart over an unknown model, got %v", http.StatusInternalServerError, code)
+	}
+}
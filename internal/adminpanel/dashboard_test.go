@@ -0,0 +1,78 @@
+package adminpanel
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type Order struct {
+	ID       uint
+	Status   string
+	Total    float64
+	Discount *int
+}
+
+// MockAggregateORMIntegrator answers statistics with fixed values and records the filters it receives.
+type MockAggregateORMIntegrator struct {
+	MockORMIntegrator
+	Filters [][]FilterCondition
+}
+
+func (m *MockAggregateORMIntegrator) CountInstances(_ interface{}, filters []FilterCondition) (uint, error) {
+	m.Filters = append(m.Filters, filters)
+	return 42, nil
+}
+
+func (m *MockAggregateORMIntegrator) CountInstancesByField(_ interface{}, _ string, filters []FilterCondition) ([]GroupCount, error) {
+	m.Filters = append(m.Filters, filters)
+	return []GroupCount{{Value: "paid", Count: 40}, {Value: "new", Count: 2}}, nil
+}
+
+func (m *MockAggregateORMIntegrator) AggregateField(_ interface{}, _ string, _ AggregateFunc, filters []FilterCondition) (float64, error) {
+	m.Filters = append(m.Filters, filters)
+	return 1234.5, nil
+}
+
+func registerOrderTestModel(t *testing.T, orm ORMIntegrator) *Model {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("Shop", "Shop", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	model, err := testApp.RegisterModel(&Order{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model
+}
+
+func TestModel_Statistics_AggregateORM(t *testing.T) {
+	orm := &MockAggregateORMIntegrator{}
+	model := registerOrderTestModel(t, orm)
+	model.SoftDeleteField = "Discount"
+	paid := make([]FilterCondition, 1, 2)
+	paid[0] = FilterCondition{Field: "Status", Operator: FilterExact, Value: "paid"}
+
+	if count, err := model.CountInstances(nil, paid); err != nil || count != 42 {
+		t.Errorf("expected the count of the integrator, got %d (%v)", count, err)
+	}
+	if len(orm.Filters) != 1 || len(orm.Filters[0]) != 2 || orm.Filters[0][1] != model.trashCondition(false) {
+		t.Errorf("expected trashed instances to be left out, got %v", orm.Filters)
+	}
+	if paid[:2][1] != (FilterCondition{}) {
+		t.Error("expected the filters of the caller to be left untouched")
+	}
+
+	code, body := model.App.Panel.GetHandler()(map[string]string{})
+	if code != http.StatusOK || !strings.Contains(body, `title="Instances">42</span>`) {
+		t.Errorf("expected the dashboard to show the instance count, got %v", code)
+	}
+	code, body = model.App.GetHandler()(map[string]string{})
+	if code != http.StatusOK || !strings.Contains(body, `title="Instances">42</span>`) {
+		t.Errorf("expected the app page to show the instance count, got %v", code)
+	}
+}
@@ -1,10 +1,43 @@
 package adminpanel
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 )
 
+// ErrInvalidInstanceID is returned when an instance ID taken from a request cannot be parsed as the model's primary
+// key.
+var ErrInvalidInstanceID = errors.New("invalid instance id")
+
+// ErrPermissionDenied is returned when the permission checker denies part of an operation, such as a row of an
+// inline form set.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrInstanceNotFound reports that no instance has the requested primary key. ORM integrators wrap it in the errors
+// they return for missing instances, so the panel can tell them from failures of the database.
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// ErrVersionConflict is returned when an instance was saved by someone else since the version being saved was
+// fetched.
+var ErrVersionConflict = errors.New("version conflict")
+
 // GetErrorHTML generates an HTML string representing an error message with the given code and error.
 func GetErrorHTML(code uint, err error) (uint, string) {
 	return code, fmt.Sprintf("Code: %v. Error: %v", code, err)
 }
+
+// getRequestErrorCode returns the HTTP status code matching an error raised while processing a request.
+func getRequestErrorCode(err error) uint {
+	switch {
+	case errors.Is(err, ErrInvalidInstanceID):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInstanceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVersionConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,281 @@
+package adminpanel
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"io"
+	"math"
+	"net/http"
+	"time"
+)
+
+// ExportFormat identifies the file format of a list view export.
+type ExportFormat string
+
+const (
+	// ExportCSV exports a header row with the column names followed by a row per instance.
+	ExportCSV ExportFormat = "csv"
+	// ExportJSON exports an array holding an object per instance.
+	ExportJSON ExportFormat = "json"
+	// ExportNDJSON exports an object per instance and per line.
+	ExportNDJSON ExportFormat = "ndjson"
+)
+
+// exportContentTypes maps the supported export formats to their content type.
+var exportContentTypes = map[ExportFormat]string{
+	ExportCSV:    "text/csv; charset=utf-8",
+	ExportJSON:   "application/json",
+	ExportNDJSON: "application/x-ndjson",
+}
+
+// exportBatchSize is the number of instances fetched at once from integrators implementing PaginatedORMIntegrator.
+const exportBatchSize = 500
+
+// ExportLink is a link exporting the current list view in a format.
+type ExportLink struct {
+	Format ExportFormat
+	Link   string
+}
+
+// GetExportLink returns the relative URL path exporting the list view of the model.
+func (m *Model) GetExportLink() string {
+	return fmt.Sprintf("%s/export", m.GetLink())
+}
+
+// GetFullExportLink returns the full URL path exporting the list view of the model, including the admin prefix.
+func (m *Model) GetFullExportLink() string {
+	return m.App.Panel.Config.GetLink(m.GetExportLink())
+}
+
+// exportLinks returns the links exporting the list view described by the parameters in each format, keeping its
+// search, filters and ordering.
+func (p listParams) exportLinks(m *Model) []ExportLink {
+	values := listParams{Search: p.Search, Ordering: p.Ordering, Filters: p.Filters}.values()
+	links := make([]ExportLink, 0, len(exportContentTypes))
+	for _, format := range []ExportFormat{ExportCSV, ExportJSON, ExportNDJSON} {
+		values.Set("format", string(format))
+		links = append(links, ExportLink{Format: format, Link: m.GetFullExportLink() + "?" + values.Encode()})
+	}
+	return links
+}
+
+// canExport reports whether the list view of the model can be exported by the user: the web integrator must send
+// responses of any content type and the user must be allowed to export the model.
+func (m *Model) canExport(data interface{}) (bool, error) {
+	if _, ok := m.App.Panel.Web.(ResponseWebIntegrator); !ok {
+		return false, nil
+	}
+	return m.App.Panel.PermissionChecker.HasModelExportPermission(m.App.Name, m.Name, data)
+}
+
+// CreateExportLog creates a log entry when the list view of the model is exported.
+func (m *Model) CreateExportLog(ctx interface{}, format ExportFormat, count int) error {
+	message, err := json.Marshal(map[string]interface{}{"format": format, "rows": count})
+	if err != nil {
+		return err
+	}
+	return m.App.Panel.Config.CreateLog(ctx, logging.LogStoreLevelExport, fmt.Sprintf("%s | %s", m.App.Name, m.DisplayName), nil, "", string(message))
+}
+
+// GetExportHandler returns the handler streaming the list view of the model in the format named by the "format"
+// query parameter, CSV by default. The export holds the list columns of every instance matching the search and the
+// filters of the list view that the user may read, in the list view order.
+func (m *Model) GetExportHandler() ResponseHandlerFunc {
+	return func(data interface{}) Response {
+		format := ExportFormat(m.App.Panel.Web.GetQueryParam(data, "format"))
+		if format == "" {
+			format = ExportCSV
+		}
+		contentType, ok := exportContentTypes[format]
+		if !ok {
+			return NewHTMLResponse(GetErrorHTML(http.StatusBadRequest, fmt.Errorf("unsupported export format %q", format)))
+		}
+
+		checker := m.App.Panel.PermissionChecker
+		allowed, err := checker.HasModelReadPermission(m.App.Name, m.Name, data)
+		if err == nil && allowed {
+			allowed, err = checker.HasModelExportPermission(m.App.Name, m.Name, data)
+		}
+		if err != nil {
+			return NewHTMLResponse(GetErrorHTML(http.StatusInternalServerError, err))
+		}
+		if !allowed {
+			return NewHTMLResponse(GetErrorHTML(http.StatusForbidden, fmt.Errorf("you are not allowed to export %s", m.DisplayName)))
+		}
+
+		params := getListParams(m, data)
+		query := params.query(m, time.Now())
+		query.Offset = 0
+		columns := make([]FieldConfig, 0)
+		for _, fieldConfig := range m.Fields {
+			if fieldConfig.IncludeInListDisplay {
+				columns = append(columns, fieldConfig)
+			}
+		}
+
+		return NewFileResponse(fmt.Sprintf("%s.%s", m.Name, format), contentType, func(w io.Writer) error {
+			count, err := m.writeExport(w, data, format, query, columns)
+			if err != nil {
+				return err
+			}
+			return m.CreateExportLog(data, format, count)
+		})
+	}
+}
+
+// withPrimaryKeyOrdering returns ordering followed by the primary key, unless it already orders by it, so that the
+// order of the instances is total.
+func (m *Model) withPrimaryKeyOrdering(ordering []OrderBy) []OrderBy {
+	if m.PrimaryKeyField == "" {
+		return ordering
+	}
+	for _, order := range ordering {
+		if order.Field == m.PrimaryKeyField {
+			return ordering
+		}
+	}
+	return append(append([]OrderBy(nil), ordering...), OrderBy{Field: m.PrimaryKeyField})
+}
+
+// writeExport writes the instances matching query to w in the given format and returns how many were written.
+func (m *Model) writeExport(w io.Writer, data interface{}, format ExportFormat, query ListQuery, columns []FieldConfig) (int, error) {
+	var writeRow func(values []interface{}) error
+	var finish func() error
+	switch format {
+	case ExportCSV:
+		csvWriter := csv.NewWriter(w)
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = column.DisplayName
+		}
+		if err := csvWriter.Write(header); err != nil {
+			return 0, err
+		}
+		writeRow = func(values []interface{}) error {
+			record := make([]string, len(values))
+			for i, value := range values {
+				record[i] = formatExportValue(value)
+			}
+			return csvWriter.Write(record)
+		}
+		finish = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	case ExportJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return 0, err
+		}
+		separator := ""
+		writeRow = func(values []interface{}) error {
+			object, err := encodeExportObject(columns, values)
+			if err != nil {
+				return err
+			}
+			if _, err = io.WriteString(w, separator); err != nil {
+				return err
+			}
+			separator = ",\n"
+			_, err = w.Write(object)
+			return err
+		}
+		finish = func() error {
+			_, err := io.WriteString(w, "]\n")
+			return err
+		}
+	case ExportNDJSON:
+		writeRow = func(values []interface{}) error {
+			object, err := encodeExportObject(columns, values)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(append(object, '\n'))
+			return err
+		}
+		finish = func() error { return nil }
+	}
+
+	count := 0
+	err := m.forEachExportInstance(data, query, func(instance interface{}) error {
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			if field := fieldValueOf(instance, column.Name); field.IsValid() && field.CanInterface() {
+				values[i] = field.Interface()
+			}
+		}
+		count++
+		return writeRow(values)
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, finish()
+}
+
+// formatExportValue formats a field value for a CSV export. Unlike the list view, zero values are kept; only missing
+// values, such as nil pointers, give empty cells.
+func formatExportValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprint(value)
+}
+
+// encodeExportObject encodes the values of an instance as a JSON object keyed by the column field names, in column
+// order. Values keep their JSON type, missing values being null.
+func encodeExportObject(columns []FieldConfig, values []interface{}) ([]byte, error) {
+	object := []byte{'{'}
+	for i, column := range columns {
+		if i > 0 {
+			object = append(object, ',')
+		}
+		key, err := json.Marshal(column.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		object = append(append(append(object, key...), ':'), value...)
+	}
+	return append(object, '}'), nil
+}
+
+// forEachExportInstance calls fn with each instance matching query that the user may read. Integrators implementing
+// PaginatedORMIntegrator are read in batches, ordered by the primary key last so that the batches neither overlap
+// nor skip instances; others are read at once. It stops early when the request is canceled.
+func (m *Model) forEachExportInstance(data interface{}, query ListQuery, fn func(instance interface{}) error) error {
+	ctx := requestContext(m.App.Panel.Web, data)
+	query.Limit = exportBatchSize
+	if _, ok := m.getRequestORM(data).(PaginatedORMIntegrator); ok {
+		query.Ordering = m.withPrimaryKeyOrdering(query.Ordering)
+	} else {
+		query.Limit = math.MaxUint32
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		instances, totalCount, _, err := fetchListPage(m, data, query)
+		if err != nil {
+			return err
+		}
+		for _, instance := range instances {
+			if err = fn(instance); err != nil {
+				return err
+			}
+		}
+		query.Offset += query.Limit
+		if query.Offset >= totalCount {
+			return nil
+		}
+	}
+}
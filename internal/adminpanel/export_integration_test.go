@@ -0,0 +1,67 @@
+package adminpanel_test
+
+import (
+	"bytes"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func runExport(t *testing.T, model *adminpanel.Model, params map[string]string) (adminpanel.Response, string) {
+	response := model.GetExportHandler()(&adminpanel.MockRequest{Method: http.MethodGet, Params: params})
+	if response.Write == nil {
+		return response, response.Body
+	}
+	var body bytes.Buffer
+	if err := response.Write(&body); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return response, body.String()
+}
+
+func TestModel_GetExportHandler(t *testing.T) {
+	orders := newOrders()
+	orders[1].Status = `new, "rush"`
+	orders[2].Total = 0
+	model := registerOrderModel(t, newMemoryORM(t, orders))
+	model.Fields[0].Sortable = true
+
+	response, body := runExport(t, model, map[string]string{})
+	disposition := response.Header.Get("Content-Disposition")
+	if response.StatusCode != http.StatusOK || response.ContentType != "text/csv; charset=utf-8" || disposition != "attachment; filename=Order.csv" {
+		t.Fatalf("unexpected CSV response %v %q %q", response.StatusCode, response.ContentType, disposition)
+	}
+	expectedCSV := "ID,Status,Total,Discount\n1,paid,10,5\n2,\"new, \"\"rush\"\"\",20,\n3,paid,0,\n"
+	if body != expectedCSV {
+		t.Errorf("expected CSV %q, got %q", expectedCSV, body)
+	}
+
+	_, body = runExport(t, model, map[string]string{"format": "json", "order": "-ID"})
+	if !strings.HasPrefix(body, `[{"ID":3,"Status":"paid","Total":0,"Discount":null}`) || !strings.HasSuffix(body, "}]\n") || strings.Count(body, `{"ID"`) != 3 {
+		t.Errorf("expected a JSON array in the list ordering, got %s", body)
+	}
+
+	_, body = runExport(t, model, map[string]string{"format": "ndjson"})
+	if !strings.HasPrefix(body, `{"ID":1,"Status":"paid","Total":10,"Discount":5}`+"\n") || strings.Count(body, "\n") != 3 {
+		t.Errorf("expected an object per line, got %q", body)
+	}
+	if count := countLogs(t, model, logging.LogStoreLevelExport); count != 3 {
+		t.Errorf("expected 3 export log entries, got %d", count)
+	}
+
+	if response, _ = runExport(t, model, map[string]string{"format": "xml"}); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected %v for an unknown format, got %v", http.StatusBadRequest, response.StatusCode)
+	}
+
+	model.App.Panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return *request.Action != adminpanel.ExportAction, nil
+	}
+	if response, _ = runExport(t, model, map[string]string{}); response.StatusCode != http.StatusForbidden {
+		t.Errorf("expected %v without the export permission, got %v", http.StatusForbidden, response.StatusCode)
+	}
+	if _, body := model.GetViewHandler()(map[string]string{}); strings.Contains(body, model.GetFullExportLink()) {
+		t.Error("expected the export button to be hidden without the export permission")
+	}
+}
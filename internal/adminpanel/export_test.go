@@ -0,0 +1,52 @@
+package adminpanel
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func runExport(t *testing.T, model *Model, params map[string]string) (Response, string) {
+	response := model.GetExportHandler()(&MockRequest{Method: http.MethodGet, Params: params})
+	if response.Write == nil {
+		return response, response.Body
+	}
+	var body bytes.Buffer
+	if err := response.Write(&body); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return response, body.String()
+}
+
+func TestModel_GetExportHandler_Batches(t *testing.T) {
+	model, orm, web := registerPaginatedTestModel(t, exportBatchSize+2)
+
+	_, body := runExport(t, model, map[string]string{"format": "ndjson", "search": "Inst"})
+	if lines := strings.Count(body, "\n"); lines != exportBatchSize+2 {
+		t.Errorf("expected %d lines, got %d", exportBatchSize+2, lines)
+	}
+	if len(orm.Queries) != 2 || orm.Queries[0].Limit != exportBatchSize || orm.Queries[1].Offset != exportBatchSize {
+		t.Fatalf("expected the instances to be fetched in two batches, got %v", orm.Queries)
+	}
+	if orm.Queries[1].Search != "Inst" {
+		t.Errorf("expected the search to be honored, got %q", orm.Queries[1].Search)
+	}
+	if ordering := orm.Queries[1].Ordering; len(ordering) != 1 || ordering[0] != (OrderBy{Field: "ID"}) {
+		t.Errorf("expected the batches to be ordered by primary key, got %v", ordering)
+	}
+	model.Fields[0].Sortable = true
+	runExport(t, model, map[string]string{"format": "ndjson", "order": "-ID"})
+	if ordering := orm.Queries[len(orm.Queries)-1].Ordering; len(ordering) != 1 || ordering[0] != (OrderBy{Field: "ID", Descending: true}) {
+		t.Errorf("expected the requested primary key ordering to be kept, got %v", ordering)
+	}
+
+	route := "GET /admin/a/TestApp/TestModel/export"
+	if !containsString(web.Routes, route) {
+		t.Errorf("expected route %q to be registered, got %v", route, web.Routes)
+	}
+	_, body = model.GetViewHandler()(map[string]string{"search": "Inst"})
+	if !strings.Contains(body, model.GetFullExportLink()+"?format=csv&amp;search=Inst") {
+		t.Error("expected the list view to link to an export of the current search")
+	}
+}
@@ -15,8 +15,12 @@ type FieldConfig struct {
 	IncludeInListDisplay  bool
 	IncludeInSearch       bool
 	IncludeInInstanceView bool
-	AddFormField          form.Field
-	EditFormField         form.Field
+	Sortable              bool
+	Filterable            bool
+	// ForeignKey references the related model of a foreign key field as "app.Model".
+	ForeignKey    string
+	AddFormField  form.Field
+	EditFormField form.Field
 }
 
 // AdminFormFieldInterface allows a model to customize form fields for add and edit operations.
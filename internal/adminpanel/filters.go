@@ -0,0 +1,360 @@
+package adminpanel
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/form/fields"
+	"github.com/ovnicraft/go-advanced-admin/internal/utils"
+	"net/url"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ListFilterKind identifies the kind of widget a list filter is rendered with.
+type ListFilterKind string
+
+const (
+	// ListFilterKindBoolean filters boolean fields by yes, no or all.
+	ListFilterKindBoolean ListFilterKind = "boolean"
+	// ListFilterKindChoice filters fields by one of their choices.
+	ListFilterKindChoice ListFilterKind = "choice"
+	// ListFilterKindRange filters numeric fields by a minimum and maximum.
+	ListFilterKindRange ListFilterKind = "range"
+	// ListFilterKindDate filters time fields by a date range or a preset such as "today".
+	ListFilterKindDate ListFilterKind = "date"
+	// ListFilterKindText filters any other field by an exact value.
+	ListFilterKindText ListFilterKind = "text"
+)
+
+// Date presets accepted by date filters.
+const (
+	DatePresetToday     = "today"
+	DatePresetPast7Days = "past7days"
+	DatePresetThisMonth = "thisMonth"
+	DatePresetThisYear  = "thisYear"
+)
+
+var datePresets = []struct {
+	Value string
+	Label string
+}{
+	{DatePresetToday, "Today"},
+	{DatePresetPast7Days, "Past 7 days"},
+	{DatePresetThisMonth, "This month"},
+	{DatePresetThisYear, "This year"},
+}
+
+// ListFilterOption is a link selecting one value of a list filter.
+type ListFilterOption struct {
+	Label  string
+	Link   string
+	Active bool
+}
+
+// ListFilterInput is a hidden form input carrying the list view state through a filter form.
+type ListFilterInput struct {
+	Name  string
+	Value string
+}
+
+// ListFilter describes a filter shown in the list view sidebar.
+type ListFilter struct {
+	Field        FieldConfig
+	Kind         ListFilterKind
+	Active       bool
+	Options      []ListFilterOption
+	InputType    string
+	ExactParam   string
+	Exact        string
+	MinParam     string
+	Min          string
+	MaxParam     string
+	Max          string
+	ClearLink    string
+	HiddenInputs []ListFilterInput
+}
+
+func filterParam(fieldName string, operator string) string {
+	return fieldName + "__" + operator
+}
+
+// getFilterKind returns the kind of filter used for a field, based on its type and form field.
+func getFilterKind(fieldConfig FieldConfig) ListFilterKind {
+	if len(getFieldChoices(fieldConfig)) > 0 {
+		return ListFilterKindChoice
+	}
+	if fieldConfig.FieldType == reflect.TypeOf(time.Time{}) {
+		return ListFilterKindDate
+	}
+	switch fieldConfig.FieldType.Kind() {
+	case reflect.Bool:
+		return ListFilterKindBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return ListFilterKindRange
+	default:
+		return ListFilterKindText
+	}
+}
+
+// getFieldChoices returns the choices of a field whose form field is a choice field.
+func getFieldChoices(fieldConfig FieldConfig) []fields.Choice {
+	for _, formField := range []interface{}{fieldConfig.AddFormField, fieldConfig.EditFormField} {
+		if choiceField, ok := formField.(*fields.ChoiceField); ok && len(choiceField.Choices) > 0 {
+			return choiceField.Choices
+		}
+	}
+	return nil
+}
+
+// getFilterParamNames returns the query parameters read by the filter of a field.
+func getFilterParamNames(fieldConfig FieldConfig) []string {
+	switch getFilterKind(fieldConfig) {
+	case ListFilterKindRange:
+		return []string{filterParam(fieldConfig.Name, "gte"), filterParam(fieldConfig.Name, "lte")}
+	case ListFilterKindDate:
+		return []string{filterParam(fieldConfig.Name, "preset"), filterParam(fieldConfig.Name, "gte"), filterParam(fieldConfig.Name, "lte")}
+	default:
+		return []string{filterParam(fieldConfig.Name, "exact")}
+	}
+}
+
+// getFilterParams reads the active filter query parameters from the request.
+func getFilterParams(m *Model, data interface{}) url.Values {
+	values := url.Values{}
+	for _, fieldConfig := range m.Fields {
+		if !fieldConfig.Filterable {
+			continue
+		}
+		for _, name := range getFilterParamNames(fieldConfig) {
+			if value := m.App.Panel.Web.GetQueryParam(data, name); value != "" {
+				values.Set(name, value)
+			}
+		}
+	}
+	return values
+}
+
+// buildFilterConditions converts filter query parameters into ORM filter conditions. Values that cannot be parsed
+// for their field are ignored.
+func buildFilterConditions(m *Model, values url.Values, now time.Time) []FilterCondition {
+	conditions := make([]FilterCondition, 0)
+	for _, fieldConfig := range m.Fields {
+		if !fieldConfig.Filterable {
+			continue
+		}
+		if getFilterKind(fieldConfig) == ListFilterKindDate {
+			conditions = append(conditions, buildDateFilterConditions(fieldConfig.Name, values, now)...)
+			continue
+		}
+		operators := map[string]FilterOperator{"exact": FilterExact, "gte": FilterGTE, "lte": FilterLTE}
+		for _, name := range []string{"exact", "gte", "lte"} {
+			raw := values.Get(filterParam(fieldConfig.Name, name))
+			if raw == "" {
+				continue
+			}
+			value, err := utils.ConvertStringToType(raw, fieldConfig.FieldType)
+			if err != nil {
+				continue
+			}
+			conditions = append(conditions, FilterCondition{Field: fieldConfig.Name, Operator: operators[name], Value: value})
+		}
+	}
+	return conditions
+}
+
+// buildDateFilterConditions converts the preset or date range of a date filter into conditions on a half-open
+// time interval, so whole days are matched regardless of the time of day.
+func buildDateFilterConditions(fieldName string, values url.Values, now time.Time) []FilterCondition {
+	var start, end *time.Time
+	if preset := values.Get(filterParam(fieldName, "preset")); preset != "" {
+		if presetStart, presetEnd, ok := getDatePresetRange(preset, now); ok {
+			start, end = &presetStart, &presetEnd
+		}
+	} else {
+		if from, err := time.ParseInLocation("2006-01-02", values.Get(filterParam(fieldName, "gte")), now.Location()); err == nil {
+			start = &from
+		}
+		if to, err := time.ParseInLocation("2006-01-02", values.Get(filterParam(fieldName, "lte")), now.Location()); err == nil {
+			to = to.AddDate(0, 0, 1)
+			end = &to
+		}
+	}
+
+	conditions := make([]FilterCondition, 0, 2)
+	if start != nil {
+		conditions = append(conditions, FilterCondition{Field: fieldName, Operator: FilterGTE, Value: *start})
+	}
+	if end != nil {
+		conditions = append(conditions, FilterCondition{Field: fieldName, Operator: FilterLT, Value: *end})
+	}
+	return conditions
+}
+
+// getDatePresetRange returns the half-open interval covered by a date preset.
+func getDatePresetRange(preset string, now time.Time) (time.Time, time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+	switch preset {
+	case DatePresetToday:
+		return today, tomorrow, true
+	case DatePresetPast7Days:
+		return today.AddDate(0, 0, -6), tomorrow, true
+	case DatePresetThisMonth:
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return first, first.AddDate(0, 1, 0), true
+	case DatePresetThisYear:
+		first := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+		return first, first.AddDate(1, 0, 0), true
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+}
+
+// matchesFilters reports whether an instance satisfies every condition, for integrators that cannot filter in
+// the database. Nil fields never match.
+func matchesFilters(instance interface{}, conditions []FilterCondition) bool {
+	for _, condition := range conditions {
+		field := fieldValueOf(instance, condition.Field)
+		if condition.Operator == FilterIsNull {
+			if isNull, _ := condition.Value.(bool); isNull == field.IsValid() {
+				return false
+			}
+			continue
+		}
+		if !field.IsValid() {
+			return false
+		}
+		cmp := compareFieldValues(field, reflect.ValueOf(condition.Value))
+		var matches bool
+		switch condition.Operator {
+		case FilterExact:
+			matches = cmp == 0
+		case FilterGTE:
+			matches = cmp >= 0
+		case FilterLTE:
+			matches = cmp <= 0
+		case FilterLT:
+			matches = cmp < 0
+		}
+		if !matches {
+			return false
+		}
+	}
+	return true
+}
+
+// filterInstances keeps the instances matching every condition.
+func filterInstances(instances []interface{}, conditions []FilterCondition) []interface{} {
+	if len(conditions) == 0 {
+		return instances
+	}
+	filtered := make([]interface{}, 0, len(instances))
+	for _, instance := range instances {
+		if matchesFilters(instance, conditions) {
+			filtered = append(filtered, instance)
+		}
+	}
+	return filtered
+}
+
+// getListFilters returns the filters of the list view sidebar, with links preserving the rest of the list state.
+func getListFilters(m *Model, params listParams) []ListFilter {
+	filters := make([]ListFilter, 0)
+	for _, fieldConfig := range m.Fields {
+		if !fieldConfig.Filterable {
+			continue
+		}
+		paramNames := getFilterParamNames(fieldConfig)
+		filter := ListFilter{
+			Field:        fieldConfig,
+			Kind:         getFilterKind(fieldConfig),
+			ClearLink:    params.withFilter(nil, paramNames...).link(m),
+			HiddenInputs: params.withFilter(nil, paramNames...).withPage(1).hiddenInputs(),
+		}
+		for _, name := range paramNames {
+			if params.Filters.Get(name) != "" {
+				filter.Active = true
+			}
+		}
+
+		exactParam := filterParam(fieldConfig.Name, "exact")
+		addOption := func(label, param, value string) {
+			filter.Options = append(filter.Options, ListFilterOption{
+				Label:  label,
+				Link:   params.withFilter(map[string]string{param: value}, paramNames...).link(m),
+				Active: params.Filters.Get(param) == value,
+			})
+		}
+
+		switch filter.Kind {
+		case ListFilterKindBoolean:
+			addOption("All", exactParam, "")
+			addOption("Yes", exactParam, "true")
+			addOption("No", exactParam, "false")
+		case ListFilterKindChoice:
+			addOption("All", exactParam, "")
+			for _, choice := range getFieldChoices(fieldConfig) {
+				addOption(choice.Label, exactParam, choice.Value)
+			}
+		case ListFilterKindDate:
+			presetParam := filterParam(fieldConfig.Name, "preset")
+			filter.Options = append(filter.Options, ListFilterOption{Label: "Any date", Link: filter.ClearLink, Active: !filter.Active})
+			for _, preset := range datePresets {
+				addOption(preset.Label, presetParam, preset.Value)
+			}
+			filter.InputType = "date"
+		case ListFilterKindRange:
+			filter.InputType = "number"
+		case ListFilterKindText:
+			filter.InputType = "text"
+			filter.ExactParam = exactParam
+			filter.Exact = params.Filters.Get(exactParam)
+		}
+
+		if filter.Kind == ListFilterKindRange || filter.Kind == ListFilterKindDate {
+			filter.MinParam = filterParam(fieldConfig.Name, "gte")
+			filter.Min = params.Filters.Get(filter.MinParam)
+			filter.MaxParam = filterParam(fieldConfig.Name, "lte")
+			filter.Max = params.Filters.Get(filter.MaxParam)
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// withFilter returns a copy of the parameters where the given filter parameters are cleared before the new values
+// are set. Empty values leave the parameter cleared.
+func (p listParams) withFilter(set map[string]string, clear ...string) listParams {
+	filters := url.Values{}
+	for name, values := range p.Filters {
+		filters[name] = append([]string(nil), values...)
+	}
+	for _, name := range clear {
+		filters.Del(name)
+	}
+	for name, value := range set {
+		if value != "" {
+			filters.Set(name, value)
+		}
+	}
+	p.Filters = filters
+	p.Page = 1
+	return p
+}
+
+// hiddenInputs returns the parameters as hidden inputs, for forms that must keep the list view state.
+func (p listParams) hiddenInputs() []ListFilterInput {
+	values := p.values()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	inputs := make([]ListFilterInput, 0, len(names))
+	for _, name := range names {
+		inputs = append(inputs, ListFilterInput{Name: name, Value: values.Get(name)})
+	}
+	return inputs
+}
@@ -0,0 +1,171 @@
+package adminpanel
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"github.com/ovnicraft/go-advanced-admin/internal/form/fields"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type FilterTestModel struct {
+	ID        uint
+	Active    bool      `admin:"filter"`
+	Status    string    `admin:"filter"`
+	Age       int       `admin:"filter"`
+	Email     string    `admin:"filter:exclude"`
+	CreatedAt time.Time `admin:"filter;addForm:exclude;editForm:exclude"`
+}
+
+func (m *FilterTestModel) AdminFormField(name string, _ bool) form.Field {
+	if name == "Status" {
+		return &fields.ChoiceField{Choices: []fields.Choice{{Value: "open", Label: "Open"}, {Value: "closed", Label: "Closed"}}}
+	}
+	return nil
+}
+
+func registerFilterTestModel(t *testing.T, orm ORMIntegrator) *Model {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	model, err := testApp.RegisterModel(&FilterTestModel{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model
+}
+
+func TestGetFilterKind(t *testing.T) {
+	model := registerFilterTestModel(t, nil)
+
+	expected := map[string]ListFilterKind{
+		"Active":    ListFilterKindBoolean,
+		"Status":    ListFilterKindChoice,
+		"Age":       ListFilterKindRange,
+		"CreatedAt": ListFilterKindDate,
+		"Email":     ListFilterKindText,
+	}
+	for name, kind := range expected {
+		fieldConfig, _ := model.getFieldConfig(name)
+		if got := getFilterKind(fieldConfig); got != kind {
+			t.Errorf("expected %s filter kind %s, got %s", name, kind, got)
+		}
+	}
+
+	if emailConfig, _ := model.getFieldConfig("Email"); emailConfig.Filterable {
+		t.Error("expected Email not to be filterable")
+	}
+}
+
+func TestBuildFilterConditions(t *testing.T) {
+	model := registerFilterTestModel(t, nil)
+	now := time.Date(2024, time.March, 15, 13, 30, 0, 0, time.UTC)
+
+	values := url.Values{}
+	values.Set("Active__exact", "true")
+	values.Set("Status__exact", "open")
+	values.Set("Age__gte", "18")
+	values.Set("Age__lte", "not a number")
+	values.Set("CreatedAt__preset", DatePresetPast7Days)
+
+	conditions := buildFilterConditions(model, values, now)
+	expected := []FilterCondition{
+		{Field: "Active", Operator: FilterExact, Value: true},
+		{Field: "Status", Operator: FilterExact, Value: "open"},
+		{Field: "Age", Operator: FilterGTE, Value: 18},
+		{Field: "CreatedAt", Operator: FilterGTE, Value: time.Date(2024, time.March, 9, 0, 0, 0, 0, time.UTC)},
+		{Field: "CreatedAt", Operator: FilterLT, Value: time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)},
+	}
+	if !reflect.DeepEqual(conditions, expected) {
+		t.Errorf("expected %v, got %v", expected, conditions)
+	}
+
+	values = url.Values{}
+	values.Set("CreatedAt__gte", "2024-01-01")
+	values.Set("CreatedAt__lte", "2024-01-31")
+	conditions = buildFilterConditions(model, values, now)
+	expected = []FilterCondition{
+		{Field: "CreatedAt", Operator: FilterGTE, Value: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{Field: "CreatedAt", Operator: FilterLT, Value: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	if !reflect.DeepEqual(conditions, expected) {
+		t.Errorf("expected %v, got %v", expected, conditions)
+	}
+}
+
+func TestFilterInstances(t *testing.T) {
+	instances := []interface{}{
+		&FilterTestModel{ID: 1, Active: true, Age: 17},
+		&FilterTestModel{ID: 2, Active: true, Age: 30},
+		&FilterTestModel{ID: 3, Active: false, Age: 40},
+	}
+
+	filtered := filterInstances(instances, []FilterCondition{
+		{Field: "Active", Operator: FilterExact, Value: true},
+		{Field: "Age", Operator: FilterGTE, Value: 18},
+	})
+	if len(filtered) != 1 || filtered[0].(*FilterTestModel).ID != 2 {
+		t.Errorf("expected only instance 2 to match, got %v", filtered)
+	}
+}
+
+func TestModel_GetViewHandler_Filters(t *testing.T) {
+	orm := &MockPaginatedORMIntegrator{}
+	model := registerFilterTestModel(t, orm)
+
+	query := map[string]string{"Active__exact": "false", "search": "bob", "order": "Age"}
+	code, body := model.GetViewHandler()(query)
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+
+	expected := []FilterCondition{{Field: "Active", Operator: FilterExact, Value: false}}
+	if !reflect.DeepEqual(orm.Queries[0].Filters, expected) {
+		t.Errorf("expected filters %v, got %v", expected, orm.Queries[0].Filters)
+	}
+	if !strings.Contains(body, `name="Active__exact" value="false"`) {
+		t.Error("expected the search form to keep the active filter")
+	}
+	if !strings.Contains(body, "Past 7 days") {
+		t.Error("expected the date filter presets to be rendered")
+	}
+}
+
+func TestGetListFilters_Links(t *testing.T) {
+	model := registerFilterTestModel(t, nil)
+	params := listParams{Search: "bob", Page: 4, Filters: url.Values{"Status__exact": {"open"}}}
+
+	filters := getListFilters(model, params)
+	if len(filters) != 4 {
+		t.Fatalf("expected 4 filters, got %d", len(filters))
+	}
+
+	status := filters[1]
+	if !status.Active {
+		t.Error("expected the status filter to be active")
+	}
+	for _, option := range status.Options {
+		if option.Label == "Closed" {
+			if !strings.Contains(option.Link, "Status__exact=closed") || !strings.Contains(option.Link, "search=bob") {
+				t.Errorf("expected the option link to switch the choice and keep the search, got %s", option.Link)
+			}
+			if strings.Contains(option.Link, "page=") {
+				t.Errorf("expected the option link to reset the page, got %s", option.Link)
+			}
+		}
+		if option.Label == "Open" && !option.Active {
+			t.Error("expected the selected choice to be active")
+		}
+	}
+	if strings.Contains(status.ClearLink, "Status__exact") {
+		t.Errorf("expected the clear link to drop the filter, got %s", status.ClearLink)
+	}
+}
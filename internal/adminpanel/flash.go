@@ -0,0 +1,187 @@
+package adminpanel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// FlashLevel is the level of a flash message, deciding how it is shown.
+type FlashLevel string
+
+// Levels of flash messages.
+const (
+	FlashSuccess FlashLevel = "success"
+	FlashInfo    FlashLevel = "info"
+	FlashWarning FlashLevel = "warning"
+	FlashError   FlashLevel = "error"
+)
+
+// FlashMessage is a message kept for the next page shown to the user, typically after a redirect.
+type FlashMessage struct {
+	Level   FlashLevel `json:"level"`
+	Message string     `json:"message"`
+}
+
+// GetAlertClass returns the CSS class of the alert showing the message.
+func (f FlashMessage) GetAlertClass() string {
+	if f.Level == FlashError {
+		return "alert-danger"
+	}
+	return "alert-" + string(f.Level)
+}
+
+// FlashStore keeps the flash messages of a user until the next page is shown to them.
+type FlashStore interface {
+	// AddFlash keeps a message for the user of the request carried by ctx.
+	AddFlash(ctx interface{}, message FlashMessage) error
+	// PopFlashes returns the messages kept for the user of the request carried by ctx, and forgets them.
+	PopFlashes(ctx interface{}) ([]FlashMessage, error)
+}
+
+// CookieWebIntegrator is an optional extension of WebIntegrator for integrators that can read and set cookies.
+type CookieWebIntegrator interface {
+	// GetCookie returns the value of the named cookie of the request, or an empty value if it has none.
+	GetCookie(ctx interface{}, name string) string
+	// SetCookie adds a cookie to the response.
+	SetCookie(ctx interface{}, cookie *http.Cookie)
+}
+
+// ResponseCookieWebIntegrator is an optional extension of CookieWebIntegrator for integrators that can read back the
+// cookies already set on the response.
+type ResponseCookieWebIntegrator interface {
+	// GetResponseCookie returns the value of the last cookie of the response with the given name, and whether the
+	// response sets one.
+	GetResponseCookie(ctx interface{}, name string) (string, bool)
+}
+
+// MemoryFlashStore is a FlashStore keeping the messages of each session in memory. It is safe for concurrent use.
+type MemoryFlashStore struct {
+	sessionID SessionIDFunc
+	mu        sync.Mutex
+	messages  map[string][]FlashMessage
+}
+
+// NewMemoryFlashStore creates a MemoryFlashStore identifying the session of requests with sessionID.
+func NewMemoryFlashStore(sessionID SessionIDFunc) *MemoryFlashStore {
+	return &MemoryFlashStore{sessionID: sessionID, messages: make(map[string][]FlashMessage)}
+}
+
+// AddFlash keeps a message for the session of the request. Messages of requests without a session are dropped.
+func (s *MemoryFlashStore) AddFlash(ctx interface{}, message FlashMessage) error {
+	session, err := s.sessionID(ctx)
+	if err != nil || session == "" {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[session] = append(s.messages[session], message)
+	return nil
+}
+
+// PopFlashes returns and forgets the messages of the session of the request.
+func (s *MemoryFlashStore) PopFlashes(ctx interface{}) ([]FlashMessage, error) {
+	session, err := s.sessionID(ctx)
+	if err != nil || session == "" {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := s.messages[session]
+	delete(s.messages, session)
+	return messages, nil
+}
+
+// CookieFlashStore is a FlashStore keeping the messages in a cookie of the browser, so that it needs no session.
+// The cookie is not signed: users can forge the messages shown to themselves, which are escaped like any other text.
+type CookieFlashStore struct {
+	Web CookieWebIntegrator
+	// Name is the name of the cookie.
+	Name string
+	// Path is the path of the cookie, which should cover the admin panel.
+	Path string
+}
+
+// NewCookieFlashStore creates a CookieFlashStore keeping the messages in the "flash" cookie, set with web.
+func NewCookieFlashStore(web CookieWebIntegrator) *CookieFlashStore {
+	return &CookieFlashStore{Web: web, Name: "flash", Path: "/"}
+}
+
+// AddFlash appends a message to the cookie. If the integrator implements ResponseCookieWebIntegrator, the messages
+// added earlier during the same request are kept by appending to the cookie already set on the response; otherwise
+// only the messages of the cookie of the request are kept.
+func (s *CookieFlashStore) AddFlash(ctx interface{}, message FlashMessage) error {
+	messages := s.readCookie(ctx)
+	if web, ok := s.Web.(ResponseCookieWebIntegrator); ok {
+		if value, set := web.GetResponseCookie(ctx, s.Name); set {
+			messages = decodeFlashes(value)
+		}
+	}
+	messages = append(messages, message)
+	value, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+	s.Web.SetCookie(ctx, &http.Cookie{
+		Name:     s.Name,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     s.Path,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// PopFlashes returns the messages of the cookie and expires it.
+func (s *CookieFlashStore) PopFlashes(ctx interface{}) ([]FlashMessage, error) {
+	messages := s.readCookie(ctx)
+	if len(messages) > 0 {
+		s.Web.SetCookie(ctx, &http.Cookie{Name: s.Name, Path: s.Path, MaxAge: -1, HttpOnly: true})
+	}
+	return messages, nil
+}
+
+// readCookie returns the messages of the cookie of the request.
+func (s *CookieFlashStore) readCookie(ctx interface{}) []FlashMessage {
+	return decodeFlashes(s.Web.GetCookie(ctx, s.Name))
+}
+
+// decodeFlashes returns the messages of the value of a flash cookie. Malformed or empty values hold no message.
+func decodeFlashes(cookie string) []FlashMessage {
+	value, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil || len(value) == 0 {
+		return nil
+	}
+	var messages []FlashMessage
+	if err = json.Unmarshal(value, &messages); err != nil {
+		return nil
+	}
+	return messages
+}
+
+// AddFlash keeps a message of the given level for the next page shown to the user of the request carried by ctx. It
+// does nothing if the panel has no FlashStore.
+func (ap *AdminPanel) AddFlash(ctx interface{}, level FlashLevel, message string) error {
+	if ap.Config.FlashStore == nil {
+		return nil
+	}
+	return ap.Config.FlashStore.AddFlash(ctx, FlashMessage{Level: level, Message: message})
+}
+
+// PopFlashes returns and forgets the flash messages of the user of the request carried by ctx.
+func (ap *AdminPanel) PopFlashes(ctx interface{}) ([]FlashMessage, error) {
+	if ap.Config.FlashStore == nil {
+		return nil, nil
+	}
+	return ap.Config.FlashStore.PopFlashes(ctx)
+}
+
+// redirectWithError redirects to url with err as an error flash message. Without a FlashStore, or if the message
+// cannot be kept, it shows err on an error page with the given status code instead.
+func (ap *AdminPanel) redirectWithError(ctx interface{}, url string, code uint, err error) (uint, string) {
+	if ap.Config.FlashStore == nil || ap.AddFlash(ctx, FlashError, err.Error()) != nil {
+		return GetErrorHTML(code, err)
+	}
+	return http.StatusSeeOther, url
+}
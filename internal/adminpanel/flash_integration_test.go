@@ -0,0 +1,55 @@
+package adminpanel_test
+
+import (
+	"errors"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestModel_Flashes(t *testing.T) {
+	model, orm := registerProductModel(t)
+	panel := model.App.Panel
+	panel.Config.FlashStore = adminpanel.NewCookieFlashStore(panel.Web.(*adminpanel.MockWebIntegrator))
+
+	request := &adminpanel.MockRequest{Method: http.MethodPost, Form: map[string][]string{"Name": {"Gadget"}, "Stock": {"7"}}}
+	if code, body := model.GetAddHandler()(request); code != http.StatusSeeOther || len(storedRows[adminpanel.Product](t, orm)) != 2 {
+		t.Fatalf("expected the product to be added, got %v: %s", code, body)
+	}
+
+	request.Method = http.MethodGet
+	code, body := model.GetViewHandler()(request)
+	if code != http.StatusOK || !strings.Contains(body, `class="alert alert-success alert-dismissible"`) ||
+		!strings.Contains(body, "Gadget 7}&#34; was added successfully.") {
+		t.Errorf("expected the confirmation to be shown, got %v", code)
+	}
+	if _, body = model.GetViewHandler()(request); strings.Contains(body, "was added successfully") {
+		t.Errorf("expected the confirmation to be shown once")
+	}
+}
+
+// failingFlashStore is a FlashStore failing to keep any message, as a session store that is down would.
+type failingFlashStore struct{}
+
+func (failingFlashStore) AddFlash(interface{}, adminpanel.FlashMessage) error {
+	return errors.New("session store unavailable")
+}
+
+func (failingFlashStore) PopFlashes(interface{}) ([]adminpanel.FlashMessage, error) {
+	return nil, nil
+}
+
+func TestModel_FlashStoreError(t *testing.T) {
+	model, orm := registerProductModel(t)
+	model.App.Panel.Config.FlashStore = failingFlashStore{}
+
+	request := &adminpanel.MockRequest{Method: http.MethodPost, Form: map[string][]string{"Name": {"Gadget"}, "Stock": {"7"}}}
+	code, body := model.GetAddHandler()(request)
+	if code != http.StatusInternalServerError || !strings.Contains(body, "session store unavailable") {
+		t.Errorf("expected the flash store error to be reported, got %v", code)
+	}
+	if len(storedRows[adminpanel.Product](t, orm)) != 2 {
+		t.Errorf("expected the product to be added before the message failed")
+	}
+}
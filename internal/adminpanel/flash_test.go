@@ -0,0 +1,91 @@
+package adminpanel
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlashMessage_GetAlertClass(t *testing.T) {
+	tests := []struct {
+		level    FlashLevel
+		expected string
+	}{
+		{FlashSuccess, "alert-success"},
+		{FlashInfo, "alert-info"},
+		{FlashWarning, "alert-warning"},
+		{FlashError, "alert-danger"},
+	}
+	for _, tt := range tests {
+		if got := (FlashMessage{Level: tt.level}).GetAlertClass(); got != tt.expected {
+			t.Errorf("expected %q for %q, got %q", tt.expected, tt.level, got)
+		}
+	}
+}
+
+func TestFlashStores(t *testing.T) {
+	messages := []FlashMessage{{Level: FlashSuccess, Message: "Saved"}, {Level: FlashWarning, Message: "Check the stock"}}
+	stores := map[string]FlashStore{
+		"Memory": NewMemoryFlashStore(MockSessionID),
+		"Cookie": NewCookieFlashStore(&MockWebIntegrator{}),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			request := &MockRequest{Headers: map[string]string{"Session": "alice"}}
+			for _, message := range messages {
+				if err := store.AddFlash(request, message); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			}
+			popped, err := store.PopFlashes(request)
+			if err != nil || !reflect.DeepEqual(popped, messages) {
+				t.Errorf("expected %v, got %v (%v)", messages, popped, err)
+			}
+			if popped, _ = store.PopFlashes(request); len(popped) != 0 {
+				t.Errorf("expected the messages to be shown once, got %v", popped)
+			}
+		})
+	}
+
+	// A request whose cookies are not updated by the cookies set on the response, like a real one.
+	store := stores["Cookie"]
+	request := &MockRequest{}
+	if err := store.AddFlash(request, messages[0]); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	request.Cookies = nil
+	if err := store.AddFlash(request, messages[1]); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if popped := decodeFlashes(request.ResponseCookies["flash"]); !reflect.DeepEqual(popped, messages) {
+		t.Errorf("expected the response cookie to hold %v, got %v", messages, popped)
+	}
+
+	if popped, _ := stores["Cookie"].PopFlashes(&MockRequest{Cookies: map[string]string{"flash": "%%%"}}); popped != nil {
+		t.Errorf("expected a malformed cookie to hold no message, got %v", popped)
+	}
+}
+
+func TestAdminPanel_RedirectWithError(t *testing.T) {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	failure := errors.New("instance is referenced")
+
+	if code, body := panel.redirectWithError(&MockRequest{}, "/admin", http.StatusConflict, failure); code != http.StatusConflict || !strings.Contains(body, failure.Error()) {
+		t.Errorf("expected an error page without a flash store, got %v %q", code, body)
+	}
+
+	panel.Config.FlashStore = NewMemoryFlashStore(MockSessionID)
+	request := &MockRequest{Headers: map[string]string{"Session": "alice"}}
+	if code, location := panel.redirectWithError(request, "/admin", http.StatusConflict, failure); code != http.StatusSeeOther || location != "/admin" {
+		t.Errorf("expected a redirect, got %v %q", code, location)
+	}
+	flashes, _ := panel.PopFlashes(request)
+	if len(flashes) != 1 || flashes[0].Level != FlashError || flashes[0].Message != failure.Error() {
+		t.Errorf("expected the error to be flashed, got %v", flashes)
+	}
+}
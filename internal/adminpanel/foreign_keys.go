@@ -0,0 +1,235 @@
+package adminpanel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"github.com/ovnicraft/go-advanced-admin/internal/form/fields"
+	"github.com/ovnicraft/go-advanced-admin/internal/utils"
+	"html/template"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ForeignKeyField is a form field selecting an instance of a related model. It is rendered as a select2 dropdown
+// searching the related model, and only accepts IDs of instances that exist.
+type ForeignKeyField struct {
+	fields.BaseField
+	Panel *AdminPanel
+	// Related references the related model as "app.Model". It is resolved when the field is used, so related
+	// models may be registered after the models pointing at them.
+	Related   string
+	ValueType reflect.Type
+	Required  bool
+	// Context is the context of the request the field is used in. The related instances are looked up within it.
+	Context context.Context
+}
+
+// GetModelByReference returns the registered model referenced as "app.Model".
+func (ap *AdminPanel) GetModelByReference(reference string) (*Model, error) {
+	appName, modelName, ok := parseModelReference(reference)
+	if !ok {
+		return nil, fmt.Errorf("invalid model reference '%s', expected 'app.Model'", reference)
+	}
+	app, ok := ap.Apps[appName]
+	if !ok {
+		return nil, fmt.Errorf("admin app '%s' referenced by '%s' is not registered", appName, reference)
+	}
+	model, ok := app.Models[modelName]
+	if !ok {
+		return nil, fmt.Errorf("admin model '%s' referenced by '%s' is not registered", modelName, reference)
+	}
+	return model, nil
+}
+
+func parseModelReference(reference string) (string, string, bool) {
+	appName, modelName, ok := strings.Cut(reference, ".")
+	if !ok || appName == "" || modelName == "" || strings.Contains(modelName, ".") {
+		return "", "", false
+	}
+	return appName, modelName, true
+}
+
+// GetRelatedLink returns the full link to the view page of the instance a foreign key field points at, or an empty
+// string when the field is not a foreign key or holds no ID.
+func (m *Model) GetRelatedLink(fieldName string, value interface{}) string {
+	fieldConfig, ok := m.getFieldConfig(fieldName)
+	if !ok || fieldConfig.ForeignKey == "" {
+		return ""
+	}
+	related, err := m.App.Panel.GetModelByReference(fieldConfig.ForeignKey)
+	if err != nil {
+		return ""
+	}
+	id, ok := foreignKeyValue(value)
+	if !ok {
+		return ""
+	}
+	return (&Instance{InstanceID: id, Model: related}).GetFullLink()
+}
+
+// GetFieldLink returns the link of a list view cell: the related instance for foreign keys, the instance itself
+// otherwise.
+func (i *Instance) GetFieldLink(fieldName string) string {
+	value, err := utils.GetFieldValue(i.Data, fieldName)
+	if err == nil {
+		if link := i.Model.GetRelatedLink(fieldName, value); link != "" {
+			return link
+		}
+	}
+	return i.GetFullLink()
+}
+
+// foreignKeyValue dereferences a foreign key value, reporting false for nil pointers and zero values.
+func foreignKeyValue(value interface{}) (interface{}, bool) {
+	val := reflect.ValueOf(value)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil, false
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() || val.IsZero() || !val.CanInterface() {
+		return nil, false
+	}
+	return val.Interface(), true
+}
+
+// isNilInstance reports whether an instance returned by an ORM integrator is missing.
+func isNilInstance(instance interface{}) bool {
+	if instance == nil {
+		return true
+	}
+	val := reflect.ValueOf(instance)
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return val.IsNil()
+	}
+	return false
+}
+
+func (f *ForeignKeyField) relatedModel() (*Model, error) {
+	if f.Panel == nil {
+		return nil, errors.New("foreign key field is not attached to an admin panel")
+	}
+	return f.Panel.GetModelByReference(f.Related)
+}
+
+// relatedORM returns the integrator of the related model bound to the context of the request.
+func (f *ForeignKeyField) relatedORM(related *Model) ORMIntegrator {
+	return ORMWithContext(related.GetORM(), f.Context)
+}
+
+func (f *ForeignKeyField) HTML() (string, error) {
+	related, err := f.relatedModel()
+	if err != nil {
+		return "", err
+	}
+
+	options := []string{`<option value=""></option>`}
+	value, err := f.GoTypeToHTMLType(f.InitialValue)
+	if err != nil {
+		return "", err
+	}
+	if value != "" {
+		label := string(value)
+		id, _ := foreignKeyValue(f.InitialValue)
+		if instance, err := f.relatedORM(related).FetchInstance(related.PTR, id); err == nil && !isNilInstance(instance) {
+			label = (&Instance{InstanceID: id, Data: instance, Model: related}).GetRepr()
+		}
+		options = append(options, fmt.Sprintf(`<option value="%s" selected>%s</option>`,
+			template.HTMLEscapeString(string(value)),
+			template.HTMLEscapeString(label),
+		))
+	}
+
+	attributesMap := map[string]*string{}
+	setAttribute := func(name, value string) {
+		attributesMap[name] = &value
+	}
+	setAttribute("name", f.Name)
+	setAttribute("class", "form-select")
+	setAttribute("data-role", "select2-fk")
+	setAttribute("data-url", related.GetFullLink()+"/search")
+	setAttribute("data-placeholder", "Select "+related.DisplayName)
+	if f.Required {
+		attributesMap["required"] = nil
+	}
+	for key, value := range f.SupersedingAttributes {
+		attributesMap[key] = value
+	}
+	return selectHTML(attributesMap, options), nil
+}
+
+// selectHTML renders a select element with the given options. Attributes with a nil value are rendered without one.
+func selectHTML(attributesMap map[string]*string, options []string) string {
+	names := make([]string, 0, len(attributesMap))
+	for name := range attributesMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attributes := make([]string, 0, len(names))
+	for _, name := range names {
+		if attributesMap[name] == nil {
+			attributes = append(attributes, name)
+		} else {
+			attributes = append(attributes, fmt.Sprintf(`%s="%s"`, name, template.HTMLEscapeString(*attributesMap[name])))
+		}
+	}
+	return fmt.Sprintf(`<select %s>%s</select>`, strings.Join(attributes, " "), strings.Join(options, ""))
+}
+
+func (f *ForeignKeyField) GoTypeToHTMLType(value interface{}) (form.HTMLType, error) {
+	id, ok := foreignKeyValue(value)
+	if !ok {
+		return "", nil
+	}
+	text, err := f.Panel.EncodeKey(id)
+	if err != nil {
+		return "", err
+	}
+	return form.HTMLType(text), nil
+}
+
+func (f *ForeignKeyField) HTMLTypeToGoType(value form.HTMLType) (interface{}, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := f.Panel.DecodeKey(string(value), f.ValueType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid related instance id: %w", err)
+	}
+	return id, nil
+}
+
+func (f *ForeignKeyField) GetValidationFunctions() []form.FieldValidationFunc {
+	baseValidations := f.BaseField.GetValidationFunctions()
+	baseValidations = append(baseValidations, f.requiredValidation, f.existsValidation)
+	return baseValidations
+}
+
+func (f *ForeignKeyField) requiredValidation(value interface{}) ([]error, error) {
+	if f.Required && value == nil {
+		return []error{errors.New("field is required")}, nil
+	}
+	return nil, nil
+}
+
+func (f *ForeignKeyField) existsValidation(value interface{}) ([]error, error) {
+	if value == nil {
+		return nil, nil
+	}
+	related, err := f.relatedModel()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = related.fetchLiveInstance(f.relatedORM(related), value, nil); err != nil {
+		if errors.Is(err, ErrInstanceNotFound) {
+			return []error{fmt.Errorf("selected %s does not exist", related.DisplayName)}, nil
+		}
+		return nil, err
+	}
+	return nil, nil
+}
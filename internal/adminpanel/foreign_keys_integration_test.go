@@ -0,0 +1,102 @@
+package adminpanel_test
+
+import (
+	"errors"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"strings"
+	"testing"
+)
+
+// registerForeignKeyModels registers orders referencing customers stored in memory, orders first so the related model
+// is only resolved when the field is used.
+func registerForeignKeyModels(t *testing.T) (*adminpanel.Model, *failingORM) {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	shop, err := panel.RegisterApp("Shop", "Shop", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order, err := shop.RegisterModel(&adminpanel.FKOrder{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orm := &failingORM{Integrator: newMemoryORM(t, &adminpanel.FKCustomer{ID: 1, Name: "Alice"})}
+	if _, err = shop.RegisterModel(&adminpanel.FKCustomer{}, orm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return order, orm
+}
+
+func TestForeignKeyField_HTML(t *testing.T) {
+	order, _ := registerForeignKeyModels(t)
+	field := getFieldConfig(t, order, "CustomerID").EditFormField
+	_ = field.RegisterName("CustomerID")
+	field.RegisterInitialValue(uint(1))
+
+	html, err := field.HTML()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(html, `<option value="1" selected>Alice</option>`) {
+		t.Errorf("expected the current customer to be selected by its repr, got %s", html)
+	}
+	if !strings.Contains(html, `data-url="/admin/a/Shop/FKCustomer/search"`) {
+		t.Errorf("expected the dropdown to search the related model, got %s", html)
+	}
+
+	formInstance, err := order.NewAddForm(nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	rendered, err := form.RenderFormAsTabler(formInstance, nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(rendered, `data-role="select2"`) {
+		t.Errorf("expected the form renderer to keep the foreign key widget role, got %s", rendered)
+	}
+}
+
+func TestForeignKeyField_Validation(t *testing.T) {
+	order, _ := registerForeignKeyModels(t)
+	field := getFieldConfig(t, order, "CustomerID").AddFormField
+
+	value, err := field.HTMLTypeToGoType("1")
+	if err != nil || value != uint(1) {
+		t.Fatalf("expected uint 1, got %v (%v)", value, err)
+	}
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		errors int
+	}{
+		{"Existing", uint(1), 0},
+		{"Missing", uint(2), 1},
+		{"Empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := form.FieldValueIsValid(field, tt.value)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(errs) != tt.errors {
+				t.Errorf("expected %d validation errors, got %v", tt.errors, errs)
+			}
+		})
+	}
+}
+
+func TestForeignKeyField_ValidationORMError(t *testing.T) {
+	order, orm := registerForeignKeyModels(t)
+	orm.Err = errors.New("connection refused")
+
+	errs, err := form.FieldValueIsValid(getFieldConfig(t, order, "CustomerID").AddFormField, uint(1))
+	if err == nil || len(errs) != 0 {
+		t.Errorf("expected the ORM error to be returned instead of a validation error, got %v (%v)", errs, err)
+	}
+}
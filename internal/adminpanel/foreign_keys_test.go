@@ -0,0 +1,88 @@
+package adminpanel
+
+import (
+	"testing"
+)
+
+type FKCustomer struct {
+	ID   uint
+	Name string
+}
+
+func (c *FKCustomer) AdminInstanceRepr() string {
+	return c.Name
+}
+
+type FKOrder struct {
+	ID         uint
+	CustomerID uint `admin:"fk:Shop.FKCustomer;required"`
+}
+
+func registerForeignKeyTestModels(t *testing.T) *Model {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	shop, err := panel.RegisterApp("Shop", "Shop", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Orders are registered first, so the related model is only resolved when the field is used.
+	order, err := shop.RegisterModel(&FKOrder{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = shop.RegisterModel(&FKCustomer{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return order
+}
+
+func TestRegisterModel_ForeignKeyTag(t *testing.T) {
+	order := registerForeignKeyTestModels(t)
+
+	fieldConfig, _ := order.getFieldConfig("CustomerID")
+	if fieldConfig.ForeignKey != "Shop.FKCustomer" {
+		t.Errorf("expected foreign key 'Shop.FKCustomer', got %q", fieldConfig.ForeignKey)
+	}
+	field, ok := fieldConfig.EditFormField.(*ForeignKeyField)
+	if !ok {
+		t.Fatalf("expected a foreign key form field, got %T", fieldConfig.EditFormField)
+	}
+	if !field.Required {
+		t.Error("expected the foreign key field to be required")
+	}
+
+	panel, _ := NewMockAdminPanel()
+	app, _ := panel.RegisterApp("Shop", "Shop", nil)
+	type BadOrder struct {
+		ID         uint
+		CustomerID uint `admin:"fk:FKCustomer"`
+	}
+	if _, err := app.RegisterModel(&BadOrder{}, nil); err == nil {
+		t.Error("expected an error for a foreign key without an app")
+	}
+}
+
+func TestModel_GetRelatedLink(t *testing.T) {
+	order := registerForeignKeyTestModels(t)
+
+	if link := order.GetRelatedLink("CustomerID", uint(1)); link != "/admin/a/Shop/FKCustomer/1/view" {
+		t.Errorf("expected a link to the customer, got %q", link)
+	}
+	if link := order.GetRelatedLink("CustomerID", uint(0)); link != "" {
+		t.Errorf("expected no link for an empty foreign key, got %q", link)
+	}
+	if link := order.GetRelatedLink("ID", uint(1)); link != "" {
+		t.Errorf("expected no link for a plain field, got %q", link)
+	}
+
+	instance := &Instance{InstanceID: uint(5), Data: &FKOrder{ID: 5, CustomerID: 1}, Model: order}
+	if link := instance.GetFieldLink("ID"); link != "/admin/a/Shop/FKOrder/5/view" {
+		t.Errorf("expected plain cells to link to the instance, got %q", link)
+	}
+	if link := instance.GetFieldLink("CustomerID"); link != "/admin/a/Shop/FKCustomer/1/view" {
+		t.Errorf("expected foreign key cells to link to the related instance, got %q", link)
+	}
+}
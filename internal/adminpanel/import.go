@@ -0,0 +1,489 @@
+package adminpanel
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ImportRowAction is what an import does with a row of the imported file.
+type ImportRowAction string
+
+const (
+	// ImportCreate creates a new instance from the row.
+	ImportCreate ImportRowAction = "create"
+	// ImportUpdate updates the existing instance whose primary key is given by the row.
+	ImportUpdate ImportRowAction = "update"
+	// ImportError skips the row, which failed validation.
+	ImportError ImportRowAction = "error"
+)
+
+// maxImportSize is the size in bytes above which imported files are rejected.
+const maxImportSize = 10 << 20
+
+// ImportColumn is a column of an imported file and the form field it is mapped to.
+type ImportColumn struct {
+	Header string
+	// Field is the name of the add form field the column is mapped to, or empty when the column is ignored.
+	Field string
+}
+
+// ImportRow is a row of an imported file, validated and ready to be saved.
+type ImportRow struct {
+	// Number is the position of the row in the file, starting at 1 and not counting the header of CSV files.
+	Number int
+	Action ImportRowAction
+	// Values holds the values of the row, in column order.
+	Values     []string
+	Errors     []string
+	InstanceID interface{}
+
+	formData  map[string]form.HTMLType
+	columns   map[string]bool
+	current   interface{}
+	converted map[string]interface{}
+}
+
+// GetImportLink returns the relative URL path importing instances of the model.
+func (m *Model) GetImportLink() string {
+	return fmt.Sprintf("%s/import", m.GetLink())
+}
+
+// GetFullImportLink returns the full URL path importing instances of the model, including the admin prefix.
+func (m *Model) GetFullImportLink() string {
+	return m.App.Panel.Config.GetLink(m.GetImportLink())
+}
+
+// canImport reports whether the user may import instances of the model, which requires the permission to create or
+// to update them.
+func (m *Model) canImport(data interface{}) (bool, error) {
+	checker := m.App.Panel.PermissionChecker
+	allowed, err := checker.HasModelCreatePermission(m.App.Name, m.Name, data)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	return checker.HasModelUpdatePermission(m.App.Name, m.Name, data)
+}
+
+// GetImportHandler returns the HTTP handler function importing instances of the model from a CSV, JSON or NDJSON
+// file. Posting a file previews what importing it would create and update; posting it again with "confirm" set saves
+// it, provided no row has errors. Rows whose primary key matches an existing instance update the columns of that
+// instance present in the file, and other rows create new instances.
+func (m *Model) GetImportHandler() HandlerFunc {
+	return func(data interface{}) (uint, string) {
+		allowed, err := m.canImport(data)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		if !allowed {
+			return GetErrorHTML(http.StatusForbidden, fmt.Errorf("forbidden"))
+		}
+
+		switch m.App.Panel.Web.GetRequestMethod(data) {
+		case http.MethodGet:
+			return m.renderImport(data, map[string]interface{}{})
+		case http.MethodPost:
+			return m.processImportPOST(data)
+		default:
+			return GetErrorHTML(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		}
+	}
+}
+
+// renderImport renders the import page with the given extra template data.
+func (m *Model) renderImport(data interface{}, pageData map[string]interface{}) (uint, string) {
+	apps, err := GetAppsWithReadPermissions(m.App.Panel, data)
+	if err != nil {
+		return GetErrorHTML(http.StatusInternalServerError, err)
+	}
+	_, canUpload := m.App.Panel.Web.(FileWebIntegrator)
+	pageData["admin"] = m.App.Panel
+	pageData["apps"] = apps
+	pageData["navBarItems"] = m.App.Panel.Config.GetNavBarItems(data)
+	pageData["model"] = m
+	pageData["canUpload"] = canUpload
+	html, err := m.App.Panel.RenderPage(data, "import", pageData)
+	if err != nil {
+		return GetErrorHTML(http.StatusInternalServerError, err)
+	}
+	return http.StatusOK, html
+}
+
+func (m *Model) processImportPOST(data interface{}) (uint, string) {
+	formData := m.App.Panel.Web.GetFormData(data)
+	content, filename, err := m.readImportFile(data, formData)
+	if err != nil {
+		return m.renderImport(data, map[string]interface{}{"importErr": err})
+	}
+	format, err := getImportFormat(firstFormValue(formData, "format"), filename, content)
+	if err != nil {
+		return m.renderImport(data, map[string]interface{}{"importErr": err})
+	}
+	headers, records, err := parseImport(format, content)
+	if err != nil {
+		return m.renderImport(data, map[string]interface{}{"importErr": err})
+	}
+
+	columns, rows, err := m.previewImport(data, headers, records)
+	if err != nil {
+		return GetErrorHTML(getRequestErrorCode(err), err)
+	}
+	counts := map[ImportRowAction]int{}
+	for _, row := range rows {
+		counts[row.Action]++
+	}
+	pageData := map[string]interface{}{
+		"format":  format,
+		"content": content,
+		"columns": columns,
+		"rows":    rows,
+		"created": counts[ImportCreate],
+		"updated": counts[ImportUpdate],
+		"errored": counts[ImportError],
+	}
+	if firstFormValue(formData, "confirm") != "true" || counts[ImportError] > 0 || len(rows) == 0 {
+		return m.renderImport(data, pageData)
+	}
+
+	err = m.runLoggedWrites(data, m.SaveMode, func(orm ORMIntegrator, logs *writeLogs) error {
+		for _, row := range rows {
+			if err := m.saveImportRow(data, orm, logs, row); err != nil {
+				return fmt.Errorf("row %d: %w", row.Number, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return GetErrorHTML(getRequestErrorCode(err), err)
+	}
+	message := fmt.Sprintf("The import created %d and updated %d instances.", counts[ImportCreate], counts[ImportUpdate])
+	if err = m.App.Panel.AddFlash(data, FlashSuccess, message); err != nil {
+		return GetErrorHTML(http.StatusInternalServerError, err)
+	}
+	return http.StatusSeeOther, m.GetFullLink()
+}
+
+// firstFormValue returns the first value of the named form field, or an empty string.
+func firstFormValue(formData map[string][]string, name string) string {
+	if values := formData[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// readImportFile returns the content and the name of the file uploaded in the "file" form field, or else the
+// content pasted in the "content" form field.
+func (m *Model) readImportFile(data interface{}, formData map[string][]string) (string, string, error) {
+	if files, ok := m.App.Panel.Web.(FileWebIntegrator); ok {
+		filename, file, err := files.GetFormFile(data, "file")
+		if err == nil {
+			defer file.Close()
+			content, err := io.ReadAll(io.LimitReader(file, maxImportSize+1))
+			if err != nil {
+				return "", "", err
+			}
+			if len(content) > maxImportSize {
+				return "", "", fmt.Errorf("the file is larger than %d MB", maxImportSize>>20)
+			}
+			if len(bytes.TrimSpace(content)) > 0 {
+				return string(content), filename, nil
+			}
+		} else if !errors.Is(err, http.ErrMissingFile) {
+			return "", "", err
+		}
+	}
+	content := firstFormValue(formData, "content")
+	if strings.TrimSpace(content) == "" {
+		return "", "", fmt.Errorf("choose a file to import")
+	}
+	if len(content) > maxImportSize {
+		return "", "", fmt.Errorf("the file is larger than %d MB", maxImportSize>>20)
+	}
+	return content, "", nil
+}
+
+// getImportFormat returns the format of an imported file: the chosen one, or else the one given by the extension of
+// its name, or else the one its content looks like.
+func getImportFormat(chosen string, filename string, content string) (ExportFormat, error) {
+	if chosen == "" {
+		chosen = strings.TrimPrefix(strings.ToLower(path.Ext(filename)), ".")
+	}
+	if chosen == "" {
+		switch trimmed := strings.TrimSpace(content); {
+		case strings.HasPrefix(trimmed, "["):
+			chosen = string(ExportJSON)
+		case strings.HasPrefix(trimmed, "{"):
+			chosen = string(ExportNDJSON)
+		default:
+			chosen = string(ExportCSV)
+		}
+	}
+	format := ExportFormat(chosen)
+	if _, ok := exportContentTypes[format]; !ok {
+		return "", fmt.Errorf("unsupported import format %q", chosen)
+	}
+	return format, nil
+}
+
+// parseImport reads the headers and the records of an imported file. Records map the index of each of their columns to
+// its value; the headers of JSON files are the keys of their objects, and records leave out the keys they lack.
+func parseImport(format ExportFormat, content string) ([]string, []map[int]string, error) {
+	if format == ExportCSV {
+		reader := csv.NewReader(strings.NewReader(content))
+		reader.FieldsPerRecord = -1
+		lines, err := reader.ReadAll()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(lines) == 0 {
+			return nil, nil, fmt.Errorf("the file is empty")
+		}
+		records := make([]map[int]string, len(lines)-1)
+		for i, line := range lines[1:] {
+			records[i] = make(map[int]string, len(line))
+			for j, value := range line {
+				records[i][j] = value
+			}
+		}
+		return lines[0], records, nil
+	}
+
+	var objects []map[string]json.RawMessage
+	if format == ExportJSON {
+		if err := json.Unmarshal([]byte(content), &objects); err != nil {
+			return nil, nil, fmt.Errorf("the file is not a JSON array of objects: %w", err)
+		}
+	} else {
+		for i, line := range strings.Split(content, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			var object map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(line), &object); err != nil {
+				return nil, nil, fmt.Errorf("line %d is not a JSON object: %w", i+1, err)
+			}
+			objects = append(objects, object)
+		}
+	}
+
+	var headers []string
+	seen := make(map[string]bool)
+	for _, object := range objects {
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		headers = append(headers, keys...)
+	}
+	records := make([]map[int]string, len(objects))
+	for i, object := range objects {
+		records[i] = make(map[int]string, len(object))
+		for j, header := range headers {
+			raw, ok := object[header]
+			if !ok {
+				continue
+			}
+			value, err := importCellValue(raw)
+			if err != nil {
+				return nil, nil, fmt.Errorf("row %d: %w", i+1, err)
+			}
+			records[i][j] = value
+		}
+	}
+	return headers, records, nil
+}
+
+// importCellValue converts a JSON value to the form value of a cell. Arrays are encoded like the values of fields
+// with several values, so they can fill many-to-many fields.
+func importCellValue(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case []interface{}:
+		values := make([]string, len(value))
+		for i, element := range value {
+			values[i] = fmt.Sprint(element)
+		}
+		encoded, err := json.Marshal(values)
+		return string(encoded), err
+	case map[string]interface{}:
+		return "", fmt.Errorf("objects cannot be imported")
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+// mapImportColumns maps the headers of an imported file to the fields of the add form, matching field names and
+// then, regardless of case, field names and labels.
+func mapImportColumns(fields []form.Field, headers []string) []ImportColumn {
+	columns := make([]ImportColumn, len(headers))
+	mapped := make(map[string]bool)
+	for i, header := range headers {
+		columns[i].Header = header
+		name := strings.TrimSpace(header)
+		var match string
+		for _, field := range fields {
+			if field.GetName() == name {
+				match = field.GetName()
+				break
+			}
+			if match == "" && (strings.EqualFold(field.GetName(), name) || strings.EqualFold(field.GetLabel(), name)) {
+				match = field.GetName()
+			}
+		}
+		if match != "" && !mapped[match] {
+			mapped[match] = true
+			columns[i].Field = match
+		}
+	}
+	return columns
+}
+
+// previewImport maps the columns of an imported file and validates its records, without saving anything.
+func (m *Model) previewImport(data interface{}, headers []string, records []map[int]string) ([]ImportColumn, []*ImportRow, error) {
+	addForm, err := m.NewAddForm(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := mapImportColumns(addForm.GetFields(), headers)
+	rows := make([]*ImportRow, 0, len(records))
+	for i, record := range records {
+		row := &ImportRow{Number: i + 1, Values: make([]string, len(columns)), formData: make(map[string]form.HTMLType), columns: make(map[string]bool)}
+		for j, column := range columns {
+			value, ok := record[j]
+			row.Values[j] = value
+			if ok && column.Field != "" {
+				row.formData[column.Field] = form.HTMLType(value)
+				row.columns[column.Field] = true
+			}
+		}
+		if err = m.validateImportRow(data, row); err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, nil
+}
+
+// validateImportRow converts and validates the values of a row with the add form fields, and finds out whether it
+// creates or updates an instance.
+func (m *Model) validateImportRow(data interface{}, row *ImportRow) error {
+	addForm, err := m.NewAddForm(data)
+	if err != nil {
+		return err
+	}
+	converted, conversionErrs := convertFormValues(addForm, row.formData, nil)
+	row.converted = converted
+
+	row.Action = ImportCreate
+	instance := reflect.New(reflect.TypeOf(m.PTR).Elem())
+	for name, value := range row.converted {
+		if _, isRelation := m.getRelation(name); !isRelation {
+			_ = setModelField(instance.Elem(), name, value)
+		}
+	}
+	instanceID, err := m.GetPrimaryKeyValue(instance.Interface())
+	if err != nil {
+		return err
+	}
+	if instanceID != nil && !reflect.ValueOf(instanceID).IsZero() {
+		if current, err := m.fetchEditInstance(data, instanceID); err == nil && current != nil {
+			row.Action = ImportUpdate
+			row.InstanceID = instanceID
+			row.current = current
+		}
+	}
+
+	checker := m.App.Panel.PermissionChecker
+	var allowed bool
+	if row.Action == ImportUpdate {
+		allowed, err = checker.HasInstanceUpdatePermission(m.App.Name, m.Name, row.InstanceID, data)
+	} else {
+		allowed, err = checker.HasModelCreatePermission(m.App.Name, m.Name, data)
+	}
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		row.Errors = append(row.Errors, fmt.Sprintf("you are not allowed to %s %s", row.Action, m.DisplayName))
+	}
+
+	// Updates only change the columns present in the file, so the other fields keep their valid values.
+	validated := map[string]bool(nil)
+	if row.Action == ImportUpdate {
+		validated = row.columns
+	}
+	errs, err := validateFormValues(addForm, row.converted, conversionErrs, validated)
+	if err != nil {
+		return err
+	}
+	row.Errors = append(row.Errors, errs...)
+	if len(row.Errors) > 0 {
+		row.Action = ImportError
+	}
+	return nil
+}
+
+// saveImportRow creates or updates the instance of a validated row through orm, queuing its log entry.
+func (m *Model) saveImportRow(data interface{}, orm ORMIntegrator, logs *writeLogs, row *ImportRow) error {
+	if row.Action == ImportCreate {
+		addForm, err := m.NewAddForm(data)
+		if err != nil {
+			return err
+		}
+		setFormORM(addForm, orm)
+		instanceData, err := addForm.Save(row.formData)
+		if err != nil {
+			return err
+		}
+		instanceID, err := m.GetPrimaryKeyValue(instanceData)
+		if err != nil {
+			return err
+		}
+		instance := &Instance{InstanceID: instanceID, Data: instanceData, Model: m}
+		logs.add(func() error { return instance.CreateCreateLog(data) })
+		return nil
+	}
+
+	updates := make(map[string]interface{})
+	for name, value := range row.converted {
+		if row.columns[name] {
+			updates[name] = value
+		}
+	}
+	var version interface{}
+	if m.VersionField != "" {
+		version = m.getVersion(row.current)
+	}
+	instanceData, err := m.updateInstanceFields(orm, row.InstanceID, updates, version)
+	if err != nil {
+		return err
+	}
+	instance := &Instance{InstanceID: row.InstanceID, Data: instanceData, Model: m}
+	logs.add(func() error { return instance.CreateUpdateLog(data, updates) })
+	return nil
+}
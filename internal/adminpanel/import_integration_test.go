@@ -0,0 +1,82 @@
+package adminpanel_test
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestModel_GetImportHandler_Preview(t *testing.T) {
+	model, orm := registerProductModel(t)
+
+	content := "id,Name,Stock,Colour\n1,Renamed,,red\n,Gadget,x,blue\n,,5,green\n7,Gizmo,2,\n"
+	code, body := model.GetImportHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Form: map[string][]string{"content": {content}}})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	for _, expected := range []string{
+		`<tr data-action="update">`, `<tr data-action="create">`, `title="Not matched with a field, ignored">Colour</th>`,
+		"1 to create", "1 to update", "2 with errors",
+		"Stock: strconv.Atoi", "Name: field is required", "disabled>Import</button>",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected the preview to contain %q", expected)
+		}
+	}
+	if len(storedRows[adminpanel.Product](t, orm)) != 1 || len(orm.UpdatedFields) != 0 {
+		t.Error("expected the preview not to save anything")
+	}
+
+	code, _ = model.GetImportHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Form: map[string][]string{"content": {content}, "confirm": {"true"}}})
+	if code != http.StatusOK || len(storedRows[adminpanel.Product](t, orm)) != 1 {
+		t.Errorf("expected a file with errors not to be imported, got %v", code)
+	}
+
+	code, body = model.GetImportHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Form: map[string][]string{"content": {"[1, 2]"}}})
+	if code != http.StatusOK || !strings.Contains(body, "not a JSON array of objects") {
+		t.Errorf("expected the parse error to be shown, got %v", code)
+	}
+}
+
+func TestModel_GetImportHandler_Confirm(t *testing.T) {
+	model, orm := registerProductModel(t)
+
+	content := `[{"ID": 1, "Name": "Renamed"}, {"Name": "Gadget", "Stock": 2, "Note": null}]`
+	code, location := model.GetImportHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Form: map[string][]string{"content": {content}, "confirm": {"true"}}})
+	if code != http.StatusSeeOther || location != model.GetFullLink() {
+		t.Fatalf("expected a redirect to the list view, got %v: %s", code, location)
+	}
+	products := storedRows[adminpanel.Product](t, orm)
+	if len(products) != 2 || products[0].Name != "Renamed" || products[1].Name != "Gadget" || products[1].Stock != 2 {
+		t.Errorf("unexpected products %v", products)
+	}
+	if len(orm.UpdatedFields) != 1 || !reflect.DeepEqual(orm.UpdatedFields[0], []string{"ID", "Name"}) {
+		t.Errorf("expected updates to save the columns of the file only, got %v", orm.UpdatedFields)
+	}
+	if countLogs(t, model, logging.LogStoreLevelCreate) != 1 || countLogs(t, model, logging.LogStoreLevelUpdate) != 1 {
+		t.Error("expected a create and an update log entry")
+	}
+
+	code, body := model.GetImportHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Files: map[string]string{"file": "Name\nUploaded\n"}})
+	if code != http.StatusOK || !strings.Contains(body, "<td>Uploaded</td>") {
+		t.Errorf("expected the uploaded file to be previewed, got %v", code)
+	}
+
+	model.App.Panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return *request.Action != adminpanel.UpdateAction, nil
+	}
+	_, body = model.GetImportHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Form: map[string][]string{"content": {"ID,Name\n1,Again\n"}}})
+	if !strings.Contains(body, "you are not allowed to update Product") {
+		t.Error("expected rows updating instances the user may not update to be rejected")
+	}
+
+	model.App.Panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return *request.Action == adminpanel.ReadAction, nil
+	}
+	if code, _ = model.GetImportHandler()(&adminpanel.MockRequest{Method: http.MethodGet}); code != http.StatusForbidden {
+		t.Errorf("expected %v without the create and update permissions, got %v", http.StatusForbidden, code)
+	}
+}
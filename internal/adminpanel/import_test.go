@@ -0,0 +1,30 @@
+package adminpanel
+
+import (
+	"reflect"
+	"testing"
+)
+
+type Product struct {
+	ID    uint
+	Name  string `admin:"required"`
+	Stock int
+}
+
+func TestParseImport_NDJSON(t *testing.T) {
+	format, err := getImportFormat("", "", "{\"Name\": \"A\"}\n")
+	if err != nil || format != ExportNDJSON {
+		t.Fatalf("expected NDJSON to be detected, got %q (%v)", format, err)
+	}
+	headers, records, err := parseImport(format, "{\"Name\": \"A\", \"Tags\": [1, 2]}\n\n{\"Stock\": 3}\n")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(headers, []string{"Name", "Tags", "Stock"}) {
+		t.Errorf("unexpected headers %v", headers)
+	}
+	expected := []map[int]string{{0: "A", 1: `["1","2"]`}, {2: "3"}}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected records %v, got %v", expected, records)
+	}
+}
@@ -0,0 +1,453 @@
+package adminpanel
+
+import (
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"github.com/ovnicraft/go-advanced-admin/internal/form/forms"
+	"reflect"
+	"strconv"
+)
+
+// maxInlineRows caps the number of child rows fetched and accepted for an inline.
+const maxInlineRows = 1000
+
+// inlineIndexPlaceholder stands for the row index in the prefix of the template row cloned by the add-row control.
+const inlineIndexPlaceholder = "__index__"
+
+// Inline registers a child model whose instances are edited on the add and edit pages of a parent model. Each child
+// row is rendered as a prefixed sub-form and is validated and saved together with the parent instance.
+type Inline struct {
+	// Name prefixes the form fields of the rows. It defaults to the name of the child model.
+	Name string
+	// DisplayName titles the inline on the form pages. It defaults to the display name of the child model.
+	DisplayName string
+	Model       *Model
+	// ParentField is the field of the child model holding the primary key of the parent instance.
+	ParentField string
+}
+
+// InlineFormSet holds the rows of an inline on an add or edit page.
+type InlineFormSet struct {
+	Inline *Inline
+	Rows   []*InlineRow
+	// Total is the number of row indexes in use, submitted back so that rows can be found in the form data.
+	Total int
+	// Template is an empty row whose prefix holds an index placeholder, cloned by the add-row control.
+	Template *InlineRow
+	CanAdd   bool
+}
+
+// InlineRow is a single child instance of an inline form set. Rows without an instance ID create new instances.
+type InlineRow struct {
+	Prefix     string
+	InstanceID interface{}
+	Form       form.Form
+	// Delete marks an existing instance for deletion when the parent form is saved.
+	Delete    bool
+	CanDelete bool
+	FormErrs  []error
+	FieldErrs map[string][]error
+
+	model       *Model
+	instance    interface{}
+	cleanValues map[string]interface{}
+}
+
+// RegisterInline registers a child model as an inline of the model. parentField names the field of the child model
+// holding the primary key of the parent instance.
+func (m *Model) RegisterInline(child *Model, parentField string) (*Inline, error) {
+	if child == nil {
+		return nil, fmt.Errorf("inline of admin model '%s' has no child model", m.Name)
+	}
+	if child == m {
+		return nil, fmt.Errorf("admin model '%s' cannot be an inline of itself", m.Name)
+	}
+	if _, ok := child.getFieldConfig(parentField); !ok {
+		return nil, fmt.Errorf("admin model '%s' has no parent field '%s'", child.Name, parentField)
+	}
+	for _, inline := range m.Inlines {
+		if inline.Name == child.Name {
+			return nil, fmt.Errorf("admin model '%s' is already an inline of admin model '%s'", child.Name, m.Name)
+		}
+	}
+
+	inline := &Inline{Name: child.Name, DisplayName: child.DisplayName, Model: child, ParentField: parentField}
+	m.Inlines = append(m.Inlines, inline)
+	return inline, nil
+}
+
+// GetTotalName returns the name of the hidden input holding the number of row indexes in use.
+func (s *InlineFormSet) GetTotalName() string {
+	return s.Inline.Name + "-TOTAL"
+}
+
+// GetIDName returns the name of the hidden input holding the instance ID of the row.
+func (r *InlineRow) GetIDName() string {
+	return r.Prefix + "id"
+}
+
+// GetIDValue returns the value of the hidden input holding the instance ID of the row, empty for new rows.
+func (r *InlineRow) GetIDValue() string {
+	if r.IsNew() {
+		return ""
+	}
+	return r.model.FormatInstanceID(r.InstanceID)
+}
+
+// GetDeleteName returns the name of the checkbox marking the row for deletion.
+func (r *InlineRow) GetDeleteName() string {
+	return r.Prefix + "DELETE"
+}
+
+// IsNew reports whether the row creates a new instance.
+func (r *InlineRow) IsNew() bool {
+	return r.InstanceID == nil
+}
+
+func (inline *Inline) rowPrefix(index string) string {
+	return fmt.Sprintf("%s-%s-", inline.Name, index)
+}
+
+// parentFilterValue converts a parent primary key to the type of the parent field, dereferenced if the field is a
+// pointer.
+func (inline *Inline) parentFilterValue(parentID interface{}) (interface{}, error) {
+	field, ok := reflect.TypeOf(inline.Model.PTR).Elem().FieldByName(inline.ParentField)
+	if !ok {
+		return nil, fmt.Errorf("admin model '%s' has no parent field '%s'", inline.Model.Name, inline.ParentField)
+	}
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	val := reflect.ValueOf(parentID)
+	if !val.IsValid() || !val.Type().ConvertibleTo(fieldType) {
+		return nil, fmt.Errorf("parent id %v cannot be stored in field '%s' of admin model '%s'", parentID, inline.ParentField, inline.Model.Name)
+	}
+	return val.Convert(fieldType).Interface(), nil
+}
+
+// fetchChildren returns the child instances of a parent instance that the user may read.
+func (inline *Inline) fetchChildren(data interface{}, parentID interface{}) ([]interface{}, error) {
+	child := inline.Model
+	value, err := inline.parentFilterValue(parentID)
+	if err != nil {
+		return nil, err
+	}
+	fieldsToFetch := make([]string, 0, len(child.Fields))
+	for _, fieldConfig := range child.Fields {
+		fieldsToFetch = append(fieldsToFetch, fieldConfig.Name)
+	}
+	instances, _, _, err := fetchListPage(child, data, ListQuery{
+		Fields:   fieldsToFetch,
+		Limit:    maxInlineRows,
+		Ordering: child.DefaultOrdering,
+		Filters:  child.withoutTrashed([]FilterCondition{{Field: inline.ParentField, Operator: FilterExact, Value: value}}),
+	})
+	return instances, err
+}
+
+// prefixInlineForm drops the parent field from a child model form and prefixes the names of the remaining fields,
+// keeping their labels.
+func prefixInlineForm(f form.Form, prefix, parentField string) error {
+	var base *forms.BaseForm
+	switch f := f.(type) {
+	case *ModelAddForm:
+		f.Prefix = prefix
+		base = &f.BaseForm
+	case *ModelEditForm:
+		f.Prefix = prefix
+		base = &f.BaseForm
+	default:
+		return fmt.Errorf("inline forms must be model forms, got %T", f)
+	}
+
+	prefixedFields := make([]form.Field, 0, len(base.Fields))
+	for _, field := range base.Fields {
+		if field.GetName() == parentField {
+			continue
+		}
+		if err := field.RegisterLabel(field.GetLabel()); err != nil {
+			return err
+		}
+		if err := field.RegisterName(prefix + field.GetName()); err != nil {
+			return err
+		}
+		prefixedFields = append(prefixedFields, field)
+	}
+	base.Fields = prefixedFields
+	return nil
+}
+
+func (inline *Inline) newAddRow(data interface{}, prefix string) (*InlineRow, error) {
+	formInstance, err := inline.Model.NewAddForm(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = prefixInlineForm(formInstance, prefix, inline.ParentField); err != nil {
+		return nil, err
+	}
+	return &InlineRow{Prefix: prefix, Form: formInstance, CanDelete: true, model: inline.Model}, nil
+}
+
+func (inline *Inline) newEditRow(data interface{}, prefix string, instanceID interface{}, instance interface{}) (*InlineRow, error) {
+	child := inline.Model
+	formInstance, err := child.NewEditForm(data, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	initialValues := child.getEditInitialValues(instance)
+	relatedIDValues, err := child.fetchRelatedIDValues(data, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range relatedIDValues {
+		initialValues[name] = value
+	}
+	if err = formInstance.RegisterInitialValues(initialValues); err != nil {
+		return nil, err
+	}
+	if err = prefixInlineForm(formInstance, prefix, inline.ParentField); err != nil {
+		return nil, err
+	}
+
+	canDelete, err := child.App.Panel.PermissionChecker.HasInstanceDeletePermission(child.App.Name, child.Name, instanceID, data)
+	if err != nil {
+		return nil, err
+	}
+	return &InlineRow{Prefix: prefix, InstanceID: instanceID, Form: formInstance, CanDelete: canDelete, instance: instance, model: inline.Model}, nil
+}
+
+// newInlineFormSets builds the form sets of the inlines whose child model the user may read. parentID is nil on the
+// add page, where form sets start without rows.
+func (m *Model) newInlineFormSets(data interface{}, parentID interface{}) ([]*InlineFormSet, error) {
+	formSets := make([]*InlineFormSet, 0, len(m.Inlines))
+	for _, inline := range m.Inlines {
+		child := inline.Model
+		allowed, err := child.App.Panel.PermissionChecker.HasModelReadPermission(child.App.Name, child.Name, data)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			continue
+		}
+		canAdd, err := child.App.Panel.PermissionChecker.HasModelCreatePermission(child.App.Name, child.Name, data)
+		if err != nil {
+			return nil, err
+		}
+		template, err := inline.newAddRow(data, inline.rowPrefix(inlineIndexPlaceholder))
+		if err != nil {
+			return nil, err
+		}
+		formSet := &InlineFormSet{Inline: inline, Template: template, CanAdd: canAdd}
+
+		if parentID != nil {
+			instances, err := inline.fetchChildren(data, parentID)
+			if err != nil {
+				return nil, err
+			}
+			for _, instance := range instances {
+				instanceID, err := child.GetPrimaryKeyValue(instance)
+				if err != nil {
+					return nil, err
+				}
+				allowed, err := child.App.Panel.PermissionChecker.HasInstanceUpdatePermission(child.App.Name, child.Name, instanceID, data)
+				if err != nil {
+					return nil, err
+				}
+				if !allowed {
+					continue
+				}
+				row, err := inline.newEditRow(data, inline.rowPrefix(strconv.Itoa(formSet.Total)), instanceID, instance)
+				if err != nil {
+					return nil, err
+				}
+				formSet.Rows = append(formSet.Rows, row)
+				formSet.Total++
+			}
+		}
+		formSets = append(formSets, formSet)
+	}
+	return formSets, nil
+}
+
+// bind replaces the rows of the form set with the rows submitted in values and validates them, reporting whether
+// every row is valid. Rows referring to instances that are not children of the parent are rejected with
+// ErrInvalidInstanceID, and rows the user may not change with ErrPermissionDenied.
+func (s *InlineFormSet) bind(data interface{}, parentID interface{}, values map[string]form.HTMLType) (bool, error) {
+	inline := s.Inline
+	child := inline.Model
+	checker := child.App.Panel.PermissionChecker
+
+	children := make(map[string]interface{})
+	if parentID != nil {
+		instances, err := inline.fetchChildren(data, parentID)
+		if err != nil {
+			return false, err
+		}
+		for _, instance := range instances {
+			instanceID, err := child.GetPrimaryKeyValue(instance)
+			if err != nil {
+				return false, err
+			}
+			children[fmt.Sprint(instanceID)] = instance
+		}
+	}
+
+	total, _ := strconv.Atoi(string(values[s.GetTotalName()]))
+	if total < 0 {
+		total = 0
+	}
+	if total > maxInlineRows {
+		total = maxInlineRows
+	}
+
+	s.Rows = make([]*InlineRow, 0)
+	s.Total = total
+	valid := true
+	for i := 0; i < total; i++ {
+		prefix := inline.rowPrefix(strconv.Itoa(i))
+		idValue, submitted := values[prefix+"id"]
+		if !submitted {
+			continue
+		}
+		markedForDeletion := values[prefix+"DELETE"] != ""
+
+		var row *InlineRow
+		if idValue == "" {
+			if markedForDeletion {
+				continue
+			}
+			if !s.CanAdd {
+				return false, fmt.Errorf("%w: you are not allowed to add %s", ErrPermissionDenied, child.DisplayName)
+			}
+			newRow, err := inline.newAddRow(data, prefix)
+			if err != nil {
+				return false, err
+			}
+			row = newRow
+		} else {
+			instanceID, err := child.parseInstanceID(string(idValue))
+			if err != nil {
+				return false, err
+			}
+			instance, ok := children[fmt.Sprint(instanceID)]
+			if !ok {
+				return false, fmt.Errorf("%w: %s %v does not belong to this instance", ErrInvalidInstanceID, child.DisplayName, instanceID)
+			}
+			allowed, err := checker.HasInstanceUpdatePermission(child.App.Name, child.Name, instanceID, data)
+			if err != nil {
+				return false, err
+			}
+			if !allowed {
+				return false, fmt.Errorf("%w: you are not allowed to change %s %v", ErrPermissionDenied, child.DisplayName, instanceID)
+			}
+			row, err = inline.newEditRow(data, prefix, instanceID, instance)
+			if err != nil {
+				return false, err
+			}
+			if markedForDeletion {
+				if !row.CanDelete {
+					return false, fmt.Errorf("%w: you are not allowed to delete %s %v", ErrPermissionDenied, child.DisplayName, instanceID)
+				}
+				row.Delete = true
+			}
+		}
+
+		rowValid, err := row.validate(values)
+		if err != nil {
+			return false, err
+		}
+		valid = valid && rowValid
+		s.Rows = append(s.Rows, row)
+	}
+	return valid, nil
+}
+
+// validate cleans the submitted values of the row and validates them, unless the row is marked for deletion. The
+// clean values are registered as initial values so the row can be rendered again.
+func (r *InlineRow) validate(values map[string]form.HTMLType) (bool, error) {
+	cleanValues, err := form.GetCleanData(r.Form, values)
+	if err != nil {
+		return false, err
+	}
+	if err = r.Form.RegisterInitialValues(cleanValues); err != nil {
+		return false, err
+	}
+	r.cleanValues = unprefixValues(cleanValues, r.Prefix)
+	if r.Delete {
+		return true, nil
+	}
+
+	r.FormErrs, r.FieldErrs, err = form.ValuesAreValid(r.Form, cleanValues)
+	if err != nil {
+		return false, err
+	}
+	return !formHasErrors(r.FormErrs, r.FieldErrs), nil
+}
+
+// save applies the bound rows through orm once the parent instance is saved. Rows marked for deletion are deleted,
+// existing rows are updated and new rows are created with their parent field set to parentID, queuing a log entry
+// for each change.
+func (s *InlineFormSet) save(data interface{}, orm ORMIntegrator, logs *writeLogs, parentID interface{}, values map[string]form.HTMLType) error {
+	child := s.Inline.Model
+	for _, row := range s.Rows {
+		switch {
+		case row.Delete:
+			if err := child.deleteInstance(orm, row.InstanceID, orm.DeleteInstance); err != nil {
+				return err
+			}
+			instance := &Instance{InstanceID: row.InstanceID, Data: row.instance, Model: child}
+			logs.add(func() error { return instance.CreateDeleteLog(data) })
+		case row.IsNew():
+			addForm, ok := row.Form.(*ModelAddForm)
+			if !ok {
+				return fmt.Errorf("inline row %s has no add form", row.Prefix)
+			}
+			addForm.ORM = orm
+			addForm.Overrides = map[string]interface{}{s.Inline.ParentField: parentID}
+			instanceData, err := addForm.Save(values)
+			if err != nil {
+				return err
+			}
+			instanceID, err := child.GetPrimaryKeyValue(instanceData)
+			if err != nil {
+				return err
+			}
+			instance := &Instance{InstanceID: instanceID, Data: instanceData, Model: child}
+			logs.add(func() error { return instance.CreateCreateLog(data) })
+		default:
+			setFormORM(row.Form, orm)
+			instanceData, err := row.Form.Save(values)
+			if err != nil {
+				return err
+			}
+			instance := &Instance{InstanceID: row.InstanceID, Data: instanceData, Model: child}
+			logs.add(func() error { return instance.CreateUpdateLog(data, row.cleanValues) })
+		}
+	}
+	return nil
+}
+
+// bindInlineFormSets binds and validates every inline form set, reporting whether all of them are valid.
+func bindInlineFormSets(data interface{}, formSets []*InlineFormSet, parentID interface{}, values map[string]form.HTMLType) (bool, error) {
+	valid := true
+	for _, formSet := range formSets {
+		formSetValid, err := formSet.bind(data, parentID, values)
+		if err != nil {
+			return false, err
+		}
+		valid = valid && formSetValid
+	}
+	return valid, nil
+}
+
+// saveInlineFormSets saves the rows of every inline form set of a parent instance saved through orm. Child models
+// sharing the parent's integrator write through orm too, so they join its transaction.
+func (m *Model) saveInlineFormSets(data interface{}, orm ORMIntegrator, logs *writeLogs, formSets []*InlineFormSet, parentID interface{}, values map[string]form.HTMLType) error {
+	for _, formSet := range formSets {
+		if err := formSet.save(data, m.getWriteORM(data, formSet.Inline.Model, orm), logs, parentID, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
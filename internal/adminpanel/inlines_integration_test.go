@@ -0,0 +1,198 @@
+package adminpanel_test
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// registerInlineModels registers orders editing their items inline, orders 5 and 6 having items 1 and 2, and 3.
+func registerInlineModels(t *testing.T) (*adminpanel.Model, *recordingORM) {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	orm := &recordingORM{Integrator: newMemoryORM(t,
+		&adminpanel.InlineOrder{ID: 5, Number: "A1"},
+		&adminpanel.InlineOrder{ID: 6, Number: "A3"},
+		&adminpanel.InlineItem{ID: 1, OrderID: 5, Name: "Bolt"},
+		&adminpanel.InlineItem{ID: 2, OrderID: 5, Name: "Nut"},
+		&adminpanel.InlineItem{ID: 3, OrderID: 6, Name: "Screw"},
+	)}
+	shop, err := panel.RegisterApp("Shop", "Shop", orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order, err := shop.RegisterModel(&adminpanel.InlineOrder{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	item, err := shop.RegisterModel(&adminpanel.InlineItem{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = order.RegisterInline(item, "OrderID"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return order, orm
+}
+
+func TestModel_RegisterInline(t *testing.T) {
+	order, _ := registerInlineModels(t)
+	item := order.App.Models["InlineItem"]
+
+	if len(order.Inlines) != 1 || order.Inlines[0].Name != "InlineItem" || order.Inlines[0].DisplayName != item.DisplayName {
+		t.Errorf("expected a single inline named after the child model, got %v", order.Inlines)
+	}
+	if _, err := order.RegisterInline(item, "OrderID"); err == nil {
+		t.Error("expected an error for a duplicate inline")
+	}
+	if _, err := order.RegisterInline(order, "ID"); err == nil {
+		t.Error("expected an error for a model inline of itself")
+	}
+	if _, err := item.RegisterInline(order, "ItemID"); err == nil {
+		t.Error("expected an error for a missing parent field")
+	}
+}
+
+func TestModel_GetEditHandler_RendersInlines(t *testing.T) {
+	order, _ := registerInlineModels(t)
+
+	code, body := order.GetEditHandler()(&adminpanel.MockRequest{Method: http.MethodGet, Params: map[string]string{"id": "5"}})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	for _, expected := range []string{
+		`name="InlineItem-TOTAL" value="2"`,
+		`name="InlineItem-0-id" value="1"`,
+		`name="InlineItem-0-Name"`,
+		`value="Bolt"`,
+		`name="InlineItem-1-DELETE"`,
+		`name="InlineItem-__index__-Name"`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected the edit page to contain %s", expected)
+		}
+	}
+	if strings.Contains(body, "Screw") {
+		t.Error("expected items of other orders not to be rendered")
+	}
+	if strings.Contains(body, "InlineItem-0-OrderID") {
+		t.Error("expected the parent field not to be rendered in inline rows")
+	}
+}
+
+func TestModel_GetEditHandler_SavesInlines(t *testing.T) {
+	order, orm := registerInlineModels(t)
+
+	code, body := order.GetEditHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Params: map[string]string{"id": "5"},
+		Form: map[string][]string{
+			"Number":              {"A2"},
+			"InlineItem-TOTAL":    {"4"},
+			"InlineItem-0-id":     {"1"},
+			"InlineItem-0-Name":   {"Big bolt"},
+			"InlineItem-1-id":     {"2"},
+			"InlineItem-1-Name":   {"Nut"},
+			"InlineItem-1-DELETE": {"on"},
+			"InlineItem-3-id":     {""},
+			"InlineItem-3-Name":   {"Washer"},
+		},
+	})
+	if code != http.StatusSeeOther {
+		t.Fatalf("expected %v, got %v: %s", http.StatusSeeOther, code, body)
+	}
+
+	expected := []*adminpanel.InlineItem{
+		{ID: 1, OrderID: 5, Name: "Big bolt"},
+		{ID: 3, OrderID: 6, Name: "Screw"},
+		{ID: 4, OrderID: 5, Name: "Washer"},
+	}
+	if items := storedRows[adminpanel.InlineItem](t, orm); !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected items %v, got %v", expected, items)
+	}
+	if number := storedRows[adminpanel.InlineOrder](t, orm)[0].Number; number != "A2" {
+		t.Errorf("expected the order to be updated, got %q", number)
+	}
+	for _, fields := range orm.UpdatedFields {
+		if slices.Contains(fields, "OrderID") {
+			t.Errorf("expected the parent field not to be updated, got %v", fields)
+		}
+	}
+	if got := countLogs(t, order, logging.LogStoreLevelUpdate); got != 2 {
+		t.Errorf("expected 2 update log entries, got %d", got)
+	}
+	if got := countLogs(t, order, logging.LogStoreLevelDelete); got != 1 {
+		t.Errorf("expected 1 delete log entry, got %d", got)
+	}
+	if got := countLogs(t, order, logging.LogStoreLevelCreate); got != 1 {
+		t.Errorf("expected 1 create log entry, got %d", got)
+	}
+}
+
+func TestModel_GetEditHandler_InvalidInlineRow(t *testing.T) {
+	order, orm := registerInlineModels(t)
+
+	code, body := order.GetEditHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Params: map[string]string{"id": "5"},
+		Form: map[string][]string{
+			"Number":            {"A2"},
+			"InlineItem-TOTAL":  {"1"},
+			"InlineItem-0-id":   {"1"},
+			"InlineItem-0-Name": {""},
+		},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("expected the form to be rendered again, got %v: %s", code, body)
+	}
+	if len(orm.UpdatedFields) != 0 || storedRows[adminpanel.InlineItem](t, orm)[0].Name != "Bolt" {
+		t.Error("expected neither the order nor its items to be saved")
+	}
+	if !strings.Contains(body, `name="InlineItem-0-id" value="1"`) {
+		t.Error("expected the submitted row to be rendered again")
+	}
+
+	code, _ = order.GetEditHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Params: map[string]string{"id": "5"},
+		Form: map[string][]string{
+			"Number":            {"A2"},
+			"InlineItem-TOTAL":  {"1"},
+			"InlineItem-0-id":   {"3"},
+			"InlineItem-0-Name": {"Stolen"},
+		},
+	})
+	if code != http.StatusBadRequest {
+		t.Errorf("expected %v for an item of another order, got %v", http.StatusBadRequest, code)
+	}
+	if storedRows[adminpanel.InlineItem](t, orm)[2].Name != "Screw" {
+		t.Error("expected items of other orders to be left untouched")
+	}
+}
+
+func TestModel_GetAddHandler_CreatesInlines(t *testing.T) {
+	order, orm := registerInlineModels(t)
+
+	code, body := order.GetAddHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Form: map[string][]string{
+			"Number":            {"B1"},
+			"InlineItem-TOTAL":  {"1"},
+			"InlineItem-0-id":   {""},
+			"InlineItem-0-Name": {"Hinge"},
+		},
+	})
+	if code != http.StatusSeeOther {
+		t.Fatalf("expected %v, got %v: %s", http.StatusSeeOther, code, body)
+	}
+	items := storedRows[adminpanel.InlineItem](t, orm)
+	if created := items[len(items)-1]; created.Name != "Hinge" || created.OrderID != 7 {
+		t.Errorf("expected the item to be created for the new order, got %+v", created)
+	}
+}
@@ -0,0 +1,21 @@
+package adminpanel
+
+type InlineOrder struct {
+	ID     uint
+	Number string
+}
+
+type InlineItem struct {
+	ID      uint
+	OrderID uint
+	Name    string `admin:"required"`
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
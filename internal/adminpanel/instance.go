@@ -2,13 +2,14 @@ package adminpanel
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ovnicraft/go-advanced-admin/internal/form"
 	"github.com/ovnicraft/go-advanced-admin/internal/form/forms"
 	"github.com/ovnicraft/go-advanced-admin/internal/logging"
-	"github.com/ovnicraft/go-advanced-admin/internal/utils"
 	"net/http"
 	"reflect"
+	"strings"
 )
 
 // Instance represents a single instance of a model in the admin panel.
@@ -63,7 +64,7 @@ func (i *Instance) CreateDeleteLog(ctx interface{}) error {
 
 // GetLink returns the relative URL to view the instance.
 func (i *Instance) GetLink() string {
-	return fmt.Sprintf("%s/%v/view", i.Model.GetLink(), i.InstanceID)
+	return i.Model.getInstanceLink(i.InstanceID, "view")
 }
 
 // GetFullLink returns the full URL to view the instance.
@@ -73,7 +74,7 @@ func (i *Instance) GetFullLink() string {
 
 // GetEditLink returns the relative URL to edit the instance.
 func (i *Instance) GetEditLink() string {
-	return fmt.Sprintf("%s/%v/edit", i.Model.GetLink(), i.InstanceID)
+	return i.Model.getInstanceLink(i.InstanceID, "edit")
 }
 
 // GetFullEditLink returns the full URL to edit the instance.
@@ -81,6 +82,34 @@ func (i *Instance) GetFullEditLink() string {
 	return i.Model.App.Panel.Config.GetLink(i.GetEditLink())
 }
 
+// GetDeleteLink returns the relative URL of the JSON endpoint deleting the instance.
+func (i *Instance) GetDeleteLink() string {
+	return i.Model.getInstanceLink(i.InstanceID, "delete")
+}
+
+// GetFullDeleteLink returns the full URL of the JSON endpoint deleting the instance.
+func (i *Instance) GetFullDeleteLink() string {
+	return i.Model.App.Panel.Config.GetLink(i.GetDeleteLink())
+}
+
+// parseInstanceID converts an instance ID taken from a request into a value of the model's primary key type.
+// Malformed IDs are reported with an error wrapping ErrInvalidInstanceID.
+func (m *Model) parseInstanceID(instanceIDStr string) (interface{}, error) {
+	primaryKeyType, err := m.GetPrimaryKeyType()
+	if err != nil {
+		return nil, err
+	}
+	if primaryKeyType == nil {
+		return nil, fmt.Errorf("admin model '%s' has no primary key type", m.Name)
+	}
+
+	primaryKeyValue, err := m.App.Panel.DecodeKey(instanceIDStr, primaryKeyType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInstanceID, err)
+	}
+	return primaryKeyValue, nil
+}
+
 func (m *Model) GetInstanceDeleteHandler() HandlerFunc {
 	return func(data interface{}) (uint, string) {
 		instanceIDStr := m.App.Panel.Web.GetPathParam(data, "id")
@@ -88,31 +117,24 @@ func (m *Model) GetInstanceDeleteHandler() HandlerFunc {
 			return GetErrorHTML(http.StatusBadRequest, fmt.Errorf("instance id is required"))
 		}
 
-		primaryKeyType, err := m.GetPrimaryKeyType()
+		instanceIDInterface, err := m.parseInstanceID(instanceIDStr)
 		if err != nil {
-			return GetErrorHTML(http.StatusInternalServerError, err)
+			return GetErrorHTML(getRequestErrorCode(err), err)
 		}
 
-		primaryKeyValuePtr := reflect.New(primaryKeyType)
-		primaryKeyValue := primaryKeyValuePtr.Elem()
-
-		if err = utils.SetStringsAsType(primaryKeyValue, instanceIDStr); err != nil {
-			return GetErrorHTML(http.StatusBadRequest, fmt.Errorf("invalid instance id: %v", err))
-		}
-
-		instanceIDInterface := primaryKeyValue.Interface()
-
+		instanceLink := m.App.Panel.Config.GetLink(m.getInstanceLink(instanceIDInterface, "view"))
 		allowed, err := m.App.Panel.PermissionChecker.HasInstanceDeletePermission(m.App.Name, m.Name, instanceIDInterface, data)
 		if err != nil {
-			return GetErrorHTML(http.StatusInternalServerError, err)
+			return m.App.Panel.redirectWithError(data, instanceLink, http.StatusInternalServerError, err)
 		}
 		if !allowed {
-			return GetErrorHTML(http.StatusForbidden, fmt.Errorf("you are not allowed to delete this instance"))
+			return m.App.Panel.redirectWithError(data, instanceLink, http.StatusForbidden, fmt.Errorf("you are not allowed to delete this instance"))
 		}
 
-		err = m.GetORM().DeleteInstance(m.PTR, instanceIDInterface)
+		orm := m.getRequestORM(data)
+		err = m.deleteInstance(orm, instanceIDInterface, orm.DeleteInstance)
 		if err != nil {
-			return GetErrorHTML(http.StatusInternalServerError, err)
+			return m.App.Panel.redirectWithError(data, instanceLink, getRequestErrorCode(err), err)
 		}
 
 		instance := &Instance{
@@ -125,6 +147,9 @@ func (m *Model) GetInstanceDeleteHandler() HandlerFunc {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
 
+		if err = m.App.Panel.AddFlash(data, FlashSuccess, fmt.Sprintf("The %s was deleted successfully.", m.DisplayName)); err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
 		return http.StatusSeeOther, m.GetFullLink()
 	}
 }
@@ -136,20 +161,11 @@ func (m *Model) GetInstanceViewHandler() HandlerFunc {
 			return GetErrorHTML(http.StatusBadRequest, fmt.Errorf("instance id is required"))
 		}
 
-		primaryKeyType, err := m.GetPrimaryKeyType()
+		instanceIDInterface, err := m.parseInstanceID(instanceIDStr)
 		if err != nil {
-			return GetErrorHTML(http.StatusInternalServerError, err)
-		}
-
-		primaryKeyValuePtr := reflect.New(primaryKeyType)
-		primaryKeyValue := primaryKeyValuePtr.Elem()
-
-		if err = utils.SetStringsAsType(primaryKeyValue, instanceIDStr); err != nil {
-			return GetErrorHTML(http.StatusBadRequest, fmt.Errorf("invalid instance id: %v", err))
+			return GetErrorHTML(getRequestErrorCode(err), err)
 		}
 
-		instanceIDInterface := primaryKeyValue.Interface()
-
 		allowed, err := m.App.Panel.PermissionChecker.HasInstanceReadPermission(m.App.Name, m.Name, instanceIDInterface, data)
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
@@ -170,21 +186,26 @@ func (m *Model) GetInstanceViewHandler() HandlerFunc {
 			}
 		}
 
-		instanceData, err := m.GetORM().FetchInstanceOnlyFields(m.PTR, instanceIDInterface, fieldsToFetch)
+		instanceData, err := m.fetchLiveInstance(m.getRequestORM(data), instanceIDInterface, fieldsToFetch)
 		if err != nil {
-			return GetErrorHTML(http.StatusInternalServerError, err)
+			return GetErrorHTML(getRequestErrorCode(err), err)
 		}
 
 		// Compute permissions and useful links for header actions
 		canCreate, _ := m.App.Panel.PermissionChecker.HasModelCreatePermission(m.App.Name, m.Name, data)
 		canUpdate, _ := m.App.Panel.PermissionChecker.HasInstanceUpdatePermission(m.App.Name, m.Name, instanceIDInterface, data)
 		canDelete, _ := m.App.Panel.PermissionChecker.HasInstanceDeletePermission(m.App.Name, m.Name, instanceIDInterface, data)
-		editLink := m.App.Panel.Config.GetLink(fmt.Sprintf("%s/%v/edit", m.GetLink(), instanceIDInterface))
+		relations, err := m.getRelatedInstances(data, instanceIDInterface)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+
+		editLink := m.App.Panel.Config.GetLink(m.getInstanceLink(instanceIDInterface, "edit"))
 		listLink := m.GetFullLink()
 		addLink := m.GetFullAddLink()
-		deleteUrl := m.App.Panel.Config.GetLink(fmt.Sprintf("%s/%v/view", m.GetLink(), instanceIDInterface))
+		deleteUrl := m.App.Panel.Config.GetLink(m.getInstanceLink(instanceIDInterface, "delete"))
 
-		html, err := m.App.Panel.Config.Renderer.RenderTemplate("instance", map[string]interface{}{
+		html, err := m.App.Panel.RenderPage(data, "instance", map[string]interface{}{
 			"admin":       m.App.Panel,
 			"model":       m,
 			"apps":        apps,
@@ -198,6 +219,7 @@ func (m *Model) GetInstanceViewHandler() HandlerFunc {
 			"addLink":     addLink,
 			"listLink":    listLink,
 			"deleteUrl":   deleteUrl,
+			"relations":   relations,
 		})
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
@@ -216,10 +238,217 @@ func (m *Model) GetInstanceViewHandler() HandlerFunc {
 	}
 }
 
+// setModelField assigns a clean form value to the named field of a model instance, converting it to the field's
+// type and allocating pointer fields.
+func setModelField(instanceVal reflect.Value, fieldName string, value interface{}) error {
+	fieldVal := instanceVal.FieldByName(fieldName)
+
+	if !fieldVal.IsValid() {
+		return fmt.Errorf("field %s not found in model", fieldName)
+	}
+
+	if !fieldVal.CanSet() {
+		return fmt.Errorf("field %s is not settable", fieldName)
+	}
+
+	val := reflect.ValueOf(value)
+
+	if fieldVal.Kind() == reflect.Ptr {
+		if value == nil {
+			fieldVal.Set(reflect.Zero(fieldVal.Type()))
+			return nil
+		}
+		elemType := fieldVal.Type().Elem()
+		newVal := reflect.New(elemType)
+		if val.Type().AssignableTo(elemType) {
+			newVal.Elem().Set(val)
+		} else if val.Type().ConvertibleTo(elemType) {
+			newVal.Elem().Set(val.Convert(elemType))
+		} else {
+			return fmt.Errorf("field %s has invalid type", fieldName)
+		}
+		fieldVal.Set(newVal)
+		return nil
+	}
+
+	if value == nil {
+		fieldVal.Set(reflect.Zero(fieldVal.Type()))
+		return nil
+	}
+	if val.Type().AssignableTo(fieldVal.Type()) {
+		fieldVal.Set(val)
+	} else if val.Type().ConvertibleTo(fieldVal.Type()) {
+		fieldVal.Set(val.Convert(fieldVal.Type()))
+	} else {
+		return fmt.Errorf("field %s has invalid type", fieldName)
+	}
+	return nil
+}
+
+// unprefixValues strips a form prefix from the names of clean form values.
+func unprefixValues(values map[string]interface{}, prefix string) map[string]interface{} {
+	if prefix == "" {
+		return values
+	}
+	unprefixed := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		unprefixed[strings.TrimPrefix(name, prefix)] = value
+	}
+	return unprefixed
+}
+
+// getSavedFields returns the names of the model fields present in clean form values, in declaration order.
+func (m *Model) getSavedFields(cleanValues map[string]interface{}) []string {
+	savedFields := make([]string, 0, len(cleanValues))
+	for _, field := range m.Fields {
+		if _, ok := cleanValues[field.Name]; ok {
+			savedFields = append(savedFields, field.Name)
+		}
+	}
+	return savedFields
+}
+
+// getEditInitialValues returns the values of the edit form fields of an instance, dereferencing pointer fields.
+func (m *Model) getEditInitialValues(instanceData interface{}) map[string]interface{} {
+	initialValuesMap := make(map[string]interface{})
+	for _, field := range m.Fields {
+		if field.EditFormField == nil {
+			continue
+		}
+		fieldValue := reflect.ValueOf(instanceData).Elem().FieldByName(field.Name)
+		var value interface{}
+		if field.IsPointer {
+			if fieldValue.IsNil() {
+				value = nil
+			} else {
+				value = fieldValue.Elem().Interface()
+			}
+		} else {
+			value = fieldValue.Interface()
+		}
+
+		initialValuesMap[field.Name] = value
+	}
+	return initialValuesMap
+}
+
+// formHasErrors reports whether form validation returned any form or field error.
+func formHasErrors(formErrs []error, fieldErrs map[string][]error) bool {
+	if len(formErrs) > 0 {
+		return true
+	}
+	for _, errs := range fieldErrs {
+		if len(errs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// convertFormValues converts the HTML values of the fields of f to Go values, for every field or, when fields is not
+// nil, for the fields it names only. Missing values are converted from an empty value. Fields whose value cannot be
+// converted are left out of the converted values and reported with their conversion error.
+func convertFormValues(f form.Form, values map[string]form.HTMLType, fields map[string]bool) (map[string]interface{}, map[string]error) {
+	converted := make(map[string]interface{})
+	conversionErrs := make(map[string]error)
+	for _, field := range f.GetFields() {
+		if fields != nil && !fields[field.GetName()] {
+			continue
+		}
+		value, err := field.HTMLTypeToGoType(values[field.GetName()])
+		if err != nil {
+			conversionErrs[field.GetName()] = err
+			continue
+		}
+		converted[field.GetName()] = value
+	}
+	return converted, conversionErrs
+}
+
+// validateFormValues validates values converted by convertFormValues with f and returns the form errors followed by
+// the errors of each field labeled with the field, leaving out the fields not named in fields unless it is nil.
+func validateFormValues(f form.Form, values map[string]interface{}, conversionErrs map[string]error, fields map[string]bool) ([]string, error) {
+	formErrs, fieldErrs, err := form.ValuesAreValid(f, values)
+	if err != nil {
+		return nil, err
+	}
+	for name, conversionErr := range conversionErrs {
+		// The validation of fields that could not be converted is meaningless.
+		fieldErrs[name] = []error{conversionErr}
+	}
+	errs := make([]string, 0)
+	for _, formErr := range formErrs {
+		errs = append(errs, formErr.Error())
+	}
+	for _, field := range f.GetFields() {
+		if fields != nil && !fields[field.GetName()] {
+			continue
+		}
+		for _, fieldErr := range fieldErrs[field.GetName()] {
+			errs = append(errs, fmt.Sprintf("%s: %v", field.GetLabel(), fieldErr))
+		}
+	}
+	return errs, nil
+}
+
+// updateInstanceFields updates the fields of an existing instance named in updates through orm, and saves the
+// relations among them. For versioned models the update fails with ErrVersionConflict unless the instance still has
+// the given version. It returns the instance holding the updated values.
+func (m *Model) updateInstanceFields(orm ORMIntegrator, instanceID interface{}, updates map[string]interface{}, version interface{}) (interface{}, error) {
+	instancePtr := reflect.New(reflect.TypeOf(m.PTR).Elem())
+	for name, value := range updates {
+		if _, isRelation := m.getRelation(name); isRelation {
+			continue
+		}
+		if err := setModelField(instancePtr.Elem(), name, value); err != nil {
+			return nil, err
+		}
+	}
+	fields := m.getSavedFields(updates)
+	var err error
+	if m.VersionField != "" {
+		err = m.updateIfVersion(orm, instancePtr.Interface(), fields, instanceID, version)
+	} else {
+		err = orm.UpdateInstanceOnlyFields(instancePtr.Interface(), fields, instanceID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = m.saveRelations(orm, instanceID, updates); err != nil {
+		return nil, err
+	}
+	return instancePtr.Interface(), nil
+}
+
+// getFormORM returns the integrator a model form saves through.
+func (m *Model) getFormORM(orm ORMIntegrator) ORMIntegrator {
+	if orm != nil {
+		return orm
+	}
+	return m.GetORM()
+}
+
+// setFormORM makes a model form save through orm. Other forms are left untouched.
+func setFormORM(f form.Form, orm ORMIntegrator) {
+	switch f := f.(type) {
+	case *ModelAddForm:
+		f.ORM = orm
+	case *ModelEditForm:
+		f.ORM = orm
+	}
+}
+
 // ModelAddForm represents the form used to add a new instance of a model.
 type ModelAddForm struct {
 	forms.BaseForm
 	Model *Model
+	// ORM is the integrator the form saves through, such as a transaction. It defaults to the model's integrator.
+	ORM ORMIntegrator
+	// Prefix is stripped from the names of the form fields when saving, so several forms can share a request.
+	Prefix string
+	// Overrides holds values set on the new instance in addition to the form data, such as the parent key of an
+	// inline row.
+	Overrides map[string]interface{}
 }
 
 // Save processes the form data and creates a new instance of the model.
@@ -228,66 +457,46 @@ func (f *ModelAddForm) Save(values map[string]form.HTMLType) (interface{}, error
 	if err != nil {
 		return nil, err
 	}
+	cleanValues = unprefixValues(cleanValues, f.Prefix)
+	for name, value := range f.Overrides {
+		cleanValues[name] = value
+	}
 
 	modelType := reflect.TypeOf(f.Model.PTR).Elem()
 	instancePtr := reflect.New(modelType)
 	instanceVal := instancePtr.Elem()
 
 	for fieldName, value := range cleanValues {
-		fieldVal := instanceVal.FieldByName(fieldName)
-
-		if !fieldVal.IsValid() {
-			return nil, fmt.Errorf("field %s not found in model", fieldName)
-		}
-
-		if !fieldVal.CanSet() {
-			return nil, fmt.Errorf("field %s is not settable", fieldName)
+		if _, isRelation := f.Model.getRelation(fieldName); isRelation {
+			continue
 		}
-
-		val := reflect.ValueOf(value)
-
-		if fieldVal.Kind() == reflect.Ptr {
-			if value == nil {
-				fieldVal.Set(reflect.Zero(fieldVal.Type()))
-				continue
-			}
-			elemType := fieldVal.Type().Elem()
-			newVal := reflect.New(elemType)
-			if val.Type().AssignableTo(elemType) {
-				newVal.Elem().Set(val)
-			} else if val.Type().ConvertibleTo(elemType) {
-				newVal.Elem().Set(val.Convert(elemType))
-			} else {
-				return nil, fmt.Errorf("field %s has invalid type", fieldName)
-			}
-			fieldVal.Set(newVal)
-		} else {
-			if value == nil {
-				fieldVal.Set(reflect.Zero(fieldVal.Type()))
-				continue
-			}
-			if val.Type().AssignableTo(fieldVal.Type()) {
-				fieldVal.Set(val)
-			} else if val.Type().ConvertibleTo(fieldVal.Type()) {
-				fieldVal.Set(val.Convert(fieldVal.Type()))
-			} else {
-				return nil, fmt.Errorf("field %s has invalid type", fieldName)
-			}
+		if err = setModelField(instanceVal, fieldName, value); err != nil {
+			return nil, err
 		}
 	}
 
-	fieldsToInclude := make([]string, 0)
-	for _, field := range f.Model.Fields {
-		if field.AddFormField != nil {
-			fieldsToInclude = append(fieldsToInclude, field.Name)
-		}
+	fieldsToInclude := f.Model.getSavedFields(cleanValues)
+	if f.Model.VersionField != "" {
+		f.Model.setInitialVersion(instancePtr.Interface())
+		fieldsToInclude = append(fieldsToInclude, f.Model.VersionField)
 	}
 
-	err = f.Model.GetORM().CreateInstanceOnlyFields(instancePtr.Interface(), fieldsToInclude)
+	orm := f.Model.getFormORM(f.ORM)
+	err = orm.CreateInstanceOnlyFields(instancePtr.Interface(), fieldsToInclude)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(f.Model.Relations) > 0 {
+		instanceID, err := f.Model.GetPrimaryKeyValue(instancePtr.Interface())
+		if err != nil {
+			return nil, err
+		}
+		if err = f.Model.saveRelations(orm, instanceID, cleanValues); err != nil {
+			return nil, err
+		}
+	}
+
 	return instancePtr.Interface(), nil
 }
 
@@ -296,6 +505,30 @@ type ModelEditForm struct {
 	forms.BaseForm
 	Model      *Model
 	InstanceID interface{}
+	// ORM is the integrator the form saves through, such as a transaction. It defaults to the model's integrator.
+	ORM ORMIntegrator
+	// Prefix is stripped from the names of the form fields when saving, so several forms can share a request.
+	Prefix string
+	// Version is the version of the instance the form was rendered with, for models with a version field. Save fails
+	// with ErrVersionConflict if the instance was saved by someone else in the meantime. When nil, Save checks the
+	// version it reads before updating instead; the edit page rejects posts without a version rather than relying on
+	// it. Either way the version is bumped.
+	Version interface{}
+}
+
+// VersionValue returns the encoded version carried by the form as a hidden value, or an empty string.
+func (f *ModelEditForm) VersionValue() string {
+	return encodeVersion(f.Version)
+}
+
+// GetVersionName returns the name of the hidden input carrying the version.
+func (f *ModelEditForm) GetVersionName() string {
+	return f.Prefix + versionFormName
+}
+
+// GetFullEditLink returns the full URL the form is posted to.
+func (f *ModelEditForm) GetFullEditLink() string {
+	return f.Model.App.Panel.Config.GetLink(f.Model.getInstanceLink(f.InstanceID, "edit"))
 }
 
 // Save processes the form data and updates the existing instance of the model.
@@ -304,71 +537,53 @@ func (f *ModelEditForm) Save(values map[string]form.HTMLType) (interface{}, erro
 	if err != nil {
 		return nil, err
 	}
+	cleanValues = unprefixValues(cleanValues, f.Prefix)
 
 	modelType := reflect.TypeOf(f.Model.PTR).Elem()
 	instancePtr := reflect.New(modelType)
 	instanceVal := instancePtr.Elem()
 
 	for fieldName, value := range cleanValues {
-		fieldVal := instanceVal.FieldByName(fieldName)
-
-		if !fieldVal.IsValid() {
-			return nil, fmt.Errorf("field %s not found in model", fieldName)
+		if _, isRelation := f.Model.getRelation(fieldName); isRelation {
+			continue
 		}
-
-		if !fieldVal.CanSet() {
-			return nil, fmt.Errorf("field %s is not settable", fieldName)
+		if err = setModelField(instanceVal, fieldName, value); err != nil {
+			return nil, err
 		}
+	}
 
-		val := reflect.ValueOf(value)
+	fieldsToInclude := f.Model.getSavedFields(cleanValues)
 
-		if fieldVal.Kind() == reflect.Ptr {
-			if value == nil {
-				fieldVal.Set(reflect.Zero(fieldVal.Type()))
-				continue
-			}
-			elemType := fieldVal.Type().Elem()
-			newVal := reflect.New(elemType)
-			if val.Type().AssignableTo(elemType) {
-				newVal.Elem().Set(val)
-			} else if val.Type().ConvertibleTo(elemType) {
-				newVal.Elem().Set(val.Convert(elemType))
-			} else {
-				return nil, fmt.Errorf("field %s has invalid type", fieldName)
-			}
-			fieldVal.Set(newVal)
-		} else {
-			if value == nil {
-				fieldVal.Set(reflect.Zero(fieldVal.Type()))
-				continue
-			}
-			if val.Type().AssignableTo(fieldVal.Type()) {
-				fieldVal.Set(val)
-			} else if val.Type().ConvertibleTo(fieldVal.Type()) {
-				fieldVal.Set(val.Convert(fieldVal.Type()))
-			} else {
-				return nil, fmt.Errorf("field %s has invalid type", fieldName)
+	orm := f.Model.getFormORM(f.ORM)
+	if f.Model.VersionField != "" {
+		version := f.Version
+		if version == nil {
+			// Without the version the form was rendered with, the version read now is checked and bumped, so the
+			// update still invalidates the forms rendered before it.
+			current, err := f.Model.fetchLiveInstance(orm, f.InstanceID, []string{f.Model.VersionField})
+			if err != nil {
+				return nil, err
 			}
+			version = f.Model.getVersion(current)
 		}
+		err = f.Model.updateIfVersion(orm, instancePtr.Interface(), fieldsToInclude, f.InstanceID, version)
+	} else {
+		err = orm.UpdateInstanceOnlyFields(instancePtr.Interface(), fieldsToInclude, f.InstanceID)
 	}
-
-	fieldsToInclude := make([]string, 0)
-	for _, field := range f.Model.Fields {
-		if field.EditFormField != nil {
-			fieldsToInclude = append(fieldsToInclude, field.Name)
-		}
+	if err != nil {
+		return nil, err
 	}
 
-	err = f.Model.GetORM().UpdateInstanceOnlyFields(instancePtr.Interface(), fieldsToInclude, f.InstanceID)
-	if err != nil {
+	if err = f.Model.saveRelations(orm, f.InstanceID, cleanValues); err != nil {
 		return nil, err
 	}
 
 	return instancePtr.Interface(), nil
 }
 
-// NewAddForm creates a new form for adding an instance of the model.
-func (m *Model) NewAddForm() (form.Form, error) {
+// NewAddForm creates a new form for adding an instance of the model. The related instances its fields look up are
+// fetched within the context of the request.
+func (m *Model) NewAddForm(data interface{}) (form.Form, error) {
 	f := &ModelAddForm{
 		Model: m,
 	}
@@ -378,16 +593,20 @@ func (m *Model) NewAddForm() (form.Form, error) {
 			continue
 		}
 
-		err := f.AddField(fieldConfig.Name, cloneFormField(fieldConfig.AddFormField))
+		err := f.AddField(fieldConfig.Name, m.newRequestFormField(data, fieldConfig.AddFormField))
 		if err != nil {
 			return nil, err
 		}
 	}
+	if err := m.addRelationFields(data, &f.BaseForm); err != nil {
+		return nil, err
+	}
 	return f, nil
 }
 
-// NewEditForm creates a new form for editing an existing instance of the model.
-func (m *Model) NewEditForm(instanceID interface{}) (form.Form, error) {
+// NewEditForm creates a new form for editing an existing instance of the model. The related instances its fields look
+// up are fetched within the context of the request.
+func (m *Model) NewEditForm(data interface{}, instanceID interface{}) (form.Form, error) {
 	f := &ModelEditForm{
 		Model:      m,
 		InstanceID: instanceID,
@@ -398,14 +617,27 @@ func (m *Model) NewEditForm(instanceID interface{}) (form.Form, error) {
 			continue
 		}
 
-		err := f.AddField(fieldConfig.Name, cloneFormField(fieldConfig.EditFormField))
+		err := f.AddField(fieldConfig.Name, m.newRequestFormField(data, fieldConfig.EditFormField))
 		if err != nil {
 			return nil, err
 		}
 	}
+	if err := m.addRelationFields(data, &f.BaseForm); err != nil {
+		return nil, err
+	}
 	return f, nil
 }
 
+// newRequestFormField clones a form field for a request, binding the lookups of foreign key fields to the context of
+// the request.
+func (m *Model) newRequestFormField(data interface{}, f form.Field) form.Field {
+	cloned := cloneFormField(f)
+	if foreignKey, ok := cloned.(*ForeignKeyField); ok {
+		foreignKey.Context = m.App.Panel.GetRequestContext(data)
+	}
+	return cloned
+}
+
 // cloneFormField returns a shallow copy of the given form.Field to ensure
 // per-request state (like InitialValue) doesn't leak across requests.
 func cloneFormField(f form.Field) form.Field {
@@ -433,7 +665,11 @@ func (m *Model) GetAddHandler() HandlerFunc {
 			return GetErrorHTML(http.StatusForbidden, fmt.Errorf("forbidden"))
 		}
 
-		formInstance, err := m.NewAddForm()
+		formInstance, err := m.NewAddForm(data)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		inlines, err := m.newInlineFormSets(data, nil)
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
@@ -441,21 +677,21 @@ func (m *Model) GetAddHandler() HandlerFunc {
 		method := m.App.Panel.Web.GetRequestMethod(data)
 		switch method {
 		case http.MethodGet:
-			return m.renderNewInstanceGET(data, formInstance)
+			return m.renderNewInstanceGET(data, formInstance, inlines)
 		case http.MethodPost:
-			return m.processNewInstancePOST(data, formInstance)
+			return m.processNewInstancePOST(data, formInstance, inlines)
 		default:
 			return GetErrorHTML(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		}
 	}
 }
 
-func (m *Model) renderNewInstanceGET(data interface{}, formInstance form.Form) (uint, string) {
+func (m *Model) renderNewInstanceGET(data interface{}, formInstance form.Form, inlines []*InlineFormSet) (uint, string) {
 	apps, err := GetAppsWithReadPermissions(m.App.Panel, data)
 	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
-	html, err := m.App.Panel.Config.Renderer.RenderTemplate("new_instance", map[string]interface{}{
+	html, err := m.App.Panel.RenderPage(data, "new_instance", map[string]interface{}{
 		"admin":       m.App.Panel,
 		"apps":        apps,
 		"navBarItems": m.App.Panel.Config.GetNavBarItems(data),
@@ -463,6 +699,7 @@ func (m *Model) renderNewInstanceGET(data interface{}, formInstance form.Form) (
 		"model":       m,
 		"formErrs":    make([]error, 0),
 		"fieldErrs":   make(map[string][]error),
+		"inlines":     inlines,
 	})
 	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
@@ -470,7 +707,7 @@ func (m *Model) renderNewInstanceGET(data interface{}, formInstance form.Form) (
 	return http.StatusOK, html
 }
 
-func (m *Model) processNewInstancePOST(data interface{}, formInstance form.Form) (uint, string) {
+func (m *Model) processNewInstancePOST(data interface{}, formInstance form.Form, inlines []*InlineFormSet) (uint, string) {
 	formData := m.App.Panel.Web.GetFormData(data)
 	if formData == nil {
 		return GetErrorHTML(http.StatusBadRequest, fmt.Errorf("form data is required"))
@@ -487,22 +724,17 @@ func (m *Model) processNewInstancePOST(data interface{}, formInstance form.Form)
 	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
-	hasErrors := len(formErrs) > 0
-	if !hasErrors {
-		for _, errs := range fieldErrs {
-			if len(errs) > 0 {
-				hasErrors = true
-				break
-			}
-		}
+	inlinesValid, err := bindInlineFormSets(data, inlines, nil, convertedFormData)
+	if err != nil {
+		return GetErrorHTML(getRequestErrorCode(err), err)
 	}
-	if hasErrors {
+	if formHasErrors(formErrs, fieldErrs) || !inlinesValid {
 		_ = formInstance.RegisterInitialValues(cleanFormData)
 		apps, err := GetAppsWithReadPermissions(m.App.Panel, data)
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
-		html, err := m.App.Panel.Config.Renderer.RenderTemplate("new_instance", map[string]interface{}{
+		html, err := m.App.Panel.RenderPage(data, "new_instance", map[string]interface{}{
 			"admin":       m.App.Panel,
 			"apps":        apps,
 			"navBarItems": m.App.Panel.Config.GetNavBarItems(data),
@@ -510,6 +742,7 @@ func (m *Model) processNewInstancePOST(data interface{}, formInstance form.Form)
 			"model":       m,
 			"formErrs":    formErrs,
 			"fieldErrs":   fieldErrs,
+			"inlines":     inlines,
 		})
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
@@ -517,22 +750,33 @@ func (m *Model) processNewInstancePOST(data interface{}, formInstance form.Form)
 		return http.StatusOK, html
 	}
 
-	instanceInterface, err := formInstance.Save(convertedFormData)
-	if err != nil {
-		return GetErrorHTML(http.StatusInternalServerError, err)
-	}
-	instanceID, err := m.GetPrimaryKeyValue(instanceInterface)
-	if err != nil || instanceID == nil {
-		if err == nil {
-			err = fmt.Errorf("instance id is nil")
+	var instanceID interface{}
+	var repr string
+	err = m.runLoggedWrites(data, m.SaveMode, func(orm ORMIntegrator, logs *writeLogs) error {
+		setFormORM(formInstance, orm)
+		instanceInterface, err := formInstance.Save(convertedFormData)
+		if err != nil {
+			return err
 		}
+		instanceID, err = m.GetPrimaryKeyValue(instanceInterface)
+		if err != nil || instanceID == nil {
+			if err == nil {
+				err = fmt.Errorf("instance id is nil")
+			}
+			return err
+		}
+		instanceInstance := &Instance{InstanceID: instanceID, Data: instanceInterface, Model: m}
+		repr = instanceInstance.GetRepr()
+		logs.add(func() error { return instanceInstance.CreateCreateLog(data) })
+		return m.saveInlineFormSets(data, orm, logs, inlines, instanceID, convertedFormData)
+	})
+	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
-	instanceInstance := &Instance{InstanceID: instanceID, Data: instanceInterface, Model: m}
-	if err := instanceInstance.CreateCreateLog(data); err != nil {
+	if err = m.App.Panel.AddFlash(data, FlashSuccess, fmt.Sprintf("The %s \"%s\" was added successfully.", m.DisplayName, repr)); err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
-	instanceLink := fmt.Sprintf("%s/%v/view", m.GetFullLink(), instanceID)
+	instanceLink := m.App.Panel.Config.GetLink(m.getInstanceLink(instanceID, "view"))
 	return http.StatusSeeOther, instanceLink
 }
 
@@ -544,20 +788,11 @@ func (m *Model) GetEditHandler() HandlerFunc {
 			return GetErrorHTML(http.StatusBadRequest, fmt.Errorf("instance id is required"))
 		}
 
-		primaryKeyType, err := m.GetPrimaryKeyType()
+		instanceIDInterface, err := m.parseInstanceID(instanceIDStr)
 		if err != nil {
-			return GetErrorHTML(http.StatusInternalServerError, err)
-		}
-
-		primaryKeyValuePtr := reflect.New(primaryKeyType)
-		primaryKeyValue := primaryKeyValuePtr.Elem()
-
-		if err = utils.SetStringsAsType(primaryKeyValue, instanceIDStr); err != nil {
-			return GetErrorHTML(http.StatusBadRequest, fmt.Errorf("invalid instance id: %v", err))
+			return GetErrorHTML(getRequestErrorCode(err), err)
 		}
 
-		instanceIDInterface := primaryKeyValue.Interface()
-
 		allowed, err := m.App.Panel.PermissionChecker.HasInstanceUpdatePermission(m.App.Name, m.Name, instanceIDInterface, data)
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
@@ -566,41 +801,27 @@ func (m *Model) GetEditHandler() HandlerFunc {
 			return GetErrorHTML(http.StatusForbidden, fmt.Errorf("you are not allowed to view this instance"))
 		}
 
-		var fieldsToFetch []string
-		for _, fieldConfig := range m.Fields {
-			if fieldConfig.IncludeInInstanceView {
-				fieldsToFetch = append(fieldsToFetch, fieldConfig.Name)
-			}
+		instanceData, err := m.fetchEditInstance(data, instanceIDInterface)
+		if err != nil {
+			return GetErrorHTML(getRequestErrorCode(err), err)
 		}
 
-		instanceData, err := m.App.Panel.ORM.FetchInstanceOnlyFields(m.PTR, instanceIDInterface, fieldsToFetch)
+		formInstance, err := m.NewEditForm(data, instanceIDInterface)
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
+		if editForm, ok := formInstance.(*ModelEditForm); ok && m.VersionField != "" {
+			editForm.Version = m.getVersion(instanceData)
+		}
+
+		initialValuesMap := m.getEditInitialValues(instanceData)
 
-		formInstance, err := m.NewEditForm(instanceIDInterface)
+		relatedIDValues, err := m.fetchRelatedIDValues(data, instanceIDInterface)
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
-
-		initialValuesMap := make(map[string]interface{})
-		for _, field := range m.Fields {
-			if field.EditFormField == nil {
-				continue
-			}
-			fieldValue := reflect.ValueOf(instanceData).Elem().FieldByName(field.Name)
-			var value interface{}
-			if field.IsPointer {
-				if fieldValue.IsNil() {
-					value = nil
-				} else {
-					value = fieldValue.Elem().Interface()
-				}
-			} else {
-				value = fieldValue.Interface()
-			}
-
-			initialValuesMap[field.Name] = value
+		for name, value := range relatedIDValues {
+			initialValuesMap[name] = value
 		}
 
 		err = formInstance.RegisterInitialValues(initialValuesMap)
@@ -608,30 +829,48 @@ func (m *Model) GetEditHandler() HandlerFunc {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
 
+		inlines, err := m.newInlineFormSets(data, instanceIDInterface)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+
 		method := m.App.Panel.Web.GetRequestMethod(data)
 		switch method {
 		case http.MethodGet:
-			return m.renderEditGET(data, formInstance)
+			return m.renderEditGET(data, formInstance, inlines)
 		case http.MethodPost:
-			return m.processEditPOST(data, formInstance)
+			return m.processEditPOST(data, formInstance, instanceIDInterface, inlines)
 		default:
 			return GetErrorHTML(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		}
 	}
 }
 
-func (m *Model) renderEditGET(data interface{}, formInstance form.Form) (uint, string) {
+// fetchEditInstance fetches the fields of an instance shown on the edit page, including its version. Trashed instances
+// are reported as not found.
+func (m *Model) fetchEditInstance(data interface{}, instanceID interface{}) (interface{}, error) {
+	var fieldsToFetch []string
+	for _, fieldConfig := range m.Fields {
+		if fieldConfig.IncludeInInstanceView || fieldConfig.Name == m.VersionField {
+			fieldsToFetch = append(fieldsToFetch, fieldConfig.Name)
+		}
+	}
+	return m.fetchLiveInstance(m.getRequestORM(data), instanceID, fieldsToFetch)
+}
+
+func (m *Model) renderEditGET(data interface{}, formInstance form.Form, inlines []*InlineFormSet) (uint, string) {
 	apps, err := GetAppsWithReadPermissions(m.App.Panel, data)
 	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
-	html, err := m.App.Panel.Config.Renderer.RenderTemplate("edit_instance", map[string]interface{}{
+	html, err := m.App.Panel.RenderPage(data, "edit_instance", map[string]interface{}{
 		"admin": m.App.Panel,
 		"apps":  apps, "navBarItems": m.App.Panel.Config.GetNavBarItems(data),
 		"form":      formInstance,
 		"model":     m,
 		"formErrs":  make([]error, 0),
 		"fieldErrs": make(map[string][]error),
+		"inlines":   inlines,
 	})
 	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
@@ -639,7 +878,7 @@ func (m *Model) renderEditGET(data interface{}, formInstance form.Form) (uint, s
 	return http.StatusOK, html
 }
 
-func (m *Model) processEditPOST(data interface{}, formInstance form.Form) (uint, string) {
+func (m *Model) processEditPOST(data interface{}, formInstance form.Form, instanceID interface{}, inlines []*InlineFormSet) (uint, string) {
 	formData := m.App.Panel.Web.GetFormData(data)
 	if formData == nil {
 		return GetErrorHTML(http.StatusBadRequest, fmt.Errorf("form data is required"))
@@ -648,6 +887,19 @@ func (m *Model) processEditPOST(data interface{}, formInstance form.Form) (uint,
 	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
+	editForm, isEditForm := formInstance.(*ModelEditForm)
+	if isEditForm && m.VersionField != "" {
+		// Edit pages always carry the version they were rendered with, so a post without it would overwrite the
+		// changes saved since unchecked.
+		version, ok := convertedFormData[editForm.GetVersionName()]
+		if !ok {
+			return GetErrorHTML(http.StatusBadRequest, fmt.Errorf("the %s form has no version; reload the page and save again", m.DisplayName))
+		}
+		editForm.Version, err = m.decodeVersion(string(version))
+		if err != nil {
+			return GetErrorHTML(http.StatusBadRequest, err)
+		}
+	}
 	cleanFormData, err := form.GetCleanData(formInstance, convertedFormData)
 	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
@@ -656,28 +908,24 @@ func (m *Model) processEditPOST(data interface{}, formInstance form.Form) (uint,
 	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
-	hasErrors := len(formErrs) > 0
-	if !hasErrors {
-		for _, errs := range fieldErrs {
-			if len(errs) > 0 {
-				hasErrors = true
-				break
-			}
-		}
+	inlinesValid, err := bindInlineFormSets(data, inlines, instanceID, convertedFormData)
+	if err != nil {
+		return GetErrorHTML(getRequestErrorCode(err), err)
 	}
-	if hasErrors {
+	if formHasErrors(formErrs, fieldErrs) || !inlinesValid {
 		_ = formInstance.RegisterInitialValues(cleanFormData)
 		apps, err := GetAppsWithReadPermissions(m.App.Panel, data)
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
-		html, err := m.App.Panel.Config.Renderer.RenderTemplate("edit_instance", map[string]interface{}{
+		html, err := m.App.Panel.RenderPage(data, "edit_instance", map[string]interface{}{
 			"admin": m.App.Panel,
 			"apps":  apps, "navBarItems": m.App.Panel.Config.GetNavBarItems(data),
 			"form":      formInstance,
 			"model":     m,
 			"formErrs":  formErrs,
 			"fieldErrs": fieldErrs,
+			"inlines":   inlines,
 		})
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
@@ -685,21 +933,58 @@ func (m *Model) processEditPOST(data interface{}, formInstance form.Form) (uint,
 		return http.StatusOK, html
 	}
 
-	instanceInterface, err := formInstance.Save(convertedFormData)
+	var repr string
+	err = m.runLoggedWrites(data, m.SaveMode, func(orm ORMIntegrator, logs *writeLogs) error {
+		setFormORM(formInstance, orm)
+		instanceInterface, err := formInstance.Save(convertedFormData)
+		if err != nil {
+			return err
+		}
+		instanceInstance := &Instance{InstanceID: instanceID, Data: instanceInterface, Model: m}
+		repr = instanceInstance.GetRepr()
+		logs.add(func() error { return instanceInstance.CreateUpdateLog(data, cleanFormData) })
+		return m.saveInlineFormSets(data, orm, logs, inlines, instanceID, convertedFormData)
+	})
+	if errors.Is(err, ErrVersionConflict) && isEditForm {
+		return m.renderEditConflict(data, editForm, instanceID, cleanFormData, inlines)
+	}
 	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
-	instanceID, err := m.GetPrimaryKeyValue(instanceInterface)
-	if err != nil || instanceID == nil {
-		if err == nil {
-			err = fmt.Errorf("instance id is nil")
-		}
+	if err = m.App.Panel.AddFlash(data, FlashSuccess, fmt.Sprintf("The %s \"%s\" was changed successfully.", m.DisplayName, repr)); err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
-	instanceInstance := &Instance{InstanceID: instanceID, Data: instanceInterface, Model: m}
-	if err := instanceInstance.CreateUpdateLog(data, cleanFormData); err != nil {
+	instanceLink := m.App.Panel.Config.GetLink(m.getInstanceLink(instanceID, "view"))
+	return http.StatusSeeOther, instanceLink
+}
+
+// renderEditConflict re-renders the edit page after a version conflict, keeping the submitted values and showing how
+// they differ from the current ones. The form carries the current version, so submitting it again overwrites the
+// other changes knowingly.
+func (m *Model) renderEditConflict(data interface{}, editForm *ModelEditForm, instanceID interface{}, cleanFormData map[string]interface{}, inlines []*InlineFormSet) (uint, string) {
+	current, err := m.fetchEditInstance(data, instanceID)
+	if err != nil {
 		return GetErrorHTML(http.StatusInternalServerError, err)
 	}
-	instanceLink := fmt.Sprintf("%s/%v/view", m.GetFullLink(), instanceID)
-	return http.StatusSeeOther, instanceLink
+	editForm.Version = m.getVersion(current)
+	_ = editForm.RegisterInitialValues(cleanFormData)
+
+	apps, err := GetAppsWithReadPermissions(m.App.Panel, data)
+	if err != nil {
+		return GetErrorHTML(http.StatusInternalServerError, err)
+	}
+	html, err := m.App.Panel.RenderPage(data, "edit_instance", map[string]interface{}{
+		"admin": m.App.Panel,
+		"apps":  apps, "navBarItems": m.App.Panel.Config.GetNavBarItems(data),
+		"form":      editForm,
+		"model":     m,
+		"formErrs":  []error{fmt.Errorf("this %s was changed by someone else while you were editing it; review the differences below and save again to overwrite them", m.DisplayName)},
+		"fieldErrs": make(map[string][]error),
+		"inlines":   inlines,
+		"conflicts": m.getVersionConflicts(current, cleanFormData),
+	})
+	if err != nil {
+		return GetErrorHTML(http.StatusInternalServerError, err)
+	}
+	return http.StatusConflict, html
 }
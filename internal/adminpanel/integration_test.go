@@ -0,0 +1,210 @@
+package adminpanel_test
+
+import (
+	"errors"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"github.com/ovnicraft/go-advanced-admin/internal/orm/memory"
+	"testing"
+)
+
+// The tests of this package run the panel against the in-memory ORM integrator, through the exported API only.
+
+// newMemoryORM returns an in-memory integrator storing the given instances.
+func newMemoryORM(t *testing.T, instances ...interface{}) *memory.Integrator {
+	orm := memory.NewIntegrator()
+	if err := orm.Seed(instances...); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return orm
+}
+
+// storedRows returns the instances of type T stored by the integrator.
+func storedRows[T any](t *testing.T, orm adminpanel.ORMIntegrator) []*T {
+	rows, err := orm.FetchInstances(new(T))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return rows.([]*T)
+}
+
+// registerProductModel registers the product model, storing a single product in memory, on a new mock panel.
+func registerProductModel(t *testing.T) (*adminpanel.Model, *recordingORM) {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return registerProductModelOn(t, panel)
+}
+
+// registerAPIModel registers the product model, like registerProductModel, on a panel serving the JSON API under
+// "api" and its OpenAPI document at "openapi.json".
+func registerAPIModel(t *testing.T) (*adminpanel.Model, *recordingORM) {
+	config := *adminpanel.NewDefaultAdminConfig()
+	config.APIPrefix = "api"
+	config.OpenAPIPath = "openapi.json"
+	panel, err := adminpanel.NewAdminPanel(&adminpanel.MockORMIntegrator{}, &adminpanel.MockWebIntegrator{}, adminpanel.MockPermissionFunc, &config)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return registerProductModelOn(t, panel)
+}
+
+func registerProductModelOn(t *testing.T, panel *adminpanel.AdminPanel) (*adminpanel.Model, *recordingORM) {
+	testApp, err := panel.RegisterApp("Shop", "Shop", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orm := &recordingORM{Integrator: newMemoryORM(t, &adminpanel.Product{ID: 1, Name: "Widget", Stock: 3})}
+	model, err := testApp.RegisterModel(&adminpanel.Product{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model, orm
+}
+
+// callAPI runs a JSON API handler and returns the status and the response it sent.
+func callAPI(t *testing.T, model *adminpanel.Model, handler adminpanel.JSONHandlerFunc, request *adminpanel.MockRequest) (int, adminpanel.JSONResponse) {
+	if err := handler(request); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	web := model.App.Panel.Web.(*adminpanel.MockWebIntegrator)
+	return web.JSONStatus, web.JSONResponse.(adminpanel.JSONResponse)
+}
+
+// countLogs returns the number of log entries of the panel of the model with the given action.
+func countLogs(t *testing.T, model *adminpanel.Model, action logging.LogStoreLevel) int {
+	entries, err := model.App.Panel.Config.LogStore.GetLogEntries()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	count := 0
+	for _, entry := range entries {
+		if entry.ActionFlag == action {
+			count++
+		}
+	}
+	return count
+}
+
+// plainORM hides the optional extensions of an integrator, leaving the panel to fall back to the methods of
+// ORMIntegrator.
+type plainORM struct {
+	adminpanel.ORMIntegrator
+}
+
+// recordingORM is an in-memory integrator recording the fields of each partial update and the list queries it
+// receives.
+type recordingORM struct {
+	*memory.Integrator
+	UpdatedFields [][]string
+	Queries       []adminpanel.ListQuery
+}
+
+func (r *recordingORM) UpdateInstanceOnlyFields(instance interface{}, fields []string, primaryKey interface{}) error {
+	r.UpdatedFields = append(r.UpdatedFields, fields)
+	return r.Integrator.UpdateInstanceOnlyFields(instance, fields, primaryKey)
+}
+
+func (r *recordingORM) FetchInstancesPage(model interface{}, query adminpanel.ListQuery) (interface{}, uint, error) {
+	r.Queries = append(r.Queries, query)
+	return r.Integrator.FetchInstancesPage(model, query)
+}
+
+// transactionalORM runs transactions over an in-memory integrator on a copy of the instances of Models, which
+// replaces the stored instances when the transaction commits.
+type transactionalORM struct {
+	*memory.Integrator
+	Models []interface{}
+	// FailDelete and FailCreate, when set, report the primary keys and the instances whose deletion and creation
+	// fail, as locked rows and violated constraints would.
+	FailDelete func(id interface{}) bool
+	FailCreate func(instance interface{}) bool
+	Commits    int
+	Rollbacks  int
+}
+
+func (m *transactionalORM) DeleteByID(model interface{}, id interface{}) error {
+	if m.FailDelete != nil && m.FailDelete(id) {
+		return errors.New("row is locked")
+	}
+	return m.Integrator.DeleteByID(model, id)
+}
+
+func (m *transactionalORM) CreateInstanceOnlyFields(instance interface{}, fields []string) error {
+	if m.FailCreate != nil && m.FailCreate(instance) {
+		return errors.New("constraint violation")
+	}
+	return m.Integrator.CreateInstanceOnlyFields(instance, fields)
+}
+
+func (m *transactionalORM) WithTransaction(fn func(tx adminpanel.ORMIntegrator) error) error {
+	tx := &transactionalORM{Integrator: memory.NewIntegrator(), Models: m.Models, FailDelete: m.FailDelete, FailCreate: m.FailCreate}
+	for _, model := range m.Models {
+		instances, err := m.FetchInstances(model)
+		if err != nil {
+			return err
+		}
+		if err = tx.Seed(instances); err != nil {
+			return err
+		}
+	}
+	if err := fn(tx); err != nil {
+		m.Rollbacks++
+		return err
+	}
+	m.Integrator = tx.Integrator
+	m.Commits++
+	return nil
+}
+
+// failingORM is an in-memory integrator whose instance lookups fail with Err when it is set, as a failing database
+// would.
+type failingORM struct {
+	*memory.Integrator
+	Err error
+}
+
+func (f *failingORM) FetchInstance(model interface{}, id interface{}) (interface{}, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return f.Integrator.FetchInstance(model, id)
+}
+
+func (f *failingORM) FetchInstanceOnlyFields(model interface{}, id interface{}, fields []string) (interface{}, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return f.Integrator.FetchInstanceOnlyFields(model, id, fields)
+}
+
+// getFieldConfig returns the configuration of the named field of the model.
+func getFieldConfig(t *testing.T, model *adminpanel.Model, name string) adminpanel.FieldConfig {
+	for _, fieldConfig := range model.Fields {
+		if fieldConfig.Name == name {
+			return fieldConfig
+		}
+	}
+	t.Fatalf("model %s has no field %s", model.Name, name)
+	return adminpanel.FieldConfig{}
+}
+
+// relatedORM adds many-to-many relations, held in Related by relation name and instance ID, to an in-memory
+// integrator.
+type relatedORM struct {
+	*memory.Integrator
+	Related map[string]map[interface{}][]interface{}
+}
+
+func (r *relatedORM) FetchRelatedIDs(_ interface{}, id interface{}, relation string) ([]interface{}, error) {
+	return r.Related[relation][id], nil
+}
+
+func (r *relatedORM) ReplaceRelatedIDs(_ interface{}, id interface{}, relation string, relatedIDs []interface{}) error {
+	if r.Related[relation] == nil {
+		r.Related[relation] = make(map[interface{}][]interface{})
+	}
+	r.Related[relation][id] = relatedIDs
+	return nil
+}
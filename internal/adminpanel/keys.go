@@ -0,0 +1,182 @@
+package adminpanel
+
+import (
+	"encoding"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/utils"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// KeyCodec converts primary keys to the text identifying instances in URLs and forms, and back.
+type KeyCodec interface {
+	// EncodeKey formats a primary key as text.
+	EncodeKey(key interface{}) (string, error)
+	// DecodeKey parses text produced by EncodeKey into a primary key of type keyType.
+	DecodeKey(text string, keyType reflect.Type) (interface{}, error)
+}
+
+// compositeKeySeparator separates the fields of composite primary keys. Commas within field values are escaped.
+const compositeKeySeparator = ","
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// RegisterKeyCodec registers the codec used for primary keys of type keyType, replacing the default one.
+func (ap *AdminPanel) RegisterKeyCodec(keyType reflect.Type, codec KeyCodec) error {
+	if keyType == nil {
+		return fmt.Errorf("key type cannot be nil")
+	}
+	if codec == nil {
+		return fmt.Errorf("key codec for type %s cannot be nil", keyType)
+	}
+	if ap.KeyCodecs == nil {
+		ap.KeyCodecs = make(map[reflect.Type]KeyCodec)
+	}
+	ap.KeyCodecs[keyType] = codec
+	return nil
+}
+
+// GetKeyCodec returns the codec for primary keys of type keyType: the registered one if any, else a codec for types
+// implementing encoding.TextMarshaler and encoding.TextUnmarshaler, a codec joining the fields of struct keys, or the
+// codec for strings, integers and UUIDs.
+func (ap *AdminPanel) GetKeyCodec(keyType reflect.Type) KeyCodec {
+	if codec, ok := ap.KeyCodecs[keyType]; ok {
+		return codec
+	}
+	if keyType != nil && keyType.Implements(textMarshalerType) && reflect.PointerTo(keyType).Implements(textUnmarshalerType) {
+		return textKeyCodec{}
+	}
+	if keyType != nil && keyType.Kind() == reflect.Struct {
+		return compositeKeyCodec{panel: ap}
+	}
+	return scalarKeyCodec{}
+}
+
+// EncodeKey formats a primary key as text with the codec for its type.
+func (ap *AdminPanel) EncodeKey(key interface{}) (string, error) {
+	if key == nil {
+		return "", nil
+	}
+	return ap.GetKeyCodec(reflect.TypeOf(key)).EncodeKey(key)
+}
+
+// DecodeKey parses the text of a primary key of type keyType with the codec for the type.
+func (ap *AdminPanel) DecodeKey(text string, keyType reflect.Type) (interface{}, error) {
+	return ap.GetKeyCodec(keyType).DecodeKey(text, keyType)
+}
+
+// scalarKeyCodec is the codec for string, integer and UUID primary keys.
+type scalarKeyCodec struct{}
+
+func (scalarKeyCodec) EncodeKey(key interface{}) (string, error) {
+	return fmt.Sprint(key), nil
+}
+
+func (scalarKeyCodec) DecodeKey(text string, keyType reflect.Type) (interface{}, error) {
+	key := reflect.New(keyType).Elem()
+	if err := utils.SetStringsAsType(key, text); err != nil {
+		return nil, err
+	}
+	return key.Interface(), nil
+}
+
+// textKeyCodec is the codec for primary keys implementing encoding.TextMarshaler and encoding.TextUnmarshaler.
+type textKeyCodec struct{}
+
+func (textKeyCodec) EncodeKey(key interface{}) (string, error) {
+	text, err := key.(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+func (textKeyCodec) DecodeKey(text string, keyType reflect.Type) (interface{}, error) {
+	key := reflect.New(keyType)
+	if err := key.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+		return nil, err
+	}
+	return key.Elem().Interface(), nil
+}
+
+// compositeKeyCodec is the codec for struct primary keys, such as a tenant ID and an ID. The exported fields are
+// encoded in order with the codecs for their types, escaped and joined with commas.
+type compositeKeyCodec struct {
+	panel *AdminPanel
+}
+
+func (c compositeKeyCodec) EncodeKey(key interface{}) (string, error) {
+	value := reflect.ValueOf(key)
+	parts := make([]string, 0, value.NumField())
+	for _, i := range keyFieldIndexes(value.Type()) {
+		part, err := c.panel.EncodeKey(value.Field(i).Interface())
+		if err != nil {
+			return "", fmt.Errorf("key field %s: %w", value.Type().Field(i).Name, err)
+		}
+		parts = append(parts, url.PathEscape(part))
+	}
+	return strings.Join(parts, compositeKeySeparator), nil
+}
+
+func (c compositeKeyCodec) DecodeKey(text string, keyType reflect.Type) (interface{}, error) {
+	indexes := keyFieldIndexes(keyType)
+	parts := strings.Split(text, compositeKeySeparator)
+	if len(parts) != len(indexes) {
+		return nil, fmt.Errorf("expected %d key fields, got %d", len(indexes), len(parts))
+	}
+	key := reflect.New(keyType).Elem()
+	for i, index := range indexes {
+		part, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return nil, err
+		}
+		field := key.Field(index)
+		value, err := c.panel.DecodeKey(part, field.Type())
+		if err != nil {
+			return nil, fmt.Errorf("key field %s: %w", keyType.Field(index).Name, err)
+		}
+		field.Set(reflect.ValueOf(value))
+	}
+	return key.Interface(), nil
+}
+
+// keyFieldIndexes returns the indexes of the exported fields of a composite key type.
+func keyFieldIndexes(keyType reflect.Type) []int {
+	indexes := make([]int, 0, keyType.NumField())
+	for i := 0; i < keyType.NumField(); i++ {
+		if keyType.Field(i).IsExported() {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// escapeKeySegment escapes the text of a primary key for use as a URL path segment. Commas are kept, as they are
+// valid in path segments and separate the fields of composite keys.
+func escapeKeySegment(text string) string {
+	return strings.ReplaceAll(url.PathEscape(text), "%2C", compositeKeySeparator)
+}
+
+// FormatInstanceID returns the text identifying an instance of the model in URLs and forms. Keys the codec fails to
+// encode are formatted with fmt.Sprint.
+func (m *Model) FormatInstanceID(instanceID interface{}) string {
+	text, err := m.App.Panel.EncodeKey(instanceID)
+	if err != nil {
+		return fmt.Sprint(instanceID)
+	}
+	return text
+}
+
+// getInstanceLink returns the relative URL of an instance page or endpoint of the model, such as "view" or "edit".
+func (m *Model) getInstanceLink(instanceID interface{}, action string) string {
+	return fmt.Sprintf("%s/%s/%s", m.GetLink(), escapeKeySegment(m.FormatInstanceID(instanceID)), action)
+}
+
+// GetEncodedID returns the text identifying the instance in URLs and forms.
+func (i *Instance) GetEncodedID() string {
+	return i.Model.FormatInstanceID(i.InstanceID)
+}
@@ -0,0 +1,170 @@
+package adminpanel
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// OrderNumber is a primary key type implementing encoding.TextMarshaler and encoding.TextUnmarshaler.
+type OrderNumber struct {
+	Year int
+	Seq  int
+}
+
+func (n OrderNumber) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d-%04d", n.Year, n.Seq)), nil
+}
+
+func (n *OrderNumber) UnmarshalText(text []byte) error {
+	if _, err := fmt.Sscanf(string(text), "%d-%d", &n.Year, &n.Seq); err != nil {
+		return fmt.Errorf("invalid order number %q", text)
+	}
+	return nil
+}
+
+// TenantKey is a composite primary key.
+type TenantKey struct {
+	TenantID uint
+	ID       string
+}
+
+type TenantNote struct {
+	TenantID uint
+	ID       string
+	Title    string
+}
+
+// reversedKeyCodec is a custom codec storing string keys reversed.
+type reversedKeyCodec struct{}
+
+func (reversedKeyCodec) EncodeKey(key interface{}) (string, error) {
+	return reverse(key.(string)), nil
+}
+
+func (reversedKeyCodec) DecodeKey(text string, _ reflect.Type) (interface{}, error) {
+	return reverse(text), nil
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// MockTenantORMIntegrator stores TenantNote instances keyed by TenantKey, a key the in-memory integrator cannot hold.
+type MockTenantORMIntegrator struct {
+	MockORMIntegrator
+	Notes []*TenantNote
+}
+
+func (m *MockTenantORMIntegrator) GetPrimaryKeyValue(instance interface{}) (interface{}, error) {
+	note := instance.(*TenantNote)
+	return TenantKey{TenantID: note.TenantID, ID: note.ID}, nil
+}
+
+func (m *MockTenantORMIntegrator) GetPrimaryKeyType(interface{}) (reflect.Type, error) {
+	return reflect.TypeOf(TenantKey{}), nil
+}
+
+func (m *MockTenantORMIntegrator) FetchInstanceOnlyFields(_ interface{}, id interface{}, _ []string) (interface{}, error) {
+	for _, note := range m.Notes {
+		if (TenantKey{TenantID: note.TenantID, ID: note.ID}) == id {
+			return note, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func TestAdminPanel_KeyCodecs(t *testing.T) {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	for _, test := range []struct {
+		key  interface{}
+		text string
+	}{
+		{uint(42), "42"},
+		{-7, "-7"},
+		{"slug", "slug"},
+		{id, id.String()},
+		{OrderNumber{Year: 2024, Seq: 7}, "2024-0007"},
+		{TenantKey{TenantID: 3, ID: "a,b/c"}, "3,a%2Cb%2Fc"},
+	} {
+		text, err := panel.EncodeKey(test.key)
+		if err != nil {
+			t.Fatalf("expected no error encoding %v, got %v", test.key, err)
+		}
+		if text != test.text {
+			t.Errorf("expected %v to be encoded as %q, got %q", test.key, test.text, text)
+		}
+		decoded, err := panel.DecodeKey(text, reflect.TypeOf(test.key))
+		if err != nil {
+			t.Fatalf("expected no error decoding %q, got %v", text, err)
+		}
+		if decoded != test.key {
+			t.Errorf("expected %q to be decoded as %v, got %v", text, test.key, decoded)
+		}
+	}
+
+	for _, text := range []string{"3", "3,a,b", "x,a"} {
+		if _, err := panel.DecodeKey(text, reflect.TypeOf(TenantKey{})); err == nil {
+			t.Errorf("expected an error decoding the composite key %q", text)
+		}
+	}
+
+	if err = panel.RegisterKeyCodec(reflect.TypeOf(""), reversedKeyCodec{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if text, _ := panel.EncodeKey(TenantKey{TenantID: 1, ID: "abc"}); text != "1,cba" {
+		t.Errorf("expected composite keys to use the registered codecs for their fields, got %q", text)
+	}
+	if err = panel.RegisterKeyCodec(reflect.TypeOf(""), nil); err == nil {
+		t.Error("expected an error registering a nil codec")
+	}
+}
+
+func TestModel_CompositeKey(t *testing.T) {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orm := &MockTenantORMIntegrator{Notes: []*TenantNote{{TenantID: 7, ID: "n 1", Title: "Tenant note"}}}
+	model, err := testApp.RegisterModel(&TenantNote{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	instance := &Instance{InstanceID: TenantKey{TenantID: 7, ID: "n 1"}, Model: model}
+	if link := instance.GetFullEditLink(); link != "/admin/a/TestApp/TenantNote/7,n%25201/edit" {
+		t.Errorf("unexpected edit link %s", link)
+	}
+	if link := instance.GetFullRestoreLink(); !strings.HasSuffix(link, "/7,n%25201/restore") {
+		t.Errorf("unexpected restore link %s", link)
+	}
+
+	code, body := model.GetInstanceViewHandler()(&MockRequest{Method: http.MethodGet, Params: map[string]string{"id": "7,n%201"}})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if !strings.Contains(body, "Tenant note") || !strings.Contains(body, "/admin/a/TestApp/TenantNote/7,n%25201/edit") {
+		t.Error("expected the instance page to show the instance and link to its edit page")
+	}
+
+	code, _ = model.GetInstanceViewHandler()(&MockRequest{Method: http.MethodGet, Params: map[string]string{"id": "7"}})
+	if code != http.StatusBadRequest {
+		t.Errorf("expected %v for an incomplete composite key, got %v", http.StatusBadRequest, code)
+	}
+}
@@ -0,0 +1,379 @@
+package adminpanel
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListColumn describes a column of a model's list view.
+type ListColumn struct {
+	Field      FieldConfig
+	Sortable   bool
+	SortLink   string
+	Ordered    bool
+	Descending bool
+	Priority   int
+}
+
+// ListPageLink describes a link to a page of a model's list view.
+type ListPageLink struct {
+	Number uint
+	Link   string
+	Active bool
+}
+
+// listParams holds the query parameters of a list view request, so links can be rebuilt without losing state.
+type listParams struct {
+	Search   string
+	Page     uint
+	PerPage  uint
+	Ordering []OrderBy
+	Filters  url.Values
+	// Trash selects the trashed instances of a soft-deleted model instead of the live ones.
+	Trash bool
+}
+
+// getListParams reads the list view query parameters from the request.
+func getListParams(m *Model, data interface{}) listParams {
+	page, perPage := getPagination(m, data)
+	params := listParams{
+		Search:  m.App.Panel.Web.GetQueryParam(data, "search"),
+		Page:    page,
+		PerPage: perPage,
+		Filters: getFilterParams(m, data),
+	}
+
+	orderQuery := m.App.Panel.Web.GetQueryParam(data, "order")
+	if orderQuery != "" {
+		params.Ordering, _ = m.parseOrdering(strings.Split(orderQuery, ","), true)
+	}
+	if len(params.Ordering) == 0 {
+		params.Ordering = m.DefaultOrdering
+	}
+	return params
+}
+
+// values encodes the parameters as URL query values.
+func (p listParams) values() url.Values {
+	values := url.Values{}
+	if p.Search != "" {
+		values.Set("search", p.Search)
+	}
+	if p.Page > 1 {
+		values.Set("page", strconv.FormatUint(uint64(p.Page), 10))
+	}
+	if p.PerPage != 0 {
+		values.Set("perPage", strconv.FormatUint(uint64(p.PerPage), 10))
+	}
+	if len(p.Ordering) > 0 {
+		values.Set("order", formatOrdering(p.Ordering))
+	}
+	for name, filterValues := range p.Filters {
+		for _, value := range filterValues {
+			values.Add(name, value)
+		}
+	}
+	return values
+}
+
+// link returns the list view link of model m for these parameters.
+func (p listParams) link(m *Model) string {
+	base := m.GetFullLink()
+	if p.Trash {
+		base = m.GetFullTrashLink()
+	}
+	encoded := p.values().Encode()
+	if encoded == "" {
+		return base
+	}
+	return base + "?" + encoded
+}
+
+// query builds the ORM query fetching the page of model m described by the parameters.
+func (p listParams) query(m *Model, now time.Time) ListQuery {
+	query := ListQuery{
+		Fields:   getFieldsToFetch(m),
+		Search:   p.Search,
+		Offset:   (p.Page - 1) * p.PerPage,
+		Limit:    p.PerPage,
+		Ordering: p.Ordering,
+		Filters:  buildFilterConditions(m, p.Filters, now),
+	}
+	if m.SoftDeleteField != "" {
+		query.Filters = append(query.Filters, m.trashCondition(p.Trash))
+	}
+	if query.Search != "" {
+		query.SearchFields = getFieldsToSearch(m)
+	}
+	return query
+}
+
+// withPage returns a copy of the parameters pointing at the given page.
+func (p listParams) withPage(page uint) listParams {
+	p.Page = page
+	return p
+}
+
+// withPrimaryOrder returns a copy of the parameters ordered by field first. If field already comes first its
+// direction is toggled; the rest of the current ordering is kept as secondary ordering.
+func (p listParams) withPrimaryOrder(field string) listParams {
+	primary := OrderBy{Field: field}
+	ordering := make([]OrderBy, 0, len(p.Ordering)+1)
+	for i, order := range p.Ordering {
+		if order.Field != field {
+			ordering = append(ordering, order)
+			continue
+		}
+		if i == 0 {
+			primary.Descending = !order.Descending
+		}
+	}
+	p.Ordering = append([]OrderBy{primary}, ordering...)
+	p.Page = 1
+	return p
+}
+
+// parseOrdering parses entries such as "Name" or "-CreatedAt" into an ordering. When onlySortable is set, fields
+// that are not sortable are skipped; otherwise unknown fields are reported as an error.
+func (m *Model) parseOrdering(entries []string, onlySortable bool) ([]OrderBy, error) {
+	ordering := make([]OrderBy, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		order := OrderBy{Field: strings.TrimPrefix(entry, "-"), Descending: strings.HasPrefix(entry, "-")}
+		if order.Field == "" || seen[order.Field] {
+			continue
+		}
+		fieldConfig, ok := m.getFieldConfig(order.Field)
+		if !ok {
+			if onlySortable {
+				continue
+			}
+			return nil, fmt.Errorf("admin model '%s' has no field '%s' to order by", m.Name, order.Field)
+		}
+		if onlySortable && !fieldConfig.Sortable {
+			continue
+		}
+		seen[order.Field] = true
+		ordering = append(ordering, order)
+	}
+	return ordering, nil
+}
+
+// formatOrdering is the inverse of parseOrdering.
+func formatOrdering(ordering []OrderBy) string {
+	entries := make([]string, len(ordering))
+	for i, order := range ordering {
+		if order.Descending {
+			entries[i] = "-" + order.Field
+		} else {
+			entries[i] = order.Field
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+// getFieldConfig returns the configuration of the named field.
+func (m *Model) getFieldConfig(name string) (FieldConfig, bool) {
+	for _, fieldConfig := range m.Fields {
+		if fieldConfig.Name == name {
+			return fieldConfig, true
+		}
+	}
+	return FieldConfig{}, false
+}
+
+// getListColumns returns the columns displayed by the list view, with their sort links.
+func getListColumns(m *Model, params listParams) []ListColumn {
+	columns := make([]ListColumn, 0, len(m.Fields))
+	for _, fieldConfig := range m.Fields {
+		if !fieldConfig.IncludeInListDisplay {
+			continue
+		}
+		column := ListColumn{Field: fieldConfig, Sortable: fieldConfig.Sortable}
+		for i, order := range params.Ordering {
+			if order.Field == fieldConfig.Name {
+				column.Ordered = true
+				column.Descending = order.Descending
+				column.Priority = i + 1
+			}
+		}
+		if column.Sortable {
+			column.SortLink = params.withPrimaryOrder(fieldConfig.Name).link(m)
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// getListPageLinks returns links to the first and last pages and to the pages around the current one.
+func getListPageLinks(m *Model, params listParams, totalPages uint) []ListPageLink {
+	const window = 2
+	pages := []uint{1}
+	first := uint(2)
+	if params.Page > window+1 {
+		first = params.Page - window
+	}
+	for page := first; page <= params.Page+window && page < totalPages; page++ {
+		pages = append(pages, page)
+	}
+	if totalPages > 1 {
+		pages = append(pages, totalPages)
+	}
+
+	links := make([]ListPageLink, 0, len(pages))
+	for _, page := range pages {
+		links = append(links, ListPageLink{Number: page, Link: params.withPage(page).link(m), Active: page == params.Page})
+	}
+	return links
+}
+
+// listRow returns the instance as a list view row, for clients rendering the list themselves. Values of the
+// columns are formatted as strings, with empty strings for zero values, and foreign key columns link to the related
+// instance.
+func (i *Instance) listRow() map[string]interface{} {
+	values := make(map[string]string)
+	links := make(map[string]string)
+	for _, fieldConfig := range i.Model.Fields {
+		if !fieldConfig.IncludeInListDisplay {
+			continue
+		}
+		values[fieldConfig.Name] = formatListValue(i.Data, fieldConfig.Name)
+		if fieldConfig.ForeignKey != "" {
+			links[fieldConfig.Name] = i.GetFieldLink(fieldConfig.Name)
+		}
+	}
+	return map[string]interface{}{
+		"id":         i.InstanceID,
+		"key":        i.GetEncodedID(),
+		"repr":       i.GetRepr(),
+		"link":       i.GetFullLink(),
+		"editLink":   i.GetFullEditLink(),
+		"deleteLink": i.GetFullDeleteLink(),
+		"values":     values,
+		"links":      links,
+		"permissions": map[string]bool{
+			"read":   i.Permissions.Read,
+			"update": i.Permissions.Update,
+			"delete": i.Permissions.Delete,
+		},
+	}
+}
+
+// formatListValue formats the named field of an instance for display in the list view.
+func formatListValue(instance interface{}, fieldName string) string {
+	field := fieldValueOf(instance, fieldName)
+	if !field.IsValid() || field.IsZero() || !field.CanInterface() {
+		return ""
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+// sortInstances orders instances in memory, for integrators that cannot order in the database.
+func sortInstances(instances []interface{}, ordering []OrderBy) {
+	if len(ordering) == 0 {
+		return
+	}
+	sort.SliceStable(instances, func(i, j int) bool {
+		for _, order := range ordering {
+			cmp := compareFieldValues(fieldValueOf(instances[i], order.Field), fieldValueOf(instances[j], order.Field))
+			if cmp == 0 {
+				continue
+			}
+			if order.Descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// fieldValueOf returns the named field of a struct or pointer to struct, dereferencing pointer fields. It returns
+// an invalid reflect.Value for missing fields and nil pointers.
+func fieldValueOf(instance interface{}, fieldName string) reflect.Value {
+	val := reflect.ValueOf(instance)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return reflect.Value{}
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	field := val.FieldByName(fieldName)
+	for field.IsValid() && (field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) {
+		if field.IsNil() {
+			return reflect.Value{}
+		}
+		field = field.Elem()
+	}
+	return field
+}
+
+// compareFieldValues compares two field values, ordering missing values first.
+func compareFieldValues(a, b reflect.Value) int {
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return 0
+	case !a.IsValid():
+		return -1
+	case !b.IsValid():
+		return 1
+	}
+
+	if a.CanInterface() && b.CanInterface() {
+		if at, ok := a.Interface().(time.Time); ok {
+			if bt, ok := b.Interface().(time.Time); ok {
+				return at.Compare(bt)
+			}
+		}
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if b.CanInt() {
+			return compareOrdered(a.Int(), b.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if b.CanUint() {
+			return compareOrdered(a.Uint(), b.Uint())
+		}
+	case reflect.Float32, reflect.Float64:
+		if b.CanFloat() {
+			return compareOrdered(a.Float(), b.Float())
+		}
+	case reflect.Bool:
+		if b.Kind() == reflect.Bool {
+			return compareOrdered(boolRank(a.Bool()), boolRank(b.Bool()))
+		}
+	case reflect.String:
+		if b.Kind() == reflect.String {
+			return strings.Compare(a.String(), b.String())
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareOrdered[T int64 | uint64 | float64 | int](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
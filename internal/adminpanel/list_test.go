@@ -0,0 +1,158 @@
+package adminpanel
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type SortableTestModel struct {
+	ID        uint
+	Name      string     `admin:"sortable"`
+	Age       *int       `admin:"sortable:include"`
+	CreatedAt time.Time  `admin:"sortable;addForm:exclude;editForm:exclude"`
+	Notes     string     `admin:"sortable:exclude"`
+	DeletedAt *time.Time `admin:"addForm:exclude;editForm:exclude"`
+}
+
+func (m *SortableTestModel) AdminDefaultOrdering() []string {
+	return []string{"-CreatedAt", "Name"}
+}
+
+func registerSortableTestModel(t *testing.T, orm ORMIntegrator) *Model {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	model, err := testApp.RegisterModel(&SortableTestModel{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model
+}
+
+func TestRegisterModel_SortableTag(t *testing.T) {
+	model := registerSortableTestModel(t, nil)
+
+	expected := map[string]bool{"ID": false, "Name": true, "Age": true, "CreatedAt": true, "Notes": false}
+	for name, sortable := range expected {
+		fieldConfig, ok := model.getFieldConfig(name)
+		if !ok {
+			t.Fatalf("expected field %s to be registered", name)
+		}
+		if fieldConfig.Sortable != sortable {
+			t.Errorf("expected field %s sortable=%v, got %v", name, sortable, fieldConfig.Sortable)
+		}
+	}
+
+	expectedOrdering := []OrderBy{{Field: "CreatedAt", Descending: true}, {Field: "Name"}}
+	if !reflect.DeepEqual(model.DefaultOrdering, expectedOrdering) {
+		t.Errorf("expected default ordering %v, got %v", expectedOrdering, model.DefaultOrdering)
+	}
+}
+
+func TestModel_ParseOrdering(t *testing.T) {
+	model := registerSortableTestModel(t, nil)
+
+	ordering, err := model.parseOrdering([]string{"-Age", "Notes", "Unknown", "Name", "-Age"}, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []OrderBy{{Field: "Age", Descending: true}, {Field: "Name"}}
+	if !reflect.DeepEqual(ordering, expected) {
+		t.Errorf("expected %v, got %v", expected, ordering)
+	}
+	if formatted := formatOrdering(ordering); formatted != "-Age,Name" {
+		t.Errorf("expected '-Age,Name', got %q", formatted)
+	}
+
+	if _, err := model.parseOrdering([]string{"Unknown"}, false); err == nil {
+		t.Error("expected an error for an unknown default ordering field")
+	}
+}
+
+func TestListParams_WithPrimaryOrder(t *testing.T) {
+	params := listParams{Page: 3, Ordering: []OrderBy{{Field: "Name"}, {Field: "Age", Descending: true}}}
+
+	toggled := params.withPrimaryOrder("Name")
+	expected := []OrderBy{{Field: "Name", Descending: true}, {Field: "Age", Descending: true}}
+	if !reflect.DeepEqual(toggled.Ordering, expected) {
+		t.Errorf("expected %v, got %v", expected, toggled.Ordering)
+	}
+	if toggled.Page != 1 {
+		t.Errorf("expected changing the ordering to reset the page, got page %d", toggled.Page)
+	}
+
+	promoted := params.withPrimaryOrder("Age")
+	expected = []OrderBy{{Field: "Age"}, {Field: "Name"}}
+	if !reflect.DeepEqual(promoted.Ordering, expected) {
+		t.Errorf("expected %v, got %v", expected, promoted.Ordering)
+	}
+
+	if !reflect.DeepEqual(params.Ordering, []OrderBy{{Field: "Name"}, {Field: "Age", Descending: true}}) {
+		t.Error("expected the original parameters to be left untouched")
+	}
+}
+
+func TestSortInstances(t *testing.T) {
+	age := func(v int) *int { return &v }
+	instances := []interface{}{
+		&SortableTestModel{ID: 1, Name: "b", Age: age(30)},
+		&SortableTestModel{ID: 2, Name: "a", Age: nil},
+		&SortableTestModel{ID: 3, Name: "c", Age: age(30)},
+		&SortableTestModel{ID: 4, Name: "a", Age: age(20)},
+	}
+
+	sortInstances(instances, []OrderBy{{Field: "Age", Descending: true}, {Field: "Name"}})
+
+	var ids []uint
+	for _, instance := range instances {
+		ids = append(ids, instance.(*SortableTestModel).ID)
+	}
+	if !reflect.DeepEqual(ids, []uint{1, 3, 4, 2}) {
+		t.Errorf("expected order [1 3 4 2], got %v", ids)
+	}
+}
+
+func TestModel_GetViewHandler_Ordering(t *testing.T) {
+	orm := &MockPaginatedORMIntegrator{}
+	model := registerSortableTestModel(t, orm)
+
+	code, body := model.GetViewHandler()(map[string]string{"order": "-Name,Notes"})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if !reflect.DeepEqual(orm.Queries[0].Ordering, []OrderBy{{Field: "Name", Descending: true}}) {
+		t.Errorf("expected ordering by descending name, got %v", orm.Queries[0].Ordering)
+	}
+	if !strings.Contains(body, "order=Name") {
+		t.Error("expected the Name header to link to ascending order")
+	}
+
+	_, _ = model.GetViewHandler()(map[string]string{})
+	if !reflect.DeepEqual(orm.Queries[1].Ordering, model.DefaultOrdering) {
+		t.Errorf("expected the default ordering, got %v", orm.Queries[1].Ordering)
+	}
+}
+
+func TestGetListPageLinks(t *testing.T) {
+	model := registerSortableTestModel(t, nil)
+
+	links := getListPageLinks(model, listParams{Page: 10, PerPage: 10}, 20)
+	var pages []uint
+	for _, link := range links {
+		pages = append(pages, link.Number)
+	}
+	if !reflect.DeepEqual(pages, []uint{1, 8, 9, 10, 11, 12, 20}) {
+		t.Errorf("expected pages [1 8 9 10 11 12 20], got %v", pages)
+	}
+	if !links[3].Active || !strings.Contains(links[3].Link, "page=10") {
+		t.Errorf("expected the current page link to be active, got %+v", links[3])
+	}
+}
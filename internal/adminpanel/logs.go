@@ -44,7 +44,7 @@ func (ap *AdminPanel) GetLogHandler() HandlerFunc {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
 
-		html, err := ap.Config.Renderer.RenderTemplate("log", map[string]interface{}{
+		html, err := ap.RenderPage(data, "log", map[string]interface{}{
 			"admin":       ap,
 			"apps":        apps,
 			"navBarItems": ap.Config.GetNavBarItems(data),
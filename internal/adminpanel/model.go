@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"reflect"
 	"strconv"
+	"time"
 )
 
 // Model represents a registered model within an app in the admin panel.
@@ -16,6 +17,30 @@ type Model struct {
 	App         *App
 	Fields      []FieldConfig
 	ORM         ORMIntegrator
+	// DefaultOrdering is applied to the list view when the request does not ask for an ordering.
+	DefaultOrdering []OrderBy
+	// Relations lists the many-to-many relations of the model.
+	Relations []ManyToManyRelation
+	// Inlines lists the child models edited together with the model.
+	Inlines []*Inline
+	// Actions lists the bulk actions offered by the list view.
+	Actions []ModelAction
+	// SaveMode selects how the add and edit forms save the instance together with its relations and inlines. It
+	// defaults to WriteModeAtomic.
+	SaveMode WriteMode
+	// BulkDeleteMode selects how bulk deletes handle items that cannot be deleted. It defaults to
+	// WriteModeBestEffort.
+	BulkDeleteMode WriteMode
+	// VersionField names the field tagged `admin:"version"`, used for optimistic concurrency control on the edit
+	// form. It is empty for models without a version field.
+	VersionField string
+	// SoftDeleteField names the field tagged `admin:"softDelete"`. Deleting an instance of such a model sets the field
+	// instead of removing the instance, which then moves from the list view to the model's trash. It is empty for
+	// models deleted for good.
+	SoftDeleteField string
+	// PrimaryKeyField names the primary key field: the field tagged `admin:"pk"`, or the field named ID, following the
+	// convention of the built-in ORM integrators. It is empty if the model has neither.
+	PrimaryKeyField string
 }
 
 // CreateViewLog creates a log entry when the model's list view is accessed.
@@ -23,6 +48,11 @@ func (m *Model) CreateViewLog(ctx interface{}) error {
 	return m.App.Panel.Config.CreateLog(ctx, logging.LogStoreLevelListView, fmt.Sprintf("%s | %s", m.App.Name, m.DisplayName), nil, "", "")
 }
 
+// getRequestORM returns the model's ORM integrator bound to the context of the request carried by data.
+func (m *Model) getRequestORM(data interface{}) ORMIntegrator {
+	return ORMWithContext(m.GetORM(), m.App.Panel.GetRequestContext(data))
+}
+
 // GetORM returns the ORM integrator for the model.
 func (m *Model) GetORM() ORMIntegrator {
 	if m.ORM != nil {
@@ -43,6 +73,12 @@ type AdminModelGetIDInterface interface {
 	AdminGetID() interface{}
 }
 
+// AdminModelDefaultOrderingInterface allows a model to declare the default ordering of its list view. Each entry
+// is a field name, prefixed with "-" for descending order.
+type AdminModelDefaultOrderingInterface interface {
+	AdminDefaultOrdering() []string
+}
+
 // GetLink returns the relative URL path to the model.
 func (m *Model) GetLink() string {
 	return fmt.Sprintf("%s/%s", m.App.GetLink(), m.Name)
@@ -69,12 +105,12 @@ func getPagination(m *Model, data interface{}) (uint, uint) {
 	perPageQuery := m.App.Panel.Web.GetQueryParam(data, "perPage")
 
 	var page, perPage uint
-	if p, err := strconv.Atoi(pageQuery); err == nil {
+	if p, err := strconv.Atoi(pageQuery); err == nil && p > 0 {
 		page = uint(p)
 	} else {
 		page = 1
 	}
-	if pp, err := strconv.Atoi(perPageQuery); err == nil {
+	if pp, err := strconv.Atoi(perPageQuery); err == nil && pp > 0 {
 		perPage = uint(pp)
 	} else {
 		perPage = m.App.Panel.Config.DefaultInstancesPerPage
@@ -130,10 +166,97 @@ func buildCleanInstances(m *Model, data interface{}, instances []interface{}) ([
 	return clean, nil
 }
 
+// getListScope returns the filters of the configured list scope restricting the instances of the model to those the
+// user may read, and whether they replace the check of the read permission of each instance.
+func (m *Model) getListScope(data interface{}) ([]FilterCondition, bool, error) {
+	if m.App.Panel.Config.ListScope == nil {
+		return nil, false, nil
+	}
+	return m.App.Panel.Config.ListScope(m.App.Name, m.Name, data)
+}
+
+// fetchListPage returns the instances on the page described by query that the user may read, together with the
+// total number of matching instances and whether that number is exact. Integrators implementing
+// PaginatedORMIntegrator paginate and count in the database, so the total is only an upper bound when the read
+// permission of each instance has to be checked on the fetched page, see AdminConfig.ListScope. For the other
+// integrators every matching instance is fetched and the page is sliced in memory.
+func fetchListPage(m *Model, data interface{}, query ListQuery) ([]interface{}, uint, bool, error) {
+	scope, scoped, err := m.getListScope(data)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	query.Filters = append(append([]FilterCondition(nil), query.Filters...), scope...)
+	filterByPermission := func(instances interface{}) ([]interface{}, error) {
+		if scoped {
+			return toInstanceSlice(instances)
+		}
+		return filterInstancesByPermission(instances, m, data)
+	}
+
+	orm := m.getRequestORM(data)
+	if paginated, ok := orm.(PaginatedORMIntegrator); ok {
+		instances, totalCount, err := paginated.FetchInstancesPage(m.PTR, query)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		filteredInstances, err := filterByPermission(instances)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		return filteredInstances, totalCount, scoped, nil
+	}
+
+	fieldsToFetch := append([]string(nil), query.Fields...)
+	for _, condition := range query.Filters {
+		fieldsToFetch = appendMissing(fieldsToFetch, condition.Field)
+	}
+	for _, order := range query.Ordering {
+		fieldsToFetch = appendMissing(fieldsToFetch, order.Field)
+	}
+
+	var instances interface{}
+	if query.Search == "" {
+		instances, err = orm.FetchInstancesOnlyFields(m.PTR, fieldsToFetch)
+	} else {
+		instances, err = orm.FetchInstancesOnlyFieldWithSearch(m.PTR, fieldsToFetch, query.Search, query.SearchFields)
+	}
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	filteredInstances, err := filterByPermission(instances)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	filteredInstances = filterInstances(filteredInstances, query.Filters)
+	sortInstances(filteredInstances, query.Ordering)
+
+	totalCount := uint(len(filteredInstances))
+	startIndex := query.Offset
+	if startIndex > totalCount {
+		startIndex = totalCount
+	}
+	endIndex := startIndex + query.Limit
+	if endIndex > totalCount {
+		endIndex = totalCount
+	}
+	return filteredInstances[startIndex:endIndex], totalCount, true, nil
+}
+
+// appendMissing appends value to values unless it is already present.
+func appendMissing(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
 // GetViewHandler returns the HTTP handler function for the model's list view.
 func (m *Model) GetViewHandler() HandlerFunc {
 	return func(data interface{}) (uint, string) {
-		page, perPage := getPagination(m, data)
+		params := getListParams(m, data)
 
 		allowed, err := m.App.Panel.PermissionChecker.HasModelReadPermission(m.App.Name, m.Name, data)
 		if err != nil {
@@ -148,55 +271,53 @@ func (m *Model) GetViewHandler() HandlerFunc {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
 
-		fieldsToFetch := getFieldsToFetch(m)
-
-		searchQuery := m.App.Panel.Web.GetQueryParam(data, "search")
-		var instances interface{}
-		if searchQuery == "" {
-			instances, err = m.GetORM().FetchInstancesOnlyFields(m.PTR, fieldsToFetch)
-		} else {
-			fieldsToSearch := getFieldsToSearch(m)
-			instances, err = m.GetORM().FetchInstancesOnlyFieldWithSearch(m.PTR, fieldsToFetch, searchQuery, fieldsToSearch)
-		}
+		pagedInstances, totalCount, exactCount, err := fetchListPage(m, data, params.query(m, time.Now()))
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
+		totalPages := (totalCount + params.PerPage - 1) / params.PerPage
 
-		filteredInstances, err := filterInstancesByPermission(instances, m, data)
+		cleanInstances, err := buildCleanInstances(m, data, pagedInstances)
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
-
-		totalCount := uint(len(filteredInstances))
-		totalPages := (totalCount + perPage - 1) / perPage
-
-		startIndex := (page - 1) * perPage
-		endIndex := startIndex + perPage
-
-		if startIndex > totalCount {
-			startIndex = totalCount
+		actions, err := m.getPermittedActions(data)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
-		if endIndex > totalCount {
-			endIndex = totalCount
+		var exportLinks []ExportLink
+		canExport, err := m.canExport(data)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
-
-		pagedInstances := filteredInstances[startIndex:endIndex]
-
-		cleanInstances, err := buildCleanInstances(m, data, pagedInstances)
+		if canExport {
+			exportLinks = params.exportLinks(m)
+		}
+		canImport, err := m.canImport(data)
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
 
-		html, err := m.App.Panel.Config.Renderer.RenderTemplate("model", map[string]interface{}{
-			"admin":       m.App.Panel,
-			"apps":        apps,
-			"model":       m,
-			"instances":   cleanInstances,
-			"totalCount":  totalCount,
-			"totalPages":  totalPages,
-			"currentPage": page,
-			"perPage":     perPage,
-			"navBarItems": m.App.Panel.Config.GetNavBarItems(data),
+		html, err := m.App.Panel.RenderPage(data, "model", map[string]interface{}{
+			"admin":        m.App.Panel,
+			"apps":         apps,
+			"model":        m,
+			"instances":    cleanInstances,
+			"totalCount":   totalCount,
+			"exactCount":   exactCount,
+			"totalPages":   totalPages,
+			"currentPage":  params.Page,
+			"perPage":      params.PerPage,
+			"columns":      getListColumns(m, params),
+			"ordering":     params.Ordering,
+			"pageLinks":    getListPageLinks(m, params, totalPages),
+			"search":       params.Search,
+			"searchInputs": listParams{PerPage: params.PerPage, Ordering: params.Ordering, Filters: params.Filters}.hiddenInputs(),
+			"filters":      getListFilters(m, params),
+			"actions":      actions,
+			"exportLinks":  exportLinks,
+			"canImport":    canImport,
+			"navBarItems":  m.App.Panel.Config.GetNavBarItems(data),
 		})
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
@@ -220,20 +341,14 @@ func (m *Model) GetPrimaryKeyType() (reflect.Type, error) {
 }
 
 func filterInstancesByPermission(instances interface{}, model *Model, data interface{}) ([]interface{}, error) {
-	val := reflect.ValueOf(instances)
-
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
-		return nil, fmt.Errorf("instances must be a slice or array")
+	all, err := toInstanceSlice(instances)
+	if err != nil {
+		return nil, err
 	}
 
-	filtered := make([]interface{}, 0, val.Len())
+	filtered := make([]interface{}, 0, len(all))
 
-	for i := 0; i < val.Len(); i++ {
-		instance := val.Index(i).Interface()
+	for _, instance := range all {
 		id, err := model.GetPrimaryKeyValue(instance)
 		if err != nil {
 			return nil, err
@@ -250,134 +365,216 @@ func filterInstancesByPermission(instances interface{}, model *Model, data inter
 	return filtered, nil
 }
 
-// HandleSearchAJAX handles AJAX search requests for the model
+// toInstanceSlice converts the slice of instances returned by an ORM integrator to a slice of interfaces.
+func toInstanceSlice(instances interface{}) ([]interface{}, error) {
+	val := reflect.ValueOf(instances)
+
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil, fmt.Errorf("instances must be a slice or array")
+	}
+
+	all := make([]interface{}, 0, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		all = append(all, val.Index(i).Interface())
+	}
+	return all, nil
+}
+
+// HandleSearchAJAX handles AJAX search requests for the model. The "q" query parameter is matched against the
+// searchable fields, while pagination, ordering and filters are read from the same parameters as the list view.
 func (m *Model) HandleSearchAJAX(ctx interface{}) error {
-	query := m.App.Panel.Web.GetQueryParam(ctx, "q")
+	allowed, err := m.App.Panel.PermissionChecker.HasModelReadPermission(m.App.Name, m.Name, ctx)
+	if err != nil {
+		response := NewErrorResponse([]string{err.Error()})
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusInternalServerError, response)
+	}
+	if !allowed {
+		response := NewErrorResponse([]string{"Permission denied"})
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusForbidden, response)
+	}
+
+	params := getListParams(m, ctx)
+	params.Search = m.App.Panel.Web.GetQueryParam(ctx, "q")
 
-	// Get instances from ORM (simplified - would need actual search implementation)
-	instances, err := m.GetORM().GetAll(m.PTR)
+	instances, totalCount, exactCount, err := fetchListPage(m, ctx, params.query(m, time.Now()))
 	if err != nil {
 		response := NewErrorResponse([]string{err.Error()})
-		return m.App.Panel.Web.SetJSONResponse(ctx, 400, response)
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusInternalServerError, response)
 	}
 
-	// Filter instances based on permissions
-	filtered, err := filterInstancesByPermission(instances, m, ctx)
+	cleanInstances, err := buildCleanInstances(m, ctx, instances)
 	if err != nil {
 		response := NewErrorResponse([]string{err.Error()})
-		return m.App.Panel.Web.SetJSONResponse(ctx, 400, response)
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusInternalServerError, response)
 	}
 
-	// TODO: Implement actual search filtering based on query
-	// For now, just return all filtered instances
+	rows := make([]map[string]interface{}, len(cleanInstances))
+	for i := range cleanInstances {
+		rows[i] = cleanInstances[i].listRow()
+	}
 
 	response := NewSuccessResponse(map[string]interface{}{
-		"instances": filtered,
-		"total":     len(filtered),
-		"query":     query,
+		"instances":  rows,
+		"total":      totalCount,
+		"exactTotal": exactCount,
+		"page":       params.Page,
+		"perPage":    params.PerPage,
+		"totalPages": (totalCount + params.PerPage - 1) / params.PerPage,
+		"query":      params.Search,
 	}, "")
 
-	return m.App.Panel.Web.SetJSONResponse(ctx, 200, response)
+	return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusOK, response)
 }
 
 // HandleDeleteAJAX handles AJAX delete requests for individual instances
 func (m *Model) HandleDeleteAJAX(ctx interface{}) error {
-	instanceID := m.App.Panel.Web.GetPathParam(ctx, "id")
-	if instanceID == "" {
-		instanceID = m.App.Panel.Web.GetQueryParam(ctx, "id")
+	instanceIDStr := m.App.Panel.Web.GetPathParam(ctx, "id")
+	if instanceIDStr == "" {
+		instanceIDStr = m.App.Panel.Web.GetQueryParam(ctx, "id")
 	}
 
-	if instanceID == "" {
+	if instanceIDStr == "" {
 		response := NewErrorResponse([]string{"Instance ID is required"})
-		return m.App.Panel.Web.SetJSONResponse(ctx, 400, response)
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusBadRequest, response)
+	}
+
+	instanceID, err := m.parseInstanceID(instanceIDStr)
+	if err != nil {
+		response := NewErrorResponse([]string{err.Error()})
+		return m.App.Panel.Web.SetJSONResponse(ctx, int(getRequestErrorCode(err)), response)
 	}
 
 	// Check delete permission
 	allowed, err := m.App.Panel.PermissionChecker.HasInstanceDeletePermission(m.App.Name, m.Name, instanceID, ctx)
 	if err != nil {
 		response := NewErrorResponse([]string{err.Error()})
-		return m.App.Panel.Web.SetJSONResponse(ctx, 400, response)
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusInternalServerError, response)
 	}
 
 	if !allowed {
 		response := NewErrorResponse([]string{"Permission denied"})
-		return m.App.Panel.Web.SetJSONResponse(ctx, 403, response)
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusForbidden, response)
 	}
 
-	// Delete the instance
-	err = m.GetORM().DeleteByID(m.PTR, instanceID)
+	// Delete the instance, or move it to the trash
+	orm := m.getRequestORM(ctx)
+	err = m.deleteInstance(orm, instanceID, orm.DeleteByID)
 	if err != nil {
 		response := NewErrorResponse([]string{err.Error()})
-		return m.App.Panel.Web.SetJSONResponse(ctx, 400, response)
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusBadRequest, response)
 	}
 
-	// Create delete log
-	instanceIDStr := fmt.Sprintf("%v", instanceID)
-	m.App.Panel.Config.CreateLog(ctx, logging.LogStoreLevelInstanceDelete, instanceIDStr, nil, m.Name, "")
+	instance := &Instance{InstanceID: instanceID, Model: m}
+	if err = instance.CreateDeleteLog(ctx); err != nil {
+		response := NewErrorResponse([]string{err.Error()})
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusInternalServerError, response)
+	}
 
-	response := NewSuccessResponse(nil, "Item deleted successfully")
-	return m.App.Panel.Web.SetJSONResponse(ctx, 200, response)
+	response := NewSuccessResponse(map[string]interface{}{
+		"redirect": m.GetFullLink(),
+	}, "Item deleted successfully")
+	return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusOK, response)
 }
 
-// HandleBulkDeleteAJAX handles AJAX bulk delete requests
+// HandleBulkDeleteAJAX handles AJAX bulk delete requests. The JSON body holds the IDs to delete under "ids". In
+// WriteModeBestEffort, items that cannot be deleted are reported without stopping the others; in WriteModeAtomic, the
+// first failure cancels the whole delete.
 func (m *Model) HandleBulkDeleteAJAX(ctx interface{}) error {
 	jsonBody, err := m.App.Panel.Web.GetJSONBody(ctx)
 	if err != nil {
 		response := NewErrorResponse([]string{"Invalid JSON data"})
-		return m.App.Panel.Web.SetJSONResponse(ctx, 400, response)
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusBadRequest, response)
 	}
 
 	idsInterface, ok := jsonBody["ids"]
 	if !ok {
 		response := NewErrorResponse([]string{"No items selected"})
-		return m.App.Panel.Web.SetJSONResponse(ctx, 400, response)
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusBadRequest, response)
 	}
 
 	ids, ok := idsInterface.([]interface{})
 	if !ok {
 		response := NewErrorResponse([]string{"Invalid IDs format"})
-		return m.App.Panel.Web.SetJSONResponse(ctx, 400, response)
+		return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusBadRequest, response)
 	}
 
 	deletedCount := 0
 	errors := []string{}
-
-	for _, idInterface := range ids {
-		id := fmt.Sprintf("%v", idInterface)
-
-		// Check delete permission for each item
-		allowed, err := m.App.Panel.PermissionChecker.HasInstanceDeletePermission(m.App.Name, m.Name, id, ctx)
-		if err != nil || !allowed {
-			errors = append(errors, fmt.Sprintf("Permission denied for item %s", id))
-			continue
+	atomic := m.BulkDeleteMode == WriteModeAtomic
+
+	err = m.runLoggedWrites(ctx, m.BulkDeleteMode, func(orm ORMIntegrator, logs *writeLogs) error {
+		deletedCount = 0
+		errors = errors[:0]
+		for _, idInterface := range ids {
+			idStr := fmt.Sprintf("%v", idInterface)
+
+			id, err := m.parseInstanceID(idStr)
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("Invalid item %s: %s", idStr, err.Error()))
+				if atomic {
+					return err
+				}
+				continue
+			}
+
+			// Check delete permission for each item
+			allowed, err := m.App.Panel.PermissionChecker.HasInstanceDeletePermission(m.App.Name, m.Name, id, ctx)
+			if err != nil || !allowed {
+				errors = append(errors, fmt.Sprintf("Permission denied for item %s", idStr))
+				if atomic {
+					return ErrPermissionDenied
+				}
+				continue
+			}
+
+			// Delete the instance, or move it to the trash
+			err = m.deleteInstance(orm, id, orm.DeleteByID)
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("Failed to delete item %s: %s", idStr, err.Error()))
+				if atomic {
+					return err
+				}
+				continue
+			}
+
+			deletedCount++
+
+			instance := &Instance{InstanceID: id, Model: m}
+			logs.add(func() error {
+				if err := instance.CreateDeleteLog(ctx); err != nil {
+					errors = append(errors, fmt.Sprintf("Failed to log deletion of item %s: %s", idStr, err.Error()))
+				}
+				return nil
+			})
 		}
-
-		// Delete the instance
-		err = m.GetORM().DeleteByID(m.PTR, id)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Failed to delete item %s: %s", id, err.Error()))
-			continue
+		return nil
+	})
+	if err != nil {
+		if m.isTransactional(ctx, m.BulkDeleteMode) {
+			deletedCount = 0
+		}
+		if len(errors) == 0 {
+			errors = append(errors, err.Error())
 		}
-
-		deletedCount++
-
-		// Create delete log
-		m.App.Panel.Config.CreateLog(ctx, logging.LogStoreLevelInstanceDelete, fmt.Sprintf("%v", id), nil, m.Name, "")
 	}
 
 	if len(errors) > 0 {
 		response := JSONResponse{
 			Success: deletedCount > 0,
-			Message: fmt.Sprintf("%d items deleted, %d failed", deletedCount, len(errors)),
+			Message: fmt.Sprintf("%d items deleted, %d failed", deletedCount, len(ids)-deletedCount),
 			Data: map[string]interface{}{
 				"deleted": deletedCount,
-				"failed":  len(errors),
+				"failed":  len(ids) - deletedCount,
 			},
 			Errors: errors,
 		}
-		statusCode := 200
+		statusCode := http.StatusOK
 		if deletedCount == 0 {
-			statusCode = 400
+			statusCode = http.StatusBadRequest
 		}
 		return m.App.Panel.Web.SetJSONResponse(ctx, statusCode, response)
 	}
@@ -386,5 +583,5 @@ func (m *Model) HandleBulkDeleteAJAX(ctx interface{}) error {
 		"deleted": deletedCount,
 	}, fmt.Sprintf("%d items deleted successfully", deletedCount))
 
-	return m.App.Panel.Web.SetJSONResponse(ctx, 200, response)
+	return m.App.Panel.Web.SetJSONResponse(ctx, http.StatusOK, response)
 }
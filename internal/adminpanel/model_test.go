@@ -1,7 +1,9 @@
 package adminpanel
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -62,3 +64,239 @@ func TestModel_GetViewHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestModel_GetViewHandler_PaginatedORM(t *testing.T) {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	orm := &MockPaginatedORMIntegrator{}
+	for i := 1; i <= 25; i++ {
+		orm.Instances = append(orm.Instances, &TestModel{ID: uint(i), Name: "Instance"})
+	}
+
+	model, err := testApp.RegisterModel(&TestModel{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	code, body := model.GetViewHandler()(map[string]string{"page": "3", "perPage": "10", "search": "Inst"})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+
+	if len(orm.Queries) != 1 {
+		t.Fatalf("expected one paginated query, got %d", len(orm.Queries))
+	}
+	query := orm.Queries[0]
+	if query.Offset != 20 || query.Limit != 10 {
+		t.Errorf("expected offset 20 and limit 10, got offset %d and limit %d", query.Offset, query.Limit)
+	}
+	if query.Search != "Inst" || len(query.SearchFields) == 0 {
+		t.Errorf("expected search 'Inst' with search fields, got %q with %v", query.Search, query.SearchFields)
+	}
+}
+
+func TestFetchListPage_InMemoryFallback(t *testing.T) {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	model, err := testApp.RegisterModel(&TestModel{}, &MockORMIntegrator{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	instances, total, exact, err := fetchListPage(model, nil, ListQuery{Offset: 100, Limit: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if total != 0 || len(instances) != 0 || !exact {
+		t.Errorf("expected an empty page with an exact count, got %d instances of %d (exact %v)", len(instances), total, exact)
+	}
+}
+
+func TestFetchListPage_ListScope(t *testing.T) {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, _ := panel.RegisterApp("TestApp", "Test App", nil)
+	orm := &MockPaginatedORMIntegrator{}
+	for i := 1; i <= 12; i++ {
+		name := "Mine"
+		if i == 2 {
+			name = "Theirs"
+		}
+		orm.Instances = append(orm.Instances, &TestModel{ID: uint(i), Name: name})
+	}
+	model, err := testApp.RegisterModel(&TestModel{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	panel.PermissionChecker = func(request PermissionRequest, _ interface{}) (bool, error) {
+		return request.InstanceID != uint(2), nil
+	}
+
+	instances, total, exact, err := fetchListPage(model, nil, ListQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(instances) != 9 || total != 12 || exact {
+		t.Errorf("expected the unreadable instance to be dropped from an upper bound count, got %d instances of %d (exact %v)", len(instances), total, exact)
+	}
+	code, body := model.GetViewHandler()(map[string]string{})
+	if code != http.StatusOK || !strings.Contains(body, "(up to 12 items)") {
+		t.Errorf("expected the list view to show the count as an upper bound, got %v", code)
+	}
+
+	scope := FilterCondition{Field: "Name", Operator: FilterExact, Value: "Mine"}
+	panel.Config.ListScope = func(appName, modelName string, _ interface{}) ([]FilterCondition, bool, error) {
+		return []FilterCondition{scope}, true, nil
+	}
+	panel.PermissionChecker = func(request PermissionRequest, _ interface{}) (bool, error) {
+		if request.InstanceID != nil {
+			return false, errors.New("expected the list scope to replace the instance permissions")
+		}
+		return true, nil
+	}
+	instances, total, exact, err = fetchListPage(model, nil, ListQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(instances) != 10 || total != 12 || !exact {
+		t.Errorf("expected the count of the integrator, which applies the scope, to be exact, got %d instances of %d (exact %v)", len(instances), total, exact)
+	}
+	if filters := orm.Queries[len(orm.Queries)-1].Filters; len(filters) != 1 || filters[0] != scope {
+		t.Errorf("expected the scope to be added to the query, got %v", filters)
+	}
+}
+
+func registerPaginatedTestModel(t *testing.T, count int) (*Model, *MockPaginatedORMIntegrator, *MockWebIntegrator) {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	orm := &MockPaginatedORMIntegrator{}
+	for i := 1; i <= count; i++ {
+		orm.Instances = append(orm.Instances, &TestModel{ID: uint(i), Name: "Instance"})
+	}
+
+	model, err := testApp.RegisterModel(&TestModel{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model, orm, panel.Web.(*MockWebIntegrator)
+}
+
+func TestRegisterModel_JSONRoutes(t *testing.T) {
+	_, _, web := registerPaginatedTestModel(t, 0)
+
+	expected := []string{
+		"GET /admin/a/TestApp/TestModel/search",
+		"POST /admin/a/TestApp/TestModel/bulk-delete",
+		"DELETE /admin/a/TestApp/TestModel/:id/delete",
+		"POST /admin/a/TestApp/TestModel/action",
+		"POST /admin/a/TestApp/TestModel/action/confirm",
+	}
+	for _, route := range expected {
+		found := false
+		for _, registered := range web.Routes {
+			if registered == route {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected route %q to be registered, got %v", route, web.Routes)
+		}
+	}
+}
+
+func TestModel_HandleSearchAJAX(t *testing.T) {
+	model, orm, web := registerPaginatedTestModel(t, 25)
+
+	err := model.HandleSearchAJAX(map[string]string{"q": "Inst", "page": "2", "perPage": "10"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if web.JSONStatus != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %+v", http.StatusOK, web.JSONStatus, web.JSONResponse)
+	}
+
+	query := orm.Queries[0]
+	if query.Search != "Inst" || len(query.SearchFields) == 0 {
+		t.Errorf("expected search 'Inst' with search fields, got %q with %v", query.Search, query.SearchFields)
+	}
+	if query.Offset != 10 || query.Limit != 10 {
+		t.Errorf("expected offset 10 and limit 10, got offset %d and limit %d", query.Offset, query.Limit)
+	}
+
+	data := web.JSONResponse.(JSONResponse).Data.(map[string]interface{})
+	rows := data["instances"].([]map[string]interface{})
+	if len(rows) != 10 || data["total"] != uint(25) || data["totalPages"] != uint(3) {
+		t.Fatalf("expected 10 of 25 instances on 3 pages, got %d of %v on %v", len(rows), data["total"], data["totalPages"])
+	}
+	if rows[0]["id"] != uint(11) || rows[0]["deleteLink"] != "/admin/a/TestApp/TestModel/11/delete" {
+		t.Errorf("unexpected first row %v", rows[0])
+	}
+	if values := rows[0]["values"].(map[string]string); values["Name"] != "Instance" {
+		t.Errorf("expected the Name column value, got %v", values)
+	}
+}
+
+func TestModel_HandleDeleteAJAX(t *testing.T) {
+	model, orm, web := registerPaginatedTestModel(t, 0)
+
+	if err := model.HandleDeleteAJAX(map[string]string{"id": "3"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if web.JSONStatus != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %+v", http.StatusOK, web.JSONStatus, web.JSONResponse)
+	}
+	if len(orm.Deleted) != 1 || orm.Deleted[0] != uint(3) {
+		t.Errorf("expected instance 3 to be deleted by its typed primary key, got %v", orm.Deleted)
+	}
+
+	_ = model.HandleDeleteAJAX(map[string]string{"id": "abc"})
+	if web.JSONStatus != http.StatusBadRequest {
+		t.Errorf("expected %v for an invalid id, got %v", http.StatusBadRequest, web.JSONStatus)
+	}
+}
+
+func TestModel_HandleBulkDeleteAJAX(t *testing.T) {
+	model, orm, web := registerPaginatedTestModel(t, 0)
+
+	err := model.HandleBulkDeleteAJAX(map[string]interface{}{"ids": []interface{}{float64(1), "2", "abc"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if web.JSONStatus != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %+v", http.StatusOK, web.JSONStatus, web.JSONResponse)
+	}
+	if len(orm.Deleted) != 2 || orm.Deleted[0] != uint(1) || orm.Deleted[1] != uint(2) {
+		t.Errorf("expected instances 1 and 2 to be deleted, got %v", orm.Deleted)
+	}
+
+	response := web.JSONResponse.(JSONResponse)
+	if !response.Success || len(response.Errors) != 1 {
+		t.Errorf("expected a partial success with one error, got %+v", response)
+	}
+}
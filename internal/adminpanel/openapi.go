@@ -0,0 +1,515 @@
+package adminpanel
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"github.com/ovnicraft/go-advanced-admin/internal/form/fields"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// openAPIVersion is the version of the OpenAPI specification followed by the document of the JSON API.
+const openAPIVersion = "3.1.0"
+
+// HandleOpenAPI serves the OpenAPI document of the JSON API to the users who may read the admin panel.
+func (ap *AdminPanel) HandleOpenAPI(ctx interface{}) error {
+	allowed, err := ap.PermissionChecker.HasReadPermission(ctx)
+	if err != nil {
+		return ap.Web.SetJSONResponse(ctx, http.StatusInternalServerError, NewErrorResponse([]string{err.Error()}))
+	}
+	if !allowed {
+		return ap.Web.SetJSONResponse(ctx, http.StatusForbidden, NewErrorResponse([]string{"Permission denied"}))
+	}
+	document, err := ap.GetOpenAPIDocument(ctx)
+	if err != nil {
+		return ap.Web.SetJSONResponse(ctx, http.StatusInternalServerError, NewErrorResponse([]string{err.Error()}))
+	}
+	return ap.Web.SetJSONResponse(ctx, http.StatusOK, document)
+}
+
+// GetOpenAPIDocument returns an OpenAPI 3.1 document describing the JSON API of the models the user may read. Each
+// model has a schema for its instances and for the objects creating and updating them, built from its fields, and
+// the operations the user is not allowed to perform are left out.
+func (ap *AdminPanel) GetOpenAPIDocument(ctx interface{}) (map[string]interface{}, error) {
+	apps, err := GetAppsWithReadPermissions(ap, ctx)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]interface{})
+	schemas := map[string]interface{}{"Error": openAPIErrorSchema()}
+	tags := make([]map[string]interface{}, 0)
+	for _, app := range apps {
+		for _, modelMap := range app["models"].([]map[string]interface{}) {
+			m := modelMap["model"].(*Model)
+			permissions := modelMap["permissions"].(Permissions)
+			name := m.getOpenAPIName()
+			tags = append(tags, map[string]interface{}{
+				"name":        name,
+				"description": fmt.Sprintf("%s | %s", m.App.DisplayName, m.DisplayName),
+			})
+			for schemaName, schema := range m.getOpenAPISchemas() {
+				schemas[schemaName] = schema
+			}
+			collection, instance := m.getOpenAPIPaths(permissions)
+			if ap.Config.CSRFTokenStore != nil {
+				requireOpenAPICSRFToken(collection, instance)
+			}
+			paths[m.GetLink()] = collection
+			paths[m.GetLink()+"/{id}"] = instance
+		}
+	}
+
+	components := map[string]interface{}{
+		"schemas": schemas,
+		"responses": map[string]interface{}{
+			"Error": map[string]interface{}{
+				"description": "The request failed.",
+				"content":     openAPIJSONContent(openAPIRef("Error")),
+			},
+		},
+	}
+	if ap.Config.CSRFTokenStore != nil {
+		components["securitySchemes"] = map[string]interface{}{
+			"csrfToken": map[string]interface{}{
+				"type":        "apiKey",
+				"in":          "header",
+				"name":        CSRFHeader,
+				"description": "The CSRF token of the session, required by the operations changing data.",
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title": ap.Config.Name,
+			// The document describes the API of this version of the panel, which has no version of its own.
+			"version": "1.0.0",
+		},
+		"servers":    []map[string]interface{}{{"url": ap.Config.GetLink(ap.Config.GetAPIPrefix())}},
+		"tags":       tags,
+		"paths":      paths,
+		"components": components,
+	}, nil
+}
+
+// requireOpenAPICSRFToken marks the operations changing data in the given path items as requiring the CSRF token.
+func requireOpenAPICSRFToken(pathItems ...map[string]interface{}) {
+	for _, pathItem := range pathItems {
+		for _, method := range []string{"post", "patch", "delete"} {
+			if operation, ok := pathItem[method].(map[string]interface{}); ok {
+				operation["security"] = []map[string][]string{{"csrfToken": {}}}
+			}
+		}
+	}
+}
+
+// getOpenAPIName returns the name of the model in the OpenAPI document, used for its tag and schemas.
+func (m *Model) getOpenAPIName() string {
+	return m.App.Name + "." + m.Name
+}
+
+// getOpenAPIKeySchema returns the schema of the primary keys of the model, or an empty schema if their type is
+// unknown.
+func (m *Model) getOpenAPIKeySchema() map[string]interface{} {
+	keyType, err := m.GetPrimaryKeyType()
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	return goTypeSchema(keyType)
+}
+
+// getOpenAPISchemas returns the schemas of the model: its instances, their fields, and the objects accepted to create
+// and update them.
+func (m *Model) getOpenAPISchemas() map[string]interface{} {
+	name := m.getOpenAPIName()
+
+	fieldProperties := make(map[string]interface{})
+	createProperties := make(map[string]interface{})
+	updateProperties := make(map[string]interface{})
+	required := make([]string, 0)
+	for _, fieldConfig := range m.Fields {
+		if fieldConfig.IncludeInListDisplay || fieldConfig.IncludeInInstanceView {
+			schema := goTypeSchema(fieldConfig.FieldType)
+			if fieldConfig.IsPointer {
+				schema = nullableSchema(schema)
+			}
+			schema["title"] = fieldConfig.DisplayName
+			fieldProperties[fieldConfig.Name] = schema
+		}
+		if fieldConfig.AddFormField != nil {
+			schema, isRequired := formFieldSchema(fieldConfig.AddFormField, fieldConfig.FieldType)
+			schema["title"] = fieldConfig.DisplayName
+			createProperties[fieldConfig.Name] = schema
+			if isRequired {
+				required = append(required, fieldConfig.Name)
+			}
+		}
+		if fieldConfig.EditFormField != nil {
+			schema, _ := formFieldSchema(fieldConfig.EditFormField, fieldConfig.FieldType)
+			schema["title"] = fieldConfig.DisplayName
+			updateProperties[fieldConfig.Name] = schema
+		}
+	}
+	for _, relation := range m.Relations {
+		schema := map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"title":       relation.DisplayName,
+			"description": fmt.Sprintf("The keys of the related %s instances.", relation.Related),
+		}
+		fieldProperties[relation.Name] = schema
+		createProperties[relation.Name] = schema
+		updateProperties[relation.Name] = schema
+		if relation.Required {
+			required = append(required, relation.Name)
+		}
+	}
+	if m.VersionField != "" {
+		if field, ok := reflect.TypeOf(m.PTR).Elem().FieldByName(m.VersionField); ok {
+			schema := goTypeSchema(field.Type)
+			schema["description"] = "The version of the instance last read. The update fails with 409 Conflict if " +
+				"the instance was saved since."
+			updateProperties[m.VersionField] = schema
+		}
+	}
+
+	return map[string]interface{}{
+		name: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id":     m.getOpenAPIKeySchema(),
+				"key":    map[string]interface{}{"type": "string", "description": "The key of the instance in URLs."},
+				"repr":   map[string]interface{}{"type": "string"},
+				"fields": openAPIRef(name + ".Fields"),
+			},
+			"required": []string{"id", "key", "repr", "fields"},
+		},
+		name + ".Fields": map[string]interface{}{
+			"type":       "object",
+			"properties": fieldProperties,
+		},
+		name + ".Create": map[string]interface{}{
+			"type":                 "object",
+			"properties":           createProperties,
+			"required":             required,
+			"additionalProperties": false,
+		},
+		name + ".Update": map[string]interface{}{
+			"type":                 "object",
+			"properties":           updateProperties,
+			"additionalProperties": false,
+		},
+	}
+}
+
+// getOpenAPIPaths returns the path items of the collection and the instances of the model, holding the operations
+// allowed by permissions.
+func (m *Model) getOpenAPIPaths(permissions Permissions) (map[string]interface{}, map[string]interface{}) {
+	name := m.getOpenAPIName()
+	operationName := m.App.Name + m.Name
+	instanceResponse := openAPIJSONContent(openAPISuccessSchema(openAPIRef(name)))
+
+	listParameters := []map[string]interface{}{
+		openAPIQueryParameter("page", "The page to return, starting at 1.", map[string]interface{}{"type": "integer", "minimum": 1}),
+		openAPIQueryParameter("perPage", "The number of instances per page.", map[string]interface{}{"type": "integer", "minimum": 1}),
+		openAPIQueryParameter("search", "Text searched in the searchable fields.", map[string]interface{}{"type": "string"}),
+		openAPIQueryParameter("order", "Comma-separated fields to order by, each prefixed with - for a descending order.", map[string]interface{}{"type": "string"}),
+	}
+	for _, fieldConfig := range m.Fields {
+		if !fieldConfig.Filterable {
+			continue
+		}
+		for _, param := range getFilterParamNames(fieldConfig) {
+			description := fmt.Sprintf("Filters the instances by %s.", fieldConfig.DisplayName)
+			listParameters = append(listParameters, openAPIQueryParameter(param, description, map[string]interface{}{"type": "string"}))
+		}
+	}
+
+	collection := map[string]interface{}{
+		"get": map[string]interface{}{
+			"tags":        []string{name},
+			"operationId": "list" + operationName,
+			"summary":     fmt.Sprintf("List %s", m.DisplayName),
+			"parameters":  listParameters,
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "A page of instances.",
+					"content": openAPIJSONContent(openAPISuccessSchema(map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"instances":  map[string]interface{}{"type": "array", "items": openAPIRef(name)},
+							"total":      map[string]interface{}{"type": "integer"},
+							"exactTotal": map[string]interface{}{"type": "boolean", "description": "Whether total is exact rather than an upper bound."},
+							"page":       map[string]interface{}{"type": "integer"},
+							"perPage":    map[string]interface{}{"type": "integer"},
+							"totalPages": map[string]interface{}{"type": "integer"},
+						},
+					})),
+				},
+				"403":     openAPIErrorResponse(),
+				"default": openAPIErrorResponse(),
+			},
+		},
+	}
+	if permissions.Create {
+		collection["post"] = map[string]interface{}{
+			"tags":        []string{name},
+			"operationId": "create" + operationName,
+			"summary":     fmt.Sprintf("Create a %s", m.DisplayName),
+			"requestBody": map[string]interface{}{
+				"required": true,
+				"content":  openAPIJSONContent(openAPIRef(name + ".Create")),
+			},
+			"responses": map[string]interface{}{
+				"201":     map[string]interface{}{"description": "The created instance.", "content": instanceResponse},
+				"400":     openAPIErrorResponse(),
+				"403":     openAPIErrorResponse(),
+				"default": openAPIErrorResponse(),
+			},
+		}
+	}
+
+	instance := map[string]interface{}{
+		"parameters": []map[string]interface{}{{
+			"name":        "id",
+			"in":          "path",
+			"required":    true,
+			"description": "The key of the instance.",
+			"schema":      map[string]interface{}{"type": "string"},
+		}},
+		"get": map[string]interface{}{
+			"tags":        []string{name},
+			"operationId": "retrieve" + operationName,
+			"summary":     fmt.Sprintf("Retrieve a %s", m.DisplayName),
+			"responses": map[string]interface{}{
+				"200":     map[string]interface{}{"description": "The instance.", "content": instanceResponse},
+				"400":     openAPIErrorResponse(),
+				"403":     openAPIErrorResponse(),
+				"404":     openAPIErrorResponse(),
+				"default": openAPIErrorResponse(),
+			},
+		},
+	}
+	if permissions.Update {
+		responses := map[string]interface{}{
+			"200":     map[string]interface{}{"description": "The updated instance.", "content": instanceResponse},
+			"400":     openAPIErrorResponse(),
+			"403":     openAPIErrorResponse(),
+			"404":     openAPIErrorResponse(),
+			"default": openAPIErrorResponse(),
+		}
+		if m.VersionField != "" {
+			responses["409"] = openAPIErrorResponse()
+		}
+		instance["patch"] = map[string]interface{}{
+			"tags":        []string{name},
+			"operationId": "update" + operationName,
+			"summary":     fmt.Sprintf("Update a %s", m.DisplayName),
+			"description": "Updates the fields given in the request body; the other fields are left untouched.",
+			"requestBody": map[string]interface{}{
+				"required": true,
+				"content":  openAPIJSONContent(openAPIRef(name + ".Update")),
+			},
+			"responses": responses,
+		}
+	}
+	if permissions.Delete {
+		instance["delete"] = map[string]interface{}{
+			"tags":        []string{name},
+			"operationId": "delete" + operationName,
+			"summary":     fmt.Sprintf("Delete a %s", m.DisplayName),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The instance was deleted.",
+					"content": openAPIJSONContent(openAPISuccessSchema(map[string]interface{}{
+						"type":       "object",
+						"properties": map[string]interface{}{"id": m.getOpenAPIKeySchema()},
+					})),
+				},
+				"400":     openAPIErrorResponse(),
+				"403":     openAPIErrorResponse(),
+				"default": openAPIErrorResponse(),
+			},
+		}
+	}
+	return collection, instance
+}
+
+// openAPIRef returns a reference to a schema of the document.
+func openAPIRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// openAPIJSONContent returns the content of a request body or response holding JSON described by schema.
+func openAPIJSONContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// openAPIErrorResponse returns a reference to the response of the failed requests.
+func openAPIErrorResponse() map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/responses/Error"}
+}
+
+// openAPIQueryParameter returns an optional query parameter.
+func openAPIQueryParameter(name, description string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"name": name, "in": "query", "description": description, "schema": schema}
+}
+
+// openAPISuccessSchema returns the schema of a successful JSONResponse carrying data.
+func openAPISuccessSchema(data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"success": map[string]interface{}{"const": true},
+			"message": map[string]interface{}{"type": "string"},
+			"data":    data,
+		},
+		"required": []string{"success", "data"},
+	}
+}
+
+// openAPIErrorSchema returns the schema of a failed JSONResponse.
+func openAPIErrorSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"success": map[string]interface{}{"const": false},
+			"errors":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required": []string{"success"},
+	}
+}
+
+// goTypeSchema returns the JSON schema of the values of a Go type once encoded as JSON.
+func goTypeSchema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case reflect.TypeOf(uuid.UUID{}):
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullableSchema(goTypeSchema(t.Elem()))
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": goTypeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": goTypeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = goTypeSchema(field.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+	return map[string]interface{}{}
+}
+
+// nullableSchema returns schema allowing null as well.
+func nullableSchema(schema map[string]interface{}) map[string]interface{} {
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []string{typ, "null"}
+		return schema
+	}
+	return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+}
+
+// formFieldSchema returns the JSON schema of the values accepted by a form field of the JSON API, with the
+// constraints it validates, and whether it requires a value. Optional fields accept null to clear their value.
+// fieldType is the type of the model field, used for form fields the admin panel does not know.
+func formFieldSchema(field form.Field, fieldType reflect.Type) (map[string]interface{}, bool) {
+	var schema map[string]interface{}
+	var required bool
+	switch field := field.(type) {
+	case *fields.TextField:
+		schema, required = map[string]interface{}{"type": "string"}, field.Required
+		if field.MinLength != nil {
+			schema["minLength"] = *field.MinLength
+		}
+		if field.MaxLength != nil {
+			schema["maxLength"] = *field.MaxLength
+		}
+		if field.Regex != nil {
+			schema["pattern"] = *field.Regex
+		}
+	case *fields.IntegerField:
+		schema, required = map[string]interface{}{"type": "integer"}, field.Required
+		if field.MinValue != nil {
+			schema["minimum"] = *field.MinValue
+		}
+		if field.MaxValue != nil {
+			schema["maximum"] = *field.MaxValue
+		}
+	case *fields.FloatField:
+		schema, required = map[string]interface{}{"type": "number"}, field.Required
+		if field.MinValue != nil {
+			schema["minimum"] = *field.MinValue
+		}
+		if field.MaxValue != nil {
+			schema["maximum"] = *field.MaxValue
+		}
+	case *fields.BooleanField:
+		schema, required = map[string]interface{}{"type": "boolean"}, field.Required
+	case *fields.DateField:
+		schema, required = map[string]interface{}{"type": "string", "format": "date"}, field.Required
+	case *fields.EmailField:
+		schema, required = map[string]interface{}{"type": "string", "format": "email"}, field.Required
+	case *fields.URLField:
+		schema, required = map[string]interface{}{"type": "string", "format": "uri"}, field.Required
+	case *fields.UUIDField:
+		schema, required = map[string]interface{}{"type": "string", "format": "uuid"}, field.Required
+	case *fields.ChoiceField:
+		schema, required = map[string]interface{}{"type": "string", "enum": choiceValues(field.Choices)}, field.Required
+		if !required {
+			schema["enum"] = append(schema["enum"].([]interface{}), nil)
+		}
+	case *fields.MultipleChoiceField:
+		items := map[string]interface{}{"type": "string", "enum": choiceValues(field.Choices)}
+		return map[string]interface{}{"type": "array", "items": items}, field.Required
+	case *ForeignKeyField:
+		schema, required = map[string]interface{}{"type": "string"}, field.Required
+		if kind := field.ValueType.Kind(); kind != reflect.String && kind != reflect.Struct {
+			schema = goTypeSchema(field.ValueType)
+		}
+		schema["description"] = fmt.Sprintf("The key of the related %s instance.", field.Related)
+	default:
+		return goTypeSchema(fieldType), false
+	}
+	if !required {
+		schema = nullableSchema(schema)
+	}
+	return schema, required
+}
+
+// choiceValues returns the values of choices, as enum values of a JSON schema.
+func choiceValues(choices []fields.Choice) []interface{} {
+	values := make([]interface{}, len(choices))
+	for i, choice := range choices {
+		values[i] = choice.Value
+	}
+	return values
+}
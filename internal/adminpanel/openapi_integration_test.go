@@ -0,0 +1,113 @@
+package adminpanel_test
+
+import (
+	"encoding/json"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// getSchema returns the schema at the given path of keys in an OpenAPI document.
+func getSchema(t *testing.T, document map[string]interface{}, keys ...string) map[string]interface{} {
+	value := document
+	for _, key := range keys {
+		next, ok := value[key].(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected %q to be an object in %v", key, keys)
+		}
+		value = next
+	}
+	return value
+}
+
+func TestAdminPanel_GetOpenAPIDocument(t *testing.T) {
+	model, _ := registerAPIModel(t)
+	panel := model.App.Panel
+	if _, err := model.App.RegisterModel(&adminpanel.Coupon{}, newMemoryORM(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	document, err := panel.GetOpenAPIDocument(&adminpanel.MockRequest{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = json.Marshal(document); err != nil {
+		t.Fatalf("expected the document to encode as JSON, got %v", err)
+	}
+	if document["openapi"] != "3.1.0" {
+		t.Errorf("unexpected OpenAPI version %v", document["openapi"])
+	}
+	if servers := document["servers"].([]map[string]interface{}); servers[0]["url"] != "/admin/api" {
+		t.Errorf("expected the API to be served under /admin/api, got %v", servers)
+	}
+	for _, operation := range []string{"get", "post"} {
+		if _, ok := getSchema(t, document, "paths", "/a/Shop/Coupon")[operation]; !ok {
+			t.Errorf("expected the %s operation on the collection", operation)
+		}
+	}
+	for _, operation := range []string{"get", "patch", "delete"} {
+		if _, ok := getSchema(t, document, "paths", "/a/Shop/Coupon/{id}")[operation]; !ok {
+			t.Errorf("expected the %s operation on instances", operation)
+		}
+	}
+
+	create := getSchema(t, document, "components", "schemas", "Shop.Coupon.Create")
+	if !reflect.DeepEqual(create["required"], []string{"Code"}) {
+		t.Errorf("expected the code only to be required, got %v", create["required"])
+	}
+	code := getSchema(t, create, "properties", "Code")
+	if code["type"] != "string" || code["maxLength"] != uint(8) || code["pattern"] != "^[A-Z]+$" {
+		t.Errorf("unexpected code schema %v", code)
+	}
+	percent := getSchema(t, create, "properties", "Percent")
+	if !reflect.DeepEqual(percent["type"], []string{"integer", "null"}) || percent["minimum"] != 1 || percent["maximum"] != 90 {
+		t.Errorf("unexpected percent schema %v", percent)
+	}
+	expires := getSchema(t, document, "components", "schemas", "Shop.Coupon.Fields", "properties", "Expires")
+	if !reflect.DeepEqual(expires["type"], []string{"string", "null"}) || expires["format"] != "date-time" {
+		t.Errorf("unexpected expiry schema %v", expires)
+	}
+	if id := getSchema(t, document, "components", "schemas", "Shop.Coupon", "properties", "id"); id["type"] != "integer" {
+		t.Errorf("expected integer primary keys, got %v", id)
+	}
+
+	panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return request.Action == nil || *request.Action == adminpanel.ReadAction, nil
+	}
+	document, err = panel.GetOpenAPIDocument(&adminpanel.MockRequest{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := getSchema(t, document, "paths", "/a/Shop/Coupon")["post"]; ok {
+		t.Error("expected the operations the user may not perform to be left out")
+	}
+}
+
+func TestAdminPanel_HandleOpenAPI(t *testing.T) {
+	model, _ := registerAPIModel(t)
+	panel := model.App.Panel
+	web := panel.Web.(*adminpanel.MockWebIntegrator)
+	if defaultModel, _ := registerProductModel(t); slices.Contains(defaultModel.App.Panel.Web.(*adminpanel.MockWebIntegrator).Routes, "GET /admin/openapi.json") {
+		t.Error("expected the document not to be served by default")
+	}
+	if !slices.Contains(web.Routes, "GET /admin/openapi.json") {
+		t.Errorf("expected the document route to be registered, got %v", web.Routes)
+	}
+
+	if err := panel.HandleOpenAPI(&adminpanel.MockRequest{Method: http.MethodGet}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if document, ok := web.JSONResponse.(map[string]interface{}); web.JSONStatus != http.StatusOK || !ok || document["paths"] == nil {
+		t.Errorf("expected the document to be served, got %v", web.JSONStatus)
+	}
+
+	panel.PermissionChecker = func(adminpanel.PermissionRequest, interface{}) (bool, error) { return false, nil }
+	if err := panel.HandleOpenAPI(&adminpanel.MockRequest{Method: http.MethodGet}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if web.JSONStatus != http.StatusForbidden {
+		t.Errorf("expected %v without the read permission, got %v", http.StatusForbidden, web.JSONStatus)
+	}
+}
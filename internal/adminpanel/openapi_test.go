@@ -0,0 +1,12 @@
+package adminpanel
+
+import (
+	"time"
+)
+
+type Coupon struct {
+	ID      uint
+	Code    string `admin:"required;maxLength:8;regex:^[A-Z]+$"`
+	Percent int    `admin:"min:1;max:90"`
+	Expires *time.Time
+}
@@ -1,6 +1,9 @@
 package adminpanel
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+)
 
 // ORMIntegrator defines the interface for integrating ORMs with the admin panel.
 type ORMIntegrator interface {
@@ -22,10 +25,12 @@ type ORMIntegrator interface {
 	// DeleteInstance deletes an instance of the model by its primary key.
 	DeleteInstance(model interface{}, id interface{}) error
 
-	// FetchInstanceOnlyFields retrieves a single instance with only the specified fields.
+	// FetchInstanceOnlyFields retrieves a single instance with only the specified fields. Like FetchInstance, it
+	// returns a nil instance or an error wrapping ErrInstanceNotFound when no instance has the primary key.
 	FetchInstanceOnlyFields(model interface{}, id interface{}, fields []string) (interface{}, error)
 
-	// FetchInstance retrieves a single instance of the model by its primary key.
+	// FetchInstance retrieves a single instance of the model by its primary key. When no instance has the primary
+	// key, it returns a nil instance or an error wrapping ErrInstanceNotFound.
 	FetchInstance(model interface{}, id interface{}) (interface{}, error)
 
 	// CreateInstance creates a new instance of the model.
@@ -46,3 +51,148 @@ type ORMIntegrator interface {
 	// DeleteByID deletes an instance of the model by its primary key (used for AJAX operations).
 	DeleteByID(model interface{}, id interface{}) error
 }
+
+// VersionedORMIntegrator is an optional extension of ORMIntegrator for integrators that can update an instance on the
+// condition that its version field is unchanged, in a single atomic operation. Models with a field tagged
+// `admin:"version"` use it to detect concurrent edits; with other integrators, the panel reads the version before
+// updating, which leaves a small window for conflicts to go unnoticed outside transactions.
+type VersionedORMIntegrator interface {
+	// UpdateInstanceOnlyFieldsIfVersion updates the specified fields of the instance with the given primary key if its
+	// versionField still equals version, and reports whether it did.
+	UpdateInstanceOnlyFieldsIfVersion(instance interface{}, fields []string, primaryKey interface{}, versionField string, version interface{}) (bool, error)
+}
+
+// ContextORMIntegrator is an optional extension of ORMIntegrator for integrators that can run their operations with
+// a context.Context. The admin panel binds the integrator to the context of each request it handles, so the
+// request's cancellation, deadline and values reach the database.
+type ContextORMIntegrator interface {
+	// WithContext returns an integrator running its operations with ctx. The returned integrator should implement
+	// the same optional extensions as the integrator it was derived from.
+	WithContext(ctx context.Context) ORMIntegrator
+}
+
+// ORMWithContext binds orm to ctx when it implements ContextORMIntegrator, and returns it unchanged otherwise so
+// that integrators unaware of contexts keep working.
+func ORMWithContext(orm ORMIntegrator, ctx context.Context) ORMIntegrator {
+	if contextORM, ok := orm.(ContextORMIntegrator); ok && ctx != nil {
+		return contextORM.WithContext(ctx)
+	}
+	return orm
+}
+
+// ListQuery describes a page of instances requested from an ORM integrator by a list view.
+type ListQuery struct {
+	// Fields lists the fields to fetch for each instance.
+	Fields []string
+	// Search is the search query. An empty query matches every instance.
+	Search string
+	// SearchFields lists the fields the search query is matched against.
+	SearchFields []string
+	// Offset is the number of matching instances to skip.
+	Offset uint
+	// Limit is the maximum number of instances to return.
+	Limit uint
+	// Ordering lists the fields to order by, most significant first. An empty ordering leaves the order to the
+	// integrator.
+	Ordering []OrderBy
+	// Filters lists conditions every returned instance must satisfy.
+	Filters []FilterCondition
+}
+
+// FilterOperator identifies how a FilterCondition compares a field with its value.
+type FilterOperator string
+
+const (
+	// FilterExact matches instances whose field equals the value.
+	FilterExact FilterOperator = "exact"
+	// FilterGTE matches instances whose field is greater than or equal to the value.
+	FilterGTE FilterOperator = "gte"
+	// FilterLTE matches instances whose field is less than or equal to the value.
+	FilterLTE FilterOperator = "lte"
+	// FilterLT matches instances whose field is strictly less than the value.
+	FilterLT FilterOperator = "lt"
+	// FilterIsNull matches instances whose field is a nil pointer, or NULL, when the value is true, and the other
+	// instances when it is false.
+	FilterIsNull FilterOperator = "isnull"
+)
+
+// FilterCondition restricts a list query to the instances whose field satisfies the operator. Value holds a value
+// of the field's type, dereferenced if the field is a pointer.
+type FilterCondition struct {
+	Field    string
+	Operator FilterOperator
+	Value    interface{}
+}
+
+// OrderBy orders instances by a single field.
+type OrderBy struct {
+	// Field is the name of the struct field to order by.
+	Field string
+	// Descending reverses the order from ascending to descending.
+	Descending bool
+}
+
+// PaginatedORMIntegrator is an optional extension of ORMIntegrator for integrators that can paginate in the
+// database. When the integrator implements it, list views fetch only the requested page instead of every instance.
+// The read permission of each instance is then checked on the page only, so the total count may include instances the
+// user cannot read, unless AdminConfig.ListScope moves the permission into the query.
+type PaginatedORMIntegrator interface {
+	// FetchInstancesPage retrieves the instances described by query, together with the total number of instances
+	// matching it before Offset and Limit are applied.
+	FetchInstancesPage(model interface{}, query ListQuery) (instances interface{}, totalCount uint, err error)
+}
+
+// ManyToManyORMIntegrator is an optional extension of ORMIntegrator for integrators that store many-to-many
+// relations, such as join tables. Relations are identified by the name they are declared with through
+// AdminModelManyToManyInterface; IDs are primary key values of the related model.
+type ManyToManyORMIntegrator interface {
+	// FetchRelatedIDs returns the IDs of the instances related to the instance with the given primary key.
+	FetchRelatedIDs(model interface{}, id interface{}, relation string) ([]interface{}, error)
+
+	// ReplaceRelatedIDs replaces the instances related to the instance with the given primary key.
+	ReplaceRelatedIDs(model interface{}, id interface{}, relation string, relatedIDs []interface{}) error
+}
+
+// TransactionalORMIntegrator is an optional extension of ORMIntegrator for integrators that support transactions.
+// Operations running in WriteModeAtomic use it so that their writes either fully apply or fully roll back.
+type TransactionalORMIntegrator interface {
+	// WithTransaction runs fn with an integrator bound to a new transaction, committing it when fn returns nil and
+	// rolling it back otherwise. The transactional integrator should implement the same optional extensions as the
+	// integrator it was started from.
+	WithTransaction(fn func(tx ORMIntegrator) error) error
+}
+
+// AggregateFunc identifies a statistic computed over the instances of a model.
+type AggregateFunc string
+
+const (
+	// AggregateCount counts the instances.
+	AggregateCount AggregateFunc = "count"
+	// AggregateSum adds up the values of a numeric field.
+	AggregateSum AggregateFunc = "sum"
+	// AggregateAvg averages the values of a numeric field.
+	AggregateAvg AggregateFunc = "avg"
+)
+
+// GroupCount is the number of instances sharing a value of the field they are grouped by. Value is nil for
+// instances whose field is a nil pointer, or NULL.
+type GroupCount struct {
+	Value interface{}
+	Count uint
+}
+
+// AggregateORMIntegrator is an optional extension of ORMIntegrator for integrators that can count and aggregate
+// instances in the database. When the integrator implements it, the dashboard computes its statistics without
+// fetching every instance, and the model cards show instance counts, which are left out otherwise.
+type AggregateORMIntegrator interface {
+	// CountInstances returns the number of instances matching the filters.
+	CountInstances(model interface{}, filters []FilterCondition) (uint, error)
+
+	// CountInstancesByField returns the number of instances matching the filters for each value of the field,
+	// ordered by value.
+	CountInstancesByField(model interface{}, field string, filters []FilterCondition) ([]GroupCount, error)
+
+	// AggregateField computes AggregateSum or AggregateAvg over the numeric field of the instances matching the
+	// filters. It returns zero when no instance matches.
+	AggregateField(model interface{}, field string, aggregate AggregateFunc, filters []FilterCondition) (float64, error)
+}
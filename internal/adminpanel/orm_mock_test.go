@@ -1,6 +1,10 @@
 package adminpanel
 
-import "reflect"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
 
 type MockORMIntegrator struct{}
 
@@ -47,3 +51,54 @@ func (m *MockORMIntegrator) UpdateInstanceOnlyFields(interface{}, []string, inte
 // Newer interface methods (AJAX helpers)
 func (m *MockORMIntegrator) GetAll(interface{}) (interface{}, error)   { return []interface{}{}, nil }
 func (m *MockORMIntegrator) DeleteByID(interface{}, interface{}) error { return nil }
+
+// MockPaginatedORMIntegrator records the list queries it receives and serves them from Instances.
+type MockPaginatedORMIntegrator struct {
+	MockORMIntegrator
+	Instances []*TestModel
+	Queries   []ListQuery
+	Deleted   []interface{}
+}
+
+func (m *MockPaginatedORMIntegrator) GetPrimaryKeyValue(instance interface{}) (interface{}, error) {
+	if testModel, ok := instance.(*TestModel); ok {
+		return testModel.ID, nil
+	}
+	return nil, nil
+}
+
+func (m *MockPaginatedORMIntegrator) GetPrimaryKeyType(interface{}) (reflect.Type, error) {
+	return reflect.TypeOf(uint(0)), nil
+}
+
+func (m *MockPaginatedORMIntegrator) FetchInstance(_ interface{}, id interface{}) (interface{}, error) {
+	for _, instance := range m.Instances {
+		if instance.ID == id {
+			return instance, nil
+		}
+	}
+	return nil, fmt.Errorf("record %v: %w", id, ErrInstanceNotFound)
+}
+
+func (m *MockPaginatedORMIntegrator) DeleteByID(_ interface{}, id interface{}) error {
+	m.Deleted = append(m.Deleted, id)
+	return nil
+}
+
+func (m *MockPaginatedORMIntegrator) FetchInstancesPage(_ interface{}, query ListQuery) (interface{}, uint, error) {
+	m.Queries = append(m.Queries, query)
+	total := uint(len(m.Instances))
+	start := query.Offset
+	if start > total {
+		start = total
+	}
+	end := start + query.Limit
+	if end > total {
+		end = total
+	}
+	return m.Instances[start:end], total, nil
+}
+
+func (m *MockPaginatedORMIntegrator) FetchInstancesOnlyFields(interface{}, []string) (interface{}, error) {
+	return nil, errors.New("list views must not fetch every instance from a paginated integrator")
+}
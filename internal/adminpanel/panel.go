@@ -1,11 +1,13 @@
 package adminpanel
 
 import (
+	"context"
 	"fmt"
 	"github.com/ovnicraft/go-advanced-admin/internal"
 	"github.com/ovnicraft/go-advanced-admin/internal/logging"
 	"github.com/ovnicraft/go-advanced-admin/internal/utils"
 	"net/http"
+	"reflect"
 )
 
 // AdminPanel represents the admin panel, which manages apps, models, permissions, and configuration.
@@ -16,6 +18,8 @@ type AdminPanel struct {
 	ORM               ORMIntegrator
 	Web               WebIntegrator
 	Config            AdminConfig
+	// KeyCodecs holds the codecs registered with RegisterKeyCodec, by primary key type.
+	KeyCodecs map[reflect.Type]KeyCodec
 }
 
 // GetLogEntries retrieves log entries up to the specified maximum count.
@@ -56,6 +60,22 @@ func (ap *AdminPanel) GetORM() ORMIntegrator {
 	return ap.ORM
 }
 
+// GetRequestContext returns the context.Context of the request carried by data, see ContextWebIntegrator.
+func (ap *AdminPanel) GetRequestContext(data interface{}) context.Context {
+	return requestContext(ap.Web, data)
+}
+
+// withRequestContext wraps a permission function so that the requests it checks carry the context of the HTTP
+// request being handled.
+func withRequestContext(web WebIntegrator, permissionsCheck PermissionFunc) PermissionFunc {
+	return func(r PermissionRequest, data interface{}) (bool, error) {
+		if r.Context == nil {
+			r.Context = requestContext(web, data)
+		}
+		return permissionsCheck(r, data)
+	}
+}
+
 // NewAdminPanel creates a new admin panel with the given ORM integrator, web integrator, permission function, and configuration.
 func NewAdminPanel(orm ORMIntegrator, web WebIntegrator, permissionsCheck PermissionFunc, config *AdminConfig) (*AdminPanel, error) {
 	if orm == nil {
@@ -73,10 +93,18 @@ func NewAdminPanel(orm ORMIntegrator, web WebIntegrator, permissionsCheck Permis
 	admin := AdminPanel{
 		Apps:              make(map[string]*App),
 		AppsSlice:         make([]*App, 0),
-		PermissionChecker: permissionsCheck,
+		PermissionChecker: withRequestContext(web, permissionsCheck),
 		ORM:               orm,
 		Web:               web,
 		Config:            *config,
+		KeyCodecs:         make(map[reflect.Type]KeyCodec),
+	}
+	if admin.Config.CSRFTokenStore == nil && !admin.Config.DisableCSRF {
+		cookieWeb, ok := web.(CookieWebIntegrator)
+		if !ok {
+			return nil, fmt.Errorf("web integrator %T cannot set cookies: set a CSRFTokenStore, or DisableCSRF to accept forged requests", web)
+		}
+		admin.Config.CSRFTokenStore = NewCookieCSRFTokenStore(cookieWeb)
 	}
 
 	admin.Config.Renderer.RegisterDefaultTemplates(internal.TemplateFiles, "templates/")
@@ -85,7 +113,7 @@ func NewAdminPanel(orm ORMIntegrator, web WebIntegrator, permissionsCheck Permis
 	admin.Config.Renderer.RegisterAssetsFunc(admin.Config.GetAssetLink)
 
 	components := []string{"page.html"}
-	pages := []string{"root", "app", "model", "instance", "edit_instance", "new_instance", "log"}
+	pages := []string{"root", "app", "model", "instance", "edit_instance", "new_instance", "log", "action_confirm", "trash", "import", "csrf"}
 
 	for _, page := range pages {
 		err := admin.Config.Renderer.RegisterCompositeDefaultTemplate(page, append([]string{page + ".html"}, components...)...)
@@ -97,6 +125,9 @@ func NewAdminPanel(orm ORMIntegrator, web WebIntegrator, permissionsCheck Permis
 	web.ServeAssets(config.AssetsPrefix, config.Renderer)
 	web.HandleRoute("GET", config.GetPrefix(), admin.GetHandler())
 	web.HandleRoute("GET", config.GetPrefix()+admin.GetLogBaseLink()+"/:id", admin.GetLogHandler())
+	if config.APIPrefix != "" && config.OpenAPIPath != "" {
+		web.HandleJSONRoute("GET", config.GetPrefix()+config.GetOpenAPIPath(), admin.HandleOpenAPI)
+	}
 
 	return &admin, nil
 }
@@ -116,12 +147,27 @@ func (ap *AdminPanel) GetHandler() HandlerFunc {
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
 		}
+		for _, app := range apps {
+			if err = addModelCounts(app["models"].([]map[string]interface{}), data); err != nil {
+				return GetErrorHTML(http.StatusInternalServerError, err)
+			}
+		}
+		tiles, err := ap.getDashboardTiles(data)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		charts, err := ap.getDashboardCharts(data)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
 
-		html, err := ap.Config.Renderer.RenderTemplate("root", map[string]interface{}{
+		html, err := ap.RenderPage(data, "root", map[string]interface{}{
 			"admin":       ap,
 			"apps":        apps,
 			"navBarItems": ap.Config.GetNavBarItems(data),
 			"logs":        ap.GetLogEntries(data, 20),
+			"tiles":       tiles,
+			"charts":      charts,
 		})
 		if err != nil {
 			return GetErrorHTML(http.StatusInternalServerError, err)
@@ -155,3 +201,22 @@ func (ap *AdminPanel) RegisterApp(name, displayName string, orm ORMIntegrator) (
 func (ap *AdminPanel) GetFullLink() string {
 	return ap.Config.GetLink("")
 }
+
+// RenderPage renders a page template with data, adding the data every page needs for the request carried by ctx: its
+// CSRF token and the flash messages of its user. Extensions rendering their own pages should go through it.
+func (ap *AdminPanel) RenderPage(ctx interface{}, name string, data map[string]interface{}) (string, error) {
+	token, err := ap.GetCSRFToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	data["csrfToken"] = token
+	if _, ok := ap.Web.(HeaderWebIntegrator); ok {
+		data["csrfHeader"] = CSRFHeader
+	}
+	flashes, err := ap.PopFlashes(ctx)
+	if err != nil {
+		return "", err
+	}
+	data["flashes"] = flashes
+	return ap.Config.Renderer.RenderTemplate(name, data)
+}
@@ -1,5 +1,7 @@
 package adminpanel
 
+import "context"
+
 // Action represents an action type for permissions.
 type Action string
 
@@ -14,14 +16,31 @@ const (
 	DeleteAction Action = "delete"
 	// LogViewAction represents log viewing permissions.
 	LogViewAction Action = "log_view"
+	// ExecuteAction represents permissions to run a model action, named by PermissionRequest.ActionName.
+	ExecuteAction Action = "execute"
+	// RestoreAction represents permissions to restore a soft-deleted instance from the trash.
+	RestoreAction Action = "restore"
+	// PurgeAction represents permissions to permanently delete a soft-deleted instance from the trash.
+	PurgeAction Action = "purge"
+	// ExportAction represents permissions to export the list view of a model.
+	ExportAction Action = "export"
 )
 
+// ListScopeFunc returns the filters restricting a list of instances of a model to those the user may read. When
+// complete is true, the filters stand for the read permission of each instance, which list views then skip checking,
+// so the total count of a paginated list is exact.
+type ListScopeFunc func(appName, modelName string, data interface{}) (filters []FilterCondition, complete bool, err error)
+
 // PermissionRequest represents a request to check permissions for a specific action.
 type PermissionRequest struct {
 	AppName    *string
 	ModelName  *string
 	InstanceID interface{}
 	Action     *Action
+	// ActionName names the model action of ExecuteAction requests.
+	ActionName *string
+	// Context is the context of the HTTP request being handled, see ContextWebIntegrator.
+	Context context.Context
 }
 
 // Permissions holds the permissions for a specific operation.
@@ -30,11 +49,29 @@ type Permissions struct {
 	Create bool
 	Update bool
 	Delete bool
+	// Restore and Purge are only set for instances listed in the trash of soft-deleted models.
+	Restore bool
+	Purge   bool
 }
 
 // PermissionFunc defines a function type for checking permissions.
 type PermissionFunc func(PermissionRequest, interface{}) (bool, error)
 
+// ContextPermissionFunc is a context-aware variant of PermissionFunc, receiving the context of the request being
+// handled as its first argument.
+type ContextPermissionFunc func(context.Context, PermissionRequest, interface{}) (bool, error)
+
+// PermissionFunc adapts f to the PermissionFunc accepted by NewAdminPanel.
+func (f ContextPermissionFunc) PermissionFunc() PermissionFunc {
+	return func(r PermissionRequest, data interface{}) (bool, error) {
+		ctx := r.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		return f(ctx, r, data)
+	}
+}
+
 // HasLogViewPermission checks if the user has permission to view logs.
 func (p PermissionFunc) HasLogViewPermission(data interface{}, logID interface{}) (bool, error) {
 	action := LogViewAction
@@ -108,6 +145,57 @@ func (p PermissionFunc) HasInstanceDeletePermission(appName, modelName string, i
 	return p(permissionRequest, data)
 }
 
+// HasModelRestorePermission checks if the user has permission to restore instances of the specified model.
+func (p PermissionFunc) HasModelRestorePermission(appName, modelName string, data interface{}) (bool, error) {
+	action := RestoreAction
+	permissionRequest := PermissionRequest{AppName: &appName, ModelName: &modelName, Action: &action}
+	return p(permissionRequest, data)
+}
+
+// HasModelPurgePermission checks if the user has permission to purge instances of the specified model.
+func (p PermissionFunc) HasModelPurgePermission(appName, modelName string, data interface{}) (bool, error) {
+	action := PurgeAction
+	permissionRequest := PermissionRequest{AppName: &appName, ModelName: &modelName, Action: &action}
+	return p(permissionRequest, data)
+}
+
+// HasModelExportPermission checks if the user has permission to export the instances of the specified model.
+func (p PermissionFunc) HasModelExportPermission(appName, modelName string, data interface{}) (bool, error) {
+	action := ExportAction
+	permissionRequest := PermissionRequest{AppName: &appName, ModelName: &modelName, Action: &action}
+	return p(permissionRequest, data)
+}
+
+// HasInstanceRestorePermission checks if the user has permission to restore the specified instance from the trash.
+func (p PermissionFunc) HasInstanceRestorePermission(appName, modelName string, instanceID interface{}, data interface{}) (bool, error) {
+	action := RestoreAction
+	permissionRequest := PermissionRequest{AppName: &appName, ModelName: &modelName, Action: &action, InstanceID: instanceID}
+	return p(permissionRequest, data)
+}
+
+// HasInstancePurgePermission checks if the user has permission to permanently delete the specified instance from the
+// trash.
+func (p PermissionFunc) HasInstancePurgePermission(appName, modelName string, instanceID interface{}, data interface{}) (bool, error) {
+	action := PurgeAction
+	permissionRequest := PermissionRequest{AppName: &appName, ModelName: &modelName, Action: &action, InstanceID: instanceID}
+	return p(permissionRequest, data)
+}
+
+// HasModelActionPermission checks if the user has permission to run the named action of the specified model.
+func (p PermissionFunc) HasModelActionPermission(appName, modelName, actionName string, data interface{}) (bool, error) {
+	action := ExecuteAction
+	permissionRequest := PermissionRequest{AppName: &appName, ModelName: &modelName, Action: &action, ActionName: &actionName}
+	return p(permissionRequest, data)
+}
+
+// HasInstanceActionPermission checks if the user has permission to run the named action of the specified model on
+// the specified instance.
+func (p PermissionFunc) HasInstanceActionPermission(appName, modelName, actionName string, instanceID interface{}, data interface{}) (bool, error) {
+	action := ExecuteAction
+	permissionRequest := PermissionRequest{AppName: &appName, ModelName: &modelName, Action: &action, ActionName: &actionName, InstanceID: instanceID}
+	return p(permissionRequest, data)
+}
+
 // GetModelsWithReadPermissions returns models for which the user has read permissions.
 func GetModelsWithReadPermissions(app *App, data interface{}) ([]map[string]interface{}, error) {
 	modelsSlice := make([]map[string]interface{}, 0)
@@ -0,0 +1,376 @@
+package adminpanel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"github.com/ovnicraft/go-advanced-admin/internal/form/fields"
+	"github.com/ovnicraft/go-advanced-admin/internal/form/forms"
+	"github.com/ovnicraft/go-advanced-admin/internal/utils"
+	"html/template"
+	"strings"
+)
+
+// ManyToManyRelation declares a many-to-many relation between a model and another registered model.
+type ManyToManyRelation struct {
+	// Name identifies the relation towards the ORM integrator and names its form field.
+	Name string
+	// DisplayName labels the relation in forms and on the instance view. It defaults to the humanized name.
+	DisplayName string
+	// Related references the related model as "app.Model".
+	Related string
+	// Required makes the add and edit forms require at least one related instance.
+	Required bool
+}
+
+// AdminModelManyToManyInterface allows a model to declare its many-to-many relations. Models declaring relations
+// must be stored through an ORM integrator implementing ManyToManyORMIntegrator.
+type AdminModelManyToManyInterface interface {
+	AdminManyToMany() []ManyToManyRelation
+}
+
+// ManyToManyField is the form field of a many-to-many relation. It is rendered as a multiple select2 dropdown
+// searching the related model, which pages the related instances and only offers those the user may read, and it only
+// accepts IDs of instances that exist outside the trash. Its values are the related IDs formatted as form values.
+type ManyToManyField struct {
+	fields.BaseField
+	Panel *AdminPanel
+	// Related references the related model as "app.Model".
+	Related  string
+	Required bool
+	// Request is the data of the request the field is used in. The related instances are looked up within its
+	// context, and newly selected ones are checked against its read permissions.
+	Request interface{}
+}
+
+func (f *ManyToManyField) relatedModel() (*Model, error) {
+	if f.Panel == nil {
+		return nil, errors.New("many-to-many field is not attached to an admin panel")
+	}
+	return f.Panel.GetModelByReference(f.Related)
+}
+
+// initialValues returns the related IDs the field was initialized with, as a set of form values.
+func (f *ManyToManyField) initialValues() map[string]bool {
+	initial := make(map[string]bool)
+	values, _ := f.InitialValue.([]string)
+	for _, value := range values {
+		initial[value] = true
+	}
+	return initial
+}
+
+func (f *ManyToManyField) HTML() (string, error) {
+	related, err := f.relatedModel()
+	if err != nil {
+		return "", err
+	}
+
+	options := make([]string, 0)
+	values, _ := f.InitialValue.([]string)
+	for _, value := range values {
+		// Selected instances the user may not read are labelled with their key only, so saving keeps them.
+		label := value
+		id, err := related.parseInstanceID(value)
+		if err != nil {
+			continue
+		}
+		allowed, err := f.Panel.PermissionChecker.HasInstanceReadPermission(related.App.Name, related.Name, id, f.Request)
+		if err != nil {
+			return "", err
+		}
+		if allowed {
+			instance, err := related.fetchLiveInstance(related.getRequestORM(f.Request), id, nil)
+			if errors.Is(err, ErrInstanceNotFound) {
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			label = (&Instance{InstanceID: id, Data: instance, Model: related}).GetRepr()
+		}
+		options = append(options, fmt.Sprintf(`<option value="%s" selected>%s</option>`,
+			template.HTMLEscapeString(value),
+			template.HTMLEscapeString(label),
+		))
+	}
+
+	attributesMap := map[string]*string{"multiple": nil}
+	setAttribute := func(name, value string) {
+		attributesMap[name] = &value
+	}
+	setAttribute("name", f.Name)
+	setAttribute("class", "form-select")
+	setAttribute("data-role", "select2-fk")
+	setAttribute("data-url", related.GetFullLink()+"/search")
+	setAttribute("data-placeholder", "Select "+related.DisplayName)
+	if f.Required {
+		attributesMap["required"] = nil
+	}
+	for key, value := range f.SupersedingAttributes {
+		attributesMap[key] = value
+	}
+	return selectHTML(attributesMap, options), nil
+}
+
+func (f *ManyToManyField) GoTypeToHTMLType(value interface{}) (form.HTMLType, error) {
+	if value == nil {
+		return "", nil
+	}
+	values, ok := value.([]string)
+	if !ok {
+		return "", errors.New("value must be a slice of strings")
+	}
+	jsonValue, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return form.HTMLType(jsonValue), nil
+}
+
+func (f *ManyToManyField) HTMLTypeToGoType(value form.HTMLType) (interface{}, error) {
+	if value == "" || value == "[]" {
+		return nil, nil
+	}
+	// A single selected option is submitted as a plain value rather than a JSON list.
+	if !strings.HasPrefix(string(value), "[") {
+		return []string{string(value)}, nil
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		return nil, errors.New("invalid related instance ids")
+	}
+	return values, nil
+}
+
+func (f *ManyToManyField) GetValidationFunctions() []form.FieldValidationFunc {
+	baseValidations := f.BaseField.GetValidationFunctions()
+	baseValidations = append(baseValidations, f.requiredValidation, f.existsValidation)
+	return baseValidations
+}
+
+func (f *ManyToManyField) requiredValidation(value interface{}) ([]error, error) {
+	if values, _ := value.([]string); f.Required && len(values) == 0 {
+		return []error{errors.New("at least one instance must be selected")}, nil
+	}
+	return nil, nil
+}
+
+// existsValidation checks that the selected instances exist outside the trash. Instances the field was initialized
+// with are kept without checking read permissions, so a form does not drop relations the user cannot see.
+func (f *ManyToManyField) existsValidation(value interface{}) ([]error, error) {
+	if value == nil {
+		return nil, nil
+	}
+	values, ok := value.([]string)
+	if !ok {
+		return nil, errors.New("value must be a slice of strings")
+	}
+	related, err := f.relatedModel()
+	if err != nil {
+		return nil, err
+	}
+	initial := f.initialValues()
+	orm := related.getRequestORM(f.Request)
+	for _, value := range values {
+		id, err := related.parseInstanceID(value)
+		if err != nil {
+			return []error{fmt.Errorf("invalid %s id: %s", related.DisplayName, value)}, nil
+		}
+		if !initial[value] {
+			allowed, err := f.Panel.PermissionChecker.HasInstanceReadPermission(related.App.Name, related.Name, id, f.Request)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return []error{fmt.Errorf("selected %s does not exist", related.DisplayName)}, nil
+			}
+		}
+		if _, err = related.fetchLiveInstance(orm, id, nil); err != nil {
+			if errors.Is(err, ErrInstanceNotFound) {
+				return []error{fmt.Errorf("selected %s does not exist", related.DisplayName)}, nil
+			}
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// RelatedInstances holds the instances related to an instance through a relation.
+type RelatedInstances struct {
+	Relation  ManyToManyRelation
+	Instances []Instance
+}
+
+// registerRelations validates the relations declared by a model and adds them to it.
+func (m *Model) registerRelations(relations []ManyToManyRelation) error {
+	for _, relation := range relations {
+		if relation.Name == "" {
+			return fmt.Errorf("admin model '%s' declares a many-to-many relation without a name", m.Name)
+		}
+		if _, exists := m.getFieldConfig(relation.Name); exists {
+			return fmt.Errorf("many-to-many relation '%s' of admin model '%s' has the name of a field", relation.Name, m.Name)
+		}
+		if _, exists := m.getRelation(relation.Name); exists {
+			return fmt.Errorf("many-to-many relation '%s' is declared more than once on admin model '%s'", relation.Name, m.Name)
+		}
+		if _, _, ok := parseModelReference(relation.Related); !ok {
+			return fmt.Errorf("many-to-many relation '%s' has an invalid related model '%s', expected 'app.Model'", relation.Name, relation.Related)
+		}
+		if relation.DisplayName == "" {
+			relation.DisplayName = utils.HumanizeName(relation.Name)
+		}
+		m.Relations = append(m.Relations, relation)
+	}
+	if len(m.Relations) > 0 {
+		if _, ok := m.GetORM().(ManyToManyORMIntegrator); !ok {
+			return fmt.Errorf("admin model '%s' declares many-to-many relations but its ORM integrator does not implement ManyToManyORMIntegrator", m.Name)
+		}
+	}
+	return nil
+}
+
+// getRelation returns the many-to-many relation with the given name.
+func (m *Model) getRelation(name string) (ManyToManyRelation, bool) {
+	for _, relation := range m.Relations {
+		if relation.Name == name {
+			return relation, true
+		}
+	}
+	return ManyToManyRelation{}, false
+}
+
+// getManyToManyORM returns the integrator of the model bound to the context of the request.
+func (m *Model) getManyToManyORM(data interface{}) (ManyToManyORMIntegrator, error) {
+	return m.asManyToManyORM(m.getRequestORM(data))
+}
+
+func (m *Model) asManyToManyORM(integrator ORMIntegrator) (ManyToManyORMIntegrator, error) {
+	orm, ok := integrator.(ManyToManyORMIntegrator)
+	if !ok {
+		return nil, fmt.Errorf("the ORM integrator of admin model '%s' does not support many-to-many relations", m.Name)
+	}
+	return orm, nil
+}
+
+// newRelationField builds the form field of a relation for a request.
+func (m *Model) newRelationField(data interface{}, relation ManyToManyRelation) (form.Field, error) {
+	if _, err := m.App.Panel.GetModelByReference(relation.Related); err != nil {
+		return nil, err
+	}
+	field := &ManyToManyField{Panel: m.App.Panel, Related: relation.Related, Required: relation.Required, Request: data}
+	if err := field.RegisterLabel(relation.DisplayName); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+// addRelationFields adds the form field of each relation to a model form.
+func (m *Model) addRelationFields(data interface{}, f *forms.BaseForm) error {
+	for _, relation := range m.Relations {
+		field, err := m.newRelationField(data, relation)
+		if err != nil {
+			return err
+		}
+		if err = f.AddField(relation.Name, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchRelatedIDValues returns the related IDs of every relation of an instance, formatted as form values.
+func (m *Model) fetchRelatedIDValues(data interface{}, instanceID interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if len(m.Relations) == 0 {
+		return values, nil
+	}
+	orm, err := m.getManyToManyORM(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, relation := range m.Relations {
+		ids, err := orm.FetchRelatedIDs(m.PTR, instanceID, relation.Name)
+		if err != nil {
+			return nil, err
+		}
+		formValues := make([]string, len(ids))
+		for i, id := range ids {
+			formValues[i] = m.FormatInstanceID(id)
+		}
+		values[relation.Name] = formValues
+	}
+	return values, nil
+}
+
+// saveRelations replaces the related IDs of an instance with the values of the relation fields in cleanValues,
+// writing through writeORM. Relations missing from cleanValues are left untouched.
+func (m *Model) saveRelations(writeORM ORMIntegrator, instanceID interface{}, cleanValues map[string]interface{}) error {
+	if len(m.Relations) == 0 {
+		return nil
+	}
+	orm, err := m.asManyToManyORM(writeORM)
+	if err != nil {
+		return err
+	}
+	for _, relation := range m.Relations {
+		value, ok := cleanValues[relation.Name]
+		if !ok {
+			continue
+		}
+		related, err := m.App.Panel.GetModelByReference(relation.Related)
+		if err != nil {
+			return err
+		}
+		formValues, _ := value.([]string)
+		ids := make([]interface{}, 0, len(formValues))
+		for _, formValue := range formValues {
+			id, err := related.parseInstanceID(formValue)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if err = orm.ReplaceRelatedIDs(m.PTR, instanceID, relation.Name, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getRelatedInstances returns the instances related to an instance through each relation. Related IDs whose
+// instance no longer exists or is in the trash are skipped.
+func (m *Model) getRelatedInstances(data interface{}, instanceID interface{}) ([]RelatedInstances, error) {
+	relatedInstances := make([]RelatedInstances, 0, len(m.Relations))
+	if len(m.Relations) == 0 {
+		return relatedInstances, nil
+	}
+	orm, err := m.getManyToManyORM(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, relation := range m.Relations {
+		related, err := m.App.Panel.GetModelByReference(relation.Related)
+		if err != nil {
+			return nil, err
+		}
+		ids, err := orm.FetchRelatedIDs(m.PTR, instanceID, relation.Name)
+		if err != nil {
+			return nil, err
+		}
+		entry := RelatedInstances{Relation: relation, Instances: make([]Instance, 0, len(ids))}
+		for _, id := range ids {
+			instance, err := related.fetchLiveInstance(related.getRequestORM(data), id, nil)
+			if errors.Is(err, ErrInstanceNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			entry.Instances = append(entry.Instances, Instance{InstanceID: id, Data: instance, Model: related})
+		}
+		relatedInstances = append(relatedInstances, entry)
+	}
+	return relatedInstances, nil
+}
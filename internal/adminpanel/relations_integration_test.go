@@ -0,0 +1,145 @@
+package adminpanel_test
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// registerManyToManyModels registers users in groups, user 3 being in group 1.
+func registerManyToManyModels(t *testing.T) (*adminpanel.Model, *relatedORM) {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	orm := &relatedORM{
+		Integrator: newMemoryORM(t, &adminpanel.M2MUser{ID: 3, Name: "bob"}, &adminpanel.M2MGroup{ID: 1, Name: "Admins"}, &adminpanel.M2MGroup{ID: 2, Name: "Editors"}),
+		Related:    map[string]map[interface{}][]interface{}{"Groups": {uint(3): {uint(1)}}},
+	}
+	authApp, err := panel.RegisterApp("Auth", "Auth", orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user, err := authApp.RegisterModel(&adminpanel.M2MUser{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = authApp.RegisterModel(&adminpanel.M2MGroup{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return user, orm
+}
+
+func TestRegisterModel_ManyToMany(t *testing.T) {
+	user, _ := registerManyToManyModels(t)
+
+	expected := []adminpanel.ManyToManyRelation{{Name: "Groups", DisplayName: "Groups", Related: "Auth.M2MGroup"}}
+	if !reflect.DeepEqual(user.Relations, expected) {
+		t.Errorf("expected relations %v, got %v", expected, user.Relations)
+	}
+
+	panel, _ := adminpanel.NewMockAdminPanel()
+	app, _ := panel.RegisterApp("Auth", "Auth", newMemoryORM(t))
+	if _, err := app.RegisterModel(&adminpanel.M2MUser{}, nil); err == nil {
+		t.Error("expected an error for relations on an integrator without many-to-many support")
+	}
+}
+
+func TestModel_NewEditForm_RelationField(t *testing.T) {
+	user, _ := registerManyToManyModels(t)
+
+	formInstance, err := user.NewEditForm(nil, uint(3))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var relationField *adminpanel.ManyToManyField
+	for _, field := range formInstance.GetFields() {
+		if field.GetName() == "Groups" {
+			relationField, _ = field.(*adminpanel.ManyToManyField)
+		}
+	}
+	if relationField == nil {
+		t.Fatal("expected the form to have a many-to-many field for the relation")
+	}
+	relationField.RegisterInitialValue([]string{"1"})
+	html, err := relationField.HTML()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(html, `data-url="/admin/a/Auth/M2MGroup/search"`) || !strings.Contains(html, "multiple") {
+		t.Errorf("expected a multiple dropdown searching the related model, got %s", html)
+	}
+	if !strings.Contains(html, `<option value="1" selected>Admins</option>`) || strings.Contains(html, "Editors") {
+		t.Errorf("expected the selected groups only to be rendered, got %s", html)
+	}
+}
+
+func TestManyToManyField_Validation(t *testing.T) {
+	user, _ := registerManyToManyModels(t)
+	user.App.Panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return request.InstanceID != uint(2), nil
+	}
+	field := &adminpanel.ManyToManyField{Panel: user.App.Panel, Related: "Auth.M2MGroup"}
+
+	if errs, err := form.FieldValueIsValid(field, []string{"1"}); err != nil || len(errs) != 0 {
+		t.Errorf("expected a readable group to be accepted, got %v %v", errs, err)
+	}
+	if errs, err := form.FieldValueIsValid(field, []string{"9"}); err != nil || len(errs) != 1 {
+		t.Errorf("expected a missing group to be rejected, got %v %v", errs, err)
+	}
+	if errs, err := form.FieldValueIsValid(field, []string{"2"}); err != nil || len(errs) != 1 {
+		t.Errorf("expected a group the user may not read to be rejected, got %v %v", errs, err)
+	}
+	field.RegisterInitialValue([]string{"2"})
+	if errs, err := form.FieldValueIsValid(field, []string{"1", "2"}); err != nil || len(errs) != 0 {
+		t.Errorf("expected a group the instance is already in to be kept, got %v %v", errs, err)
+	}
+}
+
+func TestModel_GetAddHandler_SavesRelations(t *testing.T) {
+	user, orm := registerManyToManyModels(t)
+
+	code, body := user.GetAddHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Form:   map[string][]string{"Name": {"alice"}, "Groups": {"1", "2"}},
+	})
+	if code != http.StatusSeeOther {
+		t.Fatalf("expected %v, got %v: %s", http.StatusSeeOther, code, body)
+	}
+	if !reflect.DeepEqual(orm.Related["Groups"][uint(4)], []interface{}{uint(1), uint(2)}) {
+		t.Errorf("expected the new user to be in groups 1 and 2, got %v", orm.Related["Groups"][uint(4)])
+	}
+}
+
+func TestModelEditForm_Save_ReplacesRelations(t *testing.T) {
+	user, orm := registerManyToManyModels(t)
+
+	formInstance, err := user.NewEditForm(nil, uint(3))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = formInstance.Save(map[string]form.HTMLType{"Name": "bob", "Groups": "2"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(orm.Related["Groups"][uint(3)], []interface{}{uint(2)}) {
+		t.Errorf("expected user 3 to only be in group 2, got %v", orm.Related["Groups"][uint(3)])
+	}
+}
+
+func TestModel_GetInstanceViewHandler_Relations(t *testing.T) {
+	user, _ := registerManyToManyModels(t)
+
+	code, body := user.GetInstanceViewHandler()(map[string]string{"id": "3"})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if !strings.Contains(body, `href="/admin/a/Auth/M2MGroup/1/view"`) || !strings.Contains(body, "Admins") {
+		t.Error("expected the instance view to link to the related groups")
+	}
+	if strings.Contains(body, "Editors") {
+		t.Error("expected unrelated groups not to be shown")
+	}
+}
@@ -0,0 +1,19 @@
+package adminpanel
+
+type M2MUser struct {
+	ID   uint
+	Name string
+}
+
+func (u *M2MUser) AdminManyToMany() []ManyToManyRelation {
+	return []ManyToManyRelation{{Name: "Groups", Related: "Auth.M2MGroup"}}
+}
+
+type M2MGroup struct {
+	ID   uint
+	Name string
+}
+
+func (g *M2MGroup) AdminInstanceRepr() string {
+	return g.Name
+}
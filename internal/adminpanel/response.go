@@ -0,0 +1,150 @@
+package adminpanel
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// Response is the response of a ResponseHandlerFunc. Its body is either written progressively by Write, or given as
+// Body.
+type Response struct {
+	StatusCode uint
+	// ContentType defaults to HTML.
+	ContentType string
+	// Header holds the other headers of the response.
+	Header  http.Header
+	Cookies []*http.Cookie
+	Body    string
+	// Write writes the body. Errors returned after the first write cannot change the status code anymore; the web
+	// integrator should abort the response.
+	Write func(w io.Writer) error
+}
+
+// ResponseHandlerFunc represents a handler function returning a Response.
+type ResponseHandlerFunc = func(interface{}) Response
+
+// ResponseWebIntegrator is an optional extension of WebIntegrator for integrators that can send a Response with its
+// headers, cookies and content type. Routes of other integrators go through AdaptResponseHandler. Routes serving
+// downloads, such as list view exports, are only registered with integrators implementing it.
+type ResponseWebIntegrator interface {
+	// HandleResponseRoute registers a route with the given method, path, and handler function returning a Response.
+	HandleResponseRoute(method, path string, handler ResponseHandlerFunc)
+}
+
+// NewHTMLResponse returns a response with the given status code and HTML body.
+func NewHTMLResponse(statusCode uint, body string) Response {
+	return Response{StatusCode: statusCode, Body: body}
+}
+
+// NewRedirectResponse returns a 303 See Other response redirecting to url.
+func NewRedirectResponse(url string) Response {
+	return Response{StatusCode: http.StatusSeeOther, Header: http.Header{"Location": {url}}}
+}
+
+// NewFileResponse returns a response asking browsers to save the body written by write as a file of the given name.
+func NewFileResponse(filename, contentType string, write func(w io.Writer) error) Response {
+	response := Response{StatusCode: http.StatusOK, ContentType: contentType, Write: write}
+	response.SetHeader("Content-Disposition", attachmentDisposition(filename))
+	return response
+}
+
+// attachmentDisposition returns the Content-Disposition header asking browsers to save a response as a file.
+func attachmentDisposition(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
+
+// NewHandlerResponse converts the result of a HandlerFunc to a Response: a redirect to body for 3xx status codes, and
+// an HTML page otherwise.
+func NewHandlerResponse(statusCode uint, body string) Response {
+	if statusCode >= 300 && statusCode < 400 {
+		response := NewRedirectResponse(body)
+		response.StatusCode = statusCode
+		return response
+	}
+	return NewHTMLResponse(statusCode, body)
+}
+
+// SetHeader sets a header of the response, replacing its values.
+func (r *Response) SetHeader(name, value string) {
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	r.Header.Set(name, value)
+}
+
+// SetCookie adds a cookie to the response.
+func (r *Response) SetCookie(cookie *http.Cookie) {
+	r.Cookies = append(r.Cookies, cookie)
+}
+
+// GetContentType returns the content type of the response, HTML by default.
+func (r Response) GetContentType() string {
+	if r.ContentType == "" {
+		return "text/html; charset=utf-8"
+	}
+	return r.ContentType
+}
+
+// WriteHTTP writes the response to w, for integrators built on net/http. It returns the error of Write, after which
+// the response should be aborted.
+func (r Response) WriteHTTP(w http.ResponseWriter) error {
+	header := w.Header()
+	for name, values := range r.Header {
+		header[name] = values
+	}
+	for _, cookie := range r.Cookies {
+		http.SetCookie(w, cookie)
+	}
+	if r.StatusCode < 300 || r.StatusCode >= 400 || r.Body != "" || r.Write != nil {
+		header.Set("Content-Type", r.GetContentType())
+	}
+	w.WriteHeader(int(r.StatusCode))
+	if r.Write != nil {
+		return r.Write(w)
+	}
+	_, err := io.WriteString(w, r.Body)
+	return err
+}
+
+// AdaptResponseHandler adapts a ResponseHandlerFunc to a HandlerFunc, for integrators not implementing
+// ResponseWebIntegrator. Redirects are returned with the URL as body, and the body of other responses is returned as
+// an HTML page, written by Write if needed; the headers, cookies and content type are lost.
+func AdaptResponseHandler(handler ResponseHandlerFunc) HandlerFunc {
+	return func(data interface{}) (uint, string) {
+		response := handler(data)
+		if response.StatusCode >= 300 && response.StatusCode < 400 {
+			return response.StatusCode, response.Header.Get("Location")
+		}
+		if response.Write == nil {
+			return response.StatusCode, response.Body
+		}
+		var body bytes.Buffer
+		if err := response.Write(&body); err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		return response.StatusCode, body.String()
+	}
+}
+
+// HandleResponseRoute registers a route returning a Response with the web integrator of the panel, through
+// AdaptResponseHandler if it does not implement ResponseWebIntegrator. The cookies of adapted responses are then set
+// with CookieWebIntegrator, if the integrator implements it.
+func (ap *AdminPanel) HandleResponseRoute(method, path string, handler ResponseHandlerFunc) {
+	if responseWeb, ok := ap.Web.(ResponseWebIntegrator); ok {
+		responseWeb.HandleResponseRoute(method, path, handler)
+		return
+	}
+	if cookieWeb, ok := ap.Web.(CookieWebIntegrator); ok {
+		next := handler
+		handler = func(data interface{}) Response {
+			response := next(data)
+			for _, cookie := range response.Cookies {
+				cookieWeb.SetCookie(data, cookie)
+			}
+			return response
+		}
+	}
+	ap.Web.HandleRoute(method, path, AdaptResponseHandler(handler))
+}
@@ -0,0 +1,114 @@
+package adminpanel
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponse_WriteHTTP(t *testing.T) {
+	response := NewFileResponse("report.csv", "text/csv", func(w io.Writer) error {
+		_, err := io.WriteString(w, "a,b\n")
+		return err
+	})
+	response.SetCookie(&http.Cookie{Name: "seen", Value: "1"})
+	response.SetHeader("Cache-Control", "no-store")
+
+	recorder := httptest.NewRecorder()
+	if err := response.WriteHTTP(recorder); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "a,b\n" {
+		t.Errorf("unexpected response %v %q", recorder.Code, recorder.Body)
+	}
+	for name, expected := range map[string]string{
+		"Content-Type":        "text/csv",
+		"Content-Disposition": "attachment; filename=report.csv",
+		"Cache-Control":       "no-store",
+		"Set-Cookie":          "seen=1",
+	} {
+		if got := recorder.Header().Get(name); got != expected {
+			t.Errorf("expected %s %q, got %q", name, expected, got)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	if err := NewHandlerResponse(http.StatusSeeOther, "/admin").WriteHTTP(recorder); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "/admin" || recorder.Header().Get("Content-Type") != "" {
+		t.Errorf("expected a bare redirect, got %v %v", recorder.Code, recorder.Header())
+	}
+}
+
+func TestAdaptResponseHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		response Response
+		code     uint
+		body     string
+	}{
+		{"Redirect", NewRedirectResponse("/admin/a/Shop"), http.StatusSeeOther, "/admin/a/Shop"},
+		{"Body", NewHTMLResponse(http.StatusNotFound, "missing"), http.StatusNotFound, "missing"},
+		{"Written Body", Response{StatusCode: http.StatusOK, Write: func(w io.Writer) error {
+			_, err := io.WriteString(w, "written")
+			return err
+		}}, http.StatusOK, "written"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := AdaptResponseHandler(func(interface{}) Response { return tt.response })(nil)
+			if code != tt.code || body != tt.body {
+				t.Errorf("expected %v %q, got %v %q", tt.code, tt.body, code, body)
+			}
+		})
+	}
+
+	failing := Response{StatusCode: http.StatusOK, Write: func(io.Writer) error { return errors.New("disk full") }}
+	code, body := AdaptResponseHandler(func(interface{}) Response { return failing })(nil)
+	if code != http.StatusInternalServerError || !strings.Contains(body, "disk full") {
+		t.Errorf("expected the write error to be shown, got %v %q", code, body)
+	}
+}
+
+// mockCookieWebIntegrator hides the methods of a MockWebIntegrator sending a Response, so that routes returning one
+// are adapted to it.
+type mockCookieWebIntegrator struct {
+	WebIntegrator
+	CookieWebIntegrator
+}
+
+func TestAdminPanel_HandleResponseRoute(t *testing.T) {
+	handler := func(interface{}) Response {
+		response := NewRedirectResponse("/elsewhere")
+		response.SetCookie(&http.Cookie{Name: "seen", Value: "1"})
+		return response
+	}
+
+	web := &MockWebIntegrator{}
+	panel, err := NewAdminPanel(&MockORMIntegrator{}, mockCookieWebIntegrator{web, web}, MockPermissionFunc, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	panel.HandleResponseRoute("GET", "/admin/custom", handler)
+	request := &MockRequest{}
+	if code, location := web.Handlers["GET /admin/custom"](request); code != http.StatusSeeOther || location != "/elsewhere" {
+		t.Errorf("expected the handler to be adapted, got %v %q", code, location)
+	}
+	if request.ResponseCookies["seen"] != "1" {
+		t.Errorf("expected the cookie to be set, got %v", request.ResponseCookies)
+	}
+
+	responseWeb := &MockWebIntegrator{}
+	panel, err = NewAdminPanel(&MockORMIntegrator{}, responseWeb, MockPermissionFunc, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	panel.HandleResponseRoute("GET", "/admin/custom", handler)
+	if _, ok := responseWeb.ResponseHandlers["GET /admin/custom"]; !ok {
+		t.Errorf("expected the handler to be registered as is, got %v", responseWeb.Routes)
+	}
+}
@@ -0,0 +1,141 @@
+package adminpanel
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// statisticFilters returns the filters a statistic is computed with: the given filters, those of the list scope and,
+// for soft-deleted models, the condition leaving out trashed instances. Statistics follow the same rule whether the
+// integrator aggregates in the database or not: they cover the instances within the list scope, and the read
+// permission of each instance is not checked.
+func (m *Model) statisticFilters(data interface{}, filters []FilterCondition) ([]FilterCondition, error) {
+	scope, _, err := m.getListScope(data)
+	if err != nil {
+		return nil, err
+	}
+	filters = append(append([]FilterCondition(nil), filters...), scope...)
+	return m.withoutTrashed(filters), nil
+}
+
+// CountInstances returns the number of instances of the model matching the filters, leaving out trashed instances
+// of soft-deleted models.
+func (m *Model) CountInstances(data interface{}, filters []FilterCondition) (uint, error) {
+	filters, err := m.statisticFilters(data, filters)
+	if err != nil {
+		return 0, err
+	}
+	if aggregate, ok := m.getRequestORM(data).(AggregateORMIntegrator); ok {
+		return aggregate.CountInstances(m.PTR, filters)
+	}
+	instances, err := m.fetchAggregateInstances(data, nil, filters)
+	if err != nil {
+		return 0, err
+	}
+	return uint(len(instances)), nil
+}
+
+// CountInstancesByField returns the number of instances of the model matching the filters for each value of the
+// field, ordered by value.
+func (m *Model) CountInstancesByField(data interface{}, field string, filters []FilterCondition) ([]GroupCount, error) {
+	if _, ok := m.getFieldConfig(field); !ok {
+		return nil, fmt.Errorf("admin model '%s' has no field '%s'", m.Name, field)
+	}
+	filters, err := m.statisticFilters(data, filters)
+	if err != nil {
+		return nil, err
+	}
+	var groups []GroupCount
+	if aggregate, ok := m.getRequestORM(data).(AggregateORMIntegrator); ok {
+		if groups, err = aggregate.CountInstancesByField(m.PTR, field, filters); err != nil {
+			return nil, err
+		}
+	} else {
+		instances, err := m.fetchAggregateInstances(data, []string{field}, filters)
+		if err != nil {
+			return nil, err
+		}
+		indexes := make(map[string]int)
+		for _, instance := range instances {
+			var value interface{}
+			if v := fieldValueOf(instance, field); v.IsValid() {
+				value = v.Interface()
+			}
+			key := fmt.Sprintf("%T:%v", value, value)
+			if index, ok := indexes[key]; ok {
+				groups[index].Count++
+				continue
+			}
+			indexes[key] = len(groups)
+			groups = append(groups, GroupCount{Value: value, Count: 1})
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return compareFieldValues(reflect.ValueOf(groups[i].Value), reflect.ValueOf(groups[j].Value)) < 0
+	})
+	return groups, nil
+}
+
+// AggregateField computes AggregateSum or AggregateAvg over a numeric field of the instances of the model matching
+// the filters. Nil values are left out, and zero is returned when no instance matches.
+func (m *Model) AggregateField(data interface{}, field string, aggregate AggregateFunc, filters []FilterCondition) (float64, error) {
+	if aggregate != AggregateSum && aggregate != AggregateAvg {
+		return 0, fmt.Errorf("unsupported aggregate %q", aggregate)
+	}
+	if _, ok := m.getFieldConfig(field); !ok {
+		return 0, fmt.Errorf("admin model '%s' has no field '%s'", m.Name, field)
+	}
+	filters, err := m.statisticFilters(data, filters)
+	if err != nil {
+		return 0, err
+	}
+	if aggregateORM, ok := m.getRequestORM(data).(AggregateORMIntegrator); ok {
+		return aggregateORM.AggregateField(m.PTR, field, aggregate, filters)
+	}
+
+	instances, err := m.fetchAggregateInstances(data, []string{field}, filters)
+	if err != nil {
+		return 0, err
+	}
+	var sum float64
+	var count int
+	for _, instance := range instances {
+		value := fieldValueOf(instance, field)
+		switch {
+		case !value.IsValid():
+			continue
+		case value.CanInt():
+			sum += float64(value.Int())
+		case value.CanUint():
+			sum += float64(value.Uint())
+		case value.CanFloat():
+			sum += value.Float()
+		default:
+			return 0, fmt.Errorf("field '%s' of admin model '%s' is not numeric", field, m.Name)
+		}
+		count++
+	}
+	if aggregate == AggregateAvg && count > 0 {
+		return sum / float64(count), nil
+	}
+	return sum, nil
+}
+
+// fetchAggregateInstances fetches the instances of the model that match the filters, for integrators that do not
+// implement AggregateORMIntegrator.
+func (m *Model) fetchAggregateInstances(data interface{}, fields []string, filters []FilterCondition) ([]interface{}, error) {
+	fieldsToFetch := append([]string(nil), fields...)
+	for _, condition := range filters {
+		fieldsToFetch = appendMissing(fieldsToFetch, condition.Field)
+	}
+	instances, err := m.getRequestORM(data).FetchInstancesOnlyFields(m.PTR, fieldsToFetch)
+	if err != nil {
+		return nil, err
+	}
+	all, err := toInstanceSlice(instances)
+	if err != nil {
+		return nil, err
+	}
+	return filterInstances(all, filters), nil
+}
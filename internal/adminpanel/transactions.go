@@ -0,0 +1,86 @@
+package adminpanel
+
+import "reflect"
+
+// WriteMode selects how an operation writing several rows handles failures.
+type WriteMode string
+
+const (
+	// WriteModeAtomic applies every write of an operation or none of them. The writes run in a transaction when the
+	// model's integrator implements TransactionalORMIntegrator; otherwise the operation stops at the first failure.
+	WriteModeAtomic WriteMode = "atomic"
+	// WriteModeBestEffort applies each write on its own, keeping the writes that succeed when others fail.
+	WriteModeBestEffort WriteMode = "best_effort"
+)
+
+// isTransactional reports whether writes made in the given mode during the request carried by data run in a
+// transaction.
+func (m *Model) isTransactional(data interface{}, mode WriteMode) bool {
+	_, ok := m.getRequestORM(data).(TransactionalORMIntegrator)
+	return ok && mode == WriteModeAtomic
+}
+
+// runWrites runs fn with the integrator the writes of an operation must go through, bound to the context of the
+// request carried by data. In atomic mode, fn runs in a transaction when the model's integrator supports it, and
+// the transaction is rolled back if fn returns an error.
+func (m *Model) runWrites(data interface{}, mode WriteMode, fn func(orm ORMIntegrator) error) error {
+	orm := m.getRequestORM(data)
+	if transactional, ok := orm.(TransactionalORMIntegrator); ok && mode == WriteModeAtomic {
+		return transactional.WithTransaction(fn)
+	}
+	return fn(orm)
+}
+
+// writeLogs queues the log entries of an operation's writes, so they are only created for the writes that are kept.
+type writeLogs []func() error
+
+// add queues a log entry.
+func (l *writeLogs) add(createLog func() error) {
+	*l = append(*l, createLog)
+}
+
+// create creates the queued log entries in order, stopping at the first failure.
+func (l writeLogs) create() error {
+	for _, createLog := range l {
+		if err := createLog(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runLoggedWrites runs fn like runWrites and then creates the log entries fn queued: all of them when fn succeeds,
+// none when its transaction was rolled back, and those of the writes made before the failure otherwise.
+func (m *Model) runLoggedWrites(data interface{}, mode WriteMode, fn func(orm ORMIntegrator, logs *writeLogs) error) error {
+	var logs writeLogs
+	err := m.runWrites(data, mode, func(orm ORMIntegrator) error {
+		logs = nil
+		return fn(orm, &logs)
+	})
+	if err != nil && m.isTransactional(data, mode) {
+		return err
+	}
+	if logErr := logs.create(); err == nil {
+		err = logErr
+	}
+	return err
+}
+
+// getWriteORM returns the integrator a related model writes through during an operation of model m using orm: orm
+// itself when both models share an integrator, so the writes join the same transaction, and the related model's own
+// integrator bound to the request otherwise.
+func (m *Model) getWriteORM(data interface{}, related *Model, orm ORMIntegrator) ORMIntegrator {
+	if sameIntegrator(related.GetORM(), m.GetORM()) {
+		return orm
+	}
+	return related.getRequestORM(data)
+}
+
+// sameIntegrator reports whether two integrators are the same value, without panicking on uncomparable types.
+func sameIntegrator(a, b ORMIntegrator) bool {
+	typeA, typeB := reflect.TypeOf(a), reflect.TypeOf(b)
+	if typeA == nil || typeA != typeB || !typeA.Comparable() {
+		return false
+	}
+	return a == b
+}
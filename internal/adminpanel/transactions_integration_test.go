@@ -0,0 +1,181 @@
+package adminpanel_test
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// registerTransactionalModel registers a model with instances 1, 2 and 3 on a transactional integrator, on which
+// deleting instance 2 fails.
+func registerTransactionalModel(t *testing.T) (*adminpanel.Model, *transactionalORM, *adminpanel.MockWebIntegrator) {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orm := &transactionalORM{
+		Integrator: newMemoryORM(t, &adminpanel.TestModel{ID: 1}, &adminpanel.TestModel{ID: 2}, &adminpanel.TestModel{ID: 3}),
+		Models:     []interface{}{&adminpanel.TestModel{}},
+		FailDelete: func(id interface{}) bool { return id == uint(2) },
+	}
+	model, err := testApp.RegisterModel(&adminpanel.TestModel{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model, orm, panel.Web.(*adminpanel.MockWebIntegrator)
+}
+
+// storedIDs returns the IDs of the stored test models.
+func storedIDs(t *testing.T, orm adminpanel.ORMIntegrator) []uint {
+	var ids []uint
+	for _, instance := range storedRows[adminpanel.TestModel](t, orm) {
+		ids = append(ids, instance.ID)
+	}
+	return ids
+}
+
+func TestRegisterModel_DefaultWriteModes(t *testing.T) {
+	model, _, _ := registerTransactionalModel(t)
+
+	if model.SaveMode != adminpanel.WriteModeAtomic {
+		t.Errorf("expected forms to save atomically, got %q", model.SaveMode)
+	}
+	if model.BulkDeleteMode != adminpanel.WriteModeBestEffort {
+		t.Errorf("expected bulk deletes to be best effort, got %q", model.BulkDeleteMode)
+	}
+}
+
+func TestModel_HandleBulkDeleteAJAX_Atomic(t *testing.T) {
+	model, orm, web := registerTransactionalModel(t)
+	model.BulkDeleteMode = adminpanel.WriteModeAtomic
+
+	_ = model.HandleBulkDeleteAJAX(map[string]interface{}{"ids": []interface{}{"1", "2", "3"}})
+	if web.JSONStatus != http.StatusBadRequest {
+		t.Fatalf("expected %v, got %v: %+v", http.StatusBadRequest, web.JSONStatus, web.JSONResponse)
+	}
+	if ids := storedIDs(t, orm); len(ids) != 3 || orm.Rollbacks != 1 {
+		t.Errorf("expected the transaction to be rolled back, got instances %v and %d rollbacks", ids, orm.Rollbacks)
+	}
+	if got := countLogs(t, model, logging.LogStoreLevelDelete); got != 0 {
+		t.Errorf("expected no delete log entries, got %d", got)
+	}
+
+	_ = model.HandleBulkDeleteAJAX(map[string]interface{}{"ids": []interface{}{"1", "3"}})
+	if web.JSONStatus != http.StatusOK || orm.Commits != 1 {
+		t.Fatalf("expected the transaction to be committed, got %v: %+v", web.JSONStatus, web.JSONResponse)
+	}
+	if ids := storedIDs(t, orm); !reflect.DeepEqual(ids, []uint{2}) {
+		t.Errorf("expected instances 1 and 3 to be deleted, got instances %v", ids)
+	}
+	if got := countLogs(t, model, logging.LogStoreLevelDelete); got != 2 {
+		t.Errorf("expected 2 delete log entries, got %d", got)
+	}
+}
+
+func TestModel_HandleBulkDeleteAJAX_BestEffort(t *testing.T) {
+	model, orm, web := registerTransactionalModel(t)
+
+	_ = model.HandleBulkDeleteAJAX(map[string]interface{}{"ids": []interface{}{"1", "2", "3"}})
+	if web.JSONStatus != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %+v", http.StatusOK, web.JSONStatus, web.JSONResponse)
+	}
+	if ids := storedIDs(t, orm); !reflect.DeepEqual(ids, []uint{2}) {
+		t.Errorf("expected instances 1 and 3 to be deleted, got instances %v", ids)
+	}
+	if orm.Commits != 0 || orm.Rollbacks != 0 {
+		t.Error("expected best effort deletes not to run in a transaction")
+	}
+	if got := countLogs(t, model, logging.LogStoreLevelDelete); got != 2 {
+		t.Errorf("expected 2 delete log entries, got %d", got)
+	}
+}
+
+func TestModel_HandleActionAJAX_Transaction(t *testing.T) {
+	model, orm, web := registerTransactionalModel(t)
+	err := model.RegisterAction(adminpanel.ModelAction{
+		Name: "purge",
+		Func: func(tx adminpanel.ORMIntegrator, ids []interface{}, _ interface{}) (string, error) {
+			if tx == adminpanel.ORMIntegrator(orm) {
+				t.Error("expected the action to write through the transaction")
+			}
+			for _, id := range ids {
+				if err := tx.DeleteByID(model.PTR, id); err != nil {
+					return "", err
+				}
+			}
+			return "purged", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if model.Actions[0].Mode != adminpanel.WriteModeAtomic {
+		t.Errorf("expected actions to default to %q, got %q", adminpanel.WriteModeAtomic, model.Actions[0].Mode)
+	}
+
+	_ = model.HandleActionAJAX(map[string]interface{}{"action": "purge", "ids": []interface{}{"1", "2"}})
+	if web.JSONStatus != http.StatusInternalServerError {
+		t.Fatalf("expected %v, got %v: %+v", http.StatusInternalServerError, web.JSONStatus, web.JSONResponse)
+	}
+	if ids := storedIDs(t, orm); len(ids) != 3 || orm.Rollbacks != 1 {
+		t.Errorf("expected the action to be rolled back, got instances %v", ids)
+	}
+	if got := countLogs(t, model, logging.LogStoreLevelAction); got != 0 {
+		t.Errorf("expected no action log entries, got %d", got)
+	}
+}
+
+func TestModel_GetAddHandler_RollsBackInlines(t *testing.T) {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	orm := &transactionalORM{
+		Integrator: newMemoryORM(t),
+		Models:     []interface{}{&adminpanel.InlineOrder{}, &adminpanel.InlineItem{}},
+		FailCreate: func(instance interface{}) bool {
+			item, ok := instance.(*adminpanel.InlineItem)
+			return ok && item.Name == "Broken"
+		},
+	}
+	shop, _ := panel.RegisterApp("Shop", "Shop", orm)
+	order, err := shop.RegisterModel(&adminpanel.InlineOrder{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	item, err := shop.RegisterModel(&adminpanel.InlineItem{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = order.RegisterInline(item, "OrderID"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	code, body := order.GetAddHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Form: map[string][]string{
+			"Number":            {"B1"},
+			"InlineItem-TOTAL":  {"2"},
+			"InlineItem-0-id":   {""},
+			"InlineItem-0-Name": {"Hinge"},
+			"InlineItem-1-id":   {""},
+			"InlineItem-1-Name": {"Broken"},
+		},
+	})
+	if code != http.StatusInternalServerError {
+		t.Fatalf("expected %v, got %v: %s", http.StatusInternalServerError, code, body)
+	}
+	orders, items := storedRows[adminpanel.InlineOrder](t, orm), storedRows[adminpanel.InlineItem](t, orm)
+	if len(orders) != 0 || len(items) != 0 || orm.Rollbacks != 1 {
+		t.Errorf("expected the order and its items to be rolled back, got %v and %v", orders, items)
+	}
+	if got := countLogs(t, order, logging.LogStoreLevelCreate); got != 0 {
+		t.Errorf("expected no create log entries, got %d", got)
+	}
+}
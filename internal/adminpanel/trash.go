@@ -0,0 +1,290 @@
+package adminpanel
+
+import (
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// isSoftDeleteType reports whether a field of type t can mark instances as deleted: a *time.Time holding the deletion
+// time, or a bool.
+func isSoftDeleteType(t reflect.Type) bool {
+	return t == reflect.TypeOf(&time.Time{}) || t.Kind() == reflect.Bool
+}
+
+// GetTrashLink returns the relative URL path to the trash of the model.
+func (m *Model) GetTrashLink() string {
+	return fmt.Sprintf("%s/trash", m.GetLink())
+}
+
+// GetFullTrashLink returns the full URL path to the trash of the model, including the admin prefix.
+func (m *Model) GetFullTrashLink() string {
+	return m.App.Panel.Config.GetLink(m.GetTrashLink())
+}
+
+// GetFullRestoreLink returns the full URL restoring the instance from the trash.
+func (i *Instance) GetFullRestoreLink() string {
+	return i.Model.App.Panel.Config.GetLink(i.Model.getInstanceLink(i.InstanceID, "restore"))
+}
+
+// GetFullPurgeLink returns the full URL permanently deleting the instance from the trash.
+func (i *Instance) GetFullPurgeLink() string {
+	return i.Model.App.Panel.Config.GetLink(i.Model.getInstanceLink(i.InstanceID, "purge"))
+}
+
+// CreateRestoreLog creates a log entry when the instance is restored from the trash.
+func (i *Instance) CreateRestoreLog(ctx interface{}) error {
+	return i.Model.App.Panel.Config.CreateLog(ctx, logging.LogStoreLevelRestore, fmt.Sprintf("%s | %s", i.Model.App.Name, i.Model.DisplayName), i.InstanceID, i.GetRepr(), "")
+}
+
+// CreatePurgeLog creates a log entry when the instance is permanently deleted from the trash.
+func (i *Instance) CreatePurgeLog(ctx interface{}) error {
+	return i.Model.App.Panel.Config.CreateLog(ctx, logging.LogStoreLevelPurge, fmt.Sprintf("%s | %s", i.Model.App.Name, i.Model.DisplayName), i.InstanceID, i.GetRepr(), "")
+}
+
+// trashCondition returns the filter matching the trashed instances of a soft-deleted model, or the live ones when
+// trashed is false.
+func (m *Model) trashCondition(trashed bool) FilterCondition {
+	field, _ := reflect.TypeOf(m.PTR).Elem().FieldByName(m.SoftDeleteField)
+	if field.Type.Kind() == reflect.Bool {
+		return FilterCondition{Field: m.SoftDeleteField, Operator: FilterExact, Value: trashed}
+	}
+	return FilterCondition{Field: m.SoftDeleteField, Operator: FilterIsNull, Value: !trashed}
+}
+
+// withoutTrashed adds the condition excluding trashed instances to filters, for soft-deleted models.
+func (m *Model) withoutTrashed(filters []FilterCondition) []FilterCondition {
+	if m.SoftDeleteField == "" {
+		return filters
+	}
+	return append(filters, m.trashCondition(false))
+}
+
+// isTrashed reports whether an instance of a soft-deleted model is in the trash.
+func (m *Model) isTrashed(instance interface{}) bool {
+	field := fieldValueOf(instance, m.SoftDeleteField)
+	if field.Kind() == reflect.Bool {
+		return field.Bool()
+	}
+	return field.IsValid()
+}
+
+// fetchLiveInstance fetches the given fields of the instance with the given ID through orm, or every field when fields
+// is nil. It fails with an error wrapping ErrInstanceNotFound when the instance is missing or, for soft-deleted
+// models, in the trash, the way fetchTrashedInstance rejects live instances.
+func (m *Model) fetchLiveInstance(orm ORMIntegrator, instanceID interface{}, fields []string) (interface{}, error) {
+	var instance interface{}
+	var err error
+	if fields == nil {
+		instance, err = orm.FetchInstance(m.PTR, instanceID)
+	} else {
+		if m.SoftDeleteField != "" {
+			fields = appendMissing(append([]string(nil), fields...), m.SoftDeleteField)
+		}
+		instance, err = orm.FetchInstanceOnlyFields(m.PTR, instanceID, fields)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isNilInstance(instance) || (m.SoftDeleteField != "" && m.isTrashed(instance)) {
+		return nil, fmt.Errorf("%w: %s %v", ErrInstanceNotFound, m.DisplayName, instanceID)
+	}
+	return instance, nil
+}
+
+// setTrashed moves the instance with the given ID to the trash, or restores it from the trash, by updating its
+// soft-delete field only.
+func (m *Model) setTrashed(orm ORMIntegrator, id interface{}, trashed bool) error {
+	instance := reflect.New(reflect.TypeOf(m.PTR).Elem())
+	field := instance.Elem().FieldByName(m.SoftDeleteField)
+	switch {
+	case field.Kind() == reflect.Bool:
+		field.SetBool(trashed)
+	case trashed:
+		now := time.Now().UTC()
+		field.Set(reflect.ValueOf(&now))
+	}
+	return orm.UpdateInstanceOnlyFields(instance.Interface(), []string{m.SoftDeleteField}, id)
+}
+
+// deleteInstance moves the instance with the given ID to the trash for soft-deleted models, and calls hardDelete
+// otherwise. Instances already in the trash are reported as not found, so their deletion time is kept.
+func (m *Model) deleteInstance(orm ORMIntegrator, id interface{}, hardDelete func(model interface{}, id interface{}) error) error {
+	if m.SoftDeleteField == "" {
+		return hardDelete(m.PTR, id)
+	}
+	if _, err := m.fetchLiveInstance(orm, id, []string{m.SoftDeleteField}); err != nil {
+		return err
+	}
+	return m.setTrashed(orm, id, true)
+}
+
+// GetTrashHandler returns the HTTP handler function for the trash of a soft-deleted model, listing its deleted
+// instances with links to restore or purge them. It is open to users allowed to read the model and to restore or
+// purge its instances.
+func (m *Model) GetTrashHandler() HandlerFunc {
+	return func(data interface{}) (uint, string) {
+		allowed, err := m.hasTrashPermission(data)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		if !allowed {
+			return GetErrorHTML(http.StatusForbidden, fmt.Errorf("forbidden"))
+		}
+
+		apps, err := GetAppsWithReadPermissions(m.App.Panel, data)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+
+		params := getListParams(m, data)
+		params.Filters = nil
+		params.Trash = true
+		query := params.query(m, time.Now())
+		query.Fields = appendMissing(query.Fields, m.SoftDeleteField)
+		pagedInstances, totalCount, exactCount, err := fetchListPage(m, data, query)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		totalPages := (totalCount + params.PerPage - 1) / params.PerPage
+
+		cleanInstances, err := m.buildTrashInstances(data, pagedInstances)
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+
+		html, err := m.App.Panel.RenderPage(data, "trash", map[string]interface{}{
+			"admin":        m.App.Panel,
+			"apps":         apps,
+			"model":        m,
+			"instances":    cleanInstances,
+			"totalCount":   totalCount,
+			"exactCount":   exactCount,
+			"totalPages":   totalPages,
+			"currentPage":  params.Page,
+			"columns":      getListColumns(m, params),
+			"ordering":     params.Ordering,
+			"pageLinks":    getListPageLinks(m, params, totalPages),
+			"search":       params.Search,
+			"searchInputs": listParams{PerPage: params.PerPage, Ordering: params.Ordering}.hiddenInputs(),
+			"navBarItems":  m.App.Panel.Config.GetNavBarItems(data),
+		})
+		if err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		return http.StatusOK, html
+	}
+}
+
+// hasTrashPermission reports whether the user may open the trash of the model.
+func (m *Model) hasTrashPermission(data interface{}) (bool, error) {
+	checker := m.App.Panel.PermissionChecker
+	allowed, err := checker.HasModelReadPermission(m.App.Name, m.Name, data)
+	if err != nil || !allowed {
+		return false, err
+	}
+	allowed, err = checker.HasModelRestorePermission(m.App.Name, m.Name, data)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	return checker.HasModelPurgePermission(m.App.Name, m.Name, data)
+}
+
+// buildTrashInstances wraps trashed instances with their restore and purge permissions.
+func (m *Model) buildTrashInstances(data interface{}, instances []interface{}) ([]Instance, error) {
+	checker := m.App.Panel.PermissionChecker
+	clean := make([]Instance, len(instances))
+	for i, instance := range instances {
+		id, err := m.GetPrimaryKeyValue(instance)
+		if err != nil {
+			return nil, err
+		}
+		restoreAllowed, err := checker.HasInstanceRestorePermission(m.App.Name, m.Name, id, data)
+		if err != nil {
+			return nil, err
+		}
+		purgeAllowed, err := checker.HasInstancePurgePermission(m.App.Name, m.Name, id, data)
+		if err != nil {
+			return nil, err
+		}
+		clean[i] = Instance{
+			InstanceID:  id,
+			Data:        instance,
+			Model:       m,
+			Permissions: Permissions{Read: true, Restore: restoreAllowed, Purge: purgeAllowed},
+		}
+	}
+	return clean, nil
+}
+
+// fetchTrashedInstance parses the instance ID of a restore or purge request and fetches the instance, checking the
+// user's permission with hasPermission and that the instance is in the trash.
+func (m *Model) fetchTrashedInstance(data interface{}, hasPermission func(appName, modelName string, instanceID interface{}, data interface{}) (bool, error)) (*Instance, uint, error) {
+	instanceIDStr := m.App.Panel.Web.GetPathParam(data, "id")
+	if instanceIDStr == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("instance id is required")
+	}
+	instanceID, err := m.parseInstanceID(instanceIDStr)
+	if err != nil {
+		return nil, getRequestErrorCode(err), err
+	}
+
+	allowed, err := hasPermission(m.App.Name, m.Name, instanceID, data)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if !allowed {
+		return nil, http.StatusForbidden, ErrPermissionDenied
+	}
+
+	instanceData, err := m.getRequestORM(data).FetchInstance(m.PTR, instanceID)
+	if err != nil {
+		return nil, getRequestErrorCode(err), err
+	}
+	if isNilInstance(instanceData) || !m.isTrashed(instanceData) {
+		return nil, http.StatusNotFound, fmt.Errorf("instance %v is not in the trash", instanceID)
+	}
+	return &Instance{InstanceID: instanceID, Data: instanceData, Model: m}, 0, nil
+}
+
+// GetRestoreHandler returns the HTTP handler function moving an instance out of the trash, back to the list view.
+func (m *Model) GetRestoreHandler() HandlerFunc {
+	return func(data interface{}) (uint, string) {
+		instance, code, err := m.fetchTrashedInstance(data, m.App.Panel.PermissionChecker.HasInstanceRestorePermission)
+		if err != nil {
+			return GetErrorHTML(code, err)
+		}
+		if err = m.setTrashed(m.getRequestORM(data), instance.InstanceID, false); err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		if err = instance.CreateRestoreLog(data); err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		if err = m.App.Panel.AddFlash(data, FlashSuccess, fmt.Sprintf("The %s \"%s\" was restored.", m.DisplayName, instance.GetRepr())); err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		return http.StatusSeeOther, m.GetFullTrashLink()
+	}
+}
+
+// GetPurgeHandler returns the HTTP handler function permanently deleting an instance from the trash.
+func (m *Model) GetPurgeHandler() HandlerFunc {
+	return func(data interface{}) (uint, string) {
+		instance, code, err := m.fetchTrashedInstance(data, m.App.Panel.PermissionChecker.HasInstancePurgePermission)
+		if err != nil {
+			return GetErrorHTML(code, err)
+		}
+		if err = m.getRequestORM(data).DeleteInstance(m.PTR, instance.InstanceID); err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		if err = instance.CreatePurgeLog(data); err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		if err = m.App.Panel.AddFlash(data, FlashSuccess, fmt.Sprintf("The %s \"%s\" was deleted permanently.", m.DisplayName, instance.GetRepr())); err != nil {
+			return GetErrorHTML(http.StatusInternalServerError, err)
+		}
+		return http.StatusSeeOther, m.GetFullTrashLink()
+	}
+}
@@ -0,0 +1,175 @@
+package adminpanel_test
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"github.com/ovnicraft/go-advanced-admin/internal/logging"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// registerTrashModel registers notes with a live note 1 and a trashed note 2.
+func registerTrashModel(t *testing.T) (*adminpanel.Model, *recordingORM) {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deletedAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	orm := &recordingORM{Integrator: newMemoryORM(t,
+		&adminpanel.TrashNote{ID: 1, Title: "Live note"},
+		&adminpanel.TrashNote{ID: 2, Title: "Old note", DeletedAt: &deletedAt},
+	)}
+	model, err := testApp.RegisterModel(&adminpanel.TrashNote{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model, orm
+}
+
+func TestModel_SoftDelete(t *testing.T) {
+	model, orm := registerTrashModel(t)
+
+	code, body := model.GetViewHandler()(map[string]string{})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if !strings.Contains(body, "Live note") || strings.Contains(body, "Old note") {
+		t.Error("expected the list view to hide trashed instances")
+	}
+	expected := adminpanel.FilterCondition{Field: "DeletedAt", Operator: adminpanel.FilterIsNull, Value: true}
+	if filters := orm.Queries[0].Filters; len(filters) != 1 || filters[0] != expected {
+		t.Errorf("expected the list query to exclude trashed instances, got %v", filters)
+	}
+
+	if err := model.HandleDeleteAJAX(map[string]string{"id": "1"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if notes := storedRows[adminpanel.TrashNote](t, orm); len(notes) != 2 || notes[0].DeletedAt == nil {
+		t.Error("expected the instance to be moved to the trash instead of deleted")
+	}
+	if !reflect.DeepEqual(orm.UpdatedFields, [][]string{{"DeletedAt"}}) {
+		t.Errorf("expected the soft-delete field only to be updated, got %v", orm.UpdatedFields)
+	}
+	if got := countLogs(t, model, logging.LogStoreLevelDelete); got != 1 {
+		t.Errorf("expected 1 delete log entry, got %d", got)
+	}
+}
+
+func TestModel_GetTrashHandler(t *testing.T) {
+	model, _ := registerTrashModel(t)
+
+	code, body := model.GetTrashHandler()(map[string]string{})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if strings.Contains(body, "Live note") || !strings.Contains(body, "Old note") {
+		t.Error("expected the trash to list trashed instances only")
+	}
+	for _, link := range []string{"/admin/a/TestApp/TrashNote/2/restore", "/admin/a/TestApp/TrashNote/2/purge"} {
+		if !strings.Contains(body, link) {
+			t.Errorf("expected the trash to link to %s", link)
+		}
+	}
+
+	model.App.Panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return *request.Action != adminpanel.RestoreAction && *request.Action != adminpanel.PurgeAction, nil
+	}
+	if code, _ = model.GetTrashHandler()(map[string]string{}); code != http.StatusForbidden {
+		t.Errorf("expected %v without restore and purge permissions, got %v", http.StatusForbidden, code)
+	}
+}
+
+func TestModel_RestoreAndPurge(t *testing.T) {
+	model, orm := registerTrashModel(t)
+
+	code, _ := model.GetRestoreHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Params: map[string]string{"id": "1"}})
+	if code != http.StatusNotFound {
+		t.Errorf("expected %v when restoring a live instance, got %v", http.StatusNotFound, code)
+	}
+
+	code, link := model.GetRestoreHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Params: map[string]string{"id": "2"}})
+	if code != http.StatusSeeOther || link != model.GetFullTrashLink() {
+		t.Fatalf("expected a redirect to the trash, got %v: %s", code, link)
+	}
+	if storedRows[adminpanel.TrashNote](t, orm)[1].DeletedAt != nil {
+		t.Error("expected the instance to be restored")
+	}
+	if got := countLogs(t, model, logging.LogStoreLevelRestore); got != 1 {
+		t.Errorf("expected 1 restore log entry, got %d", got)
+	}
+
+	code, _ = model.GetPurgeHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Params: map[string]string{"id": "2"}})
+	if code != http.StatusNotFound {
+		t.Errorf("expected %v when purging a live instance, got %v", http.StatusNotFound, code)
+	}
+
+	if err := model.HandleDeleteAJAX(map[string]string{"id": "2"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	model.App.Panel.PermissionChecker = func(request adminpanel.PermissionRequest, _ interface{}) (bool, error) {
+		return *request.Action != adminpanel.PurgeAction, nil
+	}
+	if code, _ = model.GetPurgeHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Params: map[string]string{"id": "2"}}); code != http.StatusForbidden {
+		t.Errorf("expected %v without purge permission, got %v", http.StatusForbidden, code)
+	}
+
+	model.App.Panel.PermissionChecker = adminpanel.MockPermissionFunc
+	code, _ = model.GetPurgeHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Params: map[string]string{"id": "2"}})
+	if code != http.StatusSeeOther {
+		t.Fatalf("expected %v, got %v", http.StatusSeeOther, code)
+	}
+	if notes := storedRows[adminpanel.TrashNote](t, orm); len(notes) != 1 || notes[0].ID != 1 {
+		t.Errorf("expected the instance to be deleted for good, got %v", notes)
+	}
+	if got := countLogs(t, model, logging.LogStoreLevelPurge); got != 1 {
+		t.Errorf("expected 1 purge log entry, got %d", got)
+	}
+	for name, handler := range map[string]adminpanel.HandlerFunc{"restoring": model.GetRestoreHandler(), "purging": model.GetPurgeHandler()} {
+		if code, _ = handler(&adminpanel.MockRequest{Method: http.MethodPost, Params: map[string]string{"id": "2"}}); code != http.StatusNotFound {
+			t.Errorf("expected %v when %s a missing instance, got %v", http.StatusNotFound, name, code)
+		}
+	}
+}
+
+func TestModel_TrashedInstancesNotFound(t *testing.T) {
+	model, orm := registerTrashModel(t)
+	deletedAt := *storedRows[adminpanel.TrashNote](t, orm)[1].DeletedAt
+	trashed := map[string]string{"id": "2"}
+
+	if code, _ := model.GetInstanceViewHandler()(&adminpanel.MockRequest{Method: http.MethodGet, Params: trashed}); code != http.StatusNotFound {
+		t.Errorf("expected %v when viewing a trashed instance, got %v", http.StatusNotFound, code)
+	}
+	code, _ := model.GetEditHandler()(&adminpanel.MockRequest{Method: http.MethodPost, Params: trashed, Form: map[string][]string{"Title": {"Edited"}}})
+	if code != http.StatusNotFound {
+		t.Errorf("expected %v when editing a trashed instance, got %v", http.StatusNotFound, code)
+	}
+	if status, _ := callAPI(t, model, model.HandleAPIRetrieve, &adminpanel.MockRequest{Method: http.MethodGet, Params: trashed}); status != http.StatusNotFound {
+		t.Errorf("expected %v when retrieving a trashed instance, got %v", http.StatusNotFound, status)
+	}
+	status, _ := callAPI(t, model, model.HandleAPIUpdate, &adminpanel.MockRequest{Method: http.MethodPatch, Params: trashed, Body: map[string]interface{}{"Title": "Edited"}})
+	if status != http.StatusNotFound {
+		t.Errorf("expected %v when patching a trashed instance, got %v", http.StatusNotFound, status)
+	}
+	if status, _ = callAPI(t, model, model.HandleAPIDelete, &adminpanel.MockRequest{Method: http.MethodDelete, Params: trashed}); status != http.StatusNotFound {
+		t.Errorf("expected %v when deleting a trashed instance, got %v", http.StatusNotFound, status)
+	}
+	note := storedRows[adminpanel.TrashNote](t, orm)[1]
+	if note.Title != "Old note" || note.DeletedAt == nil || !note.DeletedAt.Equal(deletedAt) {
+		t.Errorf("expected the trashed instance to be left untouched, got %+v", note)
+	}
+
+	field := &adminpanel.ForeignKeyField{Panel: model.App.Panel, Related: "TestApp.TrashNote"}
+	if errs, err := form.FieldValueIsValid(field, uint(2)); err != nil || len(errs) != 1 {
+		t.Errorf("expected a trashed instance to be rejected as a foreign key target, got %v %v", errs, err)
+	}
+	if errs, err := form.FieldValueIsValid(field, uint(1)); err != nil || len(errs) != 0 {
+		t.Errorf("expected a live instance to be accepted as a foreign key target, got %v %v", errs, err)
+	}
+}
@@ -0,0 +1,49 @@
+package adminpanel
+
+import (
+	"testing"
+	"time"
+)
+
+type TrashNote struct {
+	ID        uint
+	Title     string
+	DeletedAt *time.Time `admin:"softDelete"`
+}
+
+func registerTrashTestModel(t *testing.T) *Model {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	model, err := testApp.RegisterModel(&TrashNote{}, &MockORMIntegrator{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model
+}
+
+func TestRegisterModel_SoftDeleteField(t *testing.T) {
+	model := registerTrashTestModel(t)
+	if model.SoftDeleteField != "DeletedAt" {
+		t.Errorf("expected the soft-delete field to be DeletedAt, got %q", model.SoftDeleteField)
+	}
+	routes := model.App.Panel.Web.(*MockWebIntegrator).Routes
+	for _, route := range []string{"GET /admin/a/TestApp/TrashNote/trash", "POST /admin/a/TestApp/TrashNote/:id/restore", "POST /admin/a/TestApp/TrashNote/:id/purge"} {
+		if !containsString(routes, route) {
+			t.Errorf("expected route %s to be registered", route)
+		}
+	}
+
+	type badMarker struct {
+		ID        uint
+		DeletedAt time.Time `admin:"softDelete"`
+	}
+	if _, err := model.App.RegisterModel(&badMarker{}, &MockORMIntegrator{}); err == nil {
+		t.Error("expected an error for a soft-delete field that cannot be nil")
+	}
+}
@@ -0,0 +1,183 @@
+package adminpanel
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// versionFormName is the name of the hidden input carrying the version an edit form was rendered with.
+const versionFormName = "_version"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// isVersionType reports whether a field of type t can be used as a version: an integer counter or an updated-at
+// timestamp.
+func isVersionType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return t == timeType
+}
+
+// VersionConflict describes a field whose current value differs from the value submitted with a stale edit form.
+type VersionConflict struct {
+	Field  string
+	Yours  string
+	Theirs string
+}
+
+// getVersion returns the value of the version field of an instance.
+func (m *Model) getVersion(instance interface{}) interface{} {
+	return reflect.ValueOf(instance).Elem().FieldByName(m.VersionField).Interface()
+}
+
+// setNextVersion sets the version field of an instance to the version following current: the next integer, or the
+// current time for timestamps.
+func (m *Model) setNextVersion(instance interface{}, current interface{}) {
+	field := reflect.ValueOf(instance).Elem().FieldByName(m.VersionField)
+	if field.Type() == timeType {
+		field.Set(reflect.ValueOf(time.Now().UTC().Truncate(time.Microsecond)))
+		return
+	}
+	if current == nil {
+		current = reflect.Zero(field.Type()).Interface()
+	}
+	next := reflect.ValueOf(current)
+	if field.CanInt() {
+		field.SetInt(next.Int() + 1)
+	} else {
+		field.SetUint(next.Uint() + 1)
+	}
+}
+
+// setInitialVersion sets the version field of a new instance, unless it was already given a value.
+func (m *Model) setInitialVersion(instance interface{}) {
+	if m.VersionField == "" || !reflect.ValueOf(instance).Elem().FieldByName(m.VersionField).IsZero() {
+		return
+	}
+	m.setNextVersion(instance, nil)
+}
+
+// encodeVersion formats a version for the hidden input of the edit form.
+func encodeVersion(version interface{}) string {
+	switch version := version.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return version.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(version)
+}
+
+// decodeVersion parses a version submitted with the edit form into the type of the model's version field.
+func (m *Model) decodeVersion(value string) (interface{}, error) {
+	field, _ := reflect.TypeOf(m.PTR).Elem().FieldByName(m.VersionField)
+	version := reflect.New(field.Type).Elem()
+	switch {
+	case field.Type == timeType:
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version: %w", err)
+		}
+		version.Set(reflect.ValueOf(parsed))
+	case version.CanInt():
+		parsed, err := strconv.ParseInt(value, 10, field.Type.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid version: %w", err)
+		}
+		version.SetInt(parsed)
+	default:
+		parsed, err := strconv.ParseUint(value, 10, field.Type.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid version: %w", err)
+		}
+		version.SetUint(parsed)
+	}
+	return version.Interface(), nil
+}
+
+// versionsEqual reports whether two versions are the same, comparing timestamps by instant.
+func versionsEqual(a, b interface{}) bool {
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	}
+	return a == b
+}
+
+// updateIfVersion updates the given fields of an instance and bumps its version, provided the stored version still
+// equals version. It returns ErrVersionConflict otherwise, and an error wrapping ErrInstanceNotFound if the instance
+// is gone. Integrators implementing VersionedORMIntegrator do so in a single operation, which is the only way to
+// detect every concurrent edit. For others the stored version is read first, in a transaction with the update if the
+// integrator implements TransactionalORMIntegrator, so that edits made between the read and the update are only
+// detected as far as the isolation level of the transaction allows.
+func (m *Model) updateIfVersion(orm ORMIntegrator, instance interface{}, fields []string, instanceID interface{}, version interface{}) error {
+	m.setNextVersion(instance, version)
+	fields = append(fields, m.VersionField)
+
+	if versioned, ok := orm.(VersionedORMIntegrator); ok {
+		updated, err := versioned.UpdateInstanceOnlyFieldsIfVersion(instance, fields, instanceID, m.VersionField, version)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrVersionConflict
+		}
+		return nil
+	}
+
+	update := func(orm ORMIntegrator) error {
+		stored, err := orm.FetchInstanceOnlyFields(m.PTR, instanceID, []string{m.VersionField})
+		if err != nil {
+			return err
+		}
+		if isNilInstance(stored) {
+			return fmt.Errorf("%w: %s %v", ErrInstanceNotFound, m.DisplayName, instanceID)
+		}
+		if !versionsEqual(m.getVersion(stored), version) {
+			return ErrVersionConflict
+		}
+		return orm.UpdateInstanceOnlyFields(instance, fields, instanceID)
+	}
+	if transactional, ok := orm.(TransactionalORMIntegrator); ok {
+		return transactional.WithTransaction(update)
+	}
+	return update(orm)
+}
+
+// getVersionConflicts compares the values submitted with a stale edit form with the current values of the instance
+// and returns the fields that differ.
+func (m *Model) getVersionConflicts(current interface{}, submitted map[string]interface{}) []VersionConflict {
+	currentValues := m.getEditInitialValues(current)
+	var conflicts []VersionConflict
+	for _, field := range m.Fields {
+		theirs, ok := currentValues[field.Name]
+		if !ok {
+			continue
+		}
+		yours := formatConflictValue(submitted[field.Name])
+		if theirsText := formatConflictValue(theirs); yours != theirsText {
+			conflicts = append(conflicts, VersionConflict{Field: field.DisplayName, Yours: yours, Theirs: theirsText})
+		}
+	}
+	return conflicts
+}
+
+// formatConflictValue formats a field value for the conflict diff, dereferencing pointers.
+func formatConflictValue(value interface{}) string {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,166 @@
+package adminpanel_test
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func registerVersionedModel(t *testing.T, orm adminpanel.ORMIntegrator) *adminpanel.Model {
+	panel, err := adminpanel.NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	model, err := testApp.RegisterModel(&adminpanel.VersionedArticle{}, orm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model
+}
+
+// storedArticle returns the article stored by the integrator.
+func storedArticle(t *testing.T, orm adminpanel.ORMIntegrator) *adminpanel.VersionedArticle {
+	return storedRows[adminpanel.VersionedArticle](t, orm)[0]
+}
+
+func TestModel_GetEditHandler_Version(t *testing.T) {
+	orm := newMemoryORM(t, &adminpanel.VersionedArticle{ID: 1, Title: "Draft", Version: 3})
+	model := registerVersionedModel(t, orm)
+
+	code, body := model.GetEditHandler()(&adminpanel.MockRequest{Method: http.MethodGet, Params: map[string]string{"id": "1"}})
+	if code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %s", http.StatusOK, code, body)
+	}
+	if !strings.Contains(body, `name="_version" value="3"`) {
+		t.Error("expected the version to be carried as a hidden value")
+	}
+
+	code, body = model.GetEditHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Params: map[string]string{"id": "1"},
+		Form:   map[string][]string{"Title": {"Final"}, "_version": {"3"}},
+	})
+	if code != http.StatusSeeOther {
+		t.Fatalf("expected %v, got %v: %s", http.StatusSeeOther, code, body)
+	}
+	if article := storedArticle(t, orm); article.Title != "Final" || article.Version != 4 {
+		t.Errorf("expected the title to be saved and the version bumped, got %+v", article)
+	}
+
+	code, body = model.GetEditHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Params: map[string]string{"id": "1"},
+		Form:   map[string][]string{"Title": {"Final"}, "_version": {"three"}},
+	})
+	if code != http.StatusBadRequest {
+		t.Errorf("expected %v for an invalid version, got %v %s", http.StatusBadRequest, code, body)
+	}
+
+	code, body = model.GetEditHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Params: map[string]string{"id": "1"},
+		Form:   map[string][]string{"Title": {"Unversioned"}},
+	})
+	if code != http.StatusBadRequest {
+		t.Errorf("expected %v without a version, got %v: %s", http.StatusBadRequest, code, body)
+	}
+	if article := storedArticle(t, orm); article.Title == "Unversioned" {
+		t.Errorf("expected a post without a version not to be saved, got %+v", article)
+	}
+}
+
+func TestModelEditForm_Save_WithoutVersion(t *testing.T) {
+	orm := newMemoryORM(t, &adminpanel.VersionedArticle{ID: 1, Title: "Draft", Version: 3})
+	model := registerVersionedModel(t, orm)
+
+	formInstance, err := model.NewEditForm(nil, uint(1))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = formInstance.Save(map[string]form.HTMLType{"ID": "1", "Title": "Inline"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if article := storedArticle(t, orm); article.Title != "Inline" || article.Version != 4 {
+		t.Errorf("expected the version to be bumped and saved, got %+v", article)
+	}
+}
+
+func TestModel_GetEditHandler_VersionConflict(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		orm  func(store adminpanel.ORMIntegrator) adminpanel.ORMIntegrator
+	}{
+		{"atomic", func(store adminpanel.ORMIntegrator) adminpanel.ORMIntegrator { return store }},
+		{"fallback", func(store adminpanel.ORMIntegrator) adminpanel.ORMIntegrator { return plainORM{store} }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			store := newMemoryORM(t, &adminpanel.VersionedArticle{ID: 1, Title: "Published by Bob", Version: 4})
+			model := registerVersionedModel(t, test.orm(store))
+
+			code, body := model.GetEditHandler()(&adminpanel.MockRequest{
+				Method: http.MethodPost,
+				Params: map[string]string{"id": "1"},
+				Form:   map[string][]string{"Title": {"Edited by Alice"}, "_version": {"3"}},
+			})
+			if code != http.StatusConflict {
+				t.Fatalf("expected %v, got %v: %s", http.StatusConflict, code, body)
+			}
+			if article := storedArticle(t, store); article.Title != "Published by Bob" || article.Version != 4 {
+				t.Errorf("expected the other changes not to be overwritten, got %+v", article)
+			}
+			for _, expected := range []string{"Edited by Alice", "Published by Bob", `name="_version" value="4"`} {
+				if !strings.Contains(body, expected) {
+					t.Errorf("expected the conflict page to contain %q", expected)
+				}
+			}
+		})
+	}
+}
+
+// plainTransactionalORM runs transactions over a transactional integrator, hiding the optional extensions of the
+// integrator and its transactions.
+type plainTransactionalORM struct {
+	plainORM
+	transactional *transactionalORM
+}
+
+func (p plainTransactionalORM) WithTransaction(fn func(tx adminpanel.ORMIntegrator) error) error {
+	return p.transactional.WithTransaction(func(tx adminpanel.ORMIntegrator) error {
+		return fn(plainORM{tx})
+	})
+}
+
+func TestModel_GetEditHandler_VersionTransaction(t *testing.T) {
+	transactional := &transactionalORM{
+		Integrator: newMemoryORM(t, &adminpanel.VersionedArticle{ID: 1, Title: "Draft", Version: 3}),
+		Models:     []interface{}{&adminpanel.VersionedArticle{}},
+	}
+	model := registerVersionedModel(t, plainTransactionalORM{plainORM{transactional}, transactional})
+
+	code, body := model.GetEditHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Params: map[string]string{"id": "1"},
+		Form:   map[string][]string{"Title": {"Stale"}, "_version": {"2"}},
+	})
+	if code != http.StatusConflict || transactional.Rollbacks != 1 || storedArticle(t, transactional).Title != "Draft" {
+		t.Errorf("expected a rolled back conflict, got %v with %d rollbacks: %s", code, transactional.Rollbacks, body)
+	}
+
+	code, body = model.GetEditHandler()(&adminpanel.MockRequest{
+		Method: http.MethodPost,
+		Params: map[string]string{"id": "1"},
+		Form:   map[string][]string{"Title": {"Fresh"}, "_version": {"3"}},
+	})
+	if code != http.StatusSeeOther || transactional.Commits != 1 {
+		t.Fatalf("expected a committed update, got %v with %d commits: %s", code, transactional.Commits, body)
+	}
+	if article := storedArticle(t, transactional); article.Title != "Fresh" || article.Version != 4 {
+		t.Errorf("expected the title to be saved and the version bumped, got %+v", article)
+	}
+}
@@ -0,0 +1,89 @@
+package adminpanel
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type VersionedArticle struct {
+	ID      uint
+	Title   string
+	Version int `admin:"version"`
+}
+
+func registerVersionedTestModel(t *testing.T) *Model {
+	panel, err := NewMockAdminPanel()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	testApp, err := panel.RegisterApp("TestApp", "Test App", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	model, err := testApp.RegisterModel(&VersionedArticle{}, &MockORMIntegrator{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return model
+}
+
+func TestRegisterModel_VersionField(t *testing.T) {
+	model := registerVersionedTestModel(t)
+	if model.VersionField != "Version" {
+		t.Errorf("expected the version field to be Version, got %q", model.VersionField)
+	}
+	for _, field := range model.Fields {
+		if field.Name == "Version" && (field.AddFormField != nil || field.EditFormField != nil) {
+			t.Error("expected the version field not to be editable")
+		}
+	}
+
+	testApp := model.App
+	type stringVersion struct {
+		ID      uint
+		Version string `admin:"version"`
+	}
+	if _, err := testApp.RegisterModel(&stringVersion{}, &MockORMIntegrator{}); err == nil {
+		t.Error("expected an error for a string version field")
+	}
+	type twoVersions struct {
+		ID        uint
+		Version   int       `admin:"version"`
+		UpdatedAt time.Time `admin:"version"`
+	}
+	if _, err := testApp.RegisterModel(&twoVersions{}, &MockORMIntegrator{}); err == nil {
+		t.Error("expected an error for two version fields")
+	}
+}
+
+func TestModel_UpdateIfVersion(t *testing.T) {
+	model := registerVersionedTestModel(t)
+	err := model.updateIfVersion(&MockORMIntegrator{}, &VersionedArticle{ID: 1}, []string{"Title"}, uint(1), 3)
+	if !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("expected %v for a missing instance, got %v", ErrInstanceNotFound, err)
+	}
+}
+
+func TestVersionEncoding(t *testing.T) {
+	type stamped struct {
+		ID        uint
+		UpdatedAt time.Time `admin:"version"`
+	}
+	model := &Model{PTR: &stamped{}, VersionField: "UpdatedAt"}
+	now := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.FixedZone("CEST", 2*60*60))
+
+	decoded, err := model.decodeVersion(encodeVersion(now))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !versionsEqual(decoded, now) {
+		t.Errorf("expected %v, got %v", now, decoded)
+	}
+
+	instance := &stamped{}
+	model.setInitialVersion(instance)
+	if instance.UpdatedAt.IsZero() {
+		t.Error("expected new instances to get an initial timestamp")
+	}
+}
@@ -1,5 +1,10 @@
 package adminpanel
 
+import (
+	"context"
+	"io"
+)
+
 // HandlerFunc represents a handler function used in the admin panel routes.
 type HandlerFunc = func(interface{}) (uint, string)
 
@@ -36,6 +41,46 @@ type WebIntegrator interface {
 	GetJSONBody(ctx interface{}) (map[string]interface{}, error)
 }
 
+// FileWebIntegrator is an optional extension of WebIntegrator for integrators that can read files uploaded with
+// multipart forms. Without it, imports are pasted in a text area instead of uploaded.
+type FileWebIntegrator interface {
+	// GetFormFile returns the name and content of the file uploaded in the named form field. It returns
+	// http.ErrMissingFile when no file was uploaded.
+	GetFormFile(ctx interface{}, name string) (string, io.ReadCloser, error)
+}
+
+// ContextWebIntegrator is an optional extension of WebIntegrator for integrators that can hand over the
+// context.Context of the request being handled, so that its cancellation, deadline and values reach the ORM
+// integrator and the permission function.
+type ContextWebIntegrator interface {
+	// GetRequestContext returns the context of the request carried by ctx.
+	GetRequestContext(ctx interface{}) context.Context
+}
+
+// ValueWebIntegrator is an optional extension of WebIntegrator for integrators that can keep values for the duration
+// of a request, so that what is looked up several times per request, such as its user, is looked up once.
+type ValueWebIntegrator interface {
+	// GetRequestValue returns the value kept under key for the request carried by ctx, or nil if there is none.
+	GetRequestValue(ctx interface{}, key interface{}) interface{}
+	// SetRequestValue keeps value under key for the rest of the request carried by ctx.
+	SetRequestValue(ctx interface{}, key, value interface{})
+}
+
+// requestContext returns the context of the request carried by data. It comes from the web integrator when it
+// implements ContextWebIntegrator, or is data itself when data is a context.Context; it defaults to
+// context.Background().
+func requestContext(web WebIntegrator, data interface{}) context.Context {
+	if contextWeb, ok := web.(ContextWebIntegrator); ok {
+		if ctx := contextWeb.GetRequestContext(data); ctx != nil {
+			return ctx
+		}
+	}
+	if ctx, ok := data.(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
 // JSONResponse represents a standard JSON response structure.
 type JSONResponse struct {
 	Success bool        `json:"success"`
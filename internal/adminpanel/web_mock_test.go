@@ -1,41 +1,168 @@
 package adminpanel
 
-type MockWebIntegrator struct{}
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
 
-func (m *MockWebIntegrator) HandleRoute(string, string, HandlerFunc)         {}
-func (m *MockWebIntegrator) HandleJSONRoute(string, string, JSONHandlerFunc) {}
-func (m *MockWebIntegrator) ServeAssets(string, TemplateRenderer)            {}
+// MockWebIntegrator records the routes registered with it, the handlers of the HTML routes and of the routes returning
+// a Response, and the last JSON response it was asked to send.
+type MockWebIntegrator struct {
+	Routes           []string
+	Handlers         map[string]HandlerFunc
+	ResponseHandlers map[string]ResponseHandlerFunc
+	JSONHandlers     map[string]JSONHandlerFunc
+	JSONStatus       int
+	JSONResponse     interface{}
+}
+
+// MockRequest carries a method, path and query parameters, form data and a context together, for handlers reading
+// more than one of them.
+type MockRequest struct {
+	Method  string
+	Params  map[string]string
+	Form    map[string][]string
+	Context context.Context
+	// Files maps the names of file inputs to the content of the file uploaded with them.
+	Files map[string]string
+	// Body is the decoded JSON body of the request.
+	Body    map[string]interface{}
+	Headers map[string]string
+	// Cookies holds the cookies of the request, and receives the cookies set by handlers like a browser would.
+	Cookies map[string]string
+	// ResponseCookies holds the values of the cookies set on the response, expired cookies having an empty value.
+	ResponseCookies map[string]string
+}
+
+func (m *MockWebIntegrator) HandleRoute(method, path string, handler HandlerFunc) {
+	if m.Handlers == nil {
+		m.Handlers = make(map[string]HandlerFunc)
+	}
+	m.Routes = append(m.Routes, method+" "+path)
+	m.Handlers[method+" "+path] = handler
+}
+func (m *MockWebIntegrator) HandleResponseRoute(method, path string, handler ResponseHandlerFunc) {
+	if m.ResponseHandlers == nil {
+		m.ResponseHandlers = make(map[string]ResponseHandlerFunc)
+	}
+	m.Routes = append(m.Routes, method+" "+path)
+	m.ResponseHandlers[method+" "+path] = handler
+}
+func (m *MockWebIntegrator) HandleJSONRoute(method, path string, handler JSONHandlerFunc) {
+	if m.JSONHandlers == nil {
+		m.JSONHandlers = make(map[string]JSONHandlerFunc)
+	}
+	m.Routes = append(m.Routes, method+" "+path)
+	m.JSONHandlers[method+" "+path] = handler
+}
+func (m *MockWebIntegrator) ServeAssets(string, TemplateRenderer) {}
 func (m *MockWebIntegrator) GetQueryParam(ctx interface{}, name string) string {
 	if query, ok := ctx.(map[string]string); ok {
 		return query[name]
 	}
+	if request, ok := ctx.(*MockRequest); ok {
+		return request.Params[name]
+	}
 	return ""
 }
 func (m *MockWebIntegrator) GetPathParam(ctx interface{}, name string) string {
 	if path, ok := ctx.(map[string]string); ok {
 		return path[name]
 	}
+	if request, ok := ctx.(*MockRequest); ok {
+		return request.Params[name]
+	}
 	return ""
 }
 func (m *MockWebIntegrator) GetRequestMethod(ctx interface{}) string {
 	if request, ok := ctx.(map[string]string); ok {
 		return request["method"]
 	}
+	if request, ok := ctx.(*MockRequest); ok {
+		return request.Method
+	}
 	return ""
 }
 func (m *MockWebIntegrator) GetFormData(ctx interface{}) map[string][]string {
 	if form, ok := ctx.(map[string][]string); ok {
 		return form
 	}
+	if request, ok := ctx.(*MockRequest); ok {
+		return request.Form
+	}
 	return make(map[string][]string)
 }
 
+func (m *MockWebIntegrator) GetFormFile(ctx interface{}, name string) (string, io.ReadCloser, error) {
+	if request, ok := ctx.(*MockRequest); ok {
+		if content, ok := request.Files[name]; ok {
+			return name + ".csv", io.NopCloser(strings.NewReader(content)), nil
+		}
+	}
+	return "", nil, http.ErrMissingFile
+}
+
+func (m *MockWebIntegrator) GetRequestContext(ctx interface{}) context.Context {
+	if request, ok := ctx.(*MockRequest); ok {
+		return request.Context
+	}
+	return nil
+}
+
+func (m *MockWebIntegrator) GetHeader(ctx interface{}, name string) string {
+	if request, ok := ctx.(*MockRequest); ok {
+		return request.Headers[name]
+	}
+	return ""
+}
+
+func (m *MockWebIntegrator) GetCookie(ctx interface{}, name string) string {
+	if request, ok := ctx.(*MockRequest); ok {
+		return request.Cookies[name]
+	}
+	return ""
+}
+
+func (m *MockWebIntegrator) SetCookie(ctx interface{}, cookie *http.Cookie) {
+	request, ok := ctx.(*MockRequest)
+	if !ok {
+		return
+	}
+	if request.Cookies == nil {
+		request.Cookies = make(map[string]string)
+	}
+	if request.ResponseCookies == nil {
+		request.ResponseCookies = make(map[string]string)
+	}
+	request.ResponseCookies[cookie.Name] = cookie.Value
+	if cookie.MaxAge < 0 {
+		delete(request.Cookies, cookie.Name)
+		return
+	}
+	request.Cookies[cookie.Name] = cookie.Value
+}
+
+func (m *MockWebIntegrator) GetResponseCookie(ctx interface{}, name string) (string, bool) {
+	if request, ok := ctx.(*MockRequest); ok {
+		value, set := request.ResponseCookies[name]
+		return value, set
+	}
+	return "", false
+}
+
 func (m *MockWebIntegrator) SetJSONResponse(ctx interface{}, statusCode int, data interface{}) error {
+	m.JSONStatus = statusCode
+	m.JSONResponse = data
 	return nil
 }
 func (m *MockWebIntegrator) GetJSONBody(ctx interface{}) (map[string]interface{}, error) {
 	if mp, ok := ctx.(map[string]interface{}); ok {
 		return mp, nil
 	}
+	if request, ok := ctx.(*MockRequest); ok && request.Body != nil {
+		return request.Body, nil
+	}
 	return map[string]interface{}{}, nil
 }
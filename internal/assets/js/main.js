@@ -12,29 +12,54 @@ $(document).ready(function() {
     initializeFormControls();
     
     // Initialize Go Advanced Admin functionality
+    setupCSRF();
     setupDeleteModals();
     setupSearch();
     setupBulkOperations();
+    setupInlineFormSets();
 });
 
-// Initialize enhanced form controls (Select2, Flatpickr)
-function initializeFormControls() {
+// Send the CSRF token of the page with the AJAX requests changing data, in a header if the server reads them, or as a
+// query parameter otherwise
+function setupCSRF() {
+    const meta = $('meta[name="csrf-token"]');
+    if (meta.length === 0) {
+        return;
+    }
+    const token = meta.attr('content');
+    const header = meta.data('header');
+    $.ajaxPrefilter(function(options, originalOptions, xhr) {
+        if (/^(GET|HEAD|OPTIONS|TRACE)$/i.test(options.type)) {
+            return;
+        }
+        if (header) {
+            xhr.setRequestHeader(header, token);
+        } else {
+            options.url += (options.url.indexOf('?') === -1 ? '?' : '&') + 'csrfToken=' + encodeURIComponent(token);
+        }
+    });
+}
+
+// Initialize enhanced form controls (Select2, Flatpickr) in root, or in the whole document
+function initializeFormControls(root) {
+    const scope = $(root || document);
+
     // Initialize Select2 dropdowns
     if (typeof $.fn.select2 !== 'undefined') {
-        $('.select2').select2({
+        scope.find('.select2').select2({
             theme: 'bootstrap-5'
         });
     }
     
     // Initialize Flatpickr date pickers
     if (typeof flatpickr !== 'undefined') {
-        $('.flatpickr').flatpickr({
+        scope.find('.flatpickr').flatpickr({
             dateFormat: "Y-m-d",
             allowInput: true
         });
         
         // DateTime picker
-        $('[data-enable-time="true"]').flatpickr({
+        scope.find('[data-enable-time="true"]').flatpickr({
             enableTime: true,
             dateFormat: "Y-m-d H:i",
             allowInput: true,
@@ -43,7 +68,7 @@ function initializeFormControls() {
     }
     
     // Initialize Select2 AJAX dropdowns
-    $('[data-role="select2-ajax"]').each(function() {
+    scope.find('[data-role="select2-ajax"]').each(function() {
         $(this).select2({
             theme: 'bootstrap-5',
             minimumInputLength: 1,
@@ -68,8 +93,35 @@ function initializeFormControls() {
         }
     });
 
+    // Initialize foreign key and many-to-many dropdowns, searching the related model's JSON search endpoint
+    scope.find('[data-role="select2-fk"]').each(function() {
+        $(this).select2({
+            theme: 'bootstrap-5',
+            allowClear: true,
+            placeholder: $(this).data('placeholder') || '',
+            ajax: {
+                url: $(this).data('url'),
+                dataType: 'json',
+                delay: 250,
+                data: function (params) {
+                    return {
+                        q: params.term || '',
+                        page: params.page || 1,
+                    };
+                },
+                processResults: function (data) {
+                    const result = data.data || { instances: [], page: 1, totalPages: 1 };
+                    return {
+                        results: result.instances.map(instance => ({ id: instance.key, text: instance.repr })),
+                        pagination: { more: result.page < result.totalPages },
+                    };
+                }
+            }
+        });
+    });
+
     // Initialize Select2 tags
-    $('[data-role="select2-tags"]').each(function() {
+    scope.find('[data-role="select2-tags"]').each(function() {
         $(this).select2({
             theme: 'bootstrap-5',
             tags: true,
@@ -92,6 +144,7 @@ function setupDeleteModals() {
         
         const button = $(this);
         const deleteUrl = button.data('delete-url');
+        const redirectUrl = button.data('redirect-url');
         const itemName = button.data('item-name') || 'this item';
         
         // Update modal content
@@ -104,13 +157,13 @@ function setupDeleteModals() {
         
         // Set up confirm button click handler
         confirmButton.off('click').on('click', function() {
-            performDelete(deleteUrl, modal);
+            performDelete(deleteUrl, modal, redirectUrl);
         });
     });
 }
 
-// Perform AJAX delete operation
-function performDelete(url, modal) {
+// Perform AJAX delete operation, then reload the page or go to redirectUrl when given
+function performDelete(url, modal, redirectUrl) {
     $.ajax({
         url: url,
         method: 'DELETE',
@@ -132,7 +185,11 @@ function performDelete(url, modal) {
                 
                 // Reload page after short delay
                 setTimeout(() => {
-                    window.location.reload();
+                    if (redirectUrl) {
+                        window.location.href = redirectUrl;
+                    } else {
+                        window.location.reload();
+                    }
                 }, 1000);
             } else {
                 const errors = data.errors ? data.errors.join(', ') : 'Unknown error occurred';
@@ -169,14 +226,17 @@ function setupSearch() {
         clearTimeout(searchTimeout);
         const query = $(this).val();
         
+        // Search as you type through the JSON endpoint when the list provides one
+        const search = searchInput.data('search-url') ? performAjaxSearch : performSearch;
+        
         if (query.length === 0) {
             // Clear search immediately if input is empty
-            performSearch('');
+            search('');
             return;
         }
         
         searchTimeout = setTimeout(() => {
-            performSearch(query);
+            search(query);
         }, 500); // 500ms debounce
     });
     
@@ -202,10 +262,12 @@ function performSearch(query) {
     const url = new URL(window.location);
     
     if (query && query.trim() !== '') {
-        url.searchParams.set('q', query.trim());
+        url.searchParams.set('search', query.trim());
     } else {
-        url.searchParams.delete('q');
+        url.searchParams.delete('search');
     }
+    // A new search starts from the first page; filters and ordering are kept.
+    url.searchParams.delete('page');
     
     // Only reload if URL actually changed
     if (url.toString() !== window.location.toString()) {
@@ -213,10 +275,93 @@ function performSearch(query) {
     }
 }
 
+// Search through the model's JSON search endpoint and re-render the list rows in place
+function performAjaxSearch(query) {
+    const searchUrl = new URL($('#search-input').data('search-url'), window.location.origin);
+    
+    // Keep the current filters and ordering; the search always starts from the first page.
+    new URL(window.location).searchParams.forEach((value, name) => {
+        if (name !== 'search' && name !== 'page') {
+            searchUrl.searchParams.append(name, value);
+        }
+    });
+    searchUrl.searchParams.set('q', query.trim());
+    
+    $.ajax({
+        url: searchUrl.toString(),
+        method: 'GET',
+        success: function(data) {
+            if (data.success) {
+                renderSearchResults(data.data);
+            } else {
+                const errors = data.errors ? data.errors.join(', ') : 'Unknown error occurred';
+                showNotification('Error: ' + errors, 'error');
+            }
+        },
+        error: function(xhr, status, error) {
+            console.error('Search error:', error);
+            let errorMessage = 'Failed to search';
+            
+            try {
+                const response = JSON.parse(xhr.responseText);
+                if (response.errors) {
+                    errorMessage = response.errors.join(', ');
+                }
+            } catch (e) {
+                errorMessage = xhr.responseText || errorMessage;
+            }
+            
+            showNotification(errorMessage, 'error');
+        }
+    });
+}
+
+// Replace the list rows with the instances returned by the search endpoint
+function renderSearchResults(result) {
+    const table = $('#instances-table');
+    const fields = table.find('thead th[data-field]').map(function() {
+        return $(this).data('field');
+    }).get();
+    
+    const rows = result.instances.map(function(instance) {
+        const link = escapeHtml(instance.link);
+        const cells = fields.map(function(field) {
+            const value = instance.values[field];
+            const content = value ? escapeHtml(value) : '<span class="text-muted">--</span>';
+            const cellLink = instance.links[field] ? escapeHtml(instance.links[field]) : link;
+            return `<td><a href="${cellLink}" class="text-reset text-decoration-none">${content}</a></td>`;
+        }).join('');
+        
+        let actions = `<a href="${link}" class="btn">View</a>`;
+        if (instance.permissions.update) {
+            actions += `<a href="${escapeHtml(instance.editLink)}" class="btn">Edit</a>`;
+        }
+        if (instance.permissions.delete) {
+            actions += `<button class="btn btn-outline-danger" data-bs-toggle="modal" data-bs-target="#deleteModal" ` +
+                `data-delete-url="${escapeHtml(instance.deleteLink)}" data-item-name="${escapeHtml(instance.repr)}">Delete</button>`;
+        }
+        
+        return `<tr>
+            <td><input class="form-check-input row-checkbox" type="checkbox" value="${escapeHtml(instance.key)}"></td>
+            ${cells}
+            <td><div class="btn-group btn-group-sm">${actions}</div></td>
+        </tr>`;
+    });
+    
+    table.find('tbody').html(rows.join(''));
+    // Page links point at the unfiltered list, so they are hidden while showing search results.
+    $('#list-pagination').toggle(result.totalPages > 1 && !result.query);
+    clearSelection();
+}
+
+// Escape a string for insertion into HTML
+function escapeHtml(value) {
+    return $('<div>').text(value).html().replace(/"/g, '&quot;');
+}
+
 // Setup bulk operations functionality
 function setupBulkOperations() {
     const selectAll = $('#select-all');
-    const rowCheckboxes = $('.row-checkbox');
     const bulkActionBar = $('#bulk-action-bar');
     const selectedCount = $('#selected-count');
     
@@ -225,15 +370,16 @@ function setupBulkOperations() {
     // Select all functionality
     selectAll.on('change', function() {
         const isChecked = this.checked;
-        rowCheckboxes.prop('checked', isChecked);
+        $('.row-checkbox').prop('checked', isChecked);
         updateBulkActionBar();
     });
     
-    // Individual checkbox handling
-    rowCheckboxes.on('change', function() {
+    // Individual checkbox handling; delegated so rows rendered by the search are handled too
+    $(document).on('change', '.row-checkbox', function() {
         updateBulkActionBar();
         
         // Update select-all checkbox state
+        const rowCheckboxes = $('.row-checkbox');
         const checkedCount = rowCheckboxes.filter(':checked').length;
         const totalCount = rowCheckboxes.length;
         
@@ -242,7 +388,7 @@ function setupBulkOperations() {
     });
     
     function updateBulkActionBar() {
-        const checkedBoxes = rowCheckboxes.filter(':checked');
+        const checkedBoxes = $('.row-checkbox:checked');
         const count = checkedBoxes.length;
         
         if (count > 0) {
@@ -254,6 +400,73 @@ function setupBulkOperations() {
     }
 }
 
+// Run the action chosen in the list view's action dropdown on the selected rows. Actions needing confirmation post
+// the selection to the confirmation page; the others run through AJAX.
+function runModelAction() {
+    const container = $('#model-actions');
+    const option = $('#model-action-select option:selected');
+    const actionName = option.val();
+    const ids = $('.row-checkbox:checked').map(function() { return $(this).val(); }).get();
+
+    if (!actionName) {
+        showNotification('Choose an action to run', 'warning');
+        return;
+    }
+    if (ids.length === 0) {
+        showNotification('Select at least one item', 'warning');
+        return;
+    }
+
+    if (option.data('confirm')) {
+        const form = $('<form method="post"></form>').attr('action', container.data('confirm-url'));
+        form.append($('<input type="hidden" name="action">').val(actionName));
+        const csrfToken = $('meta[name="csrf-token"]').attr('content');
+        if (csrfToken) {
+            form.append($('<input type="hidden" name="csrfToken">').val(csrfToken));
+        }
+        ids.forEach(id => form.append($('<input type="hidden" name="ids">').val(id)));
+        $('body').append(form);
+        form.trigger('submit');
+        return;
+    }
+
+    $.ajax({
+        url: container.data('action-url'),
+        method: 'POST',
+        contentType: 'application/json',
+        data: JSON.stringify({ action: actionName, ids: ids }),
+        success: function(response) {
+            showNotification(escapeHtml(response.message || 'Action completed'), 'success');
+            setTimeout(() => window.location.reload(), 1000);
+        },
+        error: function(xhr) {
+            const response = xhr.responseJSON || {};
+            const errors = response.errors || ['Action failed'];
+            showNotification(escapeHtml(errors.join(', ')), 'error');
+        }
+    });
+}
+
+// Setup inline form sets: the add-row control clones the row template with the next row index, and new rows can
+// be removed before the form is submitted
+function setupInlineFormSets() {
+    $(document).on('click', '[data-role="inline-add"]', function() {
+        const formSet = $(this).closest('.inline-formset');
+        const total = formSet.find('[data-role="inline-total"]');
+        const index = parseInt(total.val(), 10) || 0;
+        const html = formSet.find('template[data-role="inline-template"]').html().replaceAll('__index__', index);
+        const row = $(html);
+
+        formSet.find('[data-role="inline-rows"]').append(row);
+        total.val(index + 1);
+        initializeFormControls(row);
+    });
+
+    $(document).on('click', '[data-role="inline-remove"]', function() {
+        $(this).closest('[data-role="inline-row"]').remove();
+    });
+}
+
 // Bulk delete operation
 function bulkDelete() {
     const checkedBoxes = $('.row-checkbox:checked');
@@ -270,10 +483,10 @@ function bulkDelete() {
         return;
     }
     
-    const currentPath = window.location.pathname;
+    const bulkDeleteUrl = $('#bulk-action-bar').data('bulk-delete-url') || window.location.pathname + '/bulk-delete';
     
     $.ajax({
-        url: currentPath + '/bulk-delete',
+        url: bulkDeleteUrl,
         method: 'POST',
         headers: {
             'Content-Type': 'application/json',
@@ -344,5 +557,7 @@ function showNotification(message, type) {
 
 // Global functions for template compatibility
 window.bulkDelete = bulkDelete;
+window.runModelAction = runModelAction;
 window.clearSelection = clearSelection;
 window.performSearch = performSearch;
+window.performAjaxSearch = performAjaxSearch;
@@ -0,0 +1,389 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MinSecretLength is the minimum length of the secret signing session cookies.
+const MinSecretLength = 32
+
+// ErrNotRegistered is returned by the methods of an Auth that needs an admin panel before Register is called.
+var ErrNotRegistered = errors.New("auth is not registered with an admin panel")
+
+// Auth authenticates the users of an admin panel with a login page and signed session cookies. Its PermissionFunc,
+// FetchUser and NavBarGenerator methods plug it into the panel:
+//
+//	authentication, err := auth.New(users, secret)
+//	panel, err := adminpanel.NewAdminPanel(orm, web, authentication.PermissionFunc(permissions), config)
+//	err = authentication.Register(panel)
+//	config.UserFetcher = authentication.FetchUser
+//	config.NavBarGenerators = []adminpanel.NavBarGenerator{authentication.NavBarGenerator, authentication.LogoutNavBarGenerator}
+type Auth struct {
+	Users UserStore
+	// Hasher verifies the passwords of the users against their hash.
+	Hasher PasswordHasher
+	// CookieName is the name of the session cookie.
+	CookieName string
+	// MaxAge is how long users stay logged in.
+	MaxAge time.Duration
+	// Secure restricts the session cookie to HTTPS, and should be set in production.
+	Secure bool
+	// Revoked keeps the sessions ended by logging out until they expire. Sessions also end when the password of their
+	// user changes.
+	Revoked RevokedSessionStore
+
+	secret []byte
+	panel  *adminpanel.AdminPanel
+	web    adminpanel.CookieWebIntegrator
+	values adminpanel.ValueWebIntegrator
+
+	dummyHashOnce sync.Once
+	dummyHash     string
+}
+
+// New creates an Auth logging in the users of users, with session cookies signed with secret. The secret must be
+// random, at least MinSecretLength bytes long, and kept the same across restarts for sessions to survive them.
+func New(users UserStore, secret []byte) (*Auth, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("session secret must be at least %d bytes long", MinSecretLength)
+	}
+	return &Auth{
+		Users:      users,
+		Hasher:     DefaultPasswordHasher,
+		CookieName: "admin_session",
+		MaxAge:     12 * time.Hour,
+		Revoked:    NewMemoryRevokedSessionStore(),
+		secret:     secret,
+	}, nil
+}
+
+// Register registers the login and logout pages of the panel, under its prefix. The web integrator of the panel
+// must implement adminpanel.CookieWebIntegrator, and sends the pages as adminpanel.Response values if it implements
+// adminpanel.ResponseWebIntegrator.
+func (a *Auth) Register(panel *adminpanel.AdminPanel) error {
+	web, ok := panel.Web.(adminpanel.CookieWebIntegrator)
+	if !ok {
+		return fmt.Errorf("web integrator %T cannot set cookies", panel.Web)
+	}
+	if err := panel.Config.Renderer.RegisterCompositeDefaultTemplate("login", "login.html", "page.html"); err != nil {
+		return err
+	}
+	a.panel = panel
+	a.web = web
+	a.values, _ = panel.Web.(adminpanel.ValueWebIntegrator)
+
+	prefix := panel.Config.GetPrefix()
+	panel.HandleResponseRoute(http.MethodGet, prefix+"/login", a.GetLoginHandler())
+	panel.HandleResponseRoute(http.MethodPost, prefix+"/login", a.GetLoginHandler())
+	panel.HandleResponseRoute(http.MethodGet, prefix+"/logout", a.GetLogoutHandler())
+	panel.HandleResponseRoute(http.MethodPost, prefix+"/logout", a.GetLogoutHandler())
+	return nil
+}
+
+// GetLoginLink returns the full link of the login page.
+func (a *Auth) GetLoginLink() string {
+	return a.panel.Config.GetLink("/login")
+}
+
+// GetLogoutLink returns the full link of the logout page.
+func (a *Auth) GetLogoutLink() string {
+	return a.panel.Config.GetLink("/logout")
+}
+
+// readSession returns the session of the request carried by ctx, or an empty session if it has no valid session
+// cookie.
+func (a *Auth) readSession(ctx interface{}) (session, error) {
+	if a.web == nil {
+		return session{}, ErrNotRegistered
+	}
+	s, _ := a.decodeSession(a.web.GetCookie(ctx, a.CookieName))
+	return s, nil
+}
+
+// userKey is the key of the requestUser kept for a request.
+type userKey struct{}
+
+// requestUser is the user of a request, kept for the rest of the request. A nil User is an anonymous request.
+type requestUser struct {
+	User *User
+}
+
+// GetUser returns the user logged in with the request carried by ctx, or nil if the request has no valid session, its
+// user is gone from the store, or their password changed since the session started. If the web integrator
+// implements adminpanel.ValueWebIntegrator, the user is looked up once per request.
+func (a *Auth) GetUser(ctx interface{}) (*User, error) {
+	if a.values == nil {
+		return a.lookUpUser(ctx)
+	}
+	if cached, ok := a.values.GetRequestValue(ctx, userKey{}).(requestUser); ok {
+		return cached.User, nil
+	}
+	user, err := a.lookUpUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.values.SetRequestValue(ctx, userKey{}, requestUser{User: user})
+	return user, nil
+}
+
+// lookUpUser returns the user logged in with the request carried by ctx, reading the session and the store.
+func (a *Auth) lookUpUser(ctx interface{}) (*User, error) {
+	s, err := a.readSession(ctx)
+	if err != nil || s.Username == "" {
+		return nil, err
+	}
+	user, err := a.Users.GetUser(s.Username)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(s.Password), []byte(a.passwordFingerprint(user.PasswordHash))) {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// SessionID returns the ID of the session of the request carried by ctx, or an empty ID for anonymous requests. It
+// is an adminpanel.SessionIDFunc, for stores keyed by session such as adminpanel.MemoryCSRFTokenStore.
+func (a *Auth) SessionID(ctx interface{}) (string, error) {
+	s, err := a.readSession(ctx)
+	return s.ID, err
+}
+
+// FetchUser is an adminpanel.UserFetchFunction returning the ID and username of the logged in user, and no user for
+// anonymous requests.
+func (a *Auth) FetchUser(ctx interface{}) (interface{}, string, error) {
+	user, err := a.GetUser(ctx)
+	if err != nil || user == nil {
+		return nil, "", err
+	}
+	return user.ID, user.Username, nil
+}
+
+// NavBarGenerator is an adminpanel.NavBarGenerator welcoming the logged in user, or linking to the login page.
+func (a *Auth) NavBarGenerator(ctx interface{}) adminpanel.NavBarItem {
+	user, err := a.GetUser(ctx)
+	if err != nil || user == nil {
+		return adminpanel.NavBarItem{Name: "Log in", Link: a.GetLoginLink()}
+	}
+	return adminpanel.NavBarItem{Name: fmt.Sprintf("Welcome, %s.", html.EscapeString(user.Username)), Bold: true}
+}
+
+// LogoutNavBarGenerator is an adminpanel.NavBarGenerator linking the logged in user to the logout page.
+func (a *Auth) LogoutNavBarGenerator(ctx interface{}) adminpanel.NavBarItem {
+	user, err := a.GetUser(ctx)
+	if err != nil || user == nil {
+		return adminpanel.NavBarItem{}
+	}
+	return adminpanel.NavBarItem{Name: "Log out", Link: a.GetLogoutLink()}
+}
+
+// PermissionFunc returns a permission function denying everything to anonymous requests, and deferring to next for
+// the requests of logged in users. A nil next allows logged in users everything.
+func (a *Auth) PermissionFunc(next adminpanel.PermissionFunc) adminpanel.PermissionFunc {
+	return func(request adminpanel.PermissionRequest, ctx interface{}) (bool, error) {
+		user, err := a.GetUser(ctx)
+		if err != nil || user == nil {
+			return false, err
+		}
+		if next == nil {
+			return true, nil
+		}
+		return next(request, ctx)
+	}
+}
+
+// RequireLogin returns a handler redirecting anonymous requests for the pages of the panel to the login page, and
+// serving the other requests with next. Anonymous requests changing data get a 401 instead. It must wrap the handler
+// serving the panel before any http.StripPrefix, as it matches the full links of the panel.
+func (a *Auth) RequireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.panel == nil || !a.isProtected(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if cookie, err := r.Cookie(a.CookieName); err == nil {
+			if _, ok := a.decodeSession(cookie.Value); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "login required", http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, a.GetLoginLink()+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+	})
+}
+
+// isProtected reports whether the path is a page of the panel needing a logged in user: a path under the admin
+// prefix, other than the login and logout pages, and outside of the assets.
+func (a *Auth) isProtected(path string) bool {
+	if path == a.GetLoginLink() || path == a.GetLogoutLink() {
+		return false
+	}
+	if a.panel.Config.AssetsPrefix != "" && hasPathPrefix(path, a.panel.Config.GetAssetLink("")) {
+		return false
+	}
+	return hasPathPrefix(path, a.panel.Config.GetLink(""))
+}
+
+// hasPathPrefix reports whether path is prefix or under it.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// GetLoginHandler returns the HTTP handler function of the login page. Posting valid credentials starts a session
+// and redirects to the "next" parameter, or to the panel.
+func (a *Auth) GetLoginHandler() adminpanel.ResponseHandlerFunc {
+	return func(data interface{}) adminpanel.Response {
+		next := a.getNextLink(data)
+		user, err := a.GetUser(data)
+		if err != nil {
+			return adminpanel.NewHTMLResponse(adminpanel.GetErrorHTML(http.StatusInternalServerError, err))
+		}
+		if user != nil {
+			return adminpanel.NewRedirectResponse(next)
+		}
+		if a.panel.Web.GetRequestMethod(data) != http.MethodPost {
+			return a.renderLogin(data, http.StatusOK, map[string]interface{}{"next": next})
+		}
+
+		form := a.panel.Web.GetFormData(data)
+		username, password := firstValue(form, "username"), firstValue(form, "password")
+		user, err = a.authenticate(username, password)
+		if err != nil {
+			return adminpanel.NewHTMLResponse(adminpanel.GetErrorHTML(http.StatusInternalServerError, err))
+		}
+		if user == nil {
+			return a.renderLogin(data, http.StatusUnauthorized, map[string]interface{}{
+				"next":     next,
+				"username": username,
+				"error":    "Please enter a correct username and password.",
+			})
+		}
+		cookie, err := a.login(user)
+		if err != nil {
+			return adminpanel.NewHTMLResponse(adminpanel.GetErrorHTML(http.StatusInternalServerError, err))
+		}
+		response := adminpanel.NewRedirectResponse(next)
+		response.SetCookie(cookie)
+		return response
+	}
+}
+
+// GetLogoutHandler returns the HTTP handler function of the logout page, asking for a confirmation before posting
+// the logout so that links cannot log users out. Logging out revokes the session, so that copies of the session
+// cookie are refused too.
+func (a *Auth) GetLogoutHandler() adminpanel.ResponseHandlerFunc {
+	return func(data interface{}) adminpanel.Response {
+		if a.panel.Web.GetRequestMethod(data) != http.MethodPost {
+			return a.renderLogin(data, http.StatusOK, map[string]interface{}{"logout": true})
+		}
+		s, err := a.readSession(data)
+		if err == nil && s.ID != "" && a.Revoked != nil {
+			err = a.Revoked.Revoke(s.ID, time.Unix(s.Expires, 0))
+		}
+		if err != nil {
+			return adminpanel.NewHTMLResponse(adminpanel.GetErrorHTML(http.StatusInternalServerError, err))
+		}
+		response := adminpanel.NewRedirectResponse(a.GetLoginLink())
+		response.SetCookie(a.newCookie("", -1))
+		return response
+	}
+}
+
+// authenticate returns the user with the given username and password, or nil if there is none. Unknown usernames
+// take as long as wrong passwords, so that response times do not tell which usernames exist.
+func (a *Auth) authenticate(username, password string) (*User, error) {
+	user, err := a.Users.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		a.dummyHashOnce.Do(func() { a.dummyHash, _ = a.Hasher.Hash("") })
+		_, _ = a.Hasher.Verify(password, a.dummyHash)
+		return nil, nil
+	}
+	valid, err := a.Hasher.Verify(password, user.PasswordHash)
+	if errors.Is(err, ErrUnknownPasswordHash) {
+		return nil, nil
+	}
+	if err != nil || !valid {
+		return nil, err
+	}
+	return user, nil
+}
+
+// login starts a session of user, returning the session cookie to set.
+func (a *Auth) login(user *User) (*http.Cookie, error) {
+	s, err := newSession(user.Username, a.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	s.Password = a.passwordFingerprint(user.PasswordHash)
+	value, err := a.encodeSession(s)
+	if err != nil {
+		return nil, err
+	}
+	return a.newCookie(value, int(a.MaxAge.Seconds())), nil
+}
+
+// newCookie returns the session cookie with the given value and max age, negative to delete it.
+func (a *Auth) newCookie(value string, maxAge int) *http.Cookie {
+	path := a.panel.Config.GetLink("")
+	if path == "" {
+		path = "/"
+	}
+	return &http.Cookie{
+		Name:     a.CookieName,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   a.Secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// getNextLink returns the link to go to after logging in, taken from the "next" parameter if it is a path of this
+// site, and the panel otherwise.
+func (a *Auth) getNextLink(ctx interface{}) string {
+	next := a.panel.Web.GetQueryParam(ctx, "next")
+	if next == "" {
+		next = firstValue(a.panel.Web.GetFormData(ctx), "next")
+	}
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return a.panel.GetFullLink()
+	}
+	return next
+}
+
+// renderLogin renders the login page with data and the given status code.
+func (a *Auth) renderLogin(ctx interface{}, code uint, data map[string]interface{}) adminpanel.Response {
+	data["admin"] = a.panel
+	data["loginLink"] = a.GetLoginLink()
+	data["logoutLink"] = a.GetLogoutLink()
+	page, err := a.panel.RenderPage(ctx, "login", data)
+	if err != nil {
+		return adminpanel.NewHTMLResponse(adminpanel.GetErrorHTML(http.StatusInternalServerError, err))
+	}
+	return adminpanel.NewHTMLResponse(code, page)
+}
+
+// firstValue returns the first value of the named form field, or an empty value.
+func firstValue(form map[string][]string, name string) string {
+	if values := form[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/orm/memory"
+	"github.com/ovnicraft/go-advanced-admin/internal/web/nethttp"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Note struct {
+	ID   uint
+	Text string
+}
+
+var testSecret = []byte(strings.Repeat("s", MinSecretLength))
+
+// newTestAuth returns an Auth logging alice in to a panel with a Note model, and the handler serving the panel.
+func newTestAuth(t *testing.T) (*Auth, *MemoryUserStore, http.Handler) {
+	users := NewMemoryUserStore()
+	users.Hasher = testHasher
+	if err := users.AddUser("alice", "secret"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	authentication, err := New(users, testSecret)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	authentication.Hasher = testHasher
+
+	web := nethttp.NewIntegrator(nil)
+	config := adminpanel.NewDefaultAdminConfig()
+	config.UserFetcher = authentication.FetchUser
+	config.NavBarGenerators = []adminpanel.NavBarGenerator{authentication.NavBarGenerator, authentication.LogoutNavBarGenerator}
+	panel, err := adminpanel.NewAdminPanel(memory.NewIntegrator(), web, authentication.PermissionFunc(nil), config)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	app, err := panel.RegisterApp("Notes", "Notes", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = app.RegisterModel(&Note{}, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = authentication.Register(panel); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return authentication, users, authentication.RequireLogin(web)
+}
+
+// serve runs a request against handler, with the given cookies, and returns the recorded response.
+func serve(handler http.Handler, method, target string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	if form != nil {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(NewMemoryUserStore(), []byte("short")); err == nil {
+		t.Errorf("expected an error for a short secret")
+	}
+}
+
+func TestAuth_Sessions(t *testing.T) {
+	authentication, err := New(NewMemoryUserStore(), testSecret)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s, err := newSession("alice", time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	value, err := authentication.encodeSession(s)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decoded, ok := authentication.decodeSession(value); !ok || decoded != s {
+		t.Errorf("expected %v, got %v", s, decoded)
+	}
+
+	expired, _ := newSession("alice", -time.Minute)
+	expiredValue, _ := authentication.encodeSession(expired)
+	other, _ := New(NewMemoryUserStore(), []byte(strings.Repeat("o", MinSecretLength)))
+	otherValue, _ := other.encodeSession(s)
+	payload, _, _ := strings.Cut(value, ".")
+	for name, value := range map[string]string{
+		"Empty":         "",
+		"Unsigned":      payload,
+		"Tampered":      strings.Replace(value, payload, payload+"x", 1),
+		"Other Secret":  otherValue,
+		"Expired":       expiredValue,
+		"Bad Signature": payload + ".%%%",
+	} {
+		if _, ok := authentication.decodeSession(value); ok {
+			t.Errorf("%s: expected the session to be refused", name)
+		}
+	}
+
+	if err = authentication.Revoked.Revoke(s.ID, time.Unix(s.Expires, 0)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := authentication.decodeSession(value); ok {
+		t.Errorf("expected a revoked session to be refused")
+	}
+}
+
+func TestMemoryRevokedSessionStore(t *testing.T) {
+	store := NewMemoryRevokedSessionStore()
+	_ = store.Revoke("expired", time.Now().Add(-time.Minute))
+	_ = store.Revoke("current", time.Now().Add(time.Hour))
+	if revoked, _ := store.IsRevoked("current"); !revoked {
+		t.Errorf("expected the session to be revoked")
+	}
+	if revoked, _ := store.IsRevoked("other"); revoked {
+		t.Errorf("expected other sessions to be valid")
+	}
+	if _, ok := store.revoked["expired"]; ok {
+		t.Errorf("expected expired sessions to be forgotten")
+	}
+}
+
+func TestAuth_Login(t *testing.T) {
+	authentication, users, handler := newTestAuth(t)
+
+	response := serve(handler, http.MethodGet, "/admin/a/Notes/Note", nil)
+	if response.Code != http.StatusSeeOther || response.Header().Get("Location") != "/admin/login?next=%2Fadmin%2Fa%2FNotes%2FNote" {
+		t.Errorf("expected a redirect to the login page, got %v %q", response.Code, response.Header().Get("Location"))
+	}
+	if response = serve(handler, http.MethodPost, "/admin/a/Notes/Note/add", url.Values{"Text": {"Hello"}}); response.Code != http.StatusUnauthorized {
+		t.Errorf("expected %v for an anonymous post, got %v", http.StatusUnauthorized, response.Code)
+	}
+	if response = serve(handler, http.MethodGet, "/admin-assets/css/main.css", nil); response.Code != http.StatusOK {
+		t.Errorf("expected the assets to be public, got %v", response.Code)
+	}
+	if response = serve(handler, http.MethodGet, "/admin/login?next=/admin/a/Notes", nil); response.Code != http.StatusOK || !strings.Contains(response.Body.String(), `name="next" value="/admin/a/Notes"`) {
+		t.Errorf("expected the login page, got %v", response.Code)
+	}
+
+	for _, credentials := range []url.Values{
+		{"username": {"alice"}, "password": {"wrong"}},
+		{"username": {"mallory"}, "password": {"secret"}},
+	} {
+		response = serve(handler, http.MethodPost, "/admin/login", credentials)
+		if response.Code != http.StatusUnauthorized || !strings.Contains(response.Body.String(), "correct username and password") || findCookie(response, "admin_session") != nil {
+			t.Errorf("expected %v to be refused, got %v", credentials, response.Code)
+		}
+	}
+
+	response = serve(handler, http.MethodPost, "/admin/login", url.Values{"username": {"alice"}, "password": {"secret"}, "next": {"/admin/a/Notes/Note"}})
+	cookies := response.Result().Cookies()
+	if response.Code != http.StatusSeeOther || response.Header().Get("Location") != "/admin/a/Notes/Note" || len(cookies) != 1 {
+		t.Fatalf("expected alice to be logged in, got %v %q %v", response.Code, response.Header().Get("Location"), cookies)
+	}
+	if cookie := cookies[0]; cookie.Name != "admin_session" || cookie.Path != "/admin" || !cookie.HttpOnly {
+		t.Errorf("unexpected session cookie %v", cookie)
+	}
+	session := cookies[0]
+
+	response = serve(handler, http.MethodGet, "/admin", nil, session)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "Welcome, alice.") || !strings.Contains(response.Body.String(), `href="/admin/logout"`) {
+		t.Errorf("expected the panel to welcome alice, got %v", response.Code)
+	}
+	if response = serve(handler, http.MethodGet, "/admin/login?next=//example.com", nil, session); response.Header().Get("Location") != "/admin" {
+		t.Errorf("expected a redirect to the panel, got %q", response.Header().Get("Location"))
+	}
+	id, repr, err := authentication.FetchUser(&nethttp.Context{Request: &http.Request{Header: http.Header{"Cookie": {session.String()}}}})
+	if err != nil || id != "alice" || repr != "alice" {
+		t.Errorf("expected alice to be fetched, got %v %q (%v)", id, repr, err)
+	}
+
+	if response = serve(handler, http.MethodGet, "/admin/logout", nil, session); response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "Are you sure you want to log out?") {
+		t.Errorf("expected the logout confirmation, got %v", response.Code)
+	}
+	response = serve(handler, http.MethodPost, "/admin/logout", url.Values{}, session)
+	if cookies = response.Result().Cookies(); response.Code != http.StatusSeeOther || len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("expected the session cookie to be expired, got %v %v", response.Code, cookies)
+	}
+
+	if response = serve(handler, http.MethodGet, "/admin", nil, session); response.Code != http.StatusSeeOther {
+		t.Errorf("expected the session to be revoked by logging out, got %v", response.Code)
+	}
+
+	session = login(t, handler, "secret")
+	if err = users.AddUser("alice", "changed"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if response = serve(handler, http.MethodGet, "/admin", nil, session); response.Code != http.StatusForbidden {
+		t.Errorf("expected the sessions to end with a password change, got %v", response.Code)
+	}
+
+	session = login(t, handler, "changed")
+	users.RemoveUser("alice")
+	if response = serve(handler, http.MethodGet, "/admin", nil, session); response.Code != http.StatusForbidden {
+		t.Errorf("expected the sessions of a removed user to be denied, got %v", response.Code)
+	}
+}
+
+// findCookie returns the named cookie set by a response, or nil if it sets none.
+func findCookie(response *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range response.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// login logs alice in with the given password and returns the session cookie.
+func login(t *testing.T, handler http.Handler, password string) *http.Cookie {
+	t.Helper()
+	response := serve(handler, http.MethodPost, "/admin/login", url.Values{"username": {"alice"}, "password": {password}})
+	cookies := response.Result().Cookies()
+	if response.Code != http.StatusSeeOther || len(cookies) != 1 {
+		t.Fatalf("expected alice to be logged in, got %v %v", response.Code, cookies)
+	}
+	return cookies[0]
+}
+
+// countingUserStore is a UserStore counting the users looked up.
+type countingUserStore struct {
+	UserStore
+	lookups int
+}
+
+func (s *countingUserStore) GetUser(username string) (*User, error) {
+	s.lookups++
+	return s.UserStore.GetUser(username)
+}
+
+func TestAuth_GetUserOncePerRequest(t *testing.T) {
+	authentication, users, handler := newTestAuth(t)
+	session := login(t, handler, "secret")
+	counting := &countingUserStore{UserStore: users}
+	authentication.Users = counting
+
+	if response := serve(handler, http.MethodGet, "/admin/a/Notes/Note", nil, session); response.Code != http.StatusOK {
+		t.Fatalf("expected the list view, got %v", response.Code)
+	}
+	if counting.lookups != 1 {
+		t.Errorf("expected the user to be looked up once, got %d lookups", counting.lookups)
+	}
+	if serve(handler, http.MethodGet, "/admin/a/Notes/Note", nil, session); counting.lookups != 2 {
+		t.Errorf("expected the user to be looked up again for the next request, got %d lookups", counting.lookups)
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownPasswordHash is returned when verifying a password against a hash of an unknown format.
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher hashes passwords for storage and verifies passwords against stored hashes.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of password, carrying its algorithm and parameters.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches the encoded hash.
+	Verify(password, encoded string) (bool, error)
+}
+
+// pbkdf2Algorithm prefixes the hashes of PBKDF2Hasher.
+const pbkdf2Algorithm = "pbkdf2_sha256"
+
+// PBKDF2Hasher is a PasswordHasher deriving keys with PBKDF2-HMAC-SHA256. Its hashes are encoded as
+// "pbkdf2_sha256$<iterations>$<salt>$<key>", with the salt and key in unpadded base64, so that hashes made with
+// another number of iterations can still be verified.
+type PBKDF2Hasher struct {
+	Iterations int
+}
+
+// DefaultPasswordHasher is the PasswordHasher used when none is given, following the OWASP recommendation of
+// 600,000 iterations.
+var DefaultPasswordHasher PasswordHasher = PBKDF2Hasher{Iterations: 600_000}
+
+// Hash returns the encoded PBKDF2 hash of password with a random salt.
+func (h PBKDF2Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, h.Iterations, sha256.Size)
+	if err != nil {
+		return "", err
+	}
+	encoding := base64.RawStdEncoding
+	return fmt.Sprintf("%s$%d$%s$%s", pbkdf2Algorithm, h.Iterations, encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches the encoded PBKDF2 hash, in constant time.
+func (h PBKDF2Hasher) Verify(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != pbkdf2Algorithm {
+		return false, ErrUnknownPasswordHash
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, ErrUnknownPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, ErrUnknownPasswordHash
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(expected) == 0 {
+		return false, ErrUnknownPasswordHash
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
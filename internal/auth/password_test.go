@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPBKDF2Hasher(t *testing.T) {
+	hasher := PBKDF2Hasher{Iterations: 1000}
+	hash, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(hash, "pbkdf2_sha256$1000$") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+	if other, _ := hasher.Hash("correct horse"); other == hash {
+		t.Errorf("expected hashes to be salted")
+	}
+
+	tests := []struct {
+		name     string
+		hasher   PasswordHasher
+		password string
+		expected bool
+	}{
+		{"Right Password", hasher, "correct horse", true},
+		{"Wrong Password", hasher, "battery staple", false},
+		{"Other Iterations", PBKDF2Hasher{Iterations: 5}, "correct horse", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := tt.hasher.Verify(tt.password, hash)
+			if err != nil || valid != tt.expected {
+				t.Errorf("expected %v, got %v (%v)", tt.expected, valid, err)
+			}
+		})
+	}
+
+	for _, encoded := range []string{"", "plain", "md5$1$c2FsdA$a2V5", "pbkdf2_sha256$x$c2FsdA$a2V5", "pbkdf2_sha256$1$c2FsdA$"} {
+		if _, err := hasher.Verify("correct horse", encoded); !errors.Is(err, ErrUnknownPasswordHash) {
+			t.Errorf("expected ErrUnknownPasswordHash for %q, got %v", encoded, err)
+		}
+	}
+}
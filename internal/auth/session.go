@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// session is the content of the session cookie.
+type session struct {
+	Username string `json:"u"`
+	// ID is a random identifier of the session, distinguishing the sessions of a user.
+	ID string `json:"i"`
+	// Password is the fingerprint of the password hash of the user when the session started, ending the session
+	// when the password changes.
+	Password string `json:"p"`
+	Expires  int64  `json:"e"`
+}
+
+// newSession returns a session of the user with the given username, expiring after maxAge.
+func newSession(username string, maxAge time.Duration) (session, error) {
+	id := make([]byte, 18)
+	if _, err := rand.Read(id); err != nil {
+		return session{}, err
+	}
+	return session{
+		Username: username,
+		ID:       base64.RawURLEncoding.EncodeToString(id),
+		Expires:  time.Now().Add(maxAge).Unix(),
+	}, nil
+}
+
+// encodeSession returns the value of the session cookie of s: its JSON encoding and HMAC-SHA256 signature, in
+// unpadded URL-safe base64 and separated by a dot.
+func (a *Auth) encodeSession(s session) (string, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(a.sign(encoded)), nil
+}
+
+// decodeSession returns the session of a session cookie value, and false if the value is not signed with the secret,
+// or the session has expired or was revoked.
+func (a *Auth) decodeSession(value string) (session, bool) {
+	encoded, signature, found := strings.Cut(value, ".")
+	if !found {
+		return session{}, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, a.sign(encoded)) {
+		return session{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return session{}, false
+	}
+	var s session
+	if err = json.Unmarshal(payload, &s); err != nil || s.Username == "" || time.Now().Unix() >= s.Expires {
+		return session{}, false
+	}
+	if a.Revoked != nil {
+		if revoked, err := a.Revoked.IsRevoked(s.ID); err != nil || revoked {
+			return session{}, false
+		}
+	}
+	return s, true
+}
+
+// passwordFingerprint returns the fingerprint of a password hash kept in the sessions of its user. It is signed with
+// the secret, so that session cookies do not leak anything about the hash.
+func (a *Auth) passwordFingerprint(hash string) string {
+	return base64.RawURLEncoding.EncodeToString(a.sign("password." + hash)[:16])
+}
+
+// sign returns the HMAC-SHA256 signature of value with the secret.
+func (a *Auth) sign(value string) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// RevokedSessionStore keeps the IDs of the sessions ended before they expire, such as by logging out.
+type RevokedSessionStore interface {
+	// Revoke ends the session with the given ID, which expires at expires.
+	Revoke(id string, expires time.Time) error
+	// IsRevoked reports whether the session with the given ID was ended.
+	IsRevoked(id string) (bool, error)
+}
+
+// MemoryRevokedSessionStore is a RevokedSessionStore keeping the revoked sessions in memory until they expire. It is
+// safe for concurrent use. Revocations are lost on restart and are not shared between the instances of a panel, which
+// then need a shared store.
+type MemoryRevokedSessionStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+// NewMemoryRevokedSessionStore creates an empty MemoryRevokedSessionStore.
+func NewMemoryRevokedSessionStore() *MemoryRevokedSessionStore {
+	return &MemoryRevokedSessionStore{revoked: make(map[string]time.Time)}
+}
+
+// Revoke ends the session with the given ID, and forgets the revoked sessions that have expired.
+func (s *MemoryRevokedSessionStore) Revoke(id string, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for revokedID, revokedExpires := range s.revoked {
+		if !now.Before(revokedExpires) {
+			delete(s.revoked, revokedID)
+		}
+	}
+	s.revoked[id] = expires
+	return nil
+}
+
+// IsRevoked reports whether the session with the given ID was ended.
+func (s *MemoryRevokedSessionStore) IsRevoked(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, revoked := s.revoked[id]
+	return revoked, nil
+}
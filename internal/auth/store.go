@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"reflect"
+	"sync"
+)
+
+// User is a user who can log in to the admin panel.
+type User struct {
+	// ID identifies the user in the logs of the panel.
+	ID       interface{}
+	Username string
+	// PasswordHash is the hash of the password of the user, as returned by a PasswordHasher.
+	PasswordHash string
+}
+
+// UserStore looks up the users who can log in to the admin panel.
+type UserStore interface {
+	// GetUser returns the user with the given username, or nil if there is none.
+	GetUser(username string) (*User, error)
+}
+
+// MemoryUserStore is a UserStore keeping its users in memory, identified by their username. It suits panels with a
+// few administrators set up in code. It is safe for concurrent use.
+type MemoryUserStore struct {
+	// Hasher hashes the passwords given to AddUser.
+	Hasher PasswordHasher
+	mu     sync.RWMutex
+	users  map[string]*User
+}
+
+// NewMemoryUserStore creates an empty MemoryUserStore hashing passwords with DefaultPasswordHasher.
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{Hasher: DefaultPasswordHasher, users: make(map[string]*User)}
+}
+
+// AddUser adds a user with the given username and password, replacing any user with the same username. Replacing the
+// password of a user ends their sessions.
+func (s *MemoryUserStore) AddUser(username, password string) error {
+	hash, err := s.Hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = &User{ID: username, Username: username, PasswordHash: hash}
+	return nil
+}
+
+// RemoveUser removes the user with the given username. Their sessions end with their next request.
+func (s *MemoryUserStore) RemoveUser(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, username)
+}
+
+// GetUser returns the user with the given username, or nil if there is none.
+func (s *MemoryUserStore) GetUser(username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.users[username], nil
+}
+
+// ORMUserStore is a UserStore reading users from a model through an ORM integrator, such as a model also registered
+// in the panel to manage the users. The username and password hash fields of the model must be strings.
+type ORMUserStore struct {
+	ORM adminpanel.ORMIntegrator
+	// Model is a pointer to the model of the users.
+	Model         interface{}
+	UsernameField string
+	PasswordField string
+}
+
+// NewORMUserStore creates an ORMUserStore reading users from the Username and PasswordHash fields of model.
+func NewORMUserStore(orm adminpanel.ORMIntegrator, model interface{}) *ORMUserStore {
+	return &ORMUserStore{ORM: orm, Model: model, UsernameField: "Username", PasswordField: "PasswordHash"}
+}
+
+// GetUser returns the user with the given username, or nil if there is none. Integrators implementing
+// adminpanel.PaginatedORMIntegrator look the username up themselves; the instances of other integrators are scanned.
+func (s *ORMUserStore) GetUser(username string) (*User, error) {
+	var instances interface{}
+	var err error
+	if paginated, ok := s.ORM.(adminpanel.PaginatedORMIntegrator); ok {
+		instances, _, err = paginated.FetchInstancesPage(s.Model, adminpanel.ListQuery{
+			Fields:  []string{s.UsernameField, s.PasswordField},
+			Filters: []adminpanel.FilterCondition{{Field: s.UsernameField, Operator: adminpanel.FilterExact, Value: username}},
+			Limit:   1,
+		})
+	} else {
+		instances, err = s.ORM.FetchInstances(s.Model)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	value := reflect.ValueOf(instances)
+	if value.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expected a slice of instances, got %T", instances)
+	}
+	for index := 0; index < value.Len(); index++ {
+		instance := value.Index(index)
+		for instance.Kind() == reflect.Ptr || instance.Kind() == reflect.Interface {
+			instance = instance.Elem()
+		}
+		usernameValue := instance.FieldByName(s.UsernameField)
+		passwordValue := instance.FieldByName(s.PasswordField)
+		if usernameValue.Kind() != reflect.String || passwordValue.Kind() != reflect.String {
+			return nil, fmt.Errorf("fields %s and %s of the user model must be strings", s.UsernameField, s.PasswordField)
+		}
+		if usernameValue.String() != username {
+			continue
+		}
+		id, err := s.ORM.GetPrimaryKeyValue(value.Index(index).Interface())
+		if err != nil {
+			return nil, err
+		}
+		return &User{ID: id, Username: username, PasswordHash: passwordValue.String()}, nil
+	}
+	return nil, nil
+}
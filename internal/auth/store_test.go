@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/orm/memory"
+	"testing"
+)
+
+// testHasher keeps the tests fast; its hashes are verified like the ones of DefaultPasswordHasher.
+var testHasher = PBKDF2Hasher{Iterations: 10}
+
+type AdminUser struct {
+	ID           uint
+	Username     string
+	PasswordHash string
+}
+
+func TestMemoryUserStore(t *testing.T) {
+	store := NewMemoryUserStore()
+	store.Hasher = testHasher
+	if err := store.AddUser("alice", "secret"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	user, err := store.GetUser("alice")
+	if err != nil || user == nil || user.ID != "alice" {
+		t.Fatalf("expected alice, got %v (%v)", user, err)
+	}
+	if valid, _ := testHasher.Verify("secret", user.PasswordHash); !valid {
+		t.Errorf("expected the password to be hashed")
+	}
+	if user, _ = store.GetUser("bob"); user != nil {
+		t.Errorf("expected no user, got %v", user)
+	}
+	store.RemoveUser("alice")
+	if user, _ = store.GetUser("alice"); user != nil {
+		t.Errorf("expected alice to be removed, got %v", user)
+	}
+}
+
+func TestORMUserStore(t *testing.T) {
+	hash, err := testHasher.Hash("secret")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	orm := memory.NewIntegrator()
+	if err = orm.Seed(&AdminUser{Username: "alice", PasswordHash: hash}, &AdminUser{Username: "bob"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	store := NewORMUserStore(orm, &AdminUser{})
+
+	user, err := store.GetUser("alice")
+	if err != nil || user == nil || user.ID != uint(1) || user.PasswordHash != hash {
+		t.Fatalf("expected alice, got %v (%v)", user, err)
+	}
+	if user, err = store.GetUser("carol"); err != nil || user != nil {
+		t.Errorf("expected no user, got %v (%v)", user, err)
+	}
+
+	store.PasswordField = "ID"
+	if _, err = store.GetUser("alice"); err == nil {
+		t.Errorf("expected an error for a password field that is not a string")
+	}
+}
@@ -65,6 +65,10 @@ func (f *MultipleChoiceField) HTMLTypeToGoType(value form.HTMLType) (interface{}
 	if value == "" || value == "[]" {
 		return nil, nil
 	}
+	// A single selected option is submitted as a plain value rather than a JSON list.
+	if !strings.HasPrefix(string(value), "[") {
+		return []string{string(value)}, nil
+	}
 	var values []string
 	if err := json.Unmarshal([]byte(value), &values); err != nil {
 		return nil, errors.New("invalid multiple choice value")
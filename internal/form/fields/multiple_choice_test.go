@@ -0,0 +1,29 @@
+package fields
+
+import (
+	"github.com/ovnicraft/go-advanced-admin/internal/form"
+	"reflect"
+	"testing"
+)
+
+func TestMultipleChoiceField_HTMLTypeToGoType(t *testing.T) {
+	f := &MultipleChoiceField{}
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"", nil},
+		{"[]", nil},
+		{"red", []string{"red"}},
+		{`["red","blue"]`, []string{"red", "blue"}},
+	}
+	for _, tt := range tests {
+		value, err := f.HTMLTypeToGoType(form.HTMLType(tt.input))
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.input, err)
+		}
+		if !reflect.DeepEqual(value, tt.expected) {
+			t.Errorf("Expected %v for %q, got %v", tt.expected, tt.input, value)
+		}
+	}
+}
@@ -97,6 +97,9 @@ func (f *TextField) GetValidationFunctions() []form.FieldValidationFunc {
 }
 
 func (f *TextField) requiredValidation(value interface{}) ([]error, error) {
+	if value == nil {
+		value = ""
+	}
 	strValue, ok := value.(string)
 	if !ok {
 		return nil, errors.New("value must be a string")
@@ -108,6 +111,9 @@ func (f *TextField) requiredValidation(value interface{}) ([]error, error) {
 }
 
 func (f *TextField) maxLengthValidation(value interface{}) ([]error, error) {
+	if value == nil {
+		return nil, nil
+	}
 	strValue, ok := value.(string)
 	if !ok {
 		return nil, errors.New("value must be a string")
@@ -119,6 +125,9 @@ func (f *TextField) maxLengthValidation(value interface{}) ([]error, error) {
 }
 
 func (f *TextField) minLengthValidation(value interface{}) ([]error, error) {
+	if value == nil {
+		return nil, nil
+	}
 	strValue, ok := value.(string)
 	if !ok {
 		return nil, errors.New("value must be a string")
@@ -130,6 +139,9 @@ func (f *TextField) minLengthValidation(value interface{}) ([]error, error) {
 }
 
 func (f *TextField) regexValidation(value interface{}) ([]error, error) {
+	if value == nil {
+		return nil, nil
+	}
 	strValue, ok := value.(string)
 	if !ok {
 		return nil, errors.New("value must be a string")
@@ -35,3 +35,19 @@ func TestTextFieldConversions(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "test", goType)
 }
+
+func TestTextFieldEmptyValueValidation(t *testing.T) {
+	minLength := uint(3)
+	textField := &TextField{Required: true, MinLength: &minLength}
+
+	goType, err := textField.HTMLTypeToGoType("")
+	assert.Nil(t, err)
+	errs, err := form.FieldValueIsValid(textField, goType)
+	assert.Nil(t, err)
+	assert.Len(t, errs, 1)
+
+	textField.Required = false
+	errs, err = form.FieldValueIsValid(textField, goType)
+	assert.Nil(t, err)
+	assert.Empty(t, errs)
+}
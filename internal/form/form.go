@@ -201,7 +201,7 @@ func renderTablerField(field Field, labelClass, label, fieldHTML string, fieldEr
 	hasErr := len(fieldErrs) > 0
 	fieldHTML = normalizeControlClasses(fieldHTML, hasErr)
 
-	if strings.Contains(fieldHTML, `<select`) && !strings.Contains(fieldHTML, `multiple`) {
+	if strings.Contains(fieldHTML, `<select`) && !strings.Contains(fieldHTML, `multiple`) && !strings.Contains(fieldHTML, `data-role=`) {
 		fieldHTML = strings.ReplaceAll(fieldHTML, `<select`, `<select data-role="select2"`)
 	}
 	if strings.Contains(fieldHTML, `type="date"`) {
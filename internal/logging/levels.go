@@ -6,20 +6,28 @@ const (
 	LogStoreLevelDelete         LogStoreLevel = "delete"
 	LogStoreLevelCreate         LogStoreLevel = "create"
 	LogStoreLevelUpdate         LogStoreLevel = "update"
+	LogStoreLevelAction         LogStoreLevel = "action"
 	LogStoreLevelInstanceView   LogStoreLevel = "instance_view"
 	LogStoreLevelInstanceDelete LogStoreLevel = "instance_delete"
 	LogStoreLevelListView       LogStoreLevel = "list_view"
 	LogStoreLevelPanelView      LogStoreLevel = "panel_view"
+	LogStoreLevelRestore        LogStoreLevel = "restore"
+	LogStoreLevelPurge          LogStoreLevel = "purge"
+	LogStoreLevelExport         LogStoreLevel = "export"
 )
 
 var levelsHierarchy = map[LogStoreLevel]int{
 	LogStoreLevelDelete:         1,
 	LogStoreLevelCreate:         2,
 	LogStoreLevelUpdate:         3,
+	LogStoreLevelAction:         3, // Same level as update
 	LogStoreLevelInstanceView:   4,
 	LogStoreLevelInstanceDelete: 1, // Same level as general delete
 	LogStoreLevelListView:       5,
 	LogStoreLevelPanelView:      6,
+	LogStoreLevelRestore:        3, // Same level as update
+	LogStoreLevelPurge:          1, // Same level as general delete
+	LogStoreLevelExport:         3, // Same level as action
 }
 
 func (l LogStoreLevel) AssessLevel(assessmentLevel LogStoreLevel) bool {
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"reflect"
+)
+
+// matchingRows returns the table of the model and its rows matching the filters, in insertion order. The caller
+// must hold the read lock.
+func (i *Integrator) matchingRows(model interface{}, filters []adminpanel.FilterCondition) (*table, []reflect.Value, error) {
+	t, err := i.readTable(model)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := adminpanel.ListQuery{Filters: filters}
+	rows := make([]reflect.Value, 0, len(t.order))
+	for _, key := range t.order {
+		row := t.rows[key]
+		matches, err := t.matches(row, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		if matches {
+			rows = append(rows, row)
+		}
+	}
+	return t, rows, nil
+}
+
+// CountInstances returns the number of instances matching the filters.
+func (i *Integrator) CountInstances(model interface{}, filters []adminpanel.FilterCondition) (uint, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	_, rows, err := i.matchingRows(model, filters)
+	if err != nil {
+		return 0, err
+	}
+	return uint(len(rows)), nil
+}
+
+// CountInstancesByField returns the number of instances matching the filters for each value of the field, ordered
+// by value.
+func (i *Integrator) CountInstancesByField(model interface{}, field string, filters []adminpanel.FilterCondition) ([]adminpanel.GroupCount, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	t, rows, err := i.matchingRows(model, filters)
+	if err != nil {
+		return nil, err
+	}
+	if err = t.sort(rows, []adminpanel.OrderBy{{Field: field}}); err != nil {
+		return nil, err
+	}
+	groups := make([]adminpanel.GroupCount, 0)
+	var previous reflect.Value
+	for index, row := range rows {
+		value := reflect.Indirect(row.FieldByName(field))
+		if index > 0 && compareValues(previous, value) == 0 {
+			groups[len(groups)-1].Count++
+			continue
+		}
+		group := adminpanel.GroupCount{Count: 1}
+		if value.IsValid() {
+			group.Value = value.Interface()
+		}
+		groups = append(groups, group)
+		previous = value
+	}
+	return groups, nil
+}
+
+// AggregateField computes the sum or the average of the numeric field over the instances matching the filters. Nil
+// values are left out, and zero is returned when no instance matches.
+func (i *Integrator) AggregateField(model interface{}, field string, aggregate adminpanel.AggregateFunc, filters []adminpanel.FilterCondition) (float64, error) {
+	if aggregate != adminpanel.AggregateSum && aggregate != adminpanel.AggregateAvg {
+		return 0, fmt.Errorf("unsupported aggregate %q", aggregate)
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	t, rows, err := i.matchingRows(model, filters)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := t.typ.FieldByName(field); !ok {
+		return 0, fmt.Errorf("model %s has no field %s", t.typ.Name(), field)
+	}
+	var sum float64
+	var count int
+	for _, row := range rows {
+		value := reflect.Indirect(row.FieldByName(field))
+		switch {
+		case !value.IsValid():
+			continue
+		case value.CanInt():
+			sum += float64(value.Int())
+		case value.CanUint():
+			sum += float64(value.Uint())
+		case value.CanFloat():
+			sum += value.Float()
+		default:
+			return 0, fmt.Errorf("field %s of model %s is not numeric", field, t.typ.Name())
+		}
+		count++
+	}
+	if aggregate == adminpanel.AggregateAvg && count > 0 {
+		return sum / float64(count), nil
+	}
+	return sum, nil
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// compareValues returns -1, 0 or 1 as a is less than, equal to or greater than b. Numbers, strings, booleans and
+// times compare naturally; other values compare by their formatted text. Invalid values, such as nil pointers, come
+// first.
+func compareValues(a, b reflect.Value) int {
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return 0
+	case !a.IsValid():
+		return -1
+	case !b.IsValid():
+		return 1
+	}
+
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+	if a.Kind() == b.Kind() {
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return compareOrdered(a.Int(), b.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return compareOrdered(a.Uint(), b.Uint())
+		case reflect.Float32, reflect.Float64:
+			return compareOrdered(a.Float(), b.Float())
+		case reflect.String:
+			return strings.Compare(a.String(), b.String())
+		case reflect.Bool:
+			return compareOrdered(boolRank(a.Bool()), boolRank(b.Bool()))
+		}
+	}
+	return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+}
+
+func compareOrdered[T int64 | uint64 | float64 | int](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolRank(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,577 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned when no instance has the requested primary key. It is adminpanel.ErrInstanceNotFound, so
+// the panel tells missing instances from other failures.
+var ErrNotFound = adminpanel.ErrInstanceNotFound
+
+// table stores the instances of one model type, keyed by primary key, in insertion order.
+type table struct {
+	typ    reflect.Type
+	pk     int
+	rows   map[interface{}]reflect.Value
+	order  []interface{}
+	nextID uint64
+}
+
+// Integrator is an adminpanel.ORMIntegrator keeping instances of any struct type in memory, for prototypes, examples
+// and tests. The primary key of a model is the field tagged `admin:"pk"`, or the field named ID; integer primary keys
+// left at zero are assigned incrementally on creation. Instances are stored and returned as shallow copies, so
+// changing a fetched struct does not change the store. An Integrator is safe for concurrent use.
+type Integrator struct {
+	mu     sync.RWMutex
+	tables map[reflect.Type]*table
+}
+
+// NewIntegrator creates an empty in-memory integrator.
+func NewIntegrator() *Integrator {
+	return &Integrator{tables: make(map[reflect.Type]*table)}
+}
+
+// Seed creates the given instances, which may be structs, pointers to structs or slices of either. The primary keys
+// assigned to pointed-to structs are set on them.
+func (i *Integrator) Seed(instances ...interface{}) error {
+	for _, instance := range instances {
+		value := reflect.ValueOf(instance)
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			for index := 0; index < value.Len(); index++ {
+				if err := i.Seed(value.Index(index).Interface()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if value.Kind() == reflect.Struct {
+			pointer := reflect.New(value.Type())
+			pointer.Elem().Set(value)
+			instance = pointer.Interface()
+		}
+		if err := i.CreateInstance(instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modelType returns the struct type of a model given as a struct or a pointer to a struct.
+func modelType(model interface{}) (reflect.Type, error) {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model must be a struct or a pointer to a struct, got %T", model)
+	}
+	return typ, nil
+}
+
+// primaryKeyIndex returns the index of the field tagged `admin:"pk"`, or of the field named ID.
+func primaryKeyIndex(typ reflect.Type) (int, error) {
+	idIndex := -1
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		for _, option := range strings.Split(field.Tag.Get("admin"), ";") {
+			if option == "pk" {
+				return index, nil
+			}
+		}
+		if field.Name == "ID" {
+			idIndex = index
+		}
+	}
+	if idIndex < 0 {
+		return 0, fmt.Errorf("model %s has no primary key, name a field ID or tag it with `admin:\"pk\"`", typ.Name())
+	}
+	return idIndex, nil
+}
+
+// getTable returns the table of a model, creating it if needed. The caller must hold the write lock.
+func (i *Integrator) getTable(model interface{}) (*table, error) {
+	t, err := i.readTable(model)
+	if err != nil {
+		return nil, err
+	}
+	i.tables[t.typ] = t
+	return t, nil
+}
+
+// readTable returns the table of a model for reading. The caller must hold the read lock; models without a table
+// get an empty one that is not stored.
+func (i *Integrator) readTable(model interface{}) (*table, error) {
+	typ, err := modelType(model)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := i.tables[typ]; ok {
+		return t, nil
+	}
+	pk, err := primaryKeyIndex(typ)
+	if err != nil {
+		return nil, err
+	}
+	return &table{typ: typ, pk: pk, rows: make(map[interface{}]reflect.Value)}, nil
+}
+
+// key converts a primary key value to the type of the primary key field.
+func (t *table) key(id interface{}) (interface{}, error) {
+	pkType := t.typ.Field(t.pk).Type
+	value := reflect.ValueOf(id)
+	if !value.IsValid() {
+		return nil, errors.New("primary key is required")
+	}
+	if value.Type() != pkType {
+		if !value.Type().ConvertibleTo(pkType) {
+			return nil, fmt.Errorf("primary key %v cannot be used as %s", id, pkType)
+		}
+		value = value.Convert(pkType)
+	}
+	if !value.Comparable() {
+		return nil, fmt.Errorf("primary key of type %s is not comparable", pkType)
+	}
+	return value.Interface(), nil
+}
+
+// fieldIndexes returns the indexes of the named fields, always including the primary key.
+func (t *table) fieldIndexes(fields []string) ([]int, error) {
+	indexes := []int{t.pk}
+	for _, name := range fields {
+		field, ok := t.typ.FieldByName(name)
+		if !ok || len(field.Index) != 1 {
+			return nil, fmt.Errorf("model %s has no field %s", t.typ.Name(), name)
+		}
+		if field.Index[0] != t.pk {
+			indexes = append(indexes, field.Index[0])
+		}
+	}
+	return indexes, nil
+}
+
+// copyFields returns a pointer to a new struct holding the given fields of row, or every field if indexes is nil.
+func (t *table) copyFields(row reflect.Value, indexes []int) interface{} {
+	instance := reflect.New(t.typ)
+	if indexes == nil {
+		instance.Elem().Set(row)
+		return instance.Interface()
+	}
+	for _, index := range indexes {
+		instance.Elem().Field(index).Set(row.Field(index))
+	}
+	return instance.Interface()
+}
+
+// GetPrimaryKeyValue returns the primary key value of the given model instance.
+func (i *Integrator) GetPrimaryKeyValue(model interface{}) (interface{}, error) {
+	typ, err := modelType(model)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := primaryKeyIndex(typ)
+	if err != nil {
+		return nil, err
+	}
+	return reflect.Indirect(reflect.ValueOf(model)).Field(pk).Interface(), nil
+}
+
+// GetPrimaryKeyType returns the reflect.Type of the primary key for the model.
+func (i *Integrator) GetPrimaryKeyType(model interface{}) (reflect.Type, error) {
+	typ, err := modelType(model)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := primaryKeyIndex(typ)
+	if err != nil {
+		return nil, err
+	}
+	return typ.Field(pk).Type, nil
+}
+
+// FetchInstances retrieves all instances of the given model.
+func (i *Integrator) FetchInstances(model interface{}) (interface{}, error) {
+	instances, _, err := i.fetch(model, nil, adminpanel.ListQuery{})
+	return instances, err
+}
+
+// FetchInstancesOnlyFields retrieves instances with only the specified fields and their primary key.
+func (i *Integrator) FetchInstancesOnlyFields(model interface{}, fields []string) (interface{}, error) {
+	instances, _, err := i.fetch(model, fields, adminpanel.ListQuery{})
+	return instances, err
+}
+
+// FetchInstancesOnlyFieldWithSearch retrieves instances whose search fields contain the query, ignoring case.
+func (i *Integrator) FetchInstancesOnlyFieldWithSearch(model interface{}, fields []string, query string, searchFields []string) (interface{}, error) {
+	instances, _, err := i.fetch(model, fields, adminpanel.ListQuery{Search: query, SearchFields: searchFields})
+	return instances, err
+}
+
+// FetchInstancesPage retrieves a page of instances together with the number of instances matching the query.
+func (i *Integrator) FetchInstancesPage(model interface{}, query adminpanel.ListQuery) (interface{}, uint, error) {
+	return i.fetch(model, query.Fields, query)
+}
+
+// GetAll retrieves all instances of the given model.
+func (i *Integrator) GetAll(model interface{}) (interface{}, error) {
+	return i.FetchInstances(model)
+}
+
+// fetch returns copies of the given fields of the instances matching query, as a slice of pointers to the model
+// type, together with the number of matching instances before Offset and Limit. Nil fields copy every field.
+func (i *Integrator) fetch(model interface{}, fields []string, query adminpanel.ListQuery) (interface{}, uint, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	t, err := i.readTable(model)
+	if err != nil {
+		return nil, 0, err
+	}
+	var indexes []int
+	if fields != nil {
+		if indexes, err = t.fieldIndexes(fields); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	rows := make([]reflect.Value, 0, len(t.order))
+	for _, key := range t.order {
+		row := t.rows[key]
+		matches, err := t.matches(row, query)
+		if err != nil {
+			return nil, 0, err
+		}
+		if matches {
+			rows = append(rows, row)
+		}
+	}
+	if err = t.sort(rows, query.Ordering); err != nil {
+		return nil, 0, err
+	}
+
+	total := uint(len(rows))
+	start := query.Offset
+	if start > total {
+		start = total
+	}
+	end := total
+	if query.Limit > 0 && start+query.Limit < total {
+		end = start + query.Limit
+	}
+
+	instances := reflect.MakeSlice(reflect.SliceOf(reflect.PointerTo(t.typ)), 0, int(end-start))
+	for _, row := range rows[start:end] {
+		instances = reflect.Append(instances, reflect.ValueOf(t.copyFields(row, indexes)))
+	}
+	return instances.Interface(), total, nil
+}
+
+// FetchInstance retrieves a single instance of the model by its primary key.
+func (i *Integrator) FetchInstance(model interface{}, id interface{}) (interface{}, error) {
+	return i.fetchOne(model, id, nil)
+}
+
+// FetchInstanceOnlyFields retrieves a single instance with only the specified fields and its primary key.
+func (i *Integrator) FetchInstanceOnlyFields(model interface{}, id interface{}, fields []string) (interface{}, error) {
+	return i.fetchOne(model, id, fields)
+}
+
+func (i *Integrator) fetchOne(model interface{}, id interface{}, fields []string) (interface{}, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	t, err := i.readTable(model)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []int
+	if fields != nil {
+		if indexes, err = t.fieldIndexes(fields); err != nil {
+			return nil, err
+		}
+	}
+	key, err := t.key(id)
+	if err != nil {
+		return nil, err
+	}
+	row, ok := t.rows[key]
+	if !ok {
+		return nil, fmt.Errorf("%s %v: %w", t.typ.Name(), id, ErrNotFound)
+	}
+	return t.copyFields(row, indexes), nil
+}
+
+// CreateInstance stores a copy of a new instance.
+func (i *Integrator) CreateInstance(instance interface{}) error {
+	return i.create(instance, nil)
+}
+
+// CreateInstanceOnlyFields stores a copy of the specified fields of a new instance, leaving the others at their
+// zero value.
+func (i *Integrator) CreateInstanceOnlyFields(instance interface{}, fields []string) error {
+	if fields == nil {
+		fields = []string{}
+	}
+	return i.create(instance, fields)
+}
+
+func (i *Integrator) create(instance interface{}, fields []string) error {
+	value := reflect.ValueOf(instance)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("instance must be a non-nil pointer to a struct, got %T", instance)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	t, err := i.getTable(instance)
+	if err != nil {
+		return err
+	}
+	var indexes []int
+	if fields != nil {
+		if indexes, err = t.fieldIndexes(fields); err != nil {
+			return err
+		}
+	}
+
+	pk := value.Elem().Field(t.pk)
+	if pk.IsZero() {
+		switch pk.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			t.nextID++
+			pk.SetInt(int64(t.nextID))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			t.nextID++
+			pk.SetUint(t.nextID)
+		default:
+			return fmt.Errorf("%s needs a primary key", t.typ.Name())
+		}
+	} else if id, ok := integerValue(pk); ok && id > t.nextID {
+		t.nextID = id
+	}
+
+	key, err := t.key(pk.Interface())
+	if err != nil {
+		return err
+	}
+	if _, exists := t.rows[key]; exists {
+		return fmt.Errorf("%s with primary key %v already exists", t.typ.Name(), key)
+	}
+	t.rows[key] = reflect.ValueOf(t.copyFields(value.Elem(), indexes)).Elem()
+	t.order = append(t.order, key)
+	return nil
+}
+
+// integerValue returns the value of a positive integer field.
+func integerValue(value reflect.Value) (uint64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Int() > 0 {
+			return uint64(value.Int()), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint(), true
+	}
+	return 0, false
+}
+
+// UpdateInstance replaces every field of an existing instance but its primary key.
+func (i *Integrator) UpdateInstance(instance interface{}, primaryKey interface{}) error {
+	return i.update(instance, nil, primaryKey, nil)
+}
+
+// UpdateInstanceOnlyFields replaces the specified fields of an existing instance.
+func (i *Integrator) UpdateInstanceOnlyFields(instance interface{}, fields []string, primaryKey interface{}) error {
+	if fields == nil {
+		fields = []string{}
+	}
+	return i.update(instance, fields, primaryKey, nil)
+}
+
+// UpdateInstanceOnlyFieldsIfVersion replaces the specified fields of an existing instance if its versionField still
+// equals version, and reports whether it did.
+func (i *Integrator) UpdateInstanceOnlyFieldsIfVersion(instance interface{}, fields []string, primaryKey interface{}, versionField string, version interface{}) (bool, error) {
+	if fields == nil {
+		fields = []string{}
+	}
+	updated := false
+	err := i.update(instance, fields, primaryKey, func(t *table, row reflect.Value) (bool, error) {
+		field, ok := t.typ.FieldByName(versionField)
+		if !ok {
+			return false, fmt.Errorf("model %s has no field %s", t.typ.Name(), versionField)
+		}
+		updated = compareValues(row.FieldByIndex(field.Index), reflect.ValueOf(version)) == 0
+		return updated, nil
+	})
+	return updated, err
+}
+
+// update replaces the given fields, or every field if fields is nil, of the row with the given primary key. When
+// check is given, the row is only changed if check approves it.
+func (i *Integrator) update(instance interface{}, fields []string, primaryKey interface{}, check func(t *table, row reflect.Value) (bool, error)) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	t, err := i.getTable(instance)
+	if err != nil {
+		return err
+	}
+	key, err := t.key(primaryKey)
+	if err != nil {
+		return err
+	}
+	row, ok := t.rows[key]
+	if !ok {
+		return fmt.Errorf("%s %v: %w", t.typ.Name(), primaryKey, ErrNotFound)
+	}
+	if check != nil {
+		if ok, err = check(t, row); err != nil || !ok {
+			return err
+		}
+	}
+	source := reflect.Indirect(reflect.ValueOf(instance))
+	for index := 0; index < t.typ.NumField(); index++ {
+		if index == t.pk {
+			continue
+		}
+		if fields == nil || containsField(fields, t.typ.Field(index).Name) {
+			row.Field(index).Set(source.Field(index))
+		}
+	}
+	return nil
+}
+
+func containsField(fields []string, name string) bool {
+	for _, field := range fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteInstance deletes an instance of the model by its primary key.
+func (i *Integrator) DeleteInstance(model interface{}, id interface{}) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	t, err := i.getTable(model)
+	if err != nil {
+		return err
+	}
+	key, err := t.key(id)
+	if err != nil {
+		return err
+	}
+	if _, ok := t.rows[key]; !ok {
+		return fmt.Errorf("%s %v: %w", t.typ.Name(), id, ErrNotFound)
+	}
+	delete(t.rows, key)
+	for index, existing := range t.order {
+		if existing == key {
+			t.order = append(t.order[:index], t.order[index+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteByID deletes an instance of the model by its primary key.
+func (i *Integrator) DeleteByID(model interface{}, id interface{}) error {
+	return i.DeleteInstance(model, id)
+}
+
+// matches reports whether a row matches the search and the filters of query.
+func (t *table) matches(row reflect.Value, query adminpanel.ListQuery) (bool, error) {
+	if query.Search != "" && len(query.SearchFields) > 0 {
+		search := strings.ToLower(query.Search)
+		found := false
+		for _, name := range query.SearchFields {
+			field := row.FieldByName(name)
+			if !field.IsValid() {
+				return false, fmt.Errorf("model %s has no field %s", t.typ.Name(), name)
+			}
+			field = reflect.Indirect(field)
+			if field.IsValid() && strings.Contains(strings.ToLower(fmt.Sprint(field.Interface())), search) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	for _, filter := range query.Filters {
+		field := row.FieldByName(filter.Field)
+		if !field.IsValid() {
+			return false, fmt.Errorf("model %s has no field %s", t.typ.Name(), filter.Field)
+		}
+		field = reflect.Indirect(field)
+		if filter.Operator == adminpanel.FilterIsNull {
+			if isNull, _ := filter.Value.(bool); isNull == field.IsValid() {
+				return false, nil
+			}
+			continue
+		}
+		if !field.IsValid() {
+			return false, nil
+		}
+		value := reflect.ValueOf(filter.Value)
+		if !value.IsValid() {
+			return false, nil
+		}
+		if value.Type() != field.Type() && value.Type().ConvertibleTo(field.Type()) {
+			value = value.Convert(field.Type())
+		}
+		comparison := compareValues(field, value)
+		var ok bool
+		switch filter.Operator {
+		case adminpanel.FilterExact:
+			ok = comparison == 0
+		case adminpanel.FilterGTE:
+			ok = comparison >= 0
+		case adminpanel.FilterLTE:
+			ok = comparison <= 0
+		case adminpanel.FilterLT:
+			ok = comparison < 0
+		default:
+			return false, fmt.Errorf("unsupported filter operator %q", filter.Operator)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// sort orders rows by the given fields, keeping insertion order between equal rows.
+func (t *table) sort(rows []reflect.Value, ordering []adminpanel.OrderBy) error {
+	indexes := make([]int, len(ordering))
+	for position, order := range ordering {
+		field, ok := t.typ.FieldByName(order.Field)
+		if !ok || len(field.Index) != 1 {
+			return fmt.Errorf("model %s has no field %s", t.typ.Name(), order.Field)
+		}
+		indexes[position] = field.Index[0]
+	}
+	sort.SliceStable(rows, func(a, b int) bool {
+		for position, order := range ordering {
+			comparison := compareValues(reflect.Indirect(rows[a].Field(indexes[position])), reflect.Indirect(rows[b].Field(indexes[position])))
+			if comparison == 0 {
+				continue
+			}
+			if order.Descending {
+				return comparison > 0
+			}
+			return comparison < 0
+		}
+		return false
+	})
+	return nil
+}
@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type Book struct {
+	ID        uint
+	Title     string
+	Pages     int
+	Published *time.Time
+}
+
+type Country struct {
+	Code string `admin:"pk"`
+	Name string
+}
+
+func seededIntegrator(t *testing.T) *Integrator {
+	integrator := NewIntegrator()
+	err := integrator.Seed(
+		[]Book{{Title: "Go in Action", Pages: 300}, {Title: "The Go Programming Language", Pages: 380}},
+		&Book{Title: "Learning SQL", Pages: 340},
+		Country{Code: "NL", Name: "Netherlands"},
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return integrator
+}
+
+func TestIntegrator_Seed(t *testing.T) {
+	integrator := seededIntegrator(t)
+
+	book := &Book{Title: "Concurrency in Go"}
+	if err := integrator.Seed(book); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if book.ID != 4 {
+		t.Errorf("expected the next ID to be set on the seeded book, got %d", book.ID)
+	}
+	if err := integrator.Seed(Country{Code: "NL"}); err == nil {
+		t.Error("expected an error for a duplicate primary key")
+	}
+	if err := integrator.Seed(Country{Name: "Nowhere"}); err == nil {
+		t.Error("expected an error for a missing string primary key")
+	}
+	if err := integrator.Seed(struct{ Name string }{"x"}); err == nil {
+		t.Error("expected an error for a model without primary key")
+	}
+}
+
+func TestIntegrator_PrimaryKey(t *testing.T) {
+	integrator := NewIntegrator()
+
+	id, err := integrator.GetPrimaryKeyValue(&Country{Code: "BE"})
+	if err != nil || id != "BE" {
+		t.Errorf("expected primary key BE, got %v (%v)", id, err)
+	}
+	pkType, err := integrator.GetPrimaryKeyType(&Book{})
+	if err != nil || pkType != reflect.TypeOf(uint(0)) {
+		t.Errorf("expected a uint primary key, got %v (%v)", pkType, err)
+	}
+}
+
+func TestIntegrator_Fetch(t *testing.T) {
+	integrator := seededIntegrator(t)
+
+	instances, err := integrator.FetchInstancesOnlyFields(&Book{}, []string{"Title"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	books := instances.([]*Book)
+	if len(books) != 3 || books[0].ID != 1 || books[0].Title != "Go in Action" || books[0].Pages != 0 {
+		t.Errorf("expected the ID and title of each book only, got %+v", books[0])
+	}
+
+	instances, err = integrator.FetchInstancesOnlyFieldWithSearch(&Book{}, []string{"Title"}, "go", []string{"Title"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(instances.([]*Book)) != 2 {
+		t.Errorf("expected two books matching go, got %d", len(instances.([]*Book)))
+	}
+
+	instance, err := integrator.FetchInstance(&Book{}, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	instance.(*Book).Title = "Changed"
+	stored, _ := integrator.FetchInstanceOnlyFields(&Book{}, uint(2), []string{"Title"})
+	if stored.(*Book).Title != "The Go Programming Language" {
+		t.Error("expected changes to a fetched instance not to change the store")
+	}
+
+	if _, err = integrator.FetchInstance(&Book{}, uint(9)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err = integrator.FetchInstancesOnlyFields(&Book{}, []string{"Author"}); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	instances, err = integrator.GetAll(&Country{})
+	if err != nil || len(instances.([]*Country)) != 1 {
+		t.Errorf("expected one country, got %v (%v)", instances, err)
+	}
+}
+
+func TestIntegrator_FetchInstancesPage(t *testing.T) {
+	integrator := seededIntegrator(t)
+
+	instances, total, err := integrator.FetchInstancesPage(&Book{}, adminpanel.ListQuery{
+		Fields:   []string{"Title", "Pages"},
+		Filters:  []adminpanel.FilterCondition{{Field: "Pages", Operator: adminpanel.FilterGTE, Value: 310}},
+		Ordering: []adminpanel.OrderBy{{Field: "Pages", Descending: true}},
+		Offset:   1,
+		Limit:    1,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	books := instances.([]*Book)
+	if total != 2 || len(books) != 1 || books[0].Title != "Learning SQL" {
+		t.Errorf("expected the second of two long books, got %d and %+v", total, books)
+	}
+
+	_, total, err = integrator.FetchInstancesPage(&Book{}, adminpanel.ListQuery{
+		Filters: []adminpanel.FilterCondition{{Field: "Published", Operator: adminpanel.FilterLT, Value: time.Now()}},
+	})
+	if err != nil || total != 0 {
+		t.Errorf("expected books without publication date not to match, got %d (%v)", total, err)
+	}
+
+	published := time.Now()
+	book := &Book{Title: "Published", Published: &published}
+	if err = integrator.CreateInstance(book); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for isNull, expected := range map[bool]uint{true: 3, false: 1} {
+		_, total, err = integrator.FetchInstancesPage(&Book{}, adminpanel.ListQuery{
+			Filters: []adminpanel.FilterCondition{{Field: "Published", Operator: adminpanel.FilterIsNull, Value: isNull}},
+		})
+		if err != nil || total != expected {
+			t.Errorf("expected %d books with isnull %v, got %d (%v)", expected, isNull, total, err)
+		}
+	}
+}
+
+func TestIntegrator_Aggregates(t *testing.T) {
+	integrator := seededIntegrator(t)
+	if err := integrator.Seed(&Book{Title: "Go Again", Pages: 300}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	long := []adminpanel.FilterCondition{{Field: "Pages", Operator: adminpanel.FilterGTE, Value: 310}}
+
+	if count, err := integrator.CountInstances(&Book{}, long); err != nil || count != 2 {
+		t.Errorf("expected 2 long books, got %d (%v)", count, err)
+	}
+	groups, err := integrator.CountInstancesByField(&Book{}, "Pages", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []adminpanel.GroupCount{{Value: 300, Count: 2}, {Value: 340, Count: 1}, {Value: 380, Count: 1}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expected groups %v, got %v", expected, groups)
+	}
+	if groups, _ = integrator.CountInstancesByField(&Book{}, "Published", nil); len(groups) != 1 || groups[0].Value != nil || groups[0].Count != 4 {
+		t.Errorf("expected books without publication date in a single nil group, got %v", groups)
+	}
+
+	if sum, err := integrator.AggregateField(&Book{}, "Pages", adminpanel.AggregateSum, long); err != nil || sum != 720 {
+		t.Errorf("expected a sum of 720 pages, got %v (%v)", sum, err)
+	}
+	if avg, err := integrator.AggregateField(&Book{}, "Pages", adminpanel.AggregateAvg, nil); err != nil || avg != 330 {
+		t.Errorf("expected an average of 330 pages, got %v (%v)", avg, err)
+	}
+	if _, err = integrator.AggregateField(&Book{}, "Title", adminpanel.AggregateSum, nil); err == nil {
+		t.Error("expected an error summing a text field")
+	}
+}
+
+func TestIntegrator_CreateUpdateDelete(t *testing.T) {
+	integrator := seededIntegrator(t)
+
+	book := &Book{Title: "Draft", Pages: 10}
+	if err := integrator.CreateInstanceOnlyFields(book, []string{"Title"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stored, _ := integrator.FetchInstance(&Book{}, book.ID)
+	if stored.(*Book).Title != "Draft" || stored.(*Book).Pages != 0 {
+		t.Errorf("expected only the title to be stored, got %+v", stored)
+	}
+
+	if err := integrator.UpdateInstanceOnlyFields(&Book{ID: 99, Title: "Final", Pages: 20}, []string{"Pages"}, book.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stored, _ = integrator.FetchInstance(&Book{}, book.ID)
+	if expected := (&Book{ID: book.ID, Title: "Draft", Pages: 20}); !reflect.DeepEqual(stored, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stored)
+	}
+	if err := integrator.UpdateInstance(&Book{Title: "Final"}, book.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stored, _ = integrator.FetchInstance(&Book{}, book.ID)
+	if stored.(*Book).Title != "Final" || stored.(*Book).Pages != 0 {
+		t.Errorf("expected every field to be replaced, got %+v", stored)
+	}
+
+	if err := integrator.DeleteByID(&Book{}, book.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := integrator.DeleteInstance(&Book{}, book.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := integrator.UpdateInstance(&Book{}, book.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestIntegrator_UpdateInstanceOnlyFieldsIfVersion(t *testing.T) {
+	integrator := seededIntegrator(t)
+
+	updated, err := integrator.UpdateInstanceOnlyFieldsIfVersion(&Book{Title: "Go in Practice", Pages: 301}, []string{"Title", "Pages"}, uint(1), "Pages", 300)
+	if err != nil || !updated {
+		t.Fatalf("expected the book to be updated, got %v (%v)", updated, err)
+	}
+	updated, err = integrator.UpdateInstanceOnlyFieldsIfVersion(&Book{Title: "Stale", Pages: 301}, []string{"Title", "Pages"}, uint(1), "Pages", 300)
+	if err != nil || updated {
+		t.Errorf("expected a stale version not to update the book, got %v (%v)", updated, err)
+	}
+	stored, _ := integrator.FetchInstance(&Book{}, uint(1))
+	if stored.(*Book).Title != "Go in Practice" {
+		t.Errorf("expected the first update only, got %+v", stored)
+	}
+	if _, err = integrator.UpdateInstanceOnlyFieldsIfVersion(&Book{}, nil, uint(1), "Edition", 1); err == nil {
+		t.Error("expected an error for an unknown version field")
+	}
+}
+
+func TestIntegrator_ConcurrentUse(t *testing.T) {
+	integrator := NewIntegrator()
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				if err := integrator.CreateInstance(&Book{Title: fmt.Sprintf("%d-%d", worker, n)}); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := integrator.FetchInstancesOnlyFieldWithSearch(&Book{}, []string{"Title"}, "1", []string{"Title"}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	instances, _ := integrator.FetchInstances(&Book{})
+	if len(instances.([]*Book)) != 400 {
+		t.Errorf("expected 400 books, got %d", len(instances.([]*Book)))
+	}
+}
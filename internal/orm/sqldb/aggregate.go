@@ -0,0 +1,106 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"reflect"
+)
+
+// aggregateFunctions maps the aggregates of AggregateField to SQL.
+var aggregateFunctions = map[adminpanel.AggregateFunc]string{
+	adminpanel.AggregateSum: "SUM",
+	adminpanel.AggregateAvg: "AVG",
+}
+
+// count returns the number of rows matching the search query and the filters.
+func (i *Integrator) count(s *schema, search string, searchFields []string, filters []adminpanel.FilterCondition) (uint, error) {
+	q := i.dialect.newQuery().write("SELECT COUNT(*) FROM ", quote(s.table))
+	if err := q.writeWhere(s, search, searchFields, filters); err != nil {
+		return 0, err
+	}
+	var total uint
+	if err := i.q.QueryRowContext(i.getContext(), q.String(), q.args...).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// CountInstances returns the number of rows matching the filters.
+func (i *Integrator) CountInstances(model interface{}, filters []adminpanel.FilterCondition) (uint, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return 0, err
+	}
+	return i.count(s, "", nil, filters)
+}
+
+// CountInstancesByField returns the number of rows matching the filters for each value of the field's column,
+// ordered by value.
+func (i *Integrator) CountInstancesByField(model interface{}, field string, filters []adminpanel.FilterCondition) ([]adminpanel.GroupCount, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return nil, err
+	}
+	col, ok := s.fields[field]
+	if !ok {
+		return nil, fmt.Errorf("model %s has no field %s", s.typ.Name(), field)
+	}
+
+	q := i.dialect.newQuery().write("SELECT ", quote(col.name), ", COUNT(*) FROM ", quote(s.table))
+	if err = q.writeWhere(s, "", nil, filters); err != nil {
+		return nil, err
+	}
+	q.write(" GROUP BY ", quote(col.name), " ORDER BY ", quote(col.name))
+	rows, err := i.q.QueryContext(i.getContext(), q.String(), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fieldType := s.typ.Field(col.index).Type
+	groups := make([]adminpanel.GroupCount, 0)
+	for rows.Next() {
+		value := reflect.New(reflect.PointerTo(fieldType))
+		var count uint
+		if err = rows.Scan(value.Interface(), &count); err != nil {
+			return nil, err
+		}
+		group := adminpanel.GroupCount{Count: count}
+		if !value.Elem().IsNil() {
+			group.Value = value.Elem().Elem().Interface()
+		}
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// AggregateField computes the sum or the average of the field's column over the rows matching the filters. NULL
+// values are left out, and zero is returned when no row matches.
+func (i *Integrator) AggregateField(model interface{}, field string, aggregate adminpanel.AggregateFunc, filters []adminpanel.FilterCondition) (float64, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return 0, err
+	}
+	col, ok := s.fields[field]
+	if !ok {
+		return 0, fmt.Errorf("model %s has no field %s", s.typ.Name(), field)
+	}
+	function, ok := aggregateFunctions[aggregate]
+	if !ok {
+		return 0, fmt.Errorf("unsupported aggregate %q", aggregate)
+	}
+
+	q := i.dialect.newQuery().write("SELECT ", function, "(", quote(col.name), ") FROM ", quote(s.table))
+	if err = q.writeWhere(s, "", nil, filters); err != nil {
+		return 0, err
+	}
+	var result sql.NullFloat64
+	if err = i.q.QueryRowContext(i.getContext(), q.String(), q.args...).Scan(&result); err != nil {
+		return 0, err
+	}
+	return result.Float64, nil
+}
@@ -0,0 +1,168 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+)
+
+// MockStatement is a statement received by MockDriver.
+type MockStatement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// MockResponse is the answer of MockDriver to a statement. Queries return Columns and Rows; other statements report
+// LastInsertID and RowsAffected.
+type MockResponse struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	LastInsertID int64
+	RowsAffected int64
+	Err          error
+}
+
+// MockDriver is a driver.Driver recording every statement it receives, including transaction boundaries as BEGIN,
+// COMMIT and ROLLBACK, and answering them with the queued Responses. Once the queue is empty, queries return no rows
+// and other statements affect one row.
+type MockDriver struct {
+	mu         sync.Mutex
+	Statements []MockStatement
+	Responses  []MockResponse
+}
+
+func (d *MockDriver) Open(string) (driver.Conn, error) {
+	return &mockConn{driver: d}, nil
+}
+
+func (d *MockDriver) Connect(context.Context) (driver.Conn, error) {
+	return d.Open("")
+}
+
+func (d *MockDriver) Driver() driver.Driver {
+	return d
+}
+
+// Queries returns the text of the received statements.
+func (d *MockDriver) Queries() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	queries := make([]string, len(d.Statements))
+	for i, statement := range d.Statements {
+		queries[i] = statement.Query
+	}
+	return queries
+}
+
+func (d *MockDriver) receive(query string, args []driver.NamedValue) MockResponse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	d.Statements = append(d.Statements, MockStatement{Query: query, Args: values})
+	if len(d.Responses) == 0 {
+		return MockResponse{RowsAffected: 1}
+	}
+	response := d.Responses[0]
+	d.Responses = d.Responses[1:]
+	return response
+}
+
+type mockConn struct {
+	driver *MockDriver
+}
+
+func (c *mockConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *mockConn) Close() error {
+	return nil
+}
+
+func (c *mockConn) Begin() (driver.Tx, error) {
+	c.driver.receive("BEGIN", nil)
+	return &mockTx{driver: c.driver}, nil
+}
+
+func (c *mockConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	response := c.driver.receive(query, args)
+	if response.Err != nil {
+		return nil, response.Err
+	}
+	return mockResult{lastInsertID: response.LastInsertID, rowsAffected: response.RowsAffected}, nil
+}
+
+func (c *mockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	response := c.driver.receive(query, args)
+	if response.Err != nil {
+		return nil, response.Err
+	}
+	return &mockRows{columns: response.Columns, rows: response.Rows}, nil
+}
+
+type mockTx struct {
+	driver *MockDriver
+}
+
+func (t *mockTx) Commit() error {
+	t.driver.receive("COMMIT", nil)
+	return nil
+}
+
+func (t *mockTx) Rollback() error {
+	t.driver.receive("ROLLBACK", nil)
+	return nil
+}
+
+type mockResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r mockResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r mockResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type mockRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *mockRows) Columns() []string {
+	return r.columns
+}
+
+func (r *mockRows) Close() error {
+	return nil
+}
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// newMockIntegrator returns an integrator in the given dialect backed by a new MockDriver.
+func newMockIntegrator(t *testing.T, dialect Dialect) (*Integrator, *MockDriver) {
+	mockDriver := &MockDriver{}
+	db := sql.OpenDB(mockDriver)
+	t.Cleanup(func() { _ = db.Close() })
+	integrator, err := NewIntegrator(db, dialect)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return integrator, mockDriver
+}
@@ -0,0 +1,432 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"reflect"
+)
+
+// queryer runs statements. It is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Integrator is an adminpanel.ORMIntegrator storing models in an SQL database through database/sql. Each model is
+// a struct mapped to a table as described by parseSchema. Besides the required methods, it paginates list views and
+// computes dashboard statistics in the database, runs atomic writes in transactions and runs statements with the
+// context of the admin request.
+type Integrator struct {
+	db      *sql.DB
+	q       queryer
+	ctx     context.Context
+	dialect Dialect
+	schemas *schemaCache
+}
+
+// NewIntegrator creates an integrator using db, generating queries in the given dialect.
+func NewIntegrator(db *sql.DB, dialect Dialect) (*Integrator, error) {
+	if db == nil {
+		return nil, errors.New("database is required")
+	}
+	if dialect != DialectSQLite && dialect != DialectPostgres {
+		return nil, fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+	return &Integrator{db: db, q: db, dialect: dialect, schemas: &schemaCache{}}, nil
+}
+
+// WithContext returns a copy of the integrator running its statements with ctx.
+func (i *Integrator) WithContext(ctx context.Context) adminpanel.ORMIntegrator {
+	bound := *i
+	bound.ctx = ctx
+	return &bound
+}
+
+// getContext returns the context statements run with.
+func (i *Integrator) getContext() context.Context {
+	if i.ctx != nil {
+		return i.ctx
+	}
+	return context.Background()
+}
+
+// notFound returns the error reported for a missing row. It wraps adminpanel.ErrInstanceNotFound and sql.ErrNoRows.
+func notFound(s *schema, id interface{}) error {
+	return fmt.Errorf("%w: %s with primary key %v: %w", adminpanel.ErrInstanceNotFound, s.typ.Name(), id, sql.ErrNoRows)
+}
+
+// GetPrimaryKeyValue returns the primary key value of the given model instance.
+func (i *Integrator) GetPrimaryKeyValue(model interface{}) (interface{}, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return nil, err
+	}
+	value := reflect.Indirect(reflect.ValueOf(model))
+	return value.Field(s.pk.index).Interface(), nil
+}
+
+// GetPrimaryKeyType returns the reflect.Type of the primary key for the model.
+func (i *Integrator) GetPrimaryKeyType(model interface{}) (reflect.Type, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return nil, err
+	}
+	return s.typ.Field(s.pk.index).Type, nil
+}
+
+// FetchInstances retrieves all instances of the given model.
+func (i *Integrator) FetchInstances(model interface{}) (interface{}, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return nil, err
+	}
+	return i.fetchList(s, s.columns, adminpanel.ListQuery{})
+}
+
+// FetchInstancesOnlyFields retrieves instances with only the specified fields and their primary key.
+func (i *Integrator) FetchInstancesOnlyFields(model interface{}, fields []string) (interface{}, error) {
+	return i.FetchInstancesOnlyFieldWithSearch(model, fields, "", nil)
+}
+
+// FetchInstancesOnlyFieldWithSearch retrieves instances whose search fields contain the query, ignoring case.
+func (i *Integrator) FetchInstancesOnlyFieldWithSearch(model interface{}, fields []string, query string, searchFields []string) (interface{}, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := s.columnsFor(fields, true)
+	if err != nil {
+		return nil, err
+	}
+	return i.fetchList(s, columns, adminpanel.ListQuery{Search: query, SearchFields: searchFields})
+}
+
+// FetchInstancesPage retrieves a page of instances together with the number of instances matching the query.
+func (i *Integrator) FetchInstancesPage(model interface{}, listQuery adminpanel.ListQuery) (interface{}, uint, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return nil, 0, err
+	}
+	columns, err := s.columnsFor(listQuery.Fields, true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := i.count(s, listQuery.Search, listQuery.SearchFields, listQuery.Filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	instances, err := i.fetchList(s, columns, listQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+	return instances, total, nil
+}
+
+// fetchList selects the given columns of the rows matching listQuery, whose Fields are ignored, into a slice of
+// pointers to the model type. A zero limit selects every row. Limited queries are ordered by the primary key last,
+// as databases only page consistently through a total order.
+func (i *Integrator) fetchList(s *schema, columns []*column, listQuery adminpanel.ListQuery) (interface{}, error) {
+	q := i.dialect.newQuery().write("SELECT ", columnList(columns), " FROM ", quote(s.table))
+	if err := q.writeWhere(s, listQuery.Search, listQuery.SearchFields, listQuery.Filters); err != nil {
+		return nil, err
+	}
+	ordering := listQuery.Ordering
+	if listQuery.Limit > 0 {
+		ordering = s.withPrimaryKeyOrdering(ordering)
+	}
+	if err := q.writeOrderBy(s, ordering); err != nil {
+		return nil, err
+	}
+	if listQuery.Limit > 0 {
+		q.write(" LIMIT ", q.arg(listQuery.Limit), " OFFSET ", q.arg(listQuery.Offset))
+	}
+	return i.query(s, columns, q)
+}
+
+// query runs a SELECT statement and scans the rows into a slice of pointers to the model type.
+func (i *Integrator) query(s *schema, columns []*column, q *query) (interface{}, error) {
+	rows, err := i.q.QueryContext(i.getContext(), q.String(), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instances := reflect.MakeSlice(reflect.SliceOf(reflect.PointerTo(s.typ)), 0, 0)
+	for rows.Next() {
+		instance := reflect.New(s.typ)
+		targets := make([]interface{}, len(columns))
+		for index, col := range columns {
+			targets[index] = instance.Elem().Field(col.index).Addr().Interface()
+		}
+		if err = rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		instances = reflect.Append(instances, instance)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return instances.Interface(), nil
+}
+
+// FetchInstance retrieves a single instance of the model by its primary key.
+func (i *Integrator) FetchInstance(model interface{}, id interface{}) (interface{}, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return nil, err
+	}
+	return i.fetchOne(s, s.columns, id)
+}
+
+// FetchInstanceOnlyFields retrieves a single instance with only the specified fields and its primary key.
+func (i *Integrator) FetchInstanceOnlyFields(model interface{}, id interface{}, fields []string) (interface{}, error) {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := s.columnsFor(fields, true)
+	if err != nil {
+		return nil, err
+	}
+	return i.fetchOne(s, columns, id)
+}
+
+// fetchOne selects the given columns of the row with the given primary key.
+func (i *Integrator) fetchOne(s *schema, columns []*column, id interface{}) (interface{}, error) {
+	instances, err := i.fetchList(s, columns, adminpanel.ListQuery{Filters: []adminpanel.FilterCondition{{
+		Field:    s.pk.field,
+		Operator: adminpanel.FilterExact,
+		Value:    id,
+	}}})
+	if err != nil {
+		return nil, err
+	}
+	list := reflect.ValueOf(instances)
+	if list.Len() == 0 {
+		return nil, notFound(s, id)
+	}
+	return list.Index(0).Interface(), nil
+}
+
+// GetAll retrieves all instances of the given model.
+func (i *Integrator) GetAll(model interface{}) (interface{}, error) {
+	return i.FetchInstances(model)
+}
+
+// CreateInstance inserts every column of a new instance. A zero primary key is left to the database, and the
+// generated key is set on the instance.
+func (i *Integrator) CreateInstance(instance interface{}) error {
+	s, err := i.schemas.get(instance)
+	if err != nil {
+		return err
+	}
+	return i.insert(s, instance, s.columns)
+}
+
+// CreateInstanceOnlyFields inserts only the specified fields of a new instance, as CreateInstance does.
+func (i *Integrator) CreateInstanceOnlyFields(instance interface{}, fields []string) error {
+	s, err := i.schemas.get(instance)
+	if err != nil {
+		return err
+	}
+	columns, err := s.columnsFor(fields, true)
+	if err != nil {
+		return err
+	}
+	return i.insert(s, instance, columns)
+}
+
+func (i *Integrator) insert(s *schema, instance interface{}, columns []*column) error {
+	value := reflect.ValueOf(instance)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("instance must be a non-nil pointer to %s", s.typ.Name())
+	}
+	value = value.Elem()
+	pk := value.Field(s.pk.index)
+
+	inserted := make([]*column, 0, len(columns))
+	for _, col := range columns {
+		if col == s.pk && pk.IsZero() {
+			continue
+		}
+		inserted = append(inserted, col)
+	}
+
+	q := i.dialect.newQuery().write("INSERT INTO ", quote(s.table))
+	if len(inserted) == 0 {
+		q.write(" DEFAULT VALUES")
+	} else {
+		q.write(" (", columnList(inserted), ") VALUES (")
+		for index, col := range inserted {
+			if index > 0 {
+				q.write(", ")
+			}
+			q.write(q.arg(value.Field(col.index).Interface()))
+		}
+		q.write(")")
+	}
+
+	if !pk.IsZero() {
+		_, err := i.q.ExecContext(i.getContext(), q.String(), q.args...)
+		return err
+	}
+	if i.dialect == DialectPostgres {
+		q.write(" RETURNING ", quote(s.pk.name))
+		return i.q.QueryRowContext(i.getContext(), q.String(), q.args...).Scan(pk.Addr().Interface())
+	}
+	result, err := i.q.ExecContext(i.getContext(), q.String(), q.args...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	switch pk.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		pk.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		pk.SetUint(uint64(id))
+	}
+	return nil
+}
+
+// UpdateInstance updates every column of an existing instance but its primary key.
+func (i *Integrator) UpdateInstance(instance interface{}, primaryKey interface{}) error {
+	s, err := i.schemas.get(instance)
+	if err != nil {
+		return err
+	}
+	return i.update(s, instance, s.columns, primaryKey)
+}
+
+// UpdateInstanceOnlyFields updates only the specified fields of an existing instance.
+func (i *Integrator) UpdateInstanceOnlyFields(instance interface{}, fields []string, primaryKey interface{}) error {
+	s, err := i.schemas.get(instance)
+	if err != nil {
+		return err
+	}
+	columns, err := s.columnsFor(fields, false)
+	if err != nil {
+		return err
+	}
+	return i.update(s, instance, columns, primaryKey)
+}
+
+// UpdateInstanceOnlyFieldsIfVersion updates the specified fields of an existing instance if its version column still
+// holds version, and reports whether a row was updated.
+func (i *Integrator) UpdateInstanceOnlyFieldsIfVersion(instance interface{}, fields []string, primaryKey interface{}, versionField string, version interface{}) (bool, error) {
+	s, err := i.schemas.get(instance)
+	if err != nil {
+		return false, err
+	}
+	columns, err := s.columnsFor(fields, false)
+	if err != nil {
+		return false, err
+	}
+	versionColumn, ok := s.fields[versionField]
+	if !ok {
+		return false, fmt.Errorf("model %s has no column for version field %s", s.typ.Name(), versionField)
+	}
+	q := i.updateQuery(s, instance, columns, primaryKey)
+	if q == nil {
+		return true, nil
+	}
+	q.write(" AND ", quote(versionColumn.name), " = ", q.arg(version))
+	result, err := i.q.ExecContext(i.getContext(), q.String(), q.args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (i *Integrator) update(s *schema, instance interface{}, columns []*column, primaryKey interface{}) error {
+	q := i.updateQuery(s, instance, columns, primaryKey)
+	if q == nil {
+		return nil
+	}
+	return i.exec(s, q, primaryKey)
+}
+
+// updateQuery builds the statement updating the given columns of the row with the given primary key, or returns nil
+// when there is nothing to update.
+func (i *Integrator) updateQuery(s *schema, instance interface{}, columns []*column, primaryKey interface{}) *query {
+	value := reflect.Indirect(reflect.ValueOf(instance))
+	q := i.dialect.newQuery().write("UPDATE ", quote(s.table), " SET ")
+	updated := 0
+	for _, col := range columns {
+		if col == s.pk {
+			continue
+		}
+		if updated > 0 {
+			q.write(", ")
+		}
+		q.write(quote(col.name), " = ", q.arg(value.Field(col.index).Interface()))
+		updated++
+	}
+	if updated == 0 {
+		return nil
+	}
+	return q.write(" WHERE ", quote(s.pk.name), " = ", q.arg(primaryKey))
+}
+
+// DeleteInstance deletes an instance of the model by its primary key.
+func (i *Integrator) DeleteInstance(model interface{}, id interface{}) error {
+	s, err := i.schemas.get(model)
+	if err != nil {
+		return err
+	}
+	q := i.dialect.newQuery().write("DELETE FROM ", quote(s.table), " WHERE ", quote(s.pk.name), " = ")
+	q.write(q.arg(id))
+	return i.exec(s, q, id)
+}
+
+// DeleteByID deletes an instance of the model by its primary key.
+func (i *Integrator) DeleteByID(model interface{}, id interface{}) error {
+	return i.DeleteInstance(model, id)
+}
+
+// exec runs a statement changing the row with the given primary key, reporting a missing row.
+func (i *Integrator) exec(s *schema, q *query, id interface{}) error {
+	result, err := i.q.ExecContext(i.getContext(), q.String(), q.args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound(s, id)
+	}
+	return nil
+}
+
+// WithTransaction runs fn with an integrator bound to a new transaction. Called on an integrator already bound to a
+// transaction, it runs fn in that transaction.
+func (i *Integrator) WithTransaction(fn func(tx adminpanel.ORMIntegrator) error) error {
+	if i.db == nil {
+		return fn(i)
+	}
+	tx, err := i.db.BeginTx(i.getContext(), nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(&Integrator{q: tx, ctx: i.ctx, dialect: i.dialect, schemas: i.schemas}); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
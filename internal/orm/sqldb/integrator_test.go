@@ -0,0 +1,409 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"reflect"
+	"testing"
+)
+
+type Article struct {
+	ID       uint
+	Title    string
+	AuthorID uint   `db:"author"`
+	Body     string `admin:"column:content"`
+	Draft    bool   `db:"-"`
+	notes    string
+}
+
+type Tag struct {
+	Slug string `admin:"pk"`
+	Name string
+}
+
+func (t *Tag) TableName() string {
+	return "tags"
+}
+
+func TestParseSchema(t *testing.T) {
+	cache := &schemaCache{}
+	s, err := cache.get(&Article{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if s.table != "article" || s.pk.name != "id" {
+		t.Errorf("expected table article with primary key id, got %s and %s", s.table, s.pk.name)
+	}
+	var names []string
+	for _, col := range s.columns {
+		names = append(names, col.name)
+	}
+	if expected := []string{"id", "title", "author", "content"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected columns %v, got %v", expected, names)
+	}
+
+	s, err = cache.get(Tag{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if s.table != "tags" || s.pk.field != "Slug" {
+		t.Errorf("expected table tags with primary key Slug, got %s and %s", s.table, s.pk.field)
+	}
+
+	if _, err = cache.get(&struct{ Name string }{}); err == nil {
+		t.Error("expected an error for a model without primary key")
+	}
+	if _, err = cache.get("article"); err == nil {
+		t.Error("expected an error for a model that is not a struct")
+	}
+}
+
+func TestSchema_ColumnsFor(t *testing.T) {
+	s, _ := (&schemaCache{}).get(&Article{})
+
+	columns, err := s.columnsFor([]string{"Title", "Draft", "ID"}, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if columnList(columns) != `"id", "title"` {
+		t.Errorf("expected the primary key first and ignored fields left out, got %s", columnList(columns))
+	}
+	if _, err = s.columnsFor([]string{"Missing"}, false); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestToSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"ID":        "id",
+		"Title":     "title",
+		"CreatedAt": "created_at",
+		"UserID":    "user_id",
+		"HTMLBody":  "html_body",
+		"Address2":  "address2",
+	} {
+		if got := toSnakeCase(name); got != expected {
+			t.Errorf("expected %s to become %s, got %s", name, expected, got)
+		}
+	}
+}
+
+func TestNewIntegrator(t *testing.T) {
+	if _, err := NewIntegrator(nil, DialectSQLite); err == nil {
+		t.Error("expected an error without database")
+	}
+	db := sql.OpenDB(&MockDriver{})
+	defer db.Close()
+	if _, err := NewIntegrator(db, Dialect("oracle")); err == nil {
+		t.Error("expected an error for an unsupported dialect")
+	}
+}
+
+func TestIntegrator_PrimaryKey(t *testing.T) {
+	integrator, _ := newMockIntegrator(t, DialectSQLite)
+
+	id, err := integrator.GetPrimaryKeyValue(&Article{ID: 4})
+	if err != nil || id != uint(4) {
+		t.Errorf("expected primary key 4, got %v (%v)", id, err)
+	}
+	pkType, err := integrator.GetPrimaryKeyType(&Tag{})
+	if err != nil || pkType != reflect.TypeOf("") {
+		t.Errorf("expected a string primary key, got %v (%v)", pkType, err)
+	}
+}
+
+func TestIntegrator_FetchInstancesPage_Postgres(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectPostgres)
+	mockDriver.Responses = []MockResponse{
+		{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(12)}}},
+		{Columns: []string{"id", "title"}, Rows: [][]driver.Value{{int64(3), "Go"}, {int64(1), "SQL"}}},
+	}
+
+	instances, total, err := integrator.FetchInstancesPage(&Article{}, adminpanel.ListQuery{
+		Fields:       []string{"Title"},
+		Search:       "Go",
+		SearchFields: []string{"Title", "Body"},
+		Filters:      []adminpanel.FilterCondition{{Field: "AuthorID", Operator: adminpanel.FilterExact, Value: uint(2)}},
+		Ordering:     []adminpanel.OrderBy{{Field: "Title", Descending: true}},
+		Offset:       10,
+		Limit:        5,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	where := ` WHERE (LOWER(CAST("title" AS TEXT)) LIKE $1 ESCAPE '\' OR LOWER(CAST("content" AS TEXT)) LIKE $2 ESCAPE '\') AND "author" = $3`
+	expected := []string{
+		`SELECT COUNT(*) FROM "article"` + where,
+		`SELECT "id", "title" FROM "article"` + where + ` ORDER BY "title" DESC, "id" ASC LIMIT $4 OFFSET $5`,
+	}
+	if queries := mockDriver.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected queries %q, got %q", expected, queries)
+	}
+	expectedArgs := []driver.Value{"%go%", "%go%", int64(2), int64(5), int64(10)}
+	if args := mockDriver.Statements[1].Args; !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected arguments %v, got %v", expectedArgs, args)
+	}
+	if total != 12 {
+		t.Errorf("expected a total of 12, got %d", total)
+	}
+	expectedInstances := []*Article{{ID: 3, Title: "Go"}, {ID: 1, Title: "SQL"}}
+	if !reflect.DeepEqual(instances, expectedInstances) {
+		t.Errorf("expected instances %v, got %v", expectedInstances, instances)
+	}
+}
+
+func TestIntegrator_FetchInstancesPage_PrimaryKeyOrdering(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectPostgres)
+	count := MockResponse{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(0)}}}
+	mockDriver.Responses = []MockResponse{count, {Columns: []string{"id"}}, count}
+
+	for _, ordering := range [][]adminpanel.OrderBy{nil, {{Field: "ID", Descending: true}}} {
+		if _, _, err := integrator.FetchInstancesPage(&Article{}, adminpanel.ListQuery{Ordering: ordering, Limit: 5}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	queries := mockDriver.Queries()
+	expected := []string{
+		`SELECT "id" FROM "article" ORDER BY "id" ASC LIMIT $1 OFFSET $2`,
+		`SELECT "id" FROM "article" ORDER BY "id" DESC LIMIT $1 OFFSET $2`,
+	}
+	if len(queries) != 4 || queries[1] != expected[0] || queries[3] != expected[1] {
+		t.Errorf("expected pages ordered by primary key %q, got %q", expected, queries)
+	}
+}
+
+func TestIntegrator_FetchInstancesPage_IsNull(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectSQLite)
+	mockDriver.Responses = []MockResponse{{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(0)}}}}
+
+	_, _, err := integrator.FetchInstancesPage(&Article{}, adminpanel.ListQuery{
+		Fields: []string{"Title"},
+		Filters: []adminpanel.FilterCondition{
+			{Field: "Body", Operator: adminpanel.FilterIsNull, Value: true},
+			{Field: "AuthorID", Operator: adminpanel.FilterIsNull, Value: false},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := `SELECT COUNT(*) FROM "article" WHERE "content" IS NULL AND "author" IS NOT NULL`
+	if statement := mockDriver.Statements[0]; statement.Query != expected || len(statement.Args) != 0 {
+		t.Errorf("expected %s without arguments, got %s with %v", expected, statement.Query, statement.Args)
+	}
+}
+
+func TestIntegrator_Aggregates(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectPostgres)
+	mockDriver.Responses = []MockResponse{
+		{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(7)}}},
+		{Columns: []string{"author", "count"}, Rows: [][]driver.Value{{int64(1), int64(4)}, {int64(2), int64(3)}}},
+		{Columns: []string{"avg"}, Rows: [][]driver.Value{{2.5}}},
+		{Columns: []string{"sum"}, Rows: [][]driver.Value{{nil}}},
+	}
+	filters := []adminpanel.FilterCondition{{Field: "Title", Operator: adminpanel.FilterExact, Value: "Go"}}
+
+	count, err := integrator.CountInstances(&Article{}, filters)
+	if err != nil || count != 7 {
+		t.Errorf("expected 7 instances, got %d (%v)", count, err)
+	}
+	groups, err := integrator.CountInstancesByField(&Article{}, "AuthorID", filters)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectedGroups := []adminpanel.GroupCount{{Value: uint(1), Count: 4}, {Value: uint(2), Count: 3}}
+	if !reflect.DeepEqual(groups, expectedGroups) {
+		t.Errorf("expected groups %v, got %v", expectedGroups, groups)
+	}
+	if avg, err := integrator.AggregateField(&Article{}, "AuthorID", adminpanel.AggregateAvg, filters); err != nil || avg != 2.5 {
+		t.Errorf("expected an average of 2.5, got %v (%v)", avg, err)
+	}
+	if sum, err := integrator.AggregateField(&Article{}, "AuthorID", adminpanel.AggregateSum, nil); err != nil || sum != 0 {
+		t.Errorf("expected a sum of 0 without rows, got %v (%v)", sum, err)
+	}
+
+	expected := []string{
+		`SELECT COUNT(*) FROM "article" WHERE "title" = $1`,
+		`SELECT "author", COUNT(*) FROM "article" WHERE "title" = $1 GROUP BY "author" ORDER BY "author"`,
+		`SELECT AVG("author") FROM "article" WHERE "title" = $1`,
+		`SELECT SUM("author") FROM "article"`,
+	}
+	if queries := mockDriver.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected queries %q, got %q", expected, queries)
+	}
+	if _, err = integrator.AggregateField(&Article{}, "AuthorID", adminpanel.AggregateCount, nil); err == nil {
+		t.Error("expected an error for an unsupported aggregate")
+	}
+}
+
+func TestIntegrator_FetchInstancesOnlyFieldWithSearch_SQLite(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectSQLite)
+
+	if _, err := integrator.FetchInstancesOnlyFieldWithSearch(&Article{}, []string{"Title"}, "50%_Off", []string{"Title"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := `SELECT "id", "title" FROM "article" WHERE (LOWER(CAST("title" AS TEXT)) LIKE ? ESCAPE '\')`
+	if statement := mockDriver.Statements[0]; statement.Query != expected || statement.Args[0] != `%50\%\_off%` {
+		t.Errorf("expected %s with an escaped pattern, got %s with %v", expected, statement.Query, statement.Args)
+	}
+}
+
+func TestIntegrator_FetchInstance(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectSQLite)
+	mockDriver.Responses = []MockResponse{
+		{Columns: []string{"id", "title", "author", "content"}, Rows: [][]driver.Value{{int64(4), "Go", int64(2), []byte("Body")}}},
+	}
+
+	instance, err := integrator.FetchInstance(&Article{}, uint(4))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := &Article{ID: 4, Title: "Go", AuthorID: 2, Body: "Body"}
+	if !reflect.DeepEqual(instance, expected) {
+		t.Errorf("expected %+v, got %+v", expected, instance)
+	}
+	if query := mockDriver.Statements[0].Query; query != `SELECT "id", "title", "author", "content" FROM "article" WHERE "id" = ?` {
+		t.Errorf("unexpected query %s", query)
+	}
+
+	_, err = integrator.FetchInstanceOnlyFields(&Article{}, uint(5), []string{"Title"})
+	if !errors.Is(err, sql.ErrNoRows) || !errors.Is(err, adminpanel.ErrInstanceNotFound) {
+		t.Errorf("expected sql.ErrNoRows and adminpanel.ErrInstanceNotFound for a missing row, got %v", err)
+	}
+}
+
+func TestIntegrator_CreateInstance(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectSQLite)
+	mockDriver.Responses = []MockResponse{{LastInsertID: 7, RowsAffected: 1}}
+
+	article := &Article{Title: "Go", AuthorID: 2}
+	if err := integrator.CreateInstanceOnlyFields(article, []string{"Title", "AuthorID"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if article.ID != 7 {
+		t.Errorf("expected the generated primary key to be set, got %d", article.ID)
+	}
+	if query := mockDriver.Statements[0].Query; query != `INSERT INTO "article" ("title", "author") VALUES (?, ?)` {
+		t.Errorf("unexpected query %s", query)
+	}
+
+	integrator, mockDriver = newMockIntegrator(t, DialectPostgres)
+	mockDriver.Responses = []MockResponse{{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(9)}}}}
+	article = &Article{Title: "SQL"}
+	if err := integrator.CreateInstance(article); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if article.ID != 9 {
+		t.Errorf("expected the returned primary key to be set, got %d", article.ID)
+	}
+	if query := mockDriver.Statements[0].Query; query != `INSERT INTO "article" ("title", "author", "content") VALUES ($1, $2, $3) RETURNING "id"` {
+		t.Errorf("unexpected query %s", query)
+	}
+
+	if err := integrator.CreateInstance(&Tag{Slug: "go", Name: "Go"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if query := mockDriver.Statements[1].Query; query != `INSERT INTO "tags" ("slug", "name") VALUES ($1, $2)` {
+		t.Errorf("expected a given primary key to be inserted, got %s", query)
+	}
+}
+
+func TestIntegrator_UpdateAndDelete(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectPostgres)
+	mockDriver.Responses = []MockResponse{{RowsAffected: 1}, {RowsAffected: 0}}
+
+	if err := integrator.UpdateInstanceOnlyFields(&Article{ID: 4, Title: "Go"}, []string{"ID", "Title"}, uint(4)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if statement := mockDriver.Statements[0]; statement.Query != `UPDATE "article" SET "title" = $1 WHERE "id" = $2` ||
+		!reflect.DeepEqual(statement.Args, []driver.Value{"Go", int64(4)}) {
+		t.Errorf("unexpected statement %+v", statement)
+	}
+
+	if err := integrator.DeleteByID(&Article{}, uint(5)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a missing row, got %v", err)
+	}
+	if query := mockDriver.Statements[1].Query; query != `DELETE FROM "article" WHERE "id" = $1` {
+		t.Errorf("unexpected query %s", query)
+	}
+}
+
+func TestIntegrator_UpdateInstanceOnlyFieldsIfVersion(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectSQLite)
+	mockDriver.Responses = []MockResponse{{RowsAffected: 1}, {RowsAffected: 0}}
+
+	article := &Article{ID: 4, Title: "Go", AuthorID: 8}
+	updated, err := integrator.UpdateInstanceOnlyFieldsIfVersion(article, []string{"Title", "AuthorID"}, uint(4), "AuthorID", uint(7))
+	if err != nil || !updated {
+		t.Fatalf("expected the row to be updated, got %v (%v)", updated, err)
+	}
+	if statement := mockDriver.Statements[0]; statement.Query != `UPDATE "article" SET "title" = ?, "author" = ? WHERE "id" = ? AND "author" = ?` ||
+		!reflect.DeepEqual(statement.Args, []driver.Value{"Go", int64(8), int64(4), int64(7)}) {
+		t.Errorf("unexpected statement %+v", statement)
+	}
+
+	updated, err = integrator.UpdateInstanceOnlyFieldsIfVersion(article, []string{"Title", "AuthorID"}, uint(4), "AuthorID", uint(7))
+	if err != nil || updated {
+		t.Errorf("expected a stale version not to update the row, got %v (%v)", updated, err)
+	}
+	if _, err = integrator.UpdateInstanceOnlyFieldsIfVersion(article, []string{"Title"}, uint(4), "Draft", true); err == nil {
+		t.Error("expected an error for an ignored version field")
+	}
+}
+
+func TestIntegrator_WithTransaction(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectSQLite)
+
+	err := integrator.WithTransaction(func(tx adminpanel.ORMIntegrator) error {
+		if err := tx.DeleteInstance(&Article{}, uint(1)); err != nil {
+			return err
+		}
+		return tx.(adminpanel.TransactionalORMIntegrator).WithTransaction(func(nested adminpanel.ORMIntegrator) error {
+			return nested.DeleteInstance(&Article{}, uint(2))
+		})
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []string{"BEGIN", `DELETE FROM "article" WHERE "id" = ?`, `DELETE FROM "article" WHERE "id" = ?`, "COMMIT"}
+	if queries := mockDriver.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected %q, got %q", expected, queries)
+	}
+
+	mockDriver.Statements = nil
+	failure := errors.New("failure")
+	err = integrator.WithTransaction(func(tx adminpanel.ORMIntegrator) error {
+		_ = tx.DeleteInstance(&Article{}, uint(1))
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+	expected = []string{"BEGIN", `DELETE FROM "article" WHERE "id" = ?`, "ROLLBACK"}
+	if queries := mockDriver.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected %q, got %q", expected, queries)
+	}
+}
+
+func TestIntegrator_WithContext(t *testing.T) {
+	integrator, mockDriver := newMockIntegrator(t, DialectSQLite)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bound := integrator.WithContext(ctx)
+	if _, err := bound.FetchInstances(&Article{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled context to stop the query, got %v", err)
+	}
+	if err := bound.(adminpanel.TransactionalORMIntegrator).WithTransaction(func(adminpanel.ORMIntegrator) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled context to stop the transaction, got %v", err)
+	}
+	if len(mockDriver.Statements) != 0 {
+		t.Errorf("expected no statement to reach the database, got %q", mockDriver.Queries())
+	}
+	if _, err := integrator.FetchInstances(&Article{}); err != nil {
+		t.Errorf("expected the original integrator to be unaffected, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package sqldb
+
+import (
+	"fmt"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"strconv"
+	"strings"
+)
+
+// Dialect selects the SQL flavor of the generated queries.
+type Dialect string
+
+const (
+	// DialectSQLite uses question mark placeholders and reads generated primary keys through
+	// sql.Result.LastInsertId.
+	DialectSQLite Dialect = "sqlite"
+	// DialectPostgres numbers placeholders as $1, $2, … and reads generated primary keys with RETURNING.
+	DialectPostgres Dialect = "postgres"
+)
+
+// filterOperators maps the filter operators of list queries to SQL.
+var filterOperators = map[adminpanel.FilterOperator]string{
+	adminpanel.FilterExact: "=",
+	adminpanel.FilterGTE:   ">=",
+	adminpanel.FilterLTE:   "<=",
+	adminpanel.FilterLT:    "<",
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, using a backslash as escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// query builds an SQL statement together with its arguments.
+type query struct {
+	dialect Dialect
+	sql     strings.Builder
+	args    []interface{}
+}
+
+func (d Dialect) newQuery() *query {
+	return &query{dialect: d}
+}
+
+// write appends SQL to the statement.
+func (q *query) write(parts ...string) *query {
+	for _, part := range parts {
+		q.sql.WriteString(part)
+	}
+	return q
+}
+
+// arg adds an argument and returns its placeholder.
+func (q *query) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	if q.dialect == DialectPostgres {
+		return "$" + strconv.Itoa(len(q.args))
+	}
+	return "?"
+}
+
+func (q *query) String() string {
+	return q.sql.String()
+}
+
+// quote quotes an identifier. Both SQLite and Postgres accept double quotes.
+func quote(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+// columnList returns the quoted names of columns separated by commas.
+func columnList(columns []*column) string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = quote(col.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeWhere appends the WHERE clause matching the search query and the filters, if any.
+func (q *query) writeWhere(s *schema, search string, searchFields []string, filters []adminpanel.FilterCondition) error {
+	var conditions []string
+	if search != "" {
+		searchColumns, err := s.columnsFor(searchFields, false)
+		if err != nil {
+			return err
+		}
+		if len(searchColumns) > 0 {
+			pattern := "%" + likeEscaper.Replace(strings.ToLower(search)) + "%"
+			matches := make([]string, len(searchColumns))
+			for i, col := range searchColumns {
+				matches[i] = fmt.Sprintf(`LOWER(CAST(%s AS TEXT)) LIKE %s ESCAPE '\'`, quote(col.name), q.arg(pattern))
+			}
+			conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+		}
+	}
+	for _, filter := range filters {
+		col, ok := s.fields[filter.Field]
+		if !ok {
+			return fmt.Errorf("model %s has no field %s", s.typ.Name(), filter.Field)
+		}
+		if filter.Operator == adminpanel.FilterIsNull {
+			if isNull, _ := filter.Value.(bool); isNull {
+				conditions = append(conditions, quote(col.name)+" IS NULL")
+			} else {
+				conditions = append(conditions, quote(col.name)+" IS NOT NULL")
+			}
+			continue
+		}
+		operator, ok := filterOperators[filter.Operator]
+		if !ok {
+			return fmt.Errorf("unsupported filter operator %q", filter.Operator)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s %s", quote(col.name), operator, q.arg(filter.Value)))
+	}
+	if len(conditions) > 0 {
+		q.write(" WHERE ", strings.Join(conditions, " AND "))
+	}
+	return nil
+}
+
+// withPrimaryKeyOrdering returns ordering followed by the primary key, unless it already orders by it.
+func (s *schema) withPrimaryKeyOrdering(ordering []adminpanel.OrderBy) []adminpanel.OrderBy {
+	for _, order := range ordering {
+		if order.Field == s.pk.field {
+			return ordering
+		}
+	}
+	return append(append([]adminpanel.OrderBy(nil), ordering...), adminpanel.OrderBy{Field: s.pk.field})
+}
+
+// writeOrderBy appends the ORDER BY clause of an ordering, if any.
+func (q *query) writeOrderBy(s *schema, ordering []adminpanel.OrderBy) error {
+	if len(ordering) == 0 {
+		return nil
+	}
+	terms := make([]string, len(ordering))
+	for i, order := range ordering {
+		col, ok := s.fields[order.Field]
+		if !ok {
+			return fmt.Errorf("model %s has no field %s", s.typ.Name(), order.Field)
+		}
+		terms[i] = quote(col.name) + " ASC"
+		if order.Descending {
+			terms[i] = quote(col.name) + " DESC"
+		}
+	}
+	q.write(" ORDER BY ", strings.Join(terms, ", "))
+	return nil
+}
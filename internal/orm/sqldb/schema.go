@@ -0,0 +1,161 @@
+package sqldb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// TableNamer is implemented by models that choose the name of their table. Other models are stored in the table
+// named after their struct type in snake case.
+type TableNamer interface {
+	TableName() string
+}
+
+// column maps a struct field to a table column.
+type column struct {
+	field string
+	name  string
+	index int
+}
+
+// schema maps a model struct to its table.
+type schema struct {
+	typ     reflect.Type
+	table   string
+	columns []*column
+	fields  map[string]*column
+	ignored map[string]bool
+	pk      *column
+}
+
+// schemaCache parses each model type once.
+type schemaCache struct {
+	schemas sync.Map
+}
+
+// get returns the schema of a model, given as a struct or a pointer to a struct.
+func (c *schemaCache) get(model interface{}) (*schema, error) {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model must be a struct or a pointer to a struct, got %T", model)
+	}
+	if cached, ok := c.schemas.Load(typ); ok {
+		return cached.(*schema), nil
+	}
+	s, err := parseSchema(typ)
+	if err != nil {
+		return nil, err
+	}
+	c.schemas.Store(typ, s)
+	return s, nil
+}
+
+// parseSchema maps the exported fields of a struct to columns. The column name comes from the `db` tag or the
+// `admin:"column:name"` tag and defaults to the field name in snake case; `db:"-"` leaves a field out. The primary
+// key is the field tagged `admin:"pk"`, or the field named ID.
+func parseSchema(typ reflect.Type) (*schema, error) {
+	s := &schema{
+		typ:     typ,
+		table:   toSnakeCase(typ.Name()),
+		fields:  make(map[string]*column),
+		ignored: make(map[string]bool),
+	}
+	if namer, ok := reflect.New(typ).Interface().(TableNamer); ok {
+		s.table = namer.TableName()
+	}
+
+	var idColumn, taggedPK *column
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		dbName, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+		if dbName == "-" {
+			s.ignored[field.Name] = true
+			continue
+		}
+
+		col := &column{field: field.Name, name: toSnakeCase(field.Name), index: i}
+		isPK := false
+		for _, option := range strings.Split(field.Tag.Get("admin"), ";") {
+			key, value, _ := strings.Cut(option, ":")
+			switch key {
+			case "column":
+				col.name = value
+			case "pk":
+				isPK = true
+			}
+		}
+		if dbName != "" {
+			col.name = dbName
+		}
+
+		if isPK {
+			if taggedPK != nil {
+				return nil, fmt.Errorf("model %s has more than one field tagged as primary key", typ.Name())
+			}
+			taggedPK = col
+		}
+		if field.Name == "ID" {
+			idColumn = col
+		}
+		s.columns = append(s.columns, col)
+		s.fields[col.field] = col
+	}
+
+	s.pk = taggedPK
+	if s.pk == nil {
+		s.pk = idColumn
+	}
+	if s.pk == nil {
+		return nil, fmt.Errorf("model %s has no primary key, name a field ID or tag it with `admin:\"pk\"`", typ.Name())
+	}
+	return s, nil
+}
+
+// columnsFor returns the columns of the given fields, leaving out fields tagged `db:"-"`. The primary key is
+// prepended when withPK is set and it is not already listed.
+func (s *schema) columnsFor(fields []string, withPK bool) ([]*column, error) {
+	columns := make([]*column, 0, len(fields)+1)
+	if withPK {
+		columns = append(columns, s.pk)
+	}
+	for _, field := range fields {
+		col, ok := s.fields[field]
+		if !ok {
+			if s.ignored[field] {
+				continue
+			}
+			return nil, fmt.Errorf("model %s has no field %s", s.typ.Name(), field)
+		}
+		if withPK && col == s.pk {
+			continue
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// toSnakeCase converts a Go identifier such as UserID to snake case such as user_id.
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				builder.WriteRune('_')
+			}
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
@@ -0,0 +1,238 @@
+package nethttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// maxMemory is the number of bytes of a multipart form kept in memory, the rest being stored in temporary files.
+const maxMemory = 32 << 20
+
+// Context carries the request being handled and its response writer. It is the ctx handed to the handlers of the
+// admin panel, and so to the permission function and the user fetcher. It also keeps the values set with
+// SetRequestValue.
+type Context struct {
+	Request *http.Request
+	Writer  http.ResponseWriter
+
+	values map[interface{}]interface{}
+	// written reports whether a JSON response was sent, after which errors can no longer be reported to the client.
+	written bool
+}
+
+// Integrator is an adminpanel.WebIntegrator registering the routes of the admin panel on an http.ServeMux, with the
+// method and wildcard patterns of Go 1.22. Paths are registered as given, so a panel with a GroupPrefix is mounted by
+// serving the integrator under that prefix with http.StripPrefix.
+type Integrator struct {
+	mux *http.ServeMux
+}
+
+// NewIntegrator creates an integrator registering routes on mux, or on a new http.ServeMux if mux is nil.
+func NewIntegrator(mux *http.ServeMux) *Integrator {
+	if mux == nil {
+		mux = http.NewServeMux()
+	}
+	return &Integrator{mux: mux}
+}
+
+// ServeHTTP serves a request with the routes registered on the mux of the integrator.
+func (i *Integrator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i.mux.ServeHTTP(w, r)
+}
+
+// Mux returns the http.ServeMux the routes are registered on.
+func (i *Integrator) Mux() *http.ServeMux {
+	return i.mux
+}
+
+// Pattern converts a route of the admin panel to an http.ServeMux pattern: ":name" segments become "{name}"
+// wildcards, "*name" segments become "{name...}", and the empty path matches "/" alone.
+func Pattern(method, routePath string) string {
+	segments := strings.Split(strings.Trim(routePath, "/"), "/")
+	for index, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			segments[index] = "{" + segment[1:] + "}"
+		case strings.HasPrefix(segment, "*"):
+			segments[index] = "{" + segment[1:] + "...}"
+		}
+	}
+	pattern := "/" + strings.Join(segments, "/")
+	if pattern == "/" {
+		pattern = "/{$}"
+	}
+	return method + " " + pattern
+}
+
+// handle registers handler for a route of the admin panel.
+func (i *Integrator) handle(method, routePath string, handler func(ctx *Context)) {
+	i.mux.HandleFunc(Pattern(method, routePath), func(w http.ResponseWriter, r *http.Request) {
+		handler(&Context{Request: r, Writer: w})
+	})
+}
+
+// HandleRoute registers a route rendering HTML. Handlers returning a 3xx status code redirect to the URL they return.
+func (i *Integrator) HandleRoute(method, routePath string, handler adminpanel.HandlerFunc) {
+	i.HandleResponseRoute(method, routePath, func(ctx interface{}) adminpanel.Response {
+		return adminpanel.NewHandlerResponse(handler(ctx))
+	})
+}
+
+// HandleJSONRoute registers a route responding with JSON through SetJSONResponse. Errors returned by handler are sent
+// as a 500 response, or logged when a response was already sent.
+func (i *Integrator) HandleJSONRoute(method, routePath string, handler adminpanel.JSONHandlerFunc) {
+	i.handle(method, routePath, func(ctx *Context) {
+		err := handler(ctx)
+		switch {
+		case err == nil:
+		case ctx.written:
+			log.Printf("%s %s: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+		default:
+			http.Error(ctx.Writer, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// HandleResponseRoute registers a route sending the Response of handler. A body failing to be written after its
+// first bytes aborts the response, so that clients do not mistake it for a complete one.
+func (i *Integrator) HandleResponseRoute(method, routePath string, handler adminpanel.ResponseHandlerFunc) {
+	i.handle(method, routePath, func(ctx *Context) {
+		if err := handler(ctx).WriteHTTP(ctx.Writer); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+// ServeAssets serves the assets of renderer under prefix, with a content type guessed from their extension.
+func (i *Integrator) ServeAssets(prefix string, renderer adminpanel.TemplateRenderer) {
+	i.handle(http.MethodGet, prefix+"/*name", func(ctx *Context) {
+		name := ctx.Request.PathValue("name")
+		asset, err := renderer.GetAsset(name)
+		if err != nil {
+			http.NotFound(ctx.Writer, ctx.Request)
+			return
+		}
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = http.DetectContentType(asset)
+		}
+		ctx.Writer.Header().Set("Content-Type", contentType)
+		_, _ = ctx.Writer.Write(asset)
+	})
+}
+
+// GetQueryParam returns the first value of the named query parameter.
+func (i *Integrator) GetQueryParam(ctx interface{}, name string) string {
+	return ctx.(*Context).Request.URL.Query().Get(name)
+}
+
+// GetPathParam returns the value of the named wildcard of the route.
+func (i *Integrator) GetPathParam(ctx interface{}, name string) string {
+	return ctx.(*Context).Request.PathValue(name)
+}
+
+// GetRequestMethod returns the HTTP method of the request.
+func (i *Integrator) GetRequestMethod(ctx interface{}) string {
+	return ctx.(*Context).Request.Method
+}
+
+// GetHeader returns the first value of the named header of the request.
+func (i *Integrator) GetHeader(ctx interface{}, name string) string {
+	return ctx.(*Context).Request.Header.Get(name)
+}
+
+// GetCookie returns the value of the named cookie of the request, or an empty value if it has none.
+func (i *Integrator) GetCookie(ctx interface{}, name string) string {
+	cookie, err := ctx.(*Context).Request.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// SetCookie adds a cookie to the response. It must be called before the response is written.
+func (i *Integrator) SetCookie(ctx interface{}, cookie *http.Cookie) {
+	http.SetCookie(ctx.(*Context).Writer, cookie)
+}
+
+// GetResponseCookie returns the value of the last cookie with the given name set on the response, and whether one was
+// set.
+func (i *Integrator) GetResponseCookie(ctx interface{}, name string) (string, bool) {
+	cookies := ctx.(*Context).Writer.Header().Values("Set-Cookie")
+	for j := len(cookies) - 1; j >= 0; j-- {
+		if cookie, err := http.ParseSetCookie(cookies[j]); err == nil && cookie.Name == name {
+			return cookie.Value, true
+		}
+	}
+	return "", false
+}
+
+// GetFormData returns the values of the URL-encoded or multipart form in the body of the request.
+func (i *Integrator) GetFormData(ctx interface{}) map[string][]string {
+	request := ctx.(*Context).Request
+	if err := request.ParseMultipartForm(maxMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return map[string][]string{}
+	}
+	return request.PostForm
+}
+
+// GetFormFile returns the name and content of the file uploaded in the named field of a multipart form.
+func (i *Integrator) GetFormFile(ctx interface{}, name string) (string, io.ReadCloser, error) {
+	file, header, err := ctx.(*Context).Request.FormFile(name)
+	if err != nil {
+		return "", nil, err
+	}
+	return header.Filename, file, nil
+}
+
+// GetRequestContext returns the context of the request.
+func (i *Integrator) GetRequestContext(ctx interface{}) context.Context {
+	return ctx.(*Context).Request.Context()
+}
+
+// GetRequestValue returns the value kept under key for the request, or nil if there is none.
+func (i *Integrator) GetRequestValue(ctx interface{}, key interface{}) interface{} {
+	return ctx.(*Context).values[key]
+}
+
+// SetRequestValue keeps value under key for the rest of the request.
+func (i *Integrator) SetRequestValue(ctx interface{}, key, value interface{}) {
+	c := ctx.(*Context)
+	if c.values == nil {
+		c.values = make(map[interface{}]interface{})
+	}
+	c.values[key] = value
+}
+
+// SetJSONResponse writes data as the JSON response, with the given status code.
+func (i *Integrator) SetJSONResponse(ctx interface{}, statusCode int, data interface{}) error {
+	c := ctx.(*Context)
+	c.written = true
+	writer := c.Writer
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	return json.NewEncoder(writer).Encode(data)
+}
+
+// GetJSONBody decodes the JSON object in the body of the request. Numbers are decoded as json.Number, so that large
+// primary keys keep their precision.
+func (i *Integrator) GetJSONBody(ctx interface{}) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(ctx.(*Context).Request.Body)
+	decoder.UseNumber()
+	var body map[string]interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, errors.New("request body is not a JSON object")
+	}
+	return body, nil
+}
@@ -0,0 +1,344 @@
+package nethttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/ovnicraft/go-advanced-admin/internal/adminpanel"
+	"github.com/ovnicraft/go-advanced-admin/internal/orm/memory"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Book struct {
+	ID    uint
+	Title string `admin:"required"`
+	Pages int
+}
+
+// newTestPanel returns an integrator serving an admin panel with a seeded Book model, stored in memory.
+func newTestPanel(t *testing.T) (*Integrator, *memory.Integrator) {
+	orm := memory.NewIntegrator()
+	if err := orm.Seed(&Book{Title: "Go in Action", Pages: 300}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	web := NewIntegrator(nil)
+	permissions := func(_ adminpanel.PermissionRequest, ctx interface{}) (bool, error) {
+		if ctx.(*Context).Request == nil {
+			t.Error("expected handlers to receive the request")
+		}
+		return true, nil
+	}
+	config := adminpanel.NewDefaultAdminConfig()
+	config.APIPrefix = "api"
+	config.OpenAPIPath = "openapi.json"
+	panel, err := adminpanel.NewAdminPanel(orm, web, permissions, config)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	app, err := panel.RegisterApp("Library", "Library", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = app.RegisterModel(&Book{}, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return web, orm
+}
+
+// serve runs a request against the integrator and returns the recorded response. The request carries a CSRF token in
+// its cookie and header, as the default CSRF token store expects.
+func serve(web *Integrator, method, target, contentType string, body []byte) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, bytes.NewReader(body))
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: "token"})
+	request.Header.Set(adminpanel.CSRFHeader, "token")
+	recorder := httptest.NewRecorder()
+	web.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{"GET", "/admin", "GET /admin"},
+		{"GET", "", "GET /{$}"},
+		{"DELETE", "/admin/a/Library/Book/:id/view", "DELETE /admin/a/Library/Book/{id}/view"},
+		{"GET", "admin-assets/*name", "GET /admin-assets/{name...}"},
+	}
+	for _, tt := range tests {
+		if got := Pattern(tt.method, tt.path); got != tt.expected {
+			t.Errorf("Pattern(%q, %q) = %q, want %q", tt.method, tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestIntegrator_Pages(t *testing.T) {
+	web, orm := newTestPanel(t)
+
+	response := serve(web, http.MethodGet, "/admin/a/Library/Book?search=Action", "", nil)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "Go in Action") {
+		t.Fatalf("expected the list view, got %v", response.Code)
+	}
+	if contentType := response.Header().Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+	if response = serve(web, http.MethodGet, "/admin/a/Library/Book/1/view", "", nil); response.Code != http.StatusOK {
+		t.Errorf("expected the instance view, got %v", response.Code)
+	}
+
+	form := url.Values{"Title": {"Learning Go"}, "Pages": {"250"}}
+	post := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/admin/a/Library/Book/add", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		web.ServeHTTP(recorder, request)
+		return recorder
+	}
+	if response = post(nil); response.Code != http.StatusForbidden {
+		t.Errorf("expected the default CSRF protection to refuse a post without token, got %v", response.Code)
+	}
+
+	response = httptest.NewRecorder()
+	web.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/admin/a/Library/Book/add", nil))
+	cookies := response.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "csrf_token" {
+		t.Fatalf("expected the CSRF cookie to be set, got %v", cookies)
+	}
+	if !strings.Contains(response.Body.String(), `name="csrfToken" value="`+cookies[0].Value+`"`) {
+		t.Errorf("expected the form to carry the token of the cookie")
+	}
+	form.Set(adminpanel.CSRFFormField, cookies[0].Value)
+	if response = post(cookies[0]); response.Code != http.StatusSeeOther || response.Header().Get("Location") == "" {
+		t.Fatalf("expected a redirect after adding, got %v: %s", response.Code, response.Body)
+	}
+	instances, err := orm.FetchInstances(&Book{})
+	if err != nil || len(instances.([]*Book)) != 2 {
+		t.Errorf("expected the book to be added, got %v (%v)", instances, err)
+	}
+
+	response = serve(web, http.MethodGet, "/admin-assets/css/main.css", "", nil)
+	if response.Code != http.StatusOK || !strings.HasPrefix(response.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("expected the stylesheet to be served, got %v %q", response.Code, response.Header().Get("Content-Type"))
+	}
+	if response = serve(web, http.MethodGet, "/admin-assets/missing.css", "", nil); response.Code != http.StatusNotFound {
+		t.Errorf("expected %v for a missing asset, got %v", http.StatusNotFound, response.Code)
+	}
+}
+
+func TestIntegrator_StreamsAndFiles(t *testing.T) {
+	web, _ := newTestPanel(t)
+
+	response := serve(web, http.MethodGet, "/admin/a/Library/Book/export?format=csv", "", nil)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "Go in Action") {
+		t.Fatalf("expected the export to be streamed, got %v", response.Code)
+	}
+	if disposition := response.Header().Get("Content-Disposition"); disposition != `attachment; filename=Book.csv` {
+		t.Errorf("unexpected content disposition %q", disposition)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "books.csv")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, _ = part.Write([]byte("Title,Pages\nConcurrency in Go,238\n"))
+	_ = writer.Close()
+	response = serve(web, http.MethodPost, "/admin/a/Library/Book/import", writer.FormDataContentType(), body.Bytes())
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "Concurrency in Go") {
+		t.Errorf("expected the uploaded file to be previewed, got %v", response.Code)
+	}
+}
+
+func TestIntegrator_JSON(t *testing.T) {
+	web, orm := newTestPanel(t)
+
+	response := serve(web, http.MethodPost, "/admin/api/a/Library/Book", "application/json", []byte(`{"Title": "Learning Go", "Pages": 250}`))
+	if response.Code != http.StatusCreated || response.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected the book to be created, got %v: %s", response.Code, response.Body)
+	}
+	var created adminpanel.JSONResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil || !created.Success {
+		t.Errorf("expected a successful JSON response, got %s (%v)", response.Body, err)
+	}
+
+	response = serve(web, http.MethodPatch, "/admin/api/a/Library/Book/1", "application/json", []byte(`{"Pages": 320}`))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected the book to be updated, got %v: %s", response.Code, response.Body)
+	}
+	book, err := orm.FetchInstance(&Book{}, uint(1))
+	if err != nil || book.(*Book).Pages != 320 || book.(*Book).Title != "Go in Action" {
+		t.Errorf("expected the pages only to be updated, got %v (%v)", book, err)
+	}
+
+	if response = serve(web, http.MethodPost, "/admin/api/a/Library/Book", "application/json", []byte(`[1]`)); response.Code != http.StatusBadRequest {
+		t.Errorf("expected %v for a body that is not an object, got %v", http.StatusBadRequest, response.Code)
+	}
+	if response = serve(web, http.MethodGet, "/admin/openapi.json", "", nil); response.Code != http.StatusOK || !strings.Contains(response.Body.String(), `"openapi":"3.1.0"`) {
+		t.Errorf("expected the OpenAPI document, got %v", response.Code)
+	}
+}
+
+func TestIntegrator_CSRF(t *testing.T) {
+	orm := memory.NewIntegrator()
+	web := NewIntegrator(nil)
+	config := adminpanel.NewDefaultAdminConfig()
+	config.APIPrefix = "api"
+	config.CSRFTokenStore = adminpanel.NewMemoryCSRFTokenStore(func(ctx interface{}) (string, error) {
+		cookie, err := ctx.(*Context).Request.Cookie("session")
+		if err != nil {
+			return "", nil
+		}
+		return cookie.Value, nil
+	})
+	permissions := func(adminpanel.PermissionRequest, interface{}) (bool, error) { return true, nil }
+	panel, err := adminpanel.NewAdminPanel(orm, web, permissions, config)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	app, err := panel.RegisterApp("Library", "Library", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = app.RegisterModel(&Book{}, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	post := func(token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/admin/api/a/Library/Book", strings.NewReader(`{"Title": "Learning Go"}`))
+		request.AddCookie(&http.Cookie{Name: "session", Value: "alice"})
+		request.Header.Set(adminpanel.CSRFHeader, token)
+		recorder := httptest.NewRecorder()
+		web.ServeHTTP(recorder, request)
+		return recorder
+	}
+	if response := post("forged"); response.Code != http.StatusForbidden {
+		t.Errorf("expected %v for a forged token, got %v", http.StatusForbidden, response.Code)
+	}
+	token, err := config.CSRFTokenStore.GetToken(&Context{Request: &http.Request{Header: http.Header{"Cookie": {"session=alice"}}}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if response := post(token); response.Code != http.StatusCreated {
+		t.Errorf("expected the header token to be accepted, got %v: %s", response.Code, response.Body)
+	}
+}
+
+func TestIntegrator_CookieFlashes(t *testing.T) {
+	orm := memory.NewIntegrator()
+	web := NewIntegrator(nil)
+	config := adminpanel.NewDefaultAdminConfig()
+	config.FlashStore = adminpanel.NewCookieFlashStore(web)
+	permissions := func(adminpanel.PermissionRequest, interface{}) (bool, error) { return true, nil }
+	panel, err := adminpanel.NewAdminPanel(orm, web, permissions, config)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	app, err := panel.RegisterApp("Library", "Library", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = app.RegisterModel(&Book{}, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	form := url.Values{"Title": {"Learning Go"}, "Pages": {"250"}}
+	response := serve(web, http.MethodPost, "/admin/a/Library/Book/add", "application/x-www-form-urlencoded", []byte(form.Encode()))
+	cookies := response.Result().Cookies()
+	if response.Code != http.StatusSeeOther || len(cookies) != 1 || cookies[0].Name != "flash" {
+		t.Fatalf("expected a redirect setting the flash cookie, got %v %v", response.Code, cookies)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, response.Header().Get("Location"), nil)
+	request.AddCookie(cookies[0])
+	request.AddCookie(&http.Cookie{Name: "csrf_token", Value: "token"})
+	recorder := httptest.NewRecorder()
+	web.ServeHTTP(recorder, request)
+	if !strings.Contains(recorder.Body.String(), "was added successfully") {
+		t.Errorf("expected the flash message to be shown, got %v", recorder.Code)
+	}
+	if cookies = recorder.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("expected the flash cookie to be expired, got %v", cookies)
+	}
+}
+
+func TestIntegrator_AddFlashTwice(t *testing.T) {
+	web := NewIntegrator(nil)
+	store := adminpanel.NewCookieFlashStore(web)
+	messages := []adminpanel.FlashMessage{
+		{Level: adminpanel.FlashSuccess, Message: "Saved"},
+		{Level: adminpanel.FlashWarning, Message: "Check the stock"},
+	}
+	web.mux.HandleFunc("GET /flash", func(writer http.ResponseWriter, request *http.Request) {
+		ctx := &Context{Request: request, Writer: writer}
+		for _, message := range messages {
+			if err := store.AddFlash(ctx, message); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		}
+	})
+
+	response := serve(web, http.MethodGet, "/flash", "", nil)
+	cookies := response.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatalf("expected the flash cookie to be set")
+	}
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.AddCookie(cookies[len(cookies)-1])
+	popped, err := store.PopFlashes(&Context{Request: request, Writer: httptest.NewRecorder()})
+	if err != nil || !reflect.DeepEqual(popped, messages) {
+		t.Errorf("expected %v, got %v (%v)", messages, popped, err)
+	}
+}
+
+func TestIntegrator_HandleResponseRoute(t *testing.T) {
+	web := NewIntegrator(nil)
+	web.HandleResponseRoute(http.MethodPost, "/admin/session/:name", func(ctx interface{}) adminpanel.Response {
+		response := adminpanel.NewRedirectResponse("/admin")
+		response.SetCookie(&http.Cookie{Name: web.GetPathParam(ctx, "name"), Value: "1", Path: "/admin"})
+		return response
+	})
+
+	response := serve(web, http.MethodPost, "/admin/session/remember", "", nil)
+	if response.Code != http.StatusSeeOther || response.Header().Get("Location") != "/admin" {
+		t.Fatalf("expected a redirect, got %v", response.Code)
+	}
+	if cookie := response.Header().Get("Set-Cookie"); cookie != "remember=1; Path=/admin" {
+		t.Errorf("unexpected cookie %q", cookie)
+	}
+}
+
+func TestIntegrator_HandleJSONRouteError(t *testing.T) {
+	web := NewIntegrator(nil)
+	web.HandleJSONRoute(http.MethodGet, "/admin/failing", func(interface{}) error {
+		return errors.New("connection refused")
+	})
+	web.HandleJSONRoute(http.MethodGet, "/admin/sent", func(ctx interface{}) error {
+		if err := web.SetJSONResponse(ctx, http.StatusOK, map[string]bool{"success": true}); err != nil {
+			return err
+		}
+		return errors.New("connection refused")
+	})
+
+	if response := serve(web, http.MethodGet, "/admin/failing", "", nil); response.Code != http.StatusInternalServerError {
+		t.Errorf("expected %v for an error before the response, got %v", http.StatusInternalServerError, response.Code)
+	}
+	response := serve(web, http.MethodGet, "/admin/sent", "", nil)
+	if response.Code != http.StatusOK || response.Body.String() != "{\"success\":true}\n" {
+		t.Errorf("expected the sent response to be left as is, got %v: %q", response.Code, response.Body)
+	}
+}